@@ -2,6 +2,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -10,9 +11,24 @@ import (
 	"github.com/Mvoii/zurura/internal/db"
 	"github.com/Mvoii/zurura/internal/handlers"
 	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/rbac"
+	"github.com/Mvoii/zurura/internal/services/audit"
 	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/cancellation"
+	"github.com/Mvoii/zurura/internal/services/fares"
+	"github.com/Mvoii/zurura/internal/services/idempotency"
+	"github.com/Mvoii/zurura/internal/services/ledger"
+	"github.com/Mvoii/zurura/internal/services/loyalty"
 	services "github.com/Mvoii/zurura/internal/services/notifications"
+	"github.com/Mvoii/zurura/internal/services/otp"
+	"github.com/Mvoii/zurura/internal/services/passes"
 	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/Mvoii/zurura/internal/services/planner"
+	"github.com/Mvoii/zurura/internal/services/promotions"
+	"github.com/Mvoii/zurura/internal/services/tickets"
+	"github.com/Mvoii/zurura/internal/services/ratelimit"
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/Mvoii/zurura/internal/services/stops"
 	"github.com/Mvoii/zurura/internal/services/tracking"
 
 	"github.com/gin-gonic/gin"
@@ -40,6 +56,9 @@ func main() {
 
 	log.Printf("[LOG] db connected")
 
+	ledgerService := ledger.NewLedgerService(db)
+	idempotencyService := idempotency.NewIdempotencyService(db)
+
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour)
 		defer ticker.Stop()
@@ -52,6 +71,43 @@ func main() {
 			if err != nil {
 				log.Printf("failed to clean up expired toks: %v", err)
 			}
+
+			_, err = db.Exec(`
+				DELETE FROM rate_limit_buckets
+				WHERE updated_at < NOW() - INTERVAL '1 day'
+			`)
+			if err != nil {
+				log.Printf("failed to clean up rate limit buckets: %v", err)
+			}
+
+			_, err = db.Exec(`
+				DELETE FROM login_failures
+				WHERE last_failure_at < NOW() - INTERVAL '1 day'
+				AND (locked_until IS NULL OR locked_until < NOW())
+			`)
+			if err != nil {
+				log.Printf("failed to clean up login failures: %v", err)
+			}
+
+			_, err = db.Exec(`
+				DELETE FROM phone_otps
+				WHERE expires_at < NOW() - INTERVAL '1 day'
+			`)
+			if err != nil {
+				log.Printf("failed to clean up expired otps: %v", err)
+			}
+
+			if _, err := idempotencyService.PurgeExpired(context.Background()); err != nil {
+				log.Printf("failed to clean up idempotency keys: %v", err)
+			}
+
+			report, err := ledgerService.Reconcile(context.Background())
+			if err != nil {
+				log.Printf("failed to reconcile ledger: %v", err)
+			} else if !report.OK {
+				log.Printf("[ERROR] ledger drift: %d passes, %d unbalanced entries, trial balance %.2f",
+					len(report.Drift), len(report.UnbalancedEntries), report.TrialBalance)
+			}
 		}
 		log.Printf("[LOG] cleared expired tokens")
 	}()
@@ -63,24 +119,139 @@ func main() {
 	// Initialize payment service
 	paymentService := payments.NewMockPaymentService()
 
+	notificationHandler := handlers.NewNotificationHandler(db)
+	notificationService := services.NewNotificationService(db, notificationHandler)
+
 	// Initialize booking service with payment service
-	bookingService := booking.NewBookingService(db, paymentService)
+	bookingService := booking.NewBookingService(db, paymentService, notificationService)
+
+	// rate limiting, in memory for a single node or in postgres when running replicas
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	var lockoutStore ratelimit.LockoutStore = ratelimit.NewMemoryLockoutStore()
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(db)
+		lockoutStore = ratelimit.NewPostgresLockoutStore(db)
+	}
+
+	loginLimit := middleware.RateLimitConfig{
+		Name:       "login",
+		PerIP:      ratelimit.Limit{Requests: 20, Per: time.Minute, Burst: 10},
+		PerAccount: ratelimit.Limit{Requests: 5, Per: time.Minute, Burst: 5},
+		AccountKey: middleware.AccountFromJSON("email"),
+	}
+	registerLimit := middleware.RateLimitConfig{
+		Name:       "register",
+		PerIP:      ratelimit.Limit{Requests: 5, Per: time.Hour, Burst: 3},
+		PerAccount: ratelimit.Limit{Requests: 3, Per: time.Hour, Burst: 3},
+		AccountKey: middleware.AccountFromJSON("email"),
+	}
+	otpLimit := middleware.RateLimitConfig{
+		Name:       "otp",
+		PerIP:      ratelimit.Limit{Requests: 10, Per: time.Hour, Burst: 5},
+		PerAccount: ratelimit.Limit{Requests: 10, Per: time.Hour, Burst: 5},
+		AccountKey: middleware.PhoneFromJSON("phone_number"),
+	}
+	bookingLimit := middleware.RateLimitConfig{
+		Name:       "booking",
+		PerIP:      ratelimit.Limit{Requests: 30, Per: time.Minute, Burst: 10},
+		PerAccount: ratelimit.Limit{Requests: 10, Per: time.Minute, Burst: 5},
+		AccountKey: middleware.AccountFromUser,
+	}
 
 	// Initialize handlers
 	bookingHandler := handlers.NewBookingHandler(db, bookingService)
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, lockoutStore)
 	userHandler := handlers.NewUserHandler(db)
 	//bussHandler := handlers
 	scheduleHandler := handlers.NewScheduleHandler(db)
 	trackingService := tracking.NewTrackingService(db)
 	trackingHandler := handlers.NewTrackingHandler(trackingService)
-	routeHandler := handlers.NewRouteHandler(db)
+	fareService := fares.NewFareService(db)
+	plannerService := planner.NewPlannerService(db, fareService)
+	if err := plannerService.Refresh(context.Background()); err != nil {
+		log.Printf("failed to load journey planner network: %v", err)
+	}
+	routeHandler := handlers.NewRouteHandler(db, plannerService)
+	plannerHandler := handlers.NewPlannerHandler(plannerService)
+	stopHandler := handlers.NewStopHandler(stops.NewStopService(db))
 	//trackingHandler :=
 	// bookingHandler :=
 	// paymentHander :=
-	operatorHandler := handlers.NewOperatorHandler(db)
-	notificationHandler := handlers.NewNotificationHandler(db)
-	notificationService := services.NewNotificationService(db, notificationHandler)
+	auditService := audit.NewAuditService(db)
+	operatorHandler := handlers.NewOperatorHandler(db, auditService)
+	otpService := otp.NewOTPService(db, notificationService)
+	otpHandler := handlers.NewOTPHandler(db, otpService)
+	staffHandler := handlers.NewStaffHandler(db, notificationService, auditService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	routeService := routes.NewRouteService(db, notificationService, auditService, plannerService)
+	routeVersionHandler := handlers.NewRouteVersionHandler(routeService)
+	routeShapeHandler := handlers.NewRouteShapeHandler(routeService)
+	kycHandler := handlers.NewKYCHandler(db, auditService)
+	adminHandler := handlers.NewAdminHandler(db, auditService, notificationService)
+	passService := passes.NewPassService(db, paymentService, auditService, notificationService)
+	passHandler := handlers.NewPassHandler(passService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	loyaltyHandler := handlers.NewLoyaltyHandler(loyalty.NewLoyaltyService(db))
+	promoHandler := handlers.NewPromoHandler(promotions.NewPromotionService(db))
+	fareHandler := handlers.NewFareHandler(bookingService, fareService)
+	cancellationHandler := handlers.NewCancellationHandler(bookingService, cancellation.NewCancellationService(db))
+	ticketService, err := tickets.NewTicketService(db)
+	if err != nil {
+		log.Fatal("Failed to load ticket signing key:", err)
+	}
+	ticketHandler := handlers.NewTicketHandler(ticketService)
+	waitlistHandler := handlers.NewWaitlistHandler(bookingService)
+	journeyHandler := handlers.NewJourneyHandler(bookingService)
+
+	// pass reminders, renewals and expiry
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := passService.ProcessExpirations(context.Background()); err != nil {
+				log.Printf("failed to process pass expirations: %v", err)
+			}
+		}
+	}()
+
+	// booking lifecycle: settle pending payments, mark no-shows and complete
+	// trips that have ended
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := bookingService.ProcessPendingPayments(context.Background()); err != nil {
+				log.Printf("failed to process pending payments: %v", err)
+			}
+			if _, err := bookingService.ProcessNoShows(context.Background()); err != nil {
+				log.Printf("failed to process no-shows: %v", err)
+			}
+			if _, err := bookingService.ProcessCompletions(context.Background()); err != nil {
+				log.Printf("failed to complete bookings: %v", err)
+			}
+		}
+	}()
+
+	// seat holds and waitlists: release expired holds, pass lapsed seat
+	// offers on and offer seats that freed up, then send refunds still owed
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := bookingService.ProcessSeatHolds(context.Background()); err != nil {
+				log.Printf("failed to release expired seat holds: %v", err)
+			}
+			if _, err := bookingService.ProcessWaitlist(context.Background()); err != nil {
+				log.Printf("failed to process waitlists: %v", err)
+			}
+			if _, err := bookingService.ProcessRefunds(context.Background()); err != nil {
+				log.Printf("failed to send refunds: %v", err)
+			}
+		}
+	}()
 
 	/// go routine to start broadcasting for websockets
 	go notificationHandler.StartBroadcasting()
@@ -97,6 +268,7 @@ func main() {
 	r.Use(
 		middleware.CORS(),
 		gin.Recovery(),
+		middleware.RequestID(),
 	)
 
 	// Setup static file serving for uploaded files
@@ -107,11 +279,21 @@ func main() {
 	{
 		public := api.Group("/")
 		{
-			public.POST("/auth/login", authHandler.Login)
-			public.POST("/auth/register", authHandler.Register)
-			public.POST("/auth/register/op", authHandler.RegisterOperator)
+			public.POST("/auth/login", middleware.RateLimit(rateLimitStore, loginLimit), authHandler.Login)
+			public.POST("/auth/register", middleware.RateLimit(rateLimitStore, registerLimit), authHandler.Register)
+			public.POST("/auth/register/op", middleware.RateLimit(rateLimitStore, registerLimit), authHandler.RegisterOperator)
+			public.POST("/auth/otp/request", middleware.RateLimit(rateLimitStore, otpLimit), otpHandler.RequestOTP)
+			public.POST("/auth/otp/verify", middleware.RateLimit(rateLimitStore, otpLimit), otpHandler.VerifyOTP)
 
 			public.GET("/schedules", scheduleHandler.ListSchedules)
+			public.GET("/passes/products", passHandler.ListProducts)
+			public.GET("/fares/quote", fareHandler.GetQuote)
+			public.GET("/journeys/plan", journeyHandler.PlanJourney)
+			public.GET("/plan", plannerHandler.Plan)
+			public.GET("/stops", stopHandler.SearchStops)
+			public.GET("/stops/nearby", stopHandler.NearbyStops)
+			public.GET("/stops/:id", stopHandler.GetStop)
+			public.GET("/tickets/public-key", ticketHandler.GetPublicKey)
 
 			public.GET("/bus/:bus_id", operatorHandler.GetBusDetails)
 		}
@@ -127,30 +309,178 @@ func main() {
 			protected.PUT("/me/profile", userHandler.UpdateProfile)
 			protected.POST("/me/profile/photo", userHandler.UploadProfilePhoto)
 
+			// retries of these are safe with an Idempotency-Key header
+			idempotent := middleware.Idempotency(idempotencyService)
+
 			// Add booking routes
-			protected.POST("/bookings", bookingHandler.CreateBooking)
-			protected.POST("/bookings/:id/cancel", bookingHandler.CancelBooking)
+			protected.POST("/bookings", middleware.RateLimit(rateLimitStore, bookingLimit), idempotent, bookingHandler.CreateBooking)
+			protected.POST("/bookings/holds", middleware.RateLimit(rateLimitStore, bookingLimit), bookingHandler.HoldSeats)
+			protected.DELETE("/bookings/holds/:id", bookingHandler.ReleaseSeatHold)
+			protected.POST("/bookings/:id/cancel", idempotent, bookingHandler.CancelBooking)
+			protected.GET("/bookings/:id/cancellation-preview", cancellationHandler.PreviewCancellation)
+			protected.GET("/bookings/:id/ticket", ticketHandler.GetTicket)
+			protected.GET("/bookings/:id/tickets", ticketHandler.GetPassengerTickets)
+			protected.GET("/bookings/:id/passengers", bookingHandler.GetBookingPassengers)
+			protected.GET("/bookings/:id/history", bookingHandler.GetBookingHistory)
 			protected.GET("/me/bookings", bookingHandler.GetUserBookings)
 
+			// multi-leg journeys
+			protected.POST("/journeys", middleware.RateLimit(rateLimitStore, bookingLimit), idempotent, journeyHandler.BookJourney)
+
+			// trip waitlists
+			protected.POST("/waitlist", waitlistHandler.JoinWaitlist)
+			protected.POST("/waitlist/:id/accept", middleware.RateLimit(rateLimitStore, bookingLimit), idempotent, waitlistHandler.AcceptOffer)
+			protected.DELETE("/waitlist/:id", waitlistHandler.LeaveWaitlist)
+			protected.GET("/me/waitlist", waitlistHandler.ListWaitlist)
+
+			// bus passes
+			protected.POST("/passes", middleware.RateLimit(rateLimitStore, bookingLimit), idempotent, passHandler.PurchasePass)
+			protected.POST("/passes/:id/top-up", middleware.RateLimit(rateLimitStore, bookingLimit), idempotent, passHandler.TopUpPass)
+			protected.PUT("/passes/:id/auto-renew", passHandler.SetAutoRenew)
+			protected.POST("/passes/:id/cancel", idempotent, passHandler.CancelPass)
+			protected.GET("/me/passes", passHandler.ListPasses)
+			protected.GET("/me/passes/:id", passHandler.GetPass)
+
+			// loyalty
+			protected.GET("/me/loyalty", loyaltyHandler.GetMyLoyalty)
+
 			// notifs
 			protected.GET("/me/notifications", notificationHandler.GetNotifications)
 			protected.GET("/me/notifications/:notification_id/read", notificationHandler.GetNotificationDetails)
 			protected.POST("/me/notifications/:notification_id/read", notificationHandler.MarkAsRead)
+
+			// operator staff
+			protected.GET("/me/operators", staffHandler.ListMemberships)
+			protected.POST("/me/invitations/accept", staffHandler.AcceptInvitation)
 		}
 
-		protected.Use(middleware.OperatorAuthRequired(db), middleware.RoleRequired("operator"))
+		// operator routes, each checked against the staff member's role permissions
+		op := protected.Group("/op")
+		op.Use(middleware.OperatorMemberRequired(db))
 		{
-			protected.POST("/op/buses", operatorHandler.AddBus)
-			protected.PUT("/op/buses/:id", operatorHandler.UpdateBus)
-			protected.GET("/op/buses", operatorHandler.ListBuses)
+			// verification stays open while the operator is under review
+			manageVerification := middleware.PermissionRequired(rbac.PermManageVerification)
+			op.GET("/kyc", kycHandler.GetVerificationStatus)
+			op.POST("/kyc/documents", manageVerification, kycHandler.UploadDocument)
+		}
 
-			protected.POST("/op/routes", routeHandler.CreateRoute)
-			protected.POST("/op/:route_id/stops", routeHandler.AddStopToRoute)
+		op.Use(middleware.OperatorActiveRequired())
+		{
+			manageBuses := middleware.PermissionRequired(rbac.PermManageBuses)
+			manageRoutes := middleware.PermissionRequired(rbac.PermManageRoutes)
+			manageSchedules := middleware.PermissionRequired(rbac.PermManageSchedules)
+			manageStaff := middleware.PermissionRequired(rbac.PermManageStaff)
+
+			op.POST("/buses", manageBuses, operatorHandler.AddBus)
+			op.PUT("/buses/:id", manageBuses, operatorHandler.UpdateBus)
+			op.GET("/buses", operatorHandler.ListBuses)
+
+			op.POST("/routes", manageRoutes, routeHandler.CreateRoute)
+			op.POST("/:route_id/stops", manageRoutes, routeHandler.AddStopToRoute)
+			op.PATCH("/routes/:route_id", manageRoutes, routeVersionHandler.UpdateRoute)
+			op.GET("/routes/:route_id/versions", manageRoutes, routeVersionHandler.ListVersions)
+			op.POST("/routes/:route_id/versions", manageRoutes, routeVersionHandler.CreateDraft)
+			op.GET("/routes/:route_id/versions/:version", manageRoutes, routeVersionHandler.GetVersion)
+			op.DELETE("/routes/:route_id/versions/:version", manageRoutes, routeVersionHandler.DeleteDraft)
+			op.PUT("/routes/:route_id/versions/:version/stops", manageRoutes, routeVersionHandler.ReplaceStops)
+			op.POST("/routes/:route_id/versions/:version/publish", manageRoutes, routeVersionHandler.Publish)
+			op.PUT("/routes/:route_id/shape", manageRoutes, routeShapeHandler.SetShape)
+			op.POST("/routes/:route_id/shape/trace", manageRoutes, routeShapeHandler.RecordShape)
+			op.DELETE("/routes/:route_id/shape", manageRoutes, routeShapeHandler.DeleteShape)
+			op.PATCH("/stops/:id", manageRoutes, stopHandler.UpdateStop)
+			op.POST("/stops/:id/aliases", manageRoutes, stopHandler.AddStopAlias)
+			op.DELETE("/stops/:id/aliases/:alias_id", manageRoutes, stopHandler.DeleteStopAlias)
+
+			op.POST("/schedules", manageSchedules, scheduleHandler.CreateSchedule)
+			// cancelling a trip refunds everyone booked on it
+			op.POST("/schedules/:id/cancel", manageSchedules, middleware.PermissionRequired(rbac.PermIssueRefunds), cancellationHandler.CancelTrip)
+			op.POST("/buses/:bus_id/assign", manageBuses, operatorHandler.AssignBusToRoute)
+			op.GET("/buses/:bus_id/assignments", operatorHandler.GetBusAssignments)
+			op.PUT("/buses/assignments/:assignment_id", manageBuses, operatorHandler.UpdateBusAssignment)
+
+			op.GET("/staff", manageStaff, staffHandler.ListStaff)
+			op.POST("/staff/invitations", manageStaff, staffHandler.InviteStaff)
+			op.PUT("/staff/:member_id", manageStaff, staffHandler.UpdateStaffRole)
+			op.DELETE("/staff/:member_id", manageStaff, staffHandler.RemoveStaff)
+
+			managePricing := middleware.PermissionRequired(rbac.PermManagePricing)
+			op.GET("/pass-products", managePricing, passHandler.ListManagedProducts)
+			op.POST("/pass-products", managePricing, passHandler.CreateProduct)
+			op.PUT("/pass-products/:id", managePricing, passHandler.UpdateProduct)
+			op.GET("/loyalty-programmes", managePricing, loyaltyHandler.ListProgrammes)
+			op.POST("/loyalty-programmes", managePricing, loyaltyHandler.CreateProgramme)
+			op.PUT("/loyalty-programmes/:id", managePricing, loyaltyHandler.UpdateProgramme)
+			op.GET("/promo-codes", managePricing, promoHandler.ListPromos)
+			op.POST("/promo-codes", managePricing, promoHandler.CreatePromo)
+			op.PUT("/promo-codes/:id", managePricing, promoHandler.UpdatePromo)
+			op.GET("/routes/:route_id/fares", managePricing, fareHandler.GetStopFares)
+			op.PUT("/routes/:route_id/fares", managePricing, fareHandler.ReplaceStopFares)
+			op.GET("/fare-multipliers", managePricing, fareHandler.ListMultipliers)
+			op.POST("/fare-multipliers", managePricing, fareHandler.CreateMultiplier)
+			op.DELETE("/fare-multipliers/:id", managePricing, fareHandler.DeactivateMultiplier)
+			op.GET("/fare-overrides", managePricing, fareHandler.ListOverrides)
+			op.POST("/fare-overrides", managePricing, fareHandler.CreateOverride)
+			op.DELETE("/fare-overrides/:id", managePricing, fareHandler.DeleteOverride)
+
+			issueRefunds := middleware.PermissionRequired(rbac.PermIssueRefunds)
+			op.GET("/cancellation-policies", issueRefunds, cancellationHandler.ListPolicies)
+			op.POST("/cancellation-policies", issueRefunds, cancellationHandler.CreatePolicy)
+			op.PUT("/cancellation-policies/:id", issueRefunds, cancellationHandler.UpdatePolicy)
+
+			op.GET("/revenue", middleware.PermissionRequired(rbac.PermViewRevenue), ledgerHandler.OperatorRevenue)
+
+			validateTickets := middleware.PermissionRequired(rbac.PermValidateTickets)
+			op.POST("/bookings/:id/complete", validateTickets, bookingHandler.CompleteBooking)
+			op.POST("/tickets/scan", validateTickets, ticketHandler.ScanTicket)
+			op.POST("/tickets/sync", validateTickets, ticketHandler.SyncScans)
+
+			viewAudit := middleware.PermissionRequired(rbac.PermViewAudit)
+			op.GET("/audit-logs", viewAudit, auditHandler.ListAuditLogs)
+			op.GET("/audit-logs/export", viewAudit, auditHandler.ExportAuditLogs)
+			op.GET("/audit-logs/verify", viewAudit, auditHandler.VerifyAuditChain)
+		}
 
-			protected.POST("/op/schedules", scheduleHandler.CreateSchedule)
-			protected.POST("/op/buses/:bus_id/assign", operatorHandler.AssignBusToRoute)
-			protected.GET("/op/buses/:bus_id/assignments", operatorHandler.GetBusAssignments)
-			protected.PUT("/op/buses/assignments/:assignment_id", operatorHandler.UpdateBusAssignment)
+		admin := protected.Group("/admin")
+		admin.Use(middleware.AdminRequired(db))
+		{
+			admin.GET("/operators", adminHandler.ListOperators)
+			admin.GET("/operators/:id", adminHandler.GetOperator)
+			admin.POST("/operators/:id/documents", adminHandler.UploadOperatorDocument)
+			admin.POST("/operators/:id/approve", adminHandler.ApproveOperator)
+			admin.POST("/operators/:id/reject", adminHandler.RejectOperator)
+			admin.POST("/operators/:id/suspend", adminHandler.SuspendOperator)
+			admin.POST("/operators/:id/reinstate", adminHandler.ReinstateOperator)
+
+			admin.GET("/kyc/documents/:document_id/file", adminHandler.GetDocumentFile)
+			admin.POST("/kyc/documents/:document_id/review", adminHandler.ReviewDocument)
+
+			admin.GET("/buses", adminHandler.ListBuses)
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+			admin.PUT("/users/:id/student", adminHandler.VerifyStudent)
+
+			admin.GET("/pass-products", passHandler.ListManagedProducts)
+			admin.POST("/pass-products", passHandler.CreateProduct)
+			admin.PUT("/pass-products/:id", passHandler.UpdateProduct)
+
+			admin.GET("/loyalty-programmes", loyaltyHandler.ListProgrammes)
+			admin.POST("/loyalty-programmes", loyaltyHandler.CreateProgramme)
+			admin.PUT("/loyalty-programmes/:id", loyaltyHandler.UpdateProgramme)
+
+			admin.GET("/promo-codes", promoHandler.ListPromos)
+			admin.POST("/promo-codes", promoHandler.CreatePromo)
+			admin.PUT("/promo-codes/:id", promoHandler.UpdatePromo)
+
+			admin.GET("/fare-multipliers", fareHandler.ListMultipliers)
+			admin.POST("/fare-multipliers", fareHandler.CreateMultiplier)
+			admin.DELETE("/fare-multipliers/:id", fareHandler.DeactivateMultiplier)
+
+			admin.GET("/audit-logs", auditHandler.ListAuditLogs)
+			admin.GET("/audit-logs/export", auditHandler.ExportAuditLogs)
+			admin.GET("/audit-logs/verify", auditHandler.VerifyAuditChain)
+
+			admin.GET("/ledger/accounts", ledgerHandler.ListAccounts)
+			admin.GET("/ledger/reconcile", ledgerHandler.Reconcile)
 		}
 
 		driverRoutes := api.Group("/driver")
@@ -0,0 +1,24 @@
+-- Migration for phone number OTP login and signup
+-- Date: 2026-10-19
+
+-- riders who sign up by phone have no email or password
+ALTER TABLE users
+    ALTER COLUMN email DROP NOT NULL,
+    ALTER COLUMN password_hash DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ;
+
+-- one time codes, only the hmac of the code is stored
+CREATE TABLE IF NOT EXISTS phone_otps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    phone_number VARCHAR(20) NOT NULL, -- E.164
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    request_ip VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_phone_otps_phone_created ON phone_otps(phone_number, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_phone_otps_expires_at ON phone_otps(expires_at);
@@ -0,0 +1,19 @@
+-- Migration for the postgres backed rate limiter and login lockout
+-- Date: 2026-10-19
+
+-- token buckets shared between replicas
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+-- failed login counters for exponential lockout
+CREATE TABLE IF NOT EXISTS login_failures (
+    key VARCHAR(255) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
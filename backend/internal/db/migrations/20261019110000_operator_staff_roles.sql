@@ -0,0 +1,35 @@
+-- Migration for operator staff membership and permissions
+-- Date: 2026-10-19
+
+CREATE TABLE IF NOT EXISTS operator_members (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID NOT NULL REFERENCES bus_operators(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id), -- NULL until the invitation is accepted
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'dispatcher', 'conductor', 'accountant')),
+    status VARCHAR(20) NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'active', 'revoked')),
+    invite_token_hash VARCHAR(64),
+    invite_expires_at TIMESTAMPTZ,
+    invited_by UUID REFERENCES users(id),
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operator_members_operator_user ON operator_members(operator_id, user_id) WHERE status <> 'revoked';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operator_members_invite_token ON operator_members(invite_token_hash) WHERE invite_token_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_operator_members_user_id ON operator_members(user_id);
+
+-- existing operator accounts become owners
+INSERT INTO operator_members (operator_id, user_id, email, role, status, accepted_at)
+SELECT o.id, o.user_id, COALESCE(u.email, o.email, ''), 'owner', 'active', NOW()
+FROM bus_operators o
+JOIN users u ON u.id = o.user_id
+WHERE NOT EXISTS (
+    SELECT 1 FROM operator_members m
+    WHERE m.operator_id = o.id AND m.user_id = o.user_id
+);
+
+-- routes belong to the operator that created them, NULL for legacy routes
+ALTER TABLE bus_routes ADD COLUMN IF NOT EXISTS operator_id UUID REFERENCES bus_operators(id);
+CREATE INDEX IF NOT EXISTS idx_bus_routes_operator_id ON bus_routes(operator_id);
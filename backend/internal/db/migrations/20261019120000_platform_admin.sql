@@ -0,0 +1,59 @@
+-- Migration for platform admins, operator verification and the audit log
+-- Date: 2026-10-19
+
+-- platform role, promote the first admin with:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin'));
+
+-- operators are reviewed before they can use the operator endpoints
+ALTER TABLE bus_operators ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending'
+    CHECK (status IN ('pending', 'active', 'suspended', 'rejected'));
+ALTER TABLE bus_operators ADD COLUMN IF NOT EXISTS status_reason TEXT;
+ALTER TABLE bus_operators ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
+ALTER TABLE bus_operators ADD COLUMN IF NOT EXISTS verified_by UUID REFERENCES users(id);
+ALTER TABLE bus_operators ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ;
+
+-- operators that already run buses keep working
+UPDATE bus_operators SET status = 'active', verified_at = NOW()
+WHERE verified_at IS NULL AND status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_bus_operators_status ON bus_operators(status);
+
+CREATE TABLE IF NOT EXISTS operator_kyc_documents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID NOT NULL REFERENCES bus_operators(id) ON DELETE CASCADE,
+    document_type VARCHAR(30) NOT NULL CHECK (document_type IN ('business_registration', 'ntsa_licence')),
+    document_number VARCHAR(100),
+    file_path TEXT NOT NULL,
+    original_filename TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    rejection_reason TEXT,
+    uploaded_by UUID REFERENCES users(id),
+    reviewed_by UUID REFERENCES users(id),
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_operator_kyc_documents_operator ON operator_kyc_documents(operator_id, document_type);
+
+-- db_schema.sql defines audit_logs with a trailing comma so it may not exist yet
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity_type VARCHAR(55) NOT NULL,
+    action VARCHAR(55) NOT NULL,
+    user_id UUID REFERENCES users(id),
+    old_data JSONB,
+    new_data JSONB,
+    user_agent TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS entity_id VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS actor_role VARCHAR(20);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS operator_id UUID REFERENCES bus_operators(id);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_operator ON audit_logs(operator_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
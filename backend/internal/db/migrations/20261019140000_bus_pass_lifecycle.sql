@@ -0,0 +1,50 @@
+-- Migration for bus pass products, purchases, top-ups and renewal
+-- Date: 2026-10-19
+
+-- passes can be cancelled by the rider
+ALTER TYPE pass_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+-- what riders can buy, e.g. a prepaid card or a monthly subscription
+CREATE TABLE IF NOT EXISTS pass_products (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    pass_type pass_type NOT NULL,
+    price FLOAT NOT NULL CHECK (price > 0),
+    credit FLOAT NOT NULL DEFAULT 0 CHECK (credit >= 0), -- balance loaded on purchase and renewal
+    validity_days INT NOT NULL CHECK (validity_days > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO pass_products (name, description, pass_type, price, credit, validity_days)
+SELECT * FROM (VALUES
+    ('Prepaid Card', 'Load KES 500 of ride credit, valid for a year', 'prepaid'::pass_type, 500.0, 500.0, 365),
+    ('Monthly Pass', 'KES 3,000 of ride credit for 30 days', 'subscription'::pass_type, 2700.0, 3000.0, 30)
+) AS seed(name, description, pass_type, price, credit, validity_days)
+WHERE NOT EXISTS (SELECT 1 FROM pass_products);
+
+ALTER TABLE bus_passes ADD COLUMN IF NOT EXISTS product_id UUID REFERENCES pass_products(id);
+ALTER TABLE bus_passes ADD COLUMN IF NOT EXISTS auto_renew BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE bus_passes ADD COLUMN IF NOT EXISTS payment_method payment_method; -- charged on renewal
+ALTER TABLE bus_passes ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMPTZ;
+ALTER TABLE bus_passes ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_bus_passes_expiration ON bus_passes(expiration_date);
+
+-- every change to a pass balance or validity
+CREATE TABLE IF NOT EXISTS pass_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pass_id UUID NOT NULL REFERENCES bus_passes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('purchase', 'top_up', 'renewal', 'ride', 'refund', 'cancellation', 'expiry')),
+    amount FLOAT NOT NULL, -- change to the balance, negative for rides
+    balance_after FLOAT NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pass_transactions_pass ON pass_transactions(pass_id, created_at DESC);
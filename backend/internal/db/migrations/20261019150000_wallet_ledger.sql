@@ -0,0 +1,95 @@
+-- Migration for the double-entry ledger behind bus pass balances
+-- Date: 2026-10-19
+
+-- accounts are named by what they hold, e.g. pass:<pass id>, operator:<operator id>,
+-- clearing:mpesa. bus_passes.balance is kept as a cached copy of the pass account.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    code VARCHAR(100) PRIMARY KEY,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(30) NOT NULL, -- e.g. ride, refund, pass_purchase
+    description TEXT,
+    pass_id UUID REFERENCES bus_passes(id) ON DELETE SET NULL,
+    booking_id UUID REFERENCES bookings(id) ON DELETE SET NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_entries_pass ON journal_entries(pass_id);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_booking ON journal_entries(booking_id);
+
+-- amounts are in cents, debits positive and credits negative
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account_code VARCHAR(100) NOT NULL REFERENCES ledger_accounts(code),
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_entry ON ledger_postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_postings_account ON ledger_postings(account_code);
+
+-- every entry must balance by the time its transaction commits
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+DECLARE
+    total BIGINT;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total FROM ledger_postings WHERE entry_id = NEW.entry_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance (off by %)', NEW.entry_id, total;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+AFTER INSERT ON ledger_postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- postings are corrected with a new entry, never edited
+CREATE OR REPLACE FUNCTION reject_ledger_change() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger postings are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_postings_append_only ON ledger_postings;
+CREATE TRIGGER ledger_postings_append_only
+BEFORE UPDATE OR DELETE ON ledger_postings
+FOR EACH ROW EXECUTE FUNCTION reject_ledger_change();
+
+ALTER TABLE pass_transactions ADD COLUMN IF NOT EXISTS journal_entry_id UUID REFERENCES journal_entries(id);
+
+-- open the ledger with the balances passes hold today
+INSERT INTO ledger_accounts (code, type) VALUES ('equity:opening_balances', 'equity')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO ledger_accounts (code, type)
+SELECT 'pass:' || id, 'liability' FROM bus_passes
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TEMP TABLE opening_entries AS
+SELECT uuid_generate_v4() AS entry_id, bp.id AS pass_id, ROUND(bp.balance * 100)::BIGINT AS cents
+FROM bus_passes bp
+WHERE ROUND(bp.balance * 100)::BIGINT <> 0
+AND NOT EXISTS (SELECT 1 FROM journal_entries je WHERE je.pass_id = bp.id AND je.kind = 'opening_balance');
+
+INSERT INTO journal_entries (id, kind, description, pass_id)
+SELECT entry_id, 'opening_balance', 'Balance held before the ledger', pass_id FROM opening_entries;
+
+INSERT INTO ledger_postings (entry_id, account_code, amount)
+SELECT entry_id, 'pass:' || pass_id, -cents FROM opening_entries
+UNION ALL
+SELECT entry_id, 'equity:opening_balances', cents FROM opening_entries;
+
+DROP TABLE opening_entries;
+
+-- the cached balance now only changes through the ledger, rounded to the cent
+UPDATE bus_passes SET balance = ROUND(balance * 100) / 100.0;
@@ -0,0 +1,28 @@
+-- Migration for time-based unlimited passes and student verification
+-- Date: 2026-10-19
+
+-- unlimited passes cover every ride in their validity period instead of holding a balance
+ALTER TYPE pass_type ADD VALUE IF NOT EXISTS 'unlimited';
+
+-- students are verified by an admin, e.g. from a student id card
+ALTER TABLE users ADD COLUMN IF NOT EXISTS student_verified_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS student_verified_by UUID REFERENCES users(id);
+
+-- operator products are sold by that operator and only cover its buses
+ALTER TABLE pass_products ADD COLUMN IF NOT EXISTS operator_id UUID REFERENCES bus_operators(id);
+-- who can buy: anyone, riders with a school on their profile, or verified students
+ALTER TABLE pass_products ADD COLUMN IF NOT EXISTS eligibility VARCHAR(20) NOT NULL DEFAULT 'anyone'
+    CHECK (eligibility IN ('anyone', 'student', 'verified_student'));
+-- limits student products to these schools, matched case-insensitively
+ALTER TABLE pass_products ADD COLUMN IF NOT EXISTS school_names TEXT[];
+-- passes never run past this, e.g. the last day of a school term
+ALTER TABLE pass_products ADD COLUMN IF NOT EXISTS ends_at TIMESTAMPTZ;
+
+-- routes a product covers, none means every route
+CREATE TABLE IF NOT EXISTS pass_product_routes (
+    product_id UUID NOT NULL REFERENCES pass_products(id) ON DELETE CASCADE,
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, route_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pass_products_operator ON pass_products(operator_id);
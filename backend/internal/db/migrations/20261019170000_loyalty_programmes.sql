@@ -0,0 +1,93 @@
+-- Migration for loyalty programmes, points, tiers and booking line items
+-- Date: 2026-10-19
+
+-- a programme with no operator is run by the platform and applies wherever
+-- the operator does not run its own
+CREATE TABLE IF NOT EXISTS loyalty_programmes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID REFERENCES bus_operators(id),
+    name VARCHAR(100) NOT NULL,
+    -- points for every completed ride plus points for every shilling paid
+    points_per_ride INT NOT NULL DEFAULT 0 CHECK (points_per_ride >= 0),
+    points_per_shilling FLOAT NOT NULL DEFAULT 0 CHECK (points_per_shilling >= 0),
+    -- shillings a point is worth when redeemed against a fare
+    redemption_value FLOAT NOT NULL CHECK (redemption_value > 0),
+    min_redeem_points INT NOT NULL DEFAULT 0 CHECK (min_redeem_points >= 0),
+    -- most of a fare that points can pay for, a booking is never free
+    max_redeem_percent FLOAT NOT NULL DEFAULT 50 CHECK (max_redeem_percent > 0 AND max_redeem_percent < 100),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- one running programme per operator, and one for the platform
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_programmes_active
+    ON loyalty_programmes (COALESCE(operator_id, '00000000-0000-0000-0000-000000000000'::UUID))
+    WHERE active;
+
+-- routes an operator programme covers, none means every route of the operator
+CREATE TABLE IF NOT EXISTS loyalty_programme_routes (
+    programme_id UUID NOT NULL REFERENCES loyalty_programmes(id) ON DELETE CASCADE,
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    PRIMARY KEY (programme_id, route_id)
+);
+
+-- riders move up a tier once their lifetime points reach its threshold
+CREATE TABLE IF NOT EXISTS loyalty_tiers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    programme_id UUID NOT NULL REFERENCES loyalty_programmes(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    threshold_points INT NOT NULL CHECK (threshold_points >= 0),
+    earn_multiplier FLOAT NOT NULL DEFAULT 1 CHECK (earn_multiplier > 0),
+    discount_percent FLOAT NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent < 100),
+    UNIQUE (programme_id, threshold_points)
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_accounts (
+    user_id UUID NOT NULL REFERENCES users(id),
+    programme_id UUID NOT NULL REFERENCES loyalty_programmes(id),
+    points_balance INT NOT NULL DEFAULT 0 CHECK (points_balance >= 0),
+    lifetime_points INT NOT NULL DEFAULT 0,
+    rides INT NOT NULL DEFAULT 0,
+    tier_id UUID REFERENCES loyalty_tiers(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, programme_id)
+);
+
+CREATE TABLE IF NOT EXISTS loyalty_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    programme_id UUID NOT NULL REFERENCES loyalty_programmes(id),
+    booking_id UUID REFERENCES bookings(id),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('earn', 'redeem', 'reverse')),
+    points INT NOT NULL,
+    balance_after INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- a booking earns, redeems and is reversed at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_transactions_booking
+    ON loyalty_transactions (booking_id, type) WHERE booking_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_transactions_user ON loyalty_transactions(user_id, created_at DESC);
+
+-- how a booking's fare was arrived at, discounts are negative
+CREATE TABLE IF NOT EXISTS booking_line_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL DEFAULT 0,
+    type VARCHAR(30) NOT NULL,
+    description TEXT NOT NULL,
+    amount FLOAT NOT NULL,
+    points INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_line_items_booking ON booking_line_items(booking_id);
+
+-- the programme a booking earns in, fixed when it is made
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS loyalty_programme_id UUID REFERENCES loyalty_programmes(id);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- replaced by loyalty programmes, the function was never attached to a trigger
+DROP FUNCTION IF EXISTS apply_loyalty_discount();
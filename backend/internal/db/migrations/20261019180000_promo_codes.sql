@@ -0,0 +1,54 @@
+-- Migration for promo codes and campaign discounts
+-- Date: 2026-10-19
+
+-- a promo with no operator is run by the platform and can apply to any bus
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID REFERENCES bus_operators(id),
+    -- stored upper case, riders can type it in any case
+    code VARCHAR(40) NOT NULL UNIQUE CHECK (code = UPPER(code)),
+    description TEXT,
+    discount_type VARCHAR(10) NOT NULL CHECK (discount_type IN ('percent', 'fixed')),
+    discount_value FLOAT NOT NULL CHECK (discount_value > 0),
+    -- caps a percentage discount, e.g. 50% off up to 100
+    max_discount FLOAT CHECK (max_discount > 0),
+    min_fare FLOAT CHECK (min_fare >= 0),
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMPTZ,
+    -- NULL means no cap
+    max_redemptions INT CHECK (max_redemptions > 0),
+    max_per_user INT CHECK (max_per_user > 0),
+    redemption_count INT NOT NULL DEFAULT 0,
+    -- only for riders without a confirmed or completed booking yet
+    first_ride_only BOOLEAN NOT NULL DEFAULT FALSE,
+    -- whether loyalty tier discounts and points can be used with the code
+    stacks_with_loyalty BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (discount_type <> 'percent' OR discount_value <= 100),
+    CHECK (max_redemptions IS NULL OR redemption_count <= max_redemptions),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- routes a promo covers, none means every route it can apply to
+CREATE TABLE IF NOT EXISTS promo_code_routes (
+    promo_id UUID NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    PRIMARY KEY (promo_id, route_id)
+);
+
+-- reversed when the booking is cancelled, which frees the use again
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    promo_id UUID NOT NULL REFERENCES promo_codes(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    booking_id UUID NOT NULL UNIQUE REFERENCES bookings(id),
+    amount FLOAT NOT NULL,
+    reversed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_codes_operator ON promo_codes(operator_id);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_user ON promo_redemptions(promo_id, user_id) WHERE reversed_at IS NULL;
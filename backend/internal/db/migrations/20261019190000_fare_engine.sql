@@ -0,0 +1,74 @@
+-- Migration for stop-to-stop fares, time of day multipliers, operator fare overrides and fare quotes
+-- Date: 2026-10-19
+
+-- per seat fare between two stops of a route, the route's base_fare applies
+-- to pairs without a row. A pair is priced the same both ways unless the
+-- reverse direction has its own row.
+CREATE TABLE IF NOT EXISTS route_stop_fares (
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    from_stop_id UUID NOT NULL REFERENCES bus_stops(id) ON DELETE CASCADE,
+    to_stop_id UUID NOT NULL REFERENCES bus_stops(id) ON DELETE CASCADE,
+    fare FLOAT NOT NULL CHECK (fare > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (route_id, from_stop_id, to_stop_id),
+    CHECK (from_stop_id <> to_stop_id)
+);
+
+-- e.g. peak hours, off-peak or rain. A multiplier with no operator is set by
+-- the platform, one with a route only applies to that route.
+CREATE TABLE IF NOT EXISTS fare_multipliers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID REFERENCES bus_operators(id),
+    route_id UUID REFERENCES bus_routes(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    multiplier FLOAT NOT NULL CHECK (multiplier > 0 AND multiplier <= 5),
+    -- 0 is Sunday, NULL means every day
+    days_of_week SMALLINT[],
+    -- local (EAT) time of day, NULL means all day. A window may wrap past midnight.
+    start_time TIME,
+    end_time TIME,
+    -- limits the multiplier to a period, e.g. a rainy afternoon
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((start_time IS NULL) = (end_time IS NULL)),
+    CHECK (route_id IS NULL OR operator_id IS NOT NULL)
+);
+
+-- a fixed per seat fare the operator sets for a period, e.g. a holiday flat
+-- fare. Overrides replace the fare table and multipliers.
+CREATE TABLE IF NOT EXISTS fare_overrides (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID NOT NULL REFERENCES bus_operators(id),
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    -- both NULL for the whole route
+    from_stop_id UUID REFERENCES bus_stops(id) ON DELETE CASCADE,
+    to_stop_id UUID REFERENCES bus_stops(id) ON DELETE CASCADE,
+    fare FLOAT NOT NULL CHECK (fare > 0),
+    reason TEXT,
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMPTZ,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((from_stop_id IS NULL) = (to_stop_id IS NULL)),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- a quoted fare is honoured by bookings for the same trip until it expires
+CREATE TABLE IF NOT EXISTS fare_quotes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bus_id UUID NOT NULL REFERENCES buses(id),
+    route_id UUID NOT NULL REFERENCES bus_routes(id),
+    boarding_stop_name VARCHAR(255) NOT NULL,
+    alighting_stop_name VARCHAR(255) NOT NULL,
+    seats INT NOT NULL CHECK (seats > 0),
+    line_items JSONB NOT NULL,
+    total FLOAT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fare_multipliers_operator ON fare_multipliers(operator_id) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_fare_overrides_route ON fare_overrides(route_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_fare_quotes_expires ON fare_quotes(expires_at);
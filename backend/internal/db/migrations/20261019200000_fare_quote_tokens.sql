@@ -0,0 +1,7 @@
+-- Migration for signed fare quote tokens
+-- Date: 2026-10-19
+
+-- quotes are now signed tokens carrying their own fare breakdown, nothing
+-- needs to be kept server side
+DROP INDEX IF EXISTS idx_fare_quotes_expires;
+DROP TABLE IF EXISTS fare_quotes;
@@ -0,0 +1,19 @@
+-- Migration for idempotency keys on booking and payment routes
+-- Date: 2026-10-19
+
+-- the first response to a request made with an Idempotency-Key, replayed
+-- when the client retries with the same key
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope VARCHAR(64) NOT NULL, -- the user the key belongs to
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT, -- NULL while the first request is still running
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
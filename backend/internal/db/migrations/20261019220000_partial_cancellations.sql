@@ -0,0 +1,29 @@
+-- Migration for partial cancellations and per-seat refunds
+-- Date: 2026-10-19
+
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'partially_refunded';
+
+ALTER TABLE payments
+    ADD COLUMN IF NOT EXISTS refunded_amount FLOAT NOT NULL DEFAULT 0;
+
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS refunded_amount FLOAT NOT NULL DEFAULT 0;
+
+-- bookings cancelled before this kept their refund on the payment only
+UPDATE payments SET refunded_amount = amount WHERE payment_status = 'refunded';
+UPDATE bookings b SET refunded_amount = p.amount
+FROM payments p
+WHERE p.booking_id = b.id AND p.payment_status = 'refunded';
+
+-- each cancellation of some or all of a booking's seats
+CREATE TABLE IF NOT EXISTS booking_cancellations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id),
+    seats INT NOT NULL CHECK (seats > 0),
+    seat_numbers TEXT[] NOT NULL DEFAULT '{}',
+    refund_amount FLOAT NOT NULL CHECK (refund_amount >= 0),
+    cancelled_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_cancellations_booking ON booking_cancellations(booking_id);
@@ -0,0 +1,53 @@
+-- Migration for cancellation and refund policies
+-- Date: 2026-10-19
+
+-- an operator's refund rules, for one route or all of its routes
+CREATE TABLE IF NOT EXISTS cancellation_policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    operator_id UUID NOT NULL REFERENCES bus_operators(id),
+    route_id UUID REFERENCES bus_routes(id), -- NULL for all the operator's routes
+    name VARCHAR(100) NOT NULL,
+    no_show_refund_percent NUMERIC(5,2) NOT NULL DEFAULT 0 CHECK (no_show_refund_percent BETWEEN 0 AND 100),
+    no_show_grace_minutes INT NOT NULL DEFAULT 30 CHECK (no_show_grace_minutes >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cancellation_policies_scope
+    ON cancellation_policies(operator_id, COALESCE(route_id, '00000000-0000-0000-0000-000000000000'))
+    WHERE active;
+
+-- cancelling at least min_minutes_before departure refunds refund_percent,
+-- negative values cover cancellations after departure
+CREATE TABLE IF NOT EXISTS cancellation_policy_rules (
+    policy_id UUID NOT NULL REFERENCES cancellation_policies(id) ON DELETE CASCADE,
+    min_minutes_before INT NOT NULL,
+    refund_percent NUMERIC(5,2) NOT NULL CHECK (refund_percent BETWEEN 0 AND 100),
+    PRIMARY KEY (policy_id, min_minutes_before)
+);
+
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'no_show';
+
+ALTER TABLE schedules
+    ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+
+-- the departure a booking is for, the bus's next one when it was booked
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES schedules(id),
+    ADD COLUMN IF NOT EXISTS departs_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_bookings_schedule ON bookings(schedule_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_departs_at ON bookings(departs_at) WHERE status = 'confirmed';
+
+-- seat_value is the cancelled seats' share of the payment before the policy
+-- applied, so withheld money is not refunded by later cancellations
+ALTER TABLE booking_cancellations
+    ADD COLUMN IF NOT EXISTS seat_value FLOAT,
+    ADD COLUMN IF NOT EXISTS refund_percent NUMERIC(5,2) NOT NULL DEFAULT 100,
+    ADD COLUMN IF NOT EXISTS reason VARCHAR(20) NOT NULL DEFAULT 'rider' CHECK (reason IN ('rider', 'operator', 'no_show')),
+    ADD COLUMN IF NOT EXISTS policy_id UUID REFERENCES cancellation_policies(id);
+
+UPDATE booking_cancellations SET seat_value = refund_amount WHERE seat_value IS NULL;
+ALTER TABLE booking_cancellations ALTER COLUMN seat_value SET NOT NULL;
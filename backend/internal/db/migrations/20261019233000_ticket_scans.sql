@@ -0,0 +1,20 @@
+-- Migration for QR e-tickets and boarding scans
+-- Date: 2026-10-19
+
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'boarded';
+
+-- every scan of a ticket, including duplicates and scans synced from
+-- conductors' devices after being collected offline
+CREATE TABLE IF NOT EXISTS ticket_scans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id),
+    bus_id UUID NOT NULL REFERENCES buses(id),
+    scanned_by UUID REFERENCES users(id),
+    device_id VARCHAR(100),
+    result VARCHAR(20) NOT NULL CHECK (result IN ('boarded', 'duplicate')),
+    offline BOOLEAN NOT NULL DEFAULT FALSE,
+    scanned_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticket_scans_booking ON ticket_scans(booking_id);
@@ -0,0 +1,27 @@
+-- Migration for the booking lifecycle and its status history
+-- Date: 2026-10-19
+
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'pending_payment';
+ALTER TYPE booking_status ADD VALUE IF NOT EXISTS 'expired';
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'expired';
+
+-- every status a booking has been through
+CREATE TABLE IF NOT EXISTS booking_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id UUID NOT NULL REFERENCES bookings(id),
+    from_status VARCHAR(20), -- NULL for the status the booking was created in
+    to_status VARCHAR(20) NOT NULL,
+    actor_id UUID REFERENCES users(id),
+    actor_role VARCHAR(20),
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_status_history_booking ON booking_status_history(booking_id);
+CREATE INDEX IF NOT EXISTS idx_bookings_boarded ON bookings(boarded_at) WHERE status = 'boarded';
+
+-- existing bookings start their history in the status they are in now
+INSERT INTO booking_status_history (booking_id, to_status, created_at)
+SELECT b.id, b.status::TEXT, b.created_at
+FROM bookings b
+WHERE NOT EXISTS (SELECT 1 FROM booking_status_history h WHERE h.booking_id = b.id);
@@ -0,0 +1,28 @@
+-- Migration for trip waitlists
+-- Date: 2026-10-19
+
+-- riders waiting for seats on a full trip, offered in the order they joined
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    schedule_id UUID NOT NULL REFERENCES schedules(id),
+    bus_id UUID NOT NULL REFERENCES buses(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    seats INT NOT NULL CHECK (seats > 0),
+    boarding_stop_name VARCHAR(100) NOT NULL,
+    alighting_stop_name VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'offered', 'booked', 'expired', 'left', 'cancelled')),
+    offered_at TIMESTAMPTZ,
+    offer_expires_at TIMESTAMPTZ, -- the offered seats are held until then
+    booking_id UUID REFERENCES bookings(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- a rider is on a trip's waitlist once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_entries_active
+    ON waitlist_entries(schedule_id, user_id) WHERE status IN ('waiting', 'offered');
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_queue
+    ON waitlist_entries(bus_id, created_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_offers
+    ON waitlist_entries(offer_expires_at) WHERE status = 'offered';
@@ -0,0 +1,22 @@
+-- Migration for seat holds during checkout
+-- Date: 2026-10-19
+
+-- seats reserved while a rider pays, taken out of the bus's occupancy until
+-- a booking uses them or the hold expires
+CREATE TABLE IF NOT EXISTS seat_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    bus_id UUID NOT NULL REFERENCES buses(id),
+    schedule_id UUID REFERENCES schedules(id),
+    seats INT NOT NULL CHECK (seats > 0),
+    seat_numbers TEXT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'held'
+        CHECK (status IN ('held', 'booked', 'released', 'expired')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    booking_id UUID REFERENCES bookings(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_seat_holds_active ON seat_holds(bus_id) WHERE status = 'held';
+CREATE INDEX IF NOT EXISTS idx_seat_holds_expiry ON seat_holds(expires_at) WHERE status = 'held';
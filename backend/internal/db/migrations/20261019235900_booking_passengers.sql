@@ -0,0 +1,26 @@
+-- Migration for named passengers on group and on-behalf bookings
+-- Date: 2026-10-19
+
+ALTER TYPE payment_method ADD VALUE IF NOT EXISTS 'invoice';
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'invoiced';
+
+-- who rides in each seat when a booking is made for other people
+CREATE TABLE IF NOT EXISTS booking_passengers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    booking_id UUID NOT NULL REFERENCES bookings(id),
+    position INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    seat_number VARCHAR(10),
+    is_child BOOLEAN NOT NULL DEFAULT FALSE,
+    user_id UUID REFERENCES users(id), -- set when the passenger is a registered rider
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'cancelled')),
+    boarded_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (booking_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_booking_passengers_user ON booking_passengers(user_id) WHERE user_id IS NOT NULL;
+
+ALTER TABLE booking_cancellations ADD COLUMN IF NOT EXISTS passenger_ids UUID[];
+ALTER TABLE ticket_scans ADD COLUMN IF NOT EXISTS passenger_id UUID REFERENCES booking_passengers(id);
@@ -0,0 +1,23 @@
+-- Migration for multi-leg journeys booked across transfers
+-- Date: 2026-10-20
+
+-- one payment covers every leg of a journey, each leg is its own booking
+CREATE TABLE IF NOT EXISTS journeys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id),
+    seats INT NOT NULL CHECK (seats > 0),
+    fare DECIMAL(10,2) NOT NULL,
+    payment_method payment_method NOT NULL,
+    transaction_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_journeys_user ON journeys(user_id, created_at DESC);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS journey_id UUID REFERENCES journeys(id);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS journey_leg INT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookings_journey_leg ON bookings(journey_id, journey_leg) WHERE journey_id IS NOT NULL;
+
+-- finding the trips that pass through a stop
+CREATE INDEX IF NOT EXISTS idx_route_bus_stops_stop ON route_bus_stops(bus_stop_id);
@@ -0,0 +1,46 @@
+-- Migration for fuzzy stop search, stop aliases and nearest stops
+-- Date: 2026-10-20
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- stop names folded for matching: lower case letters and digits only, so
+-- "KenCom", "Ken Com" and "kencom" are the same
+CREATE OR REPLACE FUNCTION stop_search_key(t TEXT) RETURNS TEXT AS $$
+    SELECT LOWER(regexp_replace(COALESCE(t, ''), '[^[:alnum:]]+', '', 'g'))
+$$ LANGUAGE sql IMMUTABLE;
+
+-- other names riders know a stop by
+CREATE TABLE IF NOT EXISTS bus_stop_aliases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    bus_stop_id UUID NOT NULL REFERENCES bus_stops(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bus_stop_aliases_key ON bus_stop_aliases(bus_stop_id, stop_search_key(alias));
+CREATE INDEX IF NOT EXISTS idx_bus_stop_aliases_trgm ON bus_stop_aliases USING GIN (stop_search_key(alias) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bus_stops_name_trgm ON bus_stops USING GIN (stop_search_key(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_bus_stops_landmark_trgm ON bus_stops USING GIN (LOWER(landmark_description) gin_trgm_ops);
+
+-- stop locations for nearest stop search, kept in step with latitude and longitude
+ALTER TABLE bus_stops ADD COLUMN IF NOT EXISTS geolocation GEOGRAPHY(POINT, 4326);
+
+CREATE OR REPLACE FUNCTION update_bus_stop_geolocation()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.geolocation = ST_SetSRID(ST_MakePoint(NEW.longitude, NEW.latitude), 4326)::geography;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS set_bus_stop_geolocation ON bus_stops;
+CREATE TRIGGER set_bus_stop_geolocation
+BEFORE INSERT OR UPDATE OF latitude, longitude ON bus_stops
+FOR EACH ROW EXECUTE FUNCTION update_bus_stop_geolocation();
+
+UPDATE bus_stops
+SET geolocation = ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography
+WHERE geolocation IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bus_stops_geolocation ON bus_stops USING GIST(geolocation);
@@ -0,0 +1,76 @@
+-- Migration for route editing with versioned stop lists
+-- Date: 2026-10-20
+
+-- each edit of a route's stops is a version: drafts are edited, then
+-- published, superseding the version before
+CREATE TABLE IF NOT EXISTS route_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    route_id UUID NOT NULL REFERENCES bus_routes(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'published', 'superseded')),
+    notes TEXT,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_by UUID REFERENCES users(id),
+    published_at TIMESTAMPTZ,
+    effective_from TIMESTAMPTZ, -- trips departing from then on run this version
+    UNIQUE (route_id, version)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_versions_draft ON route_versions(route_id) WHERE status = 'draft';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_route_versions_published ON route_versions(route_id) WHERE status = 'published';
+
+-- a version's stops in order, deactivated stops are kept but not called at
+CREATE TABLE IF NOT EXISTS route_version_stops (
+    route_version_id UUID NOT NULL REFERENCES route_versions(id) ON DELETE CASCADE,
+    bus_stop_id UUID NOT NULL REFERENCES bus_stops(id),
+    stop_order INT NOT NULL,
+    estimated_arrival_time INTERVAL, -- time from route start
+    timetable TEXT[],
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (route_version_id, bus_stop_id),
+    UNIQUE (route_version_id, stop_order)
+);
+
+ALTER TABLE bus_routes ADD COLUMN IF NOT EXISTS current_version_id UUID REFERENCES route_versions(id);
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS route_version_id UUID REFERENCES route_versions(id);
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS route_version_id UUID REFERENCES route_versions(id);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_route_version ON schedules(route_version_id);
+
+-- the stops routes had before versioning become their first version
+INSERT INTO route_versions (route_id, version, status, notes, published_at, effective_from)
+SELECT r.id, 1, 'published', 'Stops before route versioning', NOW(), r.created_at
+FROM bus_routes r
+WHERE NOT EXISTS (SELECT 1 FROM route_versions v WHERE v.route_id = r.id);
+
+INSERT INTO route_version_stops (route_version_id, bus_stop_id, stop_order, estimated_arrival_time, timetable)
+SELECT v.id, rbs.bus_stop_id, rbs.stop_order, rbs.estimated_arrival_time, rbs.timetable
+FROM route_versions v
+JOIN route_bus_stops rbs ON rbs.route_id = v.route_id
+WHERE v.version = 1
+ON CONFLICT DO NOTHING;
+
+UPDATE bus_routes r SET current_version_id = v.id
+FROM route_versions v
+WHERE v.route_id = r.id AND v.status = 'published' AND r.current_version_id IS NULL;
+
+UPDATE schedules s SET route_version_id = r.current_version_id
+FROM bus_routes r
+WHERE r.id = s.route_id AND s.route_version_id IS NULL;
+
+UPDATE bookings b SET route_version_id = s.route_version_id
+FROM schedules s
+WHERE s.id = b.schedule_id AND b.route_version_id IS NULL;
+
+-- the stops a trip calls at, from the route version it runs
+CREATE OR REPLACE VIEW schedule_stops AS
+SELECT s.id AS schedule_id, s.route_id, rvs.bus_stop_id, rvs.stop_order, rvs.estimated_arrival_time, rvs.timetable
+FROM schedules s
+JOIN route_version_stops rvs ON rvs.route_version_id = s.route_version_id
+WHERE rvs.is_active
+UNION ALL
+SELECT s.id, s.route_id, rbs.bus_stop_id, rbs.stop_order, rbs.estimated_arrival_time, rbs.timetable
+FROM schedules s
+JOIN route_bus_stops rbs ON rbs.route_id = s.route_id
+WHERE s.route_version_id IS NULL;
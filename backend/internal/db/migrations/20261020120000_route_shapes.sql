@@ -0,0 +1,20 @@
+-- Migration for route geometry, the path a route's buses drive
+-- Date: 2026-10-20
+
+-- one shape per route, uploaded as GeoJSON or built from the recorded
+-- locations of a reference trip
+CREATE TABLE IF NOT EXISTS route_shapes (
+    route_id UUID PRIMARY KEY REFERENCES bus_routes(id) ON DELETE CASCADE,
+    geometry GEOGRAPHY(LINESTRING, 4326) NOT NULL,
+    length_meters FLOAT NOT NULL,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('geojson', 'trace')),
+    source_schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL, -- reference trip of a traced shape
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_route_shapes_geometry ON route_shapes USING GIST(geometry);
+
+-- reading a reference trip's trace
+CREATE INDEX IF NOT EXISTS idx_bus_locations_bus_time ON bus_locations(bus_id, timestamp);
@@ -0,0 +1,23 @@
+-- Migration for refunds sent to the payment gateway after the cancellation commits
+-- Date: 2026-10-20
+
+-- a refund owed through the gateway is recorded with the cancellation and
+-- sent once it commits, so a cancellation that rolls back never refunds and
+-- a retried one never refunds twice
+CREATE TABLE IF NOT EXISTS gateway_refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id),
+    booking_id UUID REFERENCES bookings(id),
+    transaction_id VARCHAR(255) NOT NULL,
+    amount FLOAT NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gateway_refunds_due ON gateway_refunds(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_gateway_refunds_payment ON gateway_refunds(payment_id);
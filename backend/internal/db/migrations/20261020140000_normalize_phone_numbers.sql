@@ -0,0 +1,33 @@
+-- Migration for storing rider phone numbers in E.164, the form OTP login
+-- looks them up by
+-- Date: 2026-10-20
+
+-- the same rules as otp.NormalizePhone: local 07xx/01xx and 254... numbers
+-- become +254...; numbers that don't parse are left as they are
+WITH digits AS (
+    SELECT id, regexp_replace(phone_number, '[ ().-]', '', 'g') AS phone
+    FROM users
+    WHERE phone_number IS NOT NULL
+), normalized AS (
+    SELECT id,
+        CASE
+            WHEN phone ~ '^\+[1-9][0-9]{7,14}$' THEN phone
+            WHEN phone ~ '^00[1-9][0-9]{7,14}$' THEN '+' || substr(phone, 3)
+            WHEN phone ~ '^254[71][0-9]{8}$' THEN '+' || phone
+            WHEN phone ~ '^0[71][0-9]{8}$' THEN '+254' || substr(phone, 2)
+            WHEN phone ~ '^[71][0-9]{8}$' THEN '+254' || phone
+        END AS phone
+    FROM digits
+), ranked AS (
+    -- two spellings of one number: the first account keeps it
+    SELECT n.id, n.phone,
+        ROW_NUMBER() OVER (PARTITION BY n.phone ORDER BY u.created_at, u.id) AS rank
+    FROM normalized n
+    JOIN users u ON u.id = n.id
+    WHERE n.phone IS NOT NULL AND n.phone <> u.phone_number
+)
+UPDATE users u
+SET phone_number = r.phone, updated_at = NOW()
+FROM ranked r
+WHERE u.id = r.id AND r.rank = 1
+  AND NOT EXISTS (SELECT 1 FROM users o WHERE o.phone_number = r.phone AND o.id <> r.id);
@@ -0,0 +1,8 @@
+-- Migration for reserving promo code uses before a booking is paid for
+-- Date: 2026-10-20
+
+-- a use is reserved with no booking while the rider is charged, and tied to
+-- the booking once it is written
+ALTER TABLE promo_redemptions ALTER COLUMN booking_id DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_reserved ON promo_redemptions(promo_id, created_at) WHERE booking_id IS NULL;
@@ -0,0 +1,38 @@
+// backend/internal/errors/auth.go
+package errors
+
+import (
+	"fmt"
+	"time"
+)
+
+type AuthError struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration // set when the caller should back off
+	Err        error
+}
+
+func (e *AuthError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrInvalidPhone = &AuthError{
+		Code:    "INVALID_PHONE",
+		Message: "Phone number is not valid",
+	}
+
+	ErrOTPInvalid = &AuthError{
+		Code:    "OTP_INVALID",
+		Message: "Invalid or expired code",
+	}
+
+	ErrOTPTooManyAttempts = &AuthError{
+		Code:    "OTP_TOO_MANY_ATTEMPTS",
+		Message: "Too many incorrect attempts, request a new code",
+	}
+)
@@ -42,3 +42,90 @@ var (
         Message: "Insufficient balance in bus pass",
     }
 )
+
+var (
+    ErrNoLoyaltyProgramme = &BookingError{
+        Code:    "NO_LOYALTY_PROGRAMME",
+        Message: "No loyalty programme covers this bus",
+    }
+
+    ErrInsufficientPoints = &BookingError{
+        Code:    "INSUFFICIENT_POINTS",
+        Message: "Not enough loyalty points",
+    }
+)
+
+var (
+    ErrPromoInvalid = &BookingError{
+        Code:    "PROMO_INVALID",
+        Message: "Promo code is not valid for this booking",
+    }
+
+    ErrPromoExhausted = &BookingError{
+        Code:    "PROMO_EXHAUSTED",
+        Message: "This promo code has been fully redeemed",
+    }
+
+    ErrPromoUsed = &BookingError{
+        Code:    "PROMO_EXHAUSTED",
+        Message: "You have already used this promo code",
+    }
+
+    ErrPromoNotStackable = &BookingError{
+        Code:    "PROMO_NOT_STACKABLE",
+        Message: "This promo code cannot be combined with loyalty points",
+    }
+)
+
+var (
+    ErrQuoteInvalid = &BookingError{
+        Code:    "QUOTE_INVALID",
+        Message: "Fare quote is invalid or has been tampered with",
+    }
+
+    ErrQuoteExpired = &BookingError{
+        Code:    "QUOTE_EXPIRED",
+        Message: "Fare quote has expired, get a new quote",
+    }
+
+    ErrQuoteMismatch = &BookingError{
+        Code:    "QUOTE_MISMATCH",
+        Message: "Fare quote is for a different bus, stops or number of seats",
+    }
+)
+
+var (
+    ErrScheduleNotFound = &BookingError{
+        Code:    "SCHEDULE_NOT_FOUND",
+        Message: "Trip not found",
+    }
+
+    ErrScheduleCancelled = &BookingError{
+        Code:    "SCHEDULE_CANCELLED",
+        Message: "This trip has already been cancelled",
+    }
+)
+
+var (
+    ErrBookingNotFound = &BookingError{
+        Code:    "BOOKING_NOT_FOUND",
+        Message: "Booking not found",
+    }
+)
+
+var (
+    ErrSeatHoldNotFound = &BookingError{
+        Code:    "SEAT_HOLD_NOT_FOUND",
+        Message: "Seat hold not found",
+    }
+
+    ErrSeatHoldExpired = &BookingError{
+        Code:    "SEAT_HOLD_EXPIRED",
+        Message: "Seat hold has expired, hold the seats again",
+    }
+
+    ErrSeatHoldMismatch = &BookingError{
+        Code:    "SEAT_HOLD_MISMATCH",
+        Message: "Seat hold is for a different bus or number of seats",
+    }
+)
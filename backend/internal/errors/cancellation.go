@@ -0,0 +1,29 @@
+// backend/internal/errors/cancellation.go
+package errors
+
+import "fmt"
+
+type CancellationError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *CancellationError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrCancellationPolicyNotFound = &CancellationError{
+		Code:    "POLICY_NOT_FOUND",
+		Message: "Cancellation policy not found",
+	}
+
+	ErrCancellationPolicyExists = &CancellationError{
+		Code:    "POLICY_EXISTS",
+		Message: "An active cancellation policy already covers this route, deactivate it first",
+	}
+)
@@ -0,0 +1,34 @@
+// backend/internal/errors/fare.go
+package errors
+
+import "fmt"
+
+type FareError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *FareError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrFareRouteNotFound = &FareError{
+		Code:    "ROUTE_NOT_FOUND",
+		Message: "Route not found",
+	}
+
+	ErrFareMultiplierNotFound = &FareError{
+		Code:    "MULTIPLIER_NOT_FOUND",
+		Message: "Fare multiplier not found",
+	}
+
+	ErrFareOverrideNotFound = &FareError{
+		Code:    "OVERRIDE_NOT_FOUND",
+		Message: "Fare override not found",
+	}
+)
@@ -0,0 +1,29 @@
+// backend/internal/errors/loyalty.go
+package errors
+
+import "fmt"
+
+type LoyaltyError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *LoyaltyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrLoyaltyProgrammeNotFound = &LoyaltyError{
+		Code:    "LOYALTY_PROGRAMME_NOT_FOUND",
+		Message: "Loyalty programme not found",
+	}
+
+	ErrLoyaltyProgrammeExists = &LoyaltyError{
+		Code:    "LOYALTY_PROGRAMME_EXISTS",
+		Message: "A loyalty programme is already running, deactivate it first",
+	}
+)
@@ -0,0 +1,64 @@
+// backend/internal/errors/pass.go
+package errors
+
+import "fmt"
+
+type PassError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *PassError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrPassProductNotFound = &PassError{
+		Code:    "PASS_PRODUCT_NOT_FOUND",
+		Message: "Pass product not found",
+	}
+
+	ErrPassNotFound = &PassError{
+		Code:    "PASS_NOT_FOUND",
+		Message: "Bus pass not found",
+	}
+
+	ErrPassNotActive = &PassError{
+		Code:    "PASS_NOT_ACTIVE",
+		Message: "Bus pass is not active",
+	}
+
+	ErrPassNotRenewable = &PassError{
+		Code:    "PASS_NOT_RENEWABLE",
+		Message: "This pass was not bought from a product and cannot renew",
+	}
+
+	ErrPassNotEligible = &PassError{
+		Code:    "PASS_NOT_ELIGIBLE",
+		Message: "You are not eligible for this pass",
+	}
+
+	ErrPassNoBalance = &PassError{
+		Code:    "PASS_NO_BALANCE",
+		Message: "Unlimited passes do not hold a balance",
+	}
+
+	ErrInvalidTopUp = &PassError{
+		Code:    "INVALID_TOP_UP",
+		Message: "Top-up amount must be between 50 and 10,000",
+	}
+
+	ErrPassPaymentMethod = &PassError{
+		Code:    "INVALID_PAYMENT_METHOD",
+		Message: "Passes can only be paid for by mpesa or card",
+	}
+
+	ErrPassPaymentFailed = &PassError{
+		Code:    "PAYMENT_FAILED",
+		Message: "Payment processing failed",
+	}
+)
@@ -0,0 +1,29 @@
+// backend/internal/errors/promo.go
+package errors
+
+import "fmt"
+
+type PromoError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *PromoError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrPromoNotFound = &PromoError{
+		Code:    "PROMO_NOT_FOUND",
+		Message: "Promo code not found",
+	}
+
+	ErrPromoCodeTaken = &PromoError{
+		Code:    "PROMO_CODE_TAKEN",
+		Message: "A promo with this code already exists",
+	}
+)
@@ -0,0 +1,59 @@
+// backend/internal/errors/route.go
+package errors
+
+import "fmt"
+
+type RouteError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *RouteError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrRouteNotFound = &RouteError{
+		Code:    "ROUTE_NOT_FOUND",
+		Message: "Route not found",
+	}
+
+	ErrRouteVersionNotFound = &RouteError{
+		Code:    "VERSION_NOT_FOUND",
+		Message: "Route version not found",
+	}
+
+	ErrRouteDraftExists = &RouteError{
+		Code:    "DRAFT_EXISTS",
+		Message: "The route already has a draft version, edit or delete it first",
+	}
+
+	ErrRouteVersionNotDraft = &RouteError{
+		Code:    "NOT_DRAFT",
+		Message: "Only draft versions can be changed",
+	}
+
+	ErrRouteNameTaken = &RouteError{
+		Code:    "ROUTE_NAME_TAKEN",
+		Message: "Route name already exists",
+	}
+
+	ErrRouteShapeNotFound = &RouteError{
+		Code:    "SHAPE_NOT_FOUND",
+		Message: "The route has no shape",
+	}
+
+	ErrReferenceTripNotFound = &RouteError{
+		Code:    "TRIP_NOT_FOUND",
+		Message: "Reference trip not found, it must be a departed trip on the route",
+	}
+
+	ErrTraceTooShort = &RouteError{
+		Code:    "TRACE_TOO_SHORT",
+		Message: "The reference trip has too few recorded locations to build a shape",
+	}
+)
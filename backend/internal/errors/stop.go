@@ -0,0 +1,39 @@
+// backend/internal/errors/stop.go
+package errors
+
+import "fmt"
+
+type StopError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *StopError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrStopNotFound = &StopError{
+		Code:    "STOP_NOT_FOUND",
+		Message: "Bus stop not found",
+	}
+
+	ErrStopAliasNotFound = &StopError{
+		Code:    "ALIAS_NOT_FOUND",
+		Message: "Stop alias not found",
+	}
+
+	ErrStopAliasExists = &StopError{
+		Code:    "ALIAS_EXISTS",
+		Message: "The stop already has this name or alias",
+	}
+
+	ErrInvalidStopQuery = &StopError{
+		Code:    "INVALID_STOP_QUERY",
+		Message: "Search for a stop by a name with letters or digits",
+	}
+)
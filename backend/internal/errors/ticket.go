@@ -0,0 +1,44 @@
+// backend/internal/errors/ticket.go
+package errors
+
+import "fmt"
+
+type TicketError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *TicketError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrTicketNotFound = &TicketError{
+		Code:    "TICKET_NOT_FOUND",
+		Message: "Booking not found",
+	}
+
+	ErrTicketInvalid = &TicketError{
+		Code:    "TICKET_INVALID",
+		Message: "Ticket is not valid",
+	}
+
+	ErrTicketExpired = &TicketError{
+		Code:    "TICKET_EXPIRED",
+		Message: "Ticket has expired",
+	}
+
+	ErrTicketWrongTrip = &TicketError{
+		Code:    "TICKET_WRONG_TRIP",
+		Message: "Ticket is for a different bus or trip",
+	}
+
+	ErrTicketAlreadyUsed = &TicketError{
+		Code:    "TICKET_ALREADY_USED",
+		Message: "Ticket has already been scanned",
+	}
+)
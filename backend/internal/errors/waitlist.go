@@ -0,0 +1,44 @@
+// backend/internal/errors/waitlist.go
+package errors
+
+import "fmt"
+
+type WaitlistError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *WaitlistError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s (cause: %v)", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	ErrWaitlistEntryNotFound = &WaitlistError{
+		Code:    "WAITLIST_ENTRY_NOT_FOUND",
+		Message: "Waitlist entry not found",
+	}
+
+	ErrAlreadyWaitlisted = &WaitlistError{
+		Code:    "ALREADY_WAITLISTED",
+		Message: "You are already on the waitlist for this trip",
+	}
+
+	ErrWaitlistSeatsAvailable = &WaitlistError{
+		Code:    "SEATS_AVAILABLE",
+		Message: "This trip still has seats, book one instead",
+	}
+
+	ErrWaitlistNoOffer = &WaitlistError{
+		Code:    "NO_OFFER",
+		Message: "No seats have been offered to you yet",
+	}
+
+	ErrWaitlistOfferExpired = &WaitlistError{
+		Code:    "OFFER_EXPIRED",
+		Message: "The seat offer has expired",
+	}
+)
@@ -0,0 +1,656 @@
+// backend/internal/handlers/admin.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	db     *sql.DB
+	audit  *audit.Service
+	mailer EmailSender
+}
+
+func NewAdminHandler(db *sql.DB, auditService *audit.Service, mailer EmailSender) *AdminHandler {
+	return &AdminHandler{
+		db:     db,
+		audit:  auditService,
+		mailer: mailer,
+	}
+}
+
+type ReviewDocumentRequest struct {
+	Status string `json:"status" binding:"required,oneof=approved rejected"`
+	Reason string `json:"reason"`
+}
+
+type OperatorStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+type AdminOperator struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Email           *string    `json:"email"`
+	Phone           *string    `json:"phone"`
+	Status          string     `json:"status"`
+	StatusReason    *string    `json:"status_reason,omitempty"`
+	VerifiedAt      *time.Time `json:"verified_at,omitempty"`
+	OwnerID         string     `json:"owner_id"`
+	BusCount        int        `json:"bus_count"`
+	PendingDocCount int        `json:"pending_document_count"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// operatorState is the part of an operator the audit log tracks
+type operatorState struct {
+	Status       string  `json:"status"`
+	StatusReason *string `json:"status_reason"`
+}
+
+// ListOperators lists operators, optionally filtered by status
+func (h *AdminHandler) ListOperators(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	rows, err := h.db.Query(`
+		SELECT o.id, o.name, o.email, o.phone, o.status, o.status_reason, o.verified_at,
+			o.user_id, o.created_at,
+			(SELECT COUNT(*) FROM buses b WHERE b.operator_id = o.id),
+			(SELECT COUNT(*) FROM operator_kyc_documents d WHERE d.operator_id = o.id AND d.status = 'pending')
+		FROM bus_operators o
+		WHERE ($1 = '' OR o.status = $1)
+		ORDER BY o.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, c.Query("status"), limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	operators := []AdminOperator{}
+	for rows.Next() {
+		var o AdminOperator
+		if err := rows.Scan(
+			&o.ID, &o.Name, &o.Email, &o.Phone, &o.Status, &o.StatusReason, &o.VerifiedAt,
+			&o.OwnerID, &o.CreatedAt, &o.BusCount, &o.PendingDocCount,
+		); err != nil {
+			log.Printf("[ERROR] Failed to scan operator: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch operators"})
+			return
+		}
+		operators = append(operators, o)
+	}
+
+	c.JSON(http.StatusOK, operators)
+}
+
+// GetOperator returns an operator with its verification documents
+func (h *AdminHandler) GetOperator(c *gin.Context) {
+	operatorID := c.Param("id")
+
+	var o AdminOperator
+	err := h.db.QueryRow(`
+		SELECT o.id, o.name, o.email, o.phone, o.status, o.status_reason, o.verified_at,
+			o.user_id, o.created_at,
+			(SELECT COUNT(*) FROM buses b WHERE b.operator_id = o.id),
+			(SELECT COUNT(*) FROM operator_kyc_documents d WHERE d.operator_id = o.id AND d.status = 'pending')
+		FROM bus_operators o
+		WHERE o.id = $1
+	`, operatorID).Scan(
+		&o.ID, &o.Name, &o.Email, &o.Phone, &o.Status, &o.StatusReason, &o.VerifiedAt,
+		&o.OwnerID, &o.CreatedAt, &o.BusCount, &o.PendingDocCount,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	documents, err := listKYCDocuments(h.db, operatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"operator":  o,
+		"documents": documents,
+	})
+}
+
+// UploadOperatorDocument uploads a verification document on the operator's behalf
+func (h *AdminHandler) UploadOperatorDocument(c *gin.Context) {
+	operatorID := c.Param("id")
+
+	var exists bool
+	err := h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM bus_operators WHERE id = $1)`, operatorID).Scan(&exists)
+	if err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
+		return
+	}
+
+	doc, ok := saveKYCDocument(c, h.db, h.audit, operatorID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+// GetDocumentFile streams a verification document
+func (h *AdminHandler) GetDocumentFile(c *gin.Context) {
+	documentID := c.Param("document_id")
+
+	var operatorID, filePath string
+	var filename sql.NullString
+	err := h.db.QueryRow(`
+		SELECT operator_id, file_path, original_filename FROM operator_kyc_documents WHERE id = $1
+	`, documentID).Scan(&operatorID, &filePath, &filename)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// reading identity documents is recorded too
+	entry := auditEntry(c, "kyc_document.view", "kyc_document", documentID)
+	entry.OperatorID = operatorID
+	if err := h.audit.Record(c.Request.Context(), nil, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record access"})
+		return
+	}
+
+	c.FileAttachment(filePath, filename.String)
+}
+
+// ReviewDocument approves or rejects a verification document
+func (h *AdminHandler) ReviewDocument(c *gin.Context) {
+	documentID := c.Param("document_id")
+
+	var req ReviewDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == "rejected" && strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required when rejecting a document"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var operatorID, documentType, previousStatus string
+	err = tx.QueryRow(`
+		SELECT operator_id, document_type, status
+		FROM operator_kyc_documents
+		WHERE id = $1
+		FOR UPDATE
+	`, documentID).Scan(&operatorID, &documentType, &previousStatus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var reason interface{}
+	if req.Status == "rejected" {
+		reason = req.Reason
+	}
+
+	_, err = tx.Exec(`
+		UPDATE operator_kyc_documents
+		SET status = $1, rejection_reason = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $4
+	`, req.Status, reason, c.GetString("user_id"), documentID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to review document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review document"})
+		return
+	}
+
+	action := "kyc_document.approve"
+	if req.Status == "rejected" {
+		action = "kyc_document.reject"
+	}
+
+	entry := auditEntry(c, action, "kyc_document", documentID)
+	entry.OperatorID = operatorID
+	entry.Before = gin.H{"status": previousStatus}
+	entry.After = gin.H{"status": req.Status, "rejection_reason": reason}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review document"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            documentID,
+		"operator_id":   operatorID,
+		"document_type": documentType,
+		"status":        req.Status,
+	})
+}
+
+// ApproveOperator activates an operator once both documents are approved
+func (h *AdminHandler) ApproveOperator(c *gin.Context) {
+	var missing []string
+	rows, err := h.db.Query(`
+		SELECT t.document_type
+		FROM (VALUES ('business_registration'), ('ntsa_licence')) AS t(document_type)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM operator_kyc_documents d
+			WHERE d.operator_id = $1 AND d.document_type = t.document_type AND d.status = 'approved'
+		)
+	`, c.Param("id"))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	for rows.Next() {
+		var documentType string
+		if err := rows.Scan(&documentType); err == nil {
+			missing = append(missing, documentType)
+		}
+	}
+	rows.Close()
+
+	if len(missing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":             "Operator documents have not been approved",
+			"missing_documents": missing,
+		})
+		return
+	}
+
+	h.changeOperatorStatus(c, "operator.approve", []string{"pending", "rejected"}, "active", "")
+}
+
+// RejectOperator turns down an operator application
+func (h *AdminHandler) RejectOperator(c *gin.Context) {
+	var req OperatorStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	h.changeOperatorStatus(c, "operator.reject", []string{"pending"}, "rejected", req.Reason)
+}
+
+// SuspendOperator blocks an active operator's staff and stops new bookings on its buses
+func (h *AdminHandler) SuspendOperator(c *gin.Context) {
+	var req OperatorStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	h.changeOperatorStatus(c, "operator.suspend", []string{"active"}, "suspended", req.Reason)
+}
+
+// ReinstateOperator lifts a suspension
+func (h *AdminHandler) ReinstateOperator(c *gin.Context) {
+	h.changeOperatorStatus(c, "operator.reinstate", []string{"suspended"}, "active", "")
+}
+
+func (h *AdminHandler) changeOperatorStatus(c *gin.Context, action string, from []string, to, reason string) {
+	operatorID := c.Param("id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var before operatorState
+	var name string
+	var email sql.NullString
+	err = tx.QueryRow(`
+		SELECT status, status_reason, name, email FROM bus_operators WHERE id = $1 FOR UPDATE
+	`, operatorID).Scan(&before.Status, &before.StatusReason, &name, &email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	allowed := false
+	for _, status := range from {
+		if before.Status == status {
+			allowed = true
+		}
+	}
+	if !allowed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Cannot %s an operator that is %s", strings.TrimPrefix(action, "operator."), before.Status),
+		})
+		return
+	}
+
+	after := operatorState{Status: to}
+	if reason != "" {
+		after.StatusReason = &reason
+	}
+
+	_, err = tx.Exec(`
+		UPDATE bus_operators
+		SET status = $1,
+			status_reason = $2,
+			status_changed_at = NOW(),
+			verified_at = CASE WHEN $3 THEN NOW() ELSE verified_at END,
+			verified_by = CASE WHEN $3 THEN $4::uuid ELSE verified_by END
+		WHERE id = $5
+	`, to, after.StatusReason, action == "operator.approve", c.GetString("user_id"), operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update operator status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update operator"})
+		return
+	}
+
+	entry := auditEntry(c, action, "operator", operatorID)
+	entry.OperatorID = operatorID
+	entry.Before = before
+	entry.After = after
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update operator"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	if email.Valid && email.String != "" {
+		body := fmt.Sprintf("The status of %s on Zurura is now %s.", name, to)
+		if reason != "" {
+			body += "\n\nReason: " + reason
+		}
+		h.mailer.QueueEmail(email.String, "Your operator account has been updated", body)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":            operatorID,
+		"status":        to,
+		"status_reason": after.StatusReason,
+	})
+}
+
+// ListBuses lists buses across operators
+func (h *AdminHandler) ListBuses(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	rows, err := h.db.Query(`
+		SELECT b.id, b.operator_id, o.name, b.registration_plate, b.capacity, b.status, b.created_at
+		FROM buses b
+		JOIN bus_operators o ON o.id = b.operator_id
+		WHERE ($1 = '' OR b.operator_id::text = $1)
+		AND ($2 = '' OR b.status = $2)
+		ORDER BY b.created_at DESC
+		LIMIT $3 OFFSET $4
+	`, c.Query("operator_id"), c.Query("status"), limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	buses := []gin.H{}
+	for rows.Next() {
+		var id, operatorID, operatorName, plate, status string
+		var capacity int
+		var createdAt time.Time
+		if err := rows.Scan(&id, &operatorID, &operatorName, &plate, &capacity, &status, &createdAt); err != nil {
+			log.Printf("[ERROR] Failed to scan bus: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch buses"})
+			return
+		}
+		buses = append(buses, gin.H{
+			"id":                 id,
+			"operator_id":        operatorID,
+			"operator_name":      operatorName,
+			"registration_plate": plate,
+			"capacity":           capacity,
+			"status":             status,
+			"created_at":         createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, buses)
+}
+
+// ListUsers searches users by name, email or phone number
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	search := ""
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		search = "%" + q + "%"
+	}
+
+	rows, err := h.db.Query(`
+		SELECT u.id, u.email, u.phone_number, u.first_name, u.last_name, u.role, u.created_at,
+			EXISTS(SELECT 1 FROM operator_members m WHERE m.user_id = u.id AND m.status = 'active')
+		FROM users u
+		WHERE ($1 = '' OR u.email ILIKE $1 OR u.phone_number ILIKE $1
+			OR (u.first_name || ' ' || u.last_name) ILIKE $1)
+		AND ($2 = '' OR u.role = $2)
+		ORDER BY u.created_at DESC
+		LIMIT $3 OFFSET $4
+	`, search, c.Query("role"), limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	users := []gin.H{}
+	for rows.Next() {
+		var id, firstName, lastName, role string
+		var email, phone sql.NullString
+		var createdAt time.Time
+		var isStaff bool
+		if err := rows.Scan(&id, &email, &phone, &firstName, &lastName, &role, &createdAt, &isStaff); err != nil {
+			log.Printf("[ERROR] Failed to scan user: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+		users = append(users, gin.H{
+			"id":             id,
+			"email":          email.String,
+			"phone_number":   phone.String,
+			"first_name":     firstName,
+			"last_name":      lastName,
+			"role":           role,
+			"operator_staff": isStaff,
+			"created_at":     createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// UpdateUserRole grants or revokes platform admin
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if userID == c.GetString("user_id") && req.Role != "admin" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Admins cannot remove their own admin role"})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var previousRole string
+	err = tx.QueryRow(`SELECT role FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&previousRole)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`, req.Role, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update user role: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	entry := auditEntry(c, "user.role_change", "user", userID)
+	entry.Before = gin.H{"role": previousRole}
+	entry.After = gin.H{"role": req.Role}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": userID, "role": req.Role})
+}
+
+// VerifyStudent records that an admin has checked a rider's student status,
+// which student-only passes can require
+func (h *AdminHandler) VerifyStudent(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req struct {
+		Verified *bool `json:"verified" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var schoolName sql.NullString
+	var verifiedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT school_name, student_verified_at FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&schoolName, &verifiedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if *req.Verified {
+		_, err = tx.Exec(`
+			UPDATE users SET student_verified_at = NOW(), student_verified_by = $1, updated_at = NOW()
+			WHERE id = $2
+		`, c.GetString("user_id"), userID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE users SET student_verified_at = NULL, student_verified_by = NULL, updated_at = NOW()
+			WHERE id = $1
+		`, userID)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to update student verification: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update student verification"})
+		return
+	}
+
+	entry := auditEntry(c, "user.student_verification", "user", userID)
+	entry.Before = gin.H{"student_verified": verifiedAt.Valid, "school_name": schoolName.String}
+	entry.After = gin.H{"student_verified": *req.Verified, "school_name": schoolName.String}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update student verification"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": userID, "school_name": schoolName.String, "student_verified": *req.Verified})
+}
+
+// pagination reads limit and offset query params, defaulting to 50 rows
+func pagination(c *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -0,0 +1,115 @@
+// backend/internal/handlers/audit.go
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	audit *audit.Service
+}
+
+func NewAuditHandler(auditService *audit.Service) *AuditHandler {
+	return &AuditHandler{audit: auditService}
+}
+
+// ListAuditLogs returns the operator's audit entries, newest first. Admins see
+// every chain and can narrow it with operator_id.
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	logs, err := h.audit.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, logs)
+}
+
+// ExportAuditLogs downloads matching entries as csv or newline delimited json
+func (h *AuditHandler) ExportAuditLogs(c *gin.Context) {
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	contentType := "text/csv"
+	switch format {
+	case "csv":
+	case "json":
+		contentType = "application/x-ndjson"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(
+		`attachment; filename="audit-log-%s.%s"`, time.Now().Format("20060102-150405"), format,
+	))
+	c.Status(http.StatusOK)
+
+	if err := h.audit.Export(c.Request.Context(), filter, format, c.Writer); err != nil {
+		// headers are gone, all that is left is to log and stop
+		log.Printf("[ERROR] Audit export failed: %v", err)
+	}
+}
+
+// VerifyAuditChain recomputes the hash chain and reports the first broken entry
+func (h *AuditHandler) VerifyAuditChain(c *gin.Context) {
+	chainKey := c.GetString("operator_id")
+	if chainKey == "" {
+		chainKey = c.DefaultQuery("chain", audit.PlatformChain)
+	}
+
+	result, err := h.audit.Verify(c.Request.Context(), chainKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func auditFilter(c *gin.Context) (audit.Filter, bool) {
+	filter := audit.Filter{
+		ChainKey:   c.GetString("operator_id"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Action:     c.Query("action"),
+		ActorID:    c.Query("actor_id"),
+		RequestID:  c.Query("request_id"),
+	}
+
+	// operators only ever see their own chain, admins may pick one
+	if filter.ChainKey == "" {
+		filter.ChainKey = c.Query("operator_id")
+	}
+
+	for param, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be an RFC3339 time", param)})
+				return filter, false
+			}
+			*dest = t
+		}
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	return filter, true
+}
@@ -3,13 +3,16 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -18,11 +21,12 @@ import (
 )
 
 type AuthHandler struct {
-	db *sql.DB
+	db      *sql.DB
+	lockout ratelimit.LockoutStore
 }
 
-func NewAuthHandler(db *sql.DB) *AuthHandler {
-	return (&AuthHandler{db: db})
+func NewAuthHandler(db *sql.DB, lockout ratelimit.LockoutStore) *AuthHandler {
+	return (&AuthHandler{db: db, lockout: lockout})
 }
 
 type LoginRequest struct {
@@ -53,20 +57,33 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// refuse early while the account is locked out
+	lockoutKey := "login:" + strings.ToLower(strings.TrimSpace(req.Email))
+	lockedFor, err := h.lockout.LockedFor(c.Request.Context(), lockoutKey)
+	if err != nil {
+		log.Printf("[ERROR] Lockout check failed: %v", err)
+	}
+	if lockedFor > 0 {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(lockedFor.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+		return
+	}
+
 	// query user from db
 	var user models.User
+	var passwordHash sql.NullString
 	query := `SELECT id, email, password_hash, first_name, last_name FROM users WHERE email= $1`
-	err := h.db.QueryRow(query, req.Email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName)
+	err = h.db.QueryRow(query, req.Email).Scan(&user.ID, &user.Email, &passwordHash, &user.FirstName, &user.LastName)
 
 	if err != nil {
+		h.recordFailedLogin(c, lockoutKey)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Credentials"})
 		return
 	}
+	user.PasswordHash = passwordHash.String
 
 	//var isOperator bool
-	var role string
-	err = h.db.QueryRow(
-		`SELECT CASE WHEN EXISTS(SELECT 1 FROM bus_operators WHERE user_id = $1) THEN 'operator' ELSE 'user' END`, user.ID).Scan(&role)
+	role, err := resolveRole(h.db, user.ID)
 	if err != nil {
 		log.Printf("Operator check failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
@@ -79,24 +96,16 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		[]byte(req.Password),
 	); err != nil {
 		log.Printf("Failed login attempt for %s", req.Email)
+		h.recordFailedLogin(c, lockoutKey)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Credentials"})
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"jti":     uuid.New().String(),
+	if err := h.lockout.Reset(c.Request.Context(), lockoutKey); err != nil {
+		log.Printf("[ERROR] Failed to reset login failures: %v", err)
 	}
-/* 	if isOperator {
-		claims["role"] = "operator"
-	} */
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenStr, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokenStr, err := issueToken(user.ID, user.Email, role, time.Hour*24*7) // 7 days
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
 		return
@@ -109,7 +118,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			"email":      user.Email,
 			"first_name": user.FirstName,
 			"last_name":  user.LastName,
-			"role":       claims["role"],
+			"role":       role,
 		},
 	})
 }
@@ -150,10 +159,23 @@ func (h *AuthHandler) RegisterOperator(c *gin.Context) {
 	}
 
 	// Create operator entry
+	operatorID := uuid.New().String()
 	_, err = h.db.Exec(`
         INSERT INTO bus_operators (id, user_id, name, contact_info, email, phone, address)
         VALUES ($1, $2, $3, '', $4, '', '')
-    `, uuid.New().String(), userID, req.Company, req.Email)
+    `, operatorID, userID, req.Company, req.Email)
+
+	if err != nil {
+		log.Printf("DATABASE ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create operator"})
+		return
+	}
+
+	// the registering user owns the operator
+	_, err = h.db.Exec(`
+        INSERT INTO operator_members (operator_id, user_id, email, role, status, accepted_at)
+        VALUES ($1, $2, $3, 'owner', 'active', NOW())
+    `, operatorID, userID, req.Email)
 
 	if err != nil {
 		log.Printf("DATABASE ERROR: %v", err)
@@ -181,6 +203,11 @@ func (h *AuthHandler) RegisterOperator(c *gin.Context) {
 			"last_name":  req.LastName,
 			"role":       "operator", // Indicate operator role
 		},
+		// the operator endpoints open up once an admin has verified the documents
+		"operator": gin.H{
+			"id":     operatorID,
+			"status": "pending",
+		},
 	})
 }
 
@@ -302,6 +329,44 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 }
 
 // helpers
+
+func (h *AuthHandler) recordFailedLogin(c *gin.Context, key string) {
+	lockedFor, err := h.lockout.RecordFailure(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record login failure: %v", err)
+		return
+	}
+	if lockedFor > 0 {
+		log.Printf("[WARN] Locking %s for %s after repeated failed logins", key, lockedFor)
+	}
+}
+
+// resolveRole works out the role claim for a user's token
+func resolveRole(db *sql.DB, userID string) (string, error) {
+	var role string
+	err := db.QueryRow(
+		`SELECT CASE
+			WHEN EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin') THEN 'admin'
+			WHEN EXISTS(
+				SELECT 1 FROM operator_members WHERE user_id = $1 AND status = 'active'
+			) THEN 'operator'
+			ELSE 'user' END`, userID).Scan(&role)
+	return role, err
+}
+
+// issueToken signs a login token for the user
+func issueToken(userID, email, role string, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"email":   email,
+		"role":    role,
+		"exp":     time.Now().Add(ttl).Unix(),
+		"jti":     uuid.New().String(),
+	})
+
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
 /* func generateUUID() string {
 	return uuid.New().String()
 }
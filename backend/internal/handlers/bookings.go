@@ -3,14 +3,13 @@ package handlers
 
 import (
 	"database/sql"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
 
 	//"github.com/Mvoii/zurura/internal/errors"
-	"github.com/Mvoii/zurura/internal/models"
 	"github.com/Mvoii/zurura/internal/services/booking"
 	"github.com/Mvoii/zurura/internal/services/payments"
 	"github.com/gin-gonic/gin"
@@ -36,13 +35,26 @@ type SeatMap struct {
 	Count       int      `json:"count" binding:"required"`
 }
 
+// PassengerRequest names who rides in one of a booking's seats
+type PassengerRequest struct {
+	Name       string `json:"name"`
+	SeatNumber string `json:"seat_number"`
+	Child      bool   `json:"child"`
+	UserID     string `json:"user_id"` // a registered rider, optional
+}
+
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	var req struct {
-		BusID             string  `json:"bus_id" binding:"required"`
-		BoardingStopName  string  `json:"boarding_stop_name" binding:"required"`
-		AlightingStopName string  `json:"alighting_stop_name" binding:"required"`
-		Seats             SeatMap `json:"seats"`
-		PaymentMethod     string  `json:"payment_method" binding:"required"`
+		BusID             string             `json:"bus_id" binding:"required"`
+		BoardingStopName  string             `json:"boarding_stop_name" binding:"required"`
+		AlightingStopName string             `json:"alighting_stop_name" binding:"required"`
+		Seats             SeatMap            `json:"seats"`
+		PaymentMethod     string             `json:"payment_method" binding:"required"`
+		RedeemPoints      int                `json:"redeem_points"`
+		PromoCode         string             `json:"promo_code"`
+		QuoteToken        string             `json:"quote_token"`
+		HoldID            string             `json:"hold_id"`
+		Passengers        []PassengerRequest `json:"passengers"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -69,46 +81,123 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		SeatCount:         req.Seats.Count,
 		PaymentMethod:     payments.PaymentMethod(req.PaymentMethod),
 		UserID:            userID.(string),
+		RedeemPoints:      req.RedeemPoints,
+		PromoCode:         req.PromoCode,
+		QuoteToken:        req.QuoteToken,
+		HoldID:            req.HoldID,
+	}
+	for _, p := range req.Passengers {
+		bookingReq.Passengers = append(bookingReq.Passengers, booking.Passenger{
+			Name:       p.Name,
+			SeatNumber: p.SeatNumber,
+			Child:      p.Child,
+			UserID:     p.UserID,
+		})
 	}
 
 	booking, err := h.bookingService.CreateBooking(c.Request.Context(), bookingReq)
 	if err != nil {
-		switch e := err.(type) {
-		case *bookingerrors.BookingError:
-			switch e.Code {
-			case "SEATS_UNAVAILABLE":
-				log.Printf("[ERROR] Seats unavailable: %v", e)
-				c.JSON(http.StatusConflict, gin.H{"error": e.Message})
-			case "INVALID_SEAT_SELECTION":
-				log.Printf("[ERROR] Invalid seat selection: %v", e)
-				c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
-			case "PAYMENT_FAILED":
-				log.Printf("[ERROR] Payment failed: %v", e)
-				c.JSON(http.StatusPaymentRequired, gin.H{"error": e.Message})
-			case "INVALID_FARE":
-				log.Printf("[ERROR] Invalid fare: %v", e)
-				c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
-			default:
-				log.Printf("[ERROR] Booking error: %v", e)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": e.Message})
-			}
+		createBookingErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, booking)
+}
+
+// createBookingErrorResponse maps an error making a booking to its response
+func createBookingErrorResponse(c *gin.Context, err error) {
+	switch e := err.(type) {
+	case *bookingerrors.BookingError:
+		switch e.Code {
+		case "SEATS_UNAVAILABLE":
+			log.Printf("[ERROR] Seats unavailable: %v", e)
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		case "SEAT_HOLD_NOT_FOUND", "BUS_NOT_FOUND":
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+		case "SEAT_HOLD_CLOSED":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		case "SEAT_HOLD_EXPIRED":
+			c.JSON(http.StatusGone, gin.H{"error": e.Message})
+		case "SEAT_HOLD_MISMATCH":
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		case "INVALID_SEAT_SELECTION":
+			log.Printf("[ERROR] Invalid seat selection: %v", e)
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		case "INVALID_PASSENGERS", "INVOICE_NOT_ALLOWED":
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		case "PAYMENT_FAILED":
+			log.Printf("[ERROR] Payment failed: %v", e)
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": e.Message})
+		case "INVALID_FARE":
+			log.Printf("[ERROR] Invalid fare: %v", e)
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		case "INSUFFICIENT_BALANCE":
+			log.Printf("[ERROR] Insufficient balance: %v", e)
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": e.Message})
+		case "OPERATOR_UNAVAILABLE":
+			log.Printf("[ERROR] Operator unavailable: %v", e)
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		case "INSUFFICIENT_POINTS", "PROMO_EXHAUSTED", "QUOTE_EXPIRED":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		case "NO_LOYALTY_PROGRAMME", "INVALID_REDEMPTION", "PROMO_INVALID", "PROMO_NOT_STACKABLE",
+			"QUOTE_INVALID", "QUOTE_MISMATCH":
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
 		default:
-			log.Printf("[ERROR] Booking error: %v", err)
-			// Check if the error message contains "zero amount payment"
-			if err.Error() == "zero amount payment not allowed" {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": "Payment validation failed: Zero fare amount. The route fare may not be properly configured.",
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			log.Printf("[ERROR] Booking error: %v", e)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": e.Message})
+		}
+	default:
+		log.Printf("[ERROR] Booking error: %v", err)
+		// Check if the error message contains "zero amount payment"
+		if err.Error() == "zero amount payment not allowed" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Payment validation failed: Zero fare amount. The route fare may not be properly configured.",
+			})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// HoldSeats reserves seats for a few minutes while the rider checks out,
+// returning a hold ID for CreateBooking
+func (h *BookingHandler) HoldSeats(c *gin.Context) {
+	var req struct {
+		BusID string  `json:"bus_id" binding:"required"`
+		Seats SeatMap `json:"seats"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, booking)
+	hold, err := h.bookingService.HoldSeats(c.Request.Context(), booking.SeatHoldRequest{
+		UserID:      c.GetString("user_id"),
+		BusID:       req.BusID,
+		SeatCount:   req.Seats.Count,
+		SeatNumbers: req.Seats.SeatNumbers,
+	})
+	if err != nil {
+		createBookingErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
 }
 
+// ReleaseSeatHold gives up a seat hold before it expires
+func (h *BookingHandler) ReleaseSeatHold(c *gin.Context) {
+	if err := h.bookingService.ReleaseSeatHold(c.Request.Context(), c.Param("id"), c.GetString("user_id")); err != nil {
+		createBookingErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seats released"})
+}
+
+// CancelBooking cancels a booking, or only some of its seats when the body
+// gives a seat_count, seat_numbers or passenger_ids, refunding their share of
+// the fare
 func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	bookingID := c.Param("id")
 	userID, exists := c.Get("user_id")
@@ -117,7 +206,20 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
-	err := h.bookingService.CancelBooking(c.Request.Context(), bookingID, userID.(string))
+	var req struct {
+		SeatCount    int      `json:"seat_count"`
+		SeatNumbers  []string `json:"seat_numbers"`
+		PassengerIDs []string `json:"passenger_ids"`
+	}
+	// the body is optional, without one every seat is cancelled
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	cancellation, err := h.bookingService.CancelSeats(c.Request.Context(), bookingID, userID.(string), req.SeatCount, req.SeatNumbers, req.PassengerIDs)
 	if err != nil {
 		switch e := err.(type) {
 		case *bookingerrors.BookingError:
@@ -131,6 +233,10 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 			case "CANNOT_CANCEL_COMPLETED":
 				log.Printf("[ERROR] Cannot cancel completed booking: %v", e)
 				c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+			case "INVALID_SEAT_SELECTION":
+				c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+			case "INVALID_BOOKING_STATUS":
+				c.JSON(http.StatusConflict, gin.H{"error": e.Message})
 			default:
 				log.Printf("[ERROR] Booking error: %v", e)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": e.Message})
@@ -142,7 +248,74 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully"})
+	message := "Booking cancelled successfully"
+	if cancellation.SeatsRemaining > 0 {
+		message = fmt.Sprintf("Cancelled %d seats, %d left on the booking", cancellation.Seats, cancellation.SeatsRemaining)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "cancellation": cancellation})
+}
+
+// CompleteBooking marks a boarded booking on one of the operator's buses as ridden,
+// which counts the ride and earns the rider loyalty points
+func (h *BookingHandler) CompleteBooking(c *gin.Context) {
+	points, err := h.bookingService.CompleteBooking(c.Request.Context(), c.Param("id"), auditEntry(c, "", "", ""))
+	if err != nil {
+		switch e := err.(type) {
+		case *bookingerrors.BookingError:
+			switch e.Code {
+			case "BOOKING_NOT_FOUND":
+				c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+			case "INVALID_BOOKING_STATUS":
+				c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+			default:
+				log.Printf("[ERROR] Booking error: %v", e)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": e.Message})
+			}
+		default:
+			log.Printf("[ERROR] Booking error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Booking completed",
+		"booking_id":    c.Param("id"),
+		"points_earned": points,
+	})
+}
+
+// GetBookingHistory returns the statuses one of the rider's bookings has been
+// through
+func (h *BookingHandler) GetBookingHistory(c *gin.Context) {
+	history, err := h.bookingService.BookingHistory(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		if e, ok := err.(*bookingerrors.BookingError); ok && e.Code == "BOOKING_NOT_FOUND" {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+			return
+		}
+		log.Printf("[ERROR] Failed to fetch booking history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetBookingPassengers lists who rides in a booking's seats
+func (h *BookingHandler) GetBookingPassengers(c *gin.Context) {
+	passengers, err := h.bookingService.BookingPassengers(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		if e, ok := err.(*bookingerrors.BookingError); ok && e.Code == "BOOKING_NOT_FOUND" {
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+			return
+		}
+		log.Printf("[ERROR] Failed to fetch booking passengers: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking passengers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passengers": passengers})
 }
 
 // GetUserBookings retrieves all bookings for the authenticated user
@@ -158,11 +331,19 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 		SELECT
 		   b.id, b.user_id, b.bus_id, b.route_id,
 		   b.boarding_stop_id, b.alighting_stop_id,
-		   b.seats, b.fare, b.status,
+		   b.seats, b.fare, b.refunded_amount, b.status,
 		   b.created_at, b.expires_at, b.boarded_at,
 		   r.route_name, r.origin, r.destination,
 		   bs1.name AS boarding_name, bs1.latitude AS boarding_lat, bs1.longitude AS boarding_lng,
-		   bs2.name AS alighting_name, bs2.latitude AS alighting_lat, bs2.longitude AS alighting_lng
+		   bs2.name AS alighting_name, bs2.latitude AS alighting_lat, bs2.longitude AS alighting_lng,
+		   COALESCE((
+		       SELECT json_agg(json_build_object(
+		           'type', li.type, 'description', li.description, 'amount', li.amount, 'points', li.points
+		       ) ORDER BY li.position)
+		       FROM booking_line_items li
+		       WHERE li.booking_id = b.id
+		   ), '[]') AS line_items,
+		   b.journey_id, b.journey_leg
 		FROM bookings b
 		LEFT JOIN bus_routes r  ON b.route_id = r.id
 		LEFT JOIN bus_stops bs1 ON b.boarding_stop_id  = bs1.id
@@ -196,13 +377,17 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 			alightLng     sql.NullFloat64
 			seatData      []byte // JSON data for seats
 			fare          float64
+			refunded      float64
 			status        string
 			createdAt     time.Time
 			expiresAt     time.Time
-			boardedAt     time.Time
+			boardedAt     sql.NullTime
 			routeName     sql.NullString
 			origin        sql.NullString
 			destination   sql.NullString
+			lineItems     []byte // JSON array of fare line items
+			journeyID     sql.NullString
+			journeyLeg    sql.NullInt64
 		)
 
 		err := rows.Scan(
@@ -214,6 +399,7 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 			&alightStopID,
 			&seatData,
 			&fare,
+			&refunded,
 			&status,
 			&createdAt,
 			&expiresAt,
@@ -227,6 +413,9 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 			&alightingName,
 			&alightLat,
 			&alightLng,
+			&lineItems,
+			&journeyID,
+			&journeyLeg,
 		)
 
 		if err != nil {
@@ -267,22 +456,33 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 			}
 		}
 
+		var boarded *time.Time
+		if boardedAt.Valid {
+			boarded = &boardedAt.Time
+		}
+
 		booking := gin.H{
 			"id":          id,
 			"user_id":     userID,
 			"bus_id":      busID,
 			"route_id":    routeID,
 			"fare":        fare,
+			"refunded_amount": refunded,
 			"seats":       string(seatData), // This will be a JSON string that frontend can parse
 			"status":      status,
 			"created_at":  createdAt,
 			"expires_at":  expiresAt,
-			"boarded_at":  boardedAt,
+			"boarded_at":  boarded,
 			"route_name":  routeNameStr,
 			"origin":      originStr,
 			"destination": destStr,
 			"boarding_stop": boarding,
 			"alighting_stop": alighting,
+			"line_items":  json.RawMessage(lineItems),
+		}
+		if journeyID.Valid {
+			booking["journey_id"] = journeyID.String
+			booking["journey_leg"] = journeyLeg.Int64
 		}
 
 		bookings = append(bookings, booking)
@@ -297,52 +497,6 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 }
 
 // helpers
-func deductFromPass(userID string, amount float64, tx *sql.Tx) error {
-	var pass models.BusPass
-	err := tx.QueryRow(`
-		SELECT id, balance
-		FROM bus_passes
-		WHERE user_id = $1
-		AND status = 'active'
-		AND expiration_date > NOW()
-		ORDER BY expiration_date DESC
-		LIMIT 1
-	`, userID).Scan(&pass.ID, &pass.Balance)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[ERROR] No active bus pass found for user %s", userID)
-			return errors.New("no active bus pass found")
-		}
-		log.Printf("[ERROR] Database error: %v", err)
-		return fmt.Errorf("database error: %v", err)
-	}
-
-	if pass.Balance < amount {
-		log.Printf("[ERROR] Insufficient pass balance. Available: %.2f", pass.Balance)
-		return fmt.Errorf("insufficient pass balance. Available: %.2f", pass.Balance)
-	}
-
-	_, err = tx.Exec(`
-		UPDATE bus_passes
-		SET balance = balance - $1
-		WHERE id = $2
-	`, amount, pass.ID)
-	if err != nil {
-		log.Printf("[ERROR] Balance update failed: %v", err)
-		return fmt.Errorf("balance update failed: %v", err)
-	}
-
-	/* 	_, err = tx.Exec(`
-	   		INSERT INTO pass_usage (...) VALUES ($1, $2, $3)
-	   	`, pass.ID, bookingID, amount)
-	   	if err != nil {
-	   		log.Printf("[ERROR] %v", err)
-	   		return errors.New(err.Error())
-	   	}
-	*/
-	return nil
-}
-
 func calculateFare(tx *sql.Tx, busID string, seatCount int) (float64, error) {
 	var farePerSeat float64
 	err := tx.QueryRow(`
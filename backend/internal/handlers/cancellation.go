@@ -0,0 +1,189 @@
+// backend/internal/handlers/cancellation.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	cancellationerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/cancellation"
+	"github.com/gin-gonic/gin"
+)
+
+type CancellationHandler struct {
+	bookingService      *booking.BookingService
+	cancellationService *cancellation.Service
+}
+
+func NewCancellationHandler(bs *booking.BookingService, cs *cancellation.Service) *CancellationHandler {
+	return &CancellationHandler{
+		bookingService:      bs,
+		cancellationService: cs,
+	}
+}
+
+// CancellationPolicyRequest is the body for creating or replacing a
+// cancellation policy
+type CancellationPolicyRequest struct {
+	RouteID             string                    `json:"route_id"`
+	Name                string                    `json:"name" binding:"required"`
+	Rules               []models.CancellationRule `json:"rules" binding:"required"`
+	NoShowRefundPercent float64                   `json:"no_show_refund_percent"`
+	NoShowGraceMinutes  *int                      `json:"no_show_grace_minutes"`
+	Active              *bool                     `json:"active"`
+}
+
+func (r CancellationPolicyRequest) input(operatorID string) cancellation.PolicyInput {
+	in := cancellation.PolicyInput{
+		OperatorID:          operatorID,
+		RouteID:             r.RouteID,
+		Name:                r.Name,
+		Rules:               r.Rules,
+		NoShowRefundPercent: r.NoShowRefundPercent,
+		NoShowGraceMinutes:  30,
+		Active:              true,
+	}
+	if r.NoShowGraceMinutes != nil {
+		in.NoShowGraceMinutes = *r.NoShowGraceMinutes
+	}
+	if r.Active != nil {
+		in.Active = *r.Active
+	}
+	return in
+}
+
+// PreviewCancellation shows what cancelling a booking's seats would refund
+// right now, taking the same seat_count, seat_numbers and passenger_ids as
+// CancelBooking
+func (h *CancellationHandler) PreviewCancellation(c *gin.Context) {
+	var req struct {
+		SeatCount    int    `form:"seat_count"`
+		SeatNumbers  string `form:"seat_numbers"`  // comma separated
+		PassengerIDs string `form:"passenger_ids"` // comma separated
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.bookingService.PreviewCancellation(c.Request.Context(), c.Param("id"), c.GetString("user_id"), req.SeatCount,
+		commaList(req.SeatNumbers), commaList(req.PassengerIDs))
+	if err != nil {
+		cancellationErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// commaList splits a comma separated query parameter, dropping empty items
+func commaList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ListPolicies returns the operator's cancellation policies
+func (h *CancellationHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.cancellationService.ListPolicies(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cancellation policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy adds a cancellation policy for one of the operator's routes,
+// or for all of them when no route is given
+func (h *CancellationHandler) CreatePolicy(c *gin.Context) {
+	var req CancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.cancellationService.CreatePolicy(c.Request.Context(), req.input(c.GetString("operator_id")))
+	if err != nil {
+		cancellationErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdatePolicy replaces a cancellation policy's rules, or deactivates it
+func (h *CancellationHandler) UpdatePolicy(c *gin.Context) {
+	var req CancellationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.cancellationService.UpdatePolicy(c.Request.Context(), c.Param("id"), req.input(c.GetString("operator_id")))
+	if err != nil {
+		cancellationErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// CancelTrip cancels one of the operator's scheduled departures and refunds
+// everyone booked on it in full
+func (h *CancellationHandler) CancelTrip(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	refunded, err := h.bookingService.CancelTrip(c.Request.Context(), c.Param("id"), strings.TrimSpace(req.Reason), auditEntry(c, "", "", ""))
+	if err != nil {
+		cancellationErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Trip cancelled",
+		"schedule_id":       c.Param("id"),
+		"bookings_refunded": refunded,
+	})
+}
+
+func cancellationErrorResponse(c *gin.Context, err error) {
+	switch e := err.(type) {
+	case *cancellationerrors.CancellationError:
+		switch e.Code {
+		case "POLICY_NOT_FOUND":
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+		case "POLICY_EXISTS":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		}
+	case *cancellationerrors.BookingError:
+		switch e.Code {
+		case "BOOKING_NOT_FOUND", "SCHEDULE_NOT_FOUND":
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+		case "SCHEDULE_CANCELLED", "INVALID_BOOKING_STATUS":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		}
+	default:
+		log.Printf("[ERROR] Cancellation error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
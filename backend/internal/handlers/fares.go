@@ -0,0 +1,237 @@
+// backend/internal/handlers/fares.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	fareerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/fares"
+	"github.com/gin-gonic/gin"
+)
+
+type FareHandler struct {
+	bookingService *booking.BookingService
+	fareService    *fares.Service
+}
+
+func NewFareHandler(bs *booking.BookingService, fs *fares.Service) *FareHandler {
+	return &FareHandler{
+		bookingService: bs,
+		fareService:    fs,
+	}
+}
+
+// CreateMultiplierRequest is the body for adding a time of day multiplier
+type CreateMultiplierRequest struct {
+	Name       string     `json:"name" binding:"required"`
+	Multiplier float64    `json:"multiplier" binding:"required"`
+	RouteID    string     `json:"route_id"`
+	DaysOfWeek []int      `json:"days_of_week"`
+	StartTime  string     `json:"start_time"`
+	EndTime    string     `json:"end_time"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+}
+
+// CreateOverrideRequest is the body for setting a fixed fare
+type CreateOverrideRequest struct {
+	RouteID    string     `json:"route_id" binding:"required"`
+	FromStopID string     `json:"from_stop_id"`
+	ToStopID   string     `json:"to_stop_id"`
+	Fare       float64    `json:"fare" binding:"required"`
+	Reason     string     `json:"reason"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+}
+
+// GetQuote prices a trip and holds the price for a short while so a booking
+// made with the quote_id pays it
+func (h *FareHandler) GetQuote(c *gin.Context) {
+	busID := c.Query("bus_id")
+	boarding := c.Query("boarding_stop_name")
+	alighting := c.Query("alighting_stop_name")
+	if busID == "" || boarding == "" || alighting == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bus_id, boarding_stop_name and alighting_stop_name are required"})
+		return
+	}
+
+	seats := 1
+	if raw := c.Query("seats"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "seats must be a positive number"})
+			return
+		}
+		seats = n
+	}
+
+	quote, err := h.bookingService.QuoteFare(c.Request.Context(), busID, boarding, alighting, seats)
+	if err != nil {
+		if e, ok := err.(*fareerrors.BookingError); ok {
+			switch e.Code {
+			case "ROUTE_NOT_FOUND":
+				c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+			case "OPERATOR_UNAVAILABLE":
+				c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+			}
+			return
+		}
+		log.Printf("[ERROR] Failed to quote fare: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to quote fare"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// GetStopFares returns the stop-to-stop fare table of one of the operator's routes
+func (h *FareHandler) GetStopFares(c *gin.Context) {
+	stopFares, err := h.fareService.StopFares(c.Request.Context(), c.Param("route_id"), c.GetString("operator_id"))
+	if err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stopFares)
+}
+
+// ReplaceStopFares sets the route's fare table, replacing what was there
+func (h *FareHandler) ReplaceStopFares(c *gin.Context) {
+	var req struct {
+		Fares []models.StopFare `json:"fares" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stopFares, err := h.fareService.ReplaceStopFares(c.Request.Context(), c.Param("route_id"), c.GetString("operator_id"), req.Fares)
+	if err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stopFares)
+}
+
+// ListMultipliers returns the operator's or the platform's fare multipliers
+func (h *FareHandler) ListMultipliers(c *gin.Context) {
+	multipliers, err := h.fareService.ListMultipliers(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fare multipliers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, multipliers)
+}
+
+// CreateMultiplier adds a peak hour multiplier for the operator's buses, or
+// for every bus when called by an admin
+func (h *FareHandler) CreateMultiplier(c *gin.Context) {
+	var req CreateMultiplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	multiplier, err := h.fareService.CreateMultiplier(c.Request.Context(), fares.MultiplierInput{
+		OperatorID: c.GetString("operator_id"),
+		RouteID:    req.RouteID,
+		Name:       req.Name,
+		Multiplier: req.Multiplier,
+		DaysOfWeek: req.DaysOfWeek,
+		StartTime:  req.StartTime,
+		EndTime:    req.EndTime,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+	})
+	if err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, multiplier)
+}
+
+// DeactivateMultiplier stops a fare multiplier from applying
+func (h *FareHandler) DeactivateMultiplier(c *gin.Context) {
+	multiplier, err := h.fareService.DeactivateMultiplier(c.Request.Context(), c.Param("id"), c.GetString("operator_id"))
+	if err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, multiplier)
+}
+
+// ListOverrides returns the operator's current and upcoming fare overrides
+func (h *FareHandler) ListOverrides(c *gin.Context) {
+	overrides, err := h.fareService.ListOverrides(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch fare overrides"})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// CreateOverride sets a fixed fare on one of the operator's routes or stop pairs
+func (h *FareHandler) CreateOverride(c *gin.Context) {
+	var req CreateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	override, err := h.fareService.CreateOverride(c.Request.Context(), fares.OverrideInput{
+		OperatorID: c.GetString("operator_id"),
+		CreatedBy:  c.GetString("user_id"),
+		RouteID:    req.RouteID,
+		FromStopID: req.FromStopID,
+		ToStopID:   req.ToStopID,
+		Fare:       req.Fare,
+		Reason:     req.Reason,
+		StartsAt:   req.StartsAt,
+		EndsAt:     req.EndsAt,
+	})
+	if err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, override)
+}
+
+// DeleteOverride removes a fare override, fares go back to the engine's price
+func (h *FareHandler) DeleteOverride(c *gin.Context) {
+	if err := h.fareService.DeleteOverride(c.Request.Context(), c.Param("id"), c.GetString("operator_id")); err != nil {
+		fareErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Fare override removed"})
+}
+
+func fareErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*fareerrors.FareError)
+	if !ok {
+		log.Printf("[ERROR] Fare error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "ROUTE_NOT_FOUND", "MULTIPLIER_NOT_FOUND", "OVERRIDE_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
@@ -0,0 +1,111 @@
+// backend/internal/handlers/journeys.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	journeyerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/gin-gonic/gin"
+)
+
+type JourneyHandler struct {
+	bookingService *booking.BookingService
+}
+
+func NewJourneyHandler(bs *booking.BookingService) *JourneyHandler {
+	return &JourneyHandler{bookingService: bs}
+}
+
+// PlanJourney lists the scheduled trips from one stop to another, direct or
+// with a change of bus
+func (h *JourneyHandler) PlanJourney(c *gin.Context) {
+	var req struct {
+		From     string `form:"from" binding:"required"`
+		To       string `form:"to" binding:"required"`
+		DepartAt string `form:"depart_at"` // RFC3339, now when empty
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	departAt := time.Now()
+	if req.DepartAt != "" {
+		var err error
+		if departAt, err = time.Parse(time.RFC3339, req.DepartAt); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time format"})
+			return
+		}
+	}
+
+	itineraries, err := h.bookingService.PlanJourneys(c.Request.Context(), req.From, req.To, departAt)
+	if err != nil {
+		journeyErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"itineraries": itineraries})
+}
+
+// BookJourney books every leg of an itinerary with one payment, or none of them
+func (h *JourneyHandler) BookJourney(c *gin.Context) {
+	var req struct {
+		Legs []struct {
+			BusID             string `json:"bus_id" binding:"required"`
+			ScheduleID        string `json:"schedule_id" binding:"required"`
+			BoardingStopName  string `json:"boarding_stop_name" binding:"required"`
+			AlightingStopName string `json:"alighting_stop_name" binding:"required"`
+		} `json:"legs" binding:"required,dive"`
+		SeatCount     int    `json:"seat_count"`
+		PaymentMethod string `json:"payment_method" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	journeyReq := booking.JourneyRequest{
+		UserID:        c.GetString("user_id"),
+		SeatCount:     req.SeatCount,
+		PaymentMethod: payments.PaymentMethod(req.PaymentMethod),
+	}
+	for _, leg := range req.Legs {
+		journeyReq.Legs = append(journeyReq.Legs, booking.JourneyLegRequest{
+			BusID:             leg.BusID,
+			ScheduleID:        leg.ScheduleID,
+			BoardingStopName:  leg.BoardingStopName,
+			AlightingStopName: leg.AlightingStopName,
+		})
+	}
+
+	journey, err := h.bookingService.BookJourney(c.Request.Context(), journeyReq)
+	if err != nil {
+		journeyErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, journey)
+}
+
+func journeyErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*journeyerrors.BookingError)
+	if !ok {
+		log.Printf("[ERROR] Journey error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "INVALID_JOURNEY":
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	case "SCHEDULE_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	default:
+		// the leg that failed, mapped as a single booking would be
+		createBookingErrorResponse(c, err)
+	}
+}
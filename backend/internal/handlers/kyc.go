@@ -0,0 +1,248 @@
+// backend/internal/handlers/kyc.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// kyc documents are kept outside ./uploads, which is served publicly
+const kycDir = "./private/kyc"
+
+var kycDocumentTypes = map[string]bool{
+	"business_registration": true,
+	"ntsa_licence":          true,
+}
+
+type KYCHandler struct {
+	db    *sql.DB
+	audit *audit.Service
+}
+
+func NewKYCHandler(db *sql.DB, auditService *audit.Service) *KYCHandler {
+	return &KYCHandler{
+		db:    db,
+		audit: auditService,
+	}
+}
+
+type KYCDocument struct {
+	ID               string     `json:"id"`
+	OperatorID       string     `json:"operator_id"`
+	DocumentType     string     `json:"document_type"`
+	DocumentNumber   *string    `json:"document_number"`
+	OriginalFilename *string    `json:"original_filename"`
+	Status           string     `json:"status"`
+	RejectionReason  *string    `json:"rejection_reason,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// UploadDocument lets an operator owner submit a verification document
+func (h *KYCHandler) UploadDocument(c *gin.Context) {
+	operatorID := c.GetString("operator_id")
+
+	doc, ok := saveKYCDocument(c, h.db, h.audit, operatorID)
+	if !ok {
+		return
+	}
+
+	// a rejected operator goes back into review once new documents arrive
+	_, err := h.db.Exec(`
+		UPDATE bus_operators
+		SET status = 'pending', status_changed_at = NOW()
+		WHERE id = $1 AND status = 'rejected'
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to return operator to review: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, doc)
+}
+
+// GetVerificationStatus returns the operator's review status and documents
+func (h *KYCHandler) GetVerificationStatus(c *gin.Context) {
+	operatorID := c.GetString("operator_id")
+
+	var status string
+	var reason sql.NullString
+	var verifiedAt sql.NullTime
+	err := h.db.QueryRow(`
+		SELECT status, status_reason, verified_at FROM bus_operators WHERE id = $1
+	`, operatorID).Scan(&status, &reason, &verifiedAt)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	documents, err := listKYCDocuments(h.db, operatorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		return
+	}
+
+	response := gin.H{
+		"operator_id": operatorID,
+		"status":      status,
+		"documents":   documents,
+	}
+	if reason.Valid {
+		response["status_reason"] = reason.String
+	}
+	if verifiedAt.Valid {
+		response["verified_at"] = verifiedAt.Time
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// saveKYCDocument stores the uploaded file and its record, writing the error
+// response itself when it fails
+func saveKYCDocument(c *gin.Context, db *sql.DB, auditService *audit.Service, operatorID string) (*KYCDocument, bool) {
+	documentType := c.PostForm("document_type")
+	if !kycDocumentTypes[documentType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "document_type must be business_registration or ntsa_licence"})
+		return nil, false
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded or invalid form field"})
+		return nil, false
+	}
+
+	fileExt := strings.ToLower(filepath.Ext(file.Filename))
+	if fileExt != ".pdf" && !isValidImageType(file.Filename) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file type. Only PDF, JPG, JPEG, PNG and GIF are allowed"})
+		return nil, false
+	}
+
+	// Max file size: 10MB
+	const maxSize = 10 * 1024 * 1024
+	if file.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File too large. Maximum size is 10MB"})
+		return nil, false
+	}
+
+	operatorDir := filepath.Join(kycDir, operatorID)
+	if err := os.MkdirAll(operatorDir, 0700); err != nil {
+		log.Printf("[ERROR] Failed to create kyc directory: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Server error"})
+		return nil, false
+	}
+
+	doc := &KYCDocument{
+		ID:               uuid.New().String(),
+		OperatorID:       operatorID,
+		DocumentType:     documentType,
+		OriginalFilename: &file.Filename,
+		Status:           "pending",
+		CreatedAt:        time.Now(),
+	}
+	if number := strings.TrimSpace(c.PostForm("document_number")); number != "" {
+		doc.DocumentNumber = &number
+	}
+
+	filePath := filepath.Join(operatorDir, fmt.Sprintf("%s%s", doc.ID, fileExt))
+	if err := c.SaveUploadedFile(file, filePath); err != nil {
+		log.Printf("[ERROR] Failed to save file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return nil, false
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return nil, false
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO operator_kyc_documents (
+			id, operator_id, document_type, document_number, file_path, original_filename, uploaded_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, doc.ID, operatorID, documentType, doc.DocumentNumber, filePath, file.Filename, c.GetString("user_id"))
+	if err != nil {
+		os.Remove(filePath)
+		log.Printf("[ERROR] Failed to store kyc document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return nil, false
+	}
+
+	entry := auditEntry(c, "kyc_document.upload", "kyc_document", doc.ID)
+	entry.OperatorID = operatorID
+	entry.After = doc
+	if err := auditService.Record(c.Request.Context(), tx, entry); err != nil {
+		os.Remove(filePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return nil, false
+	}
+
+	if err := tx.Commit(); err != nil {
+		os.Remove(filePath)
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return nil, false
+	}
+
+	return doc, true
+}
+
+func listKYCDocuments(db *sql.DB, operatorID string) ([]KYCDocument, error) {
+	rows, err := db.Query(`
+		SELECT id, operator_id, document_type, document_number, original_filename,
+			status, rejection_reason, reviewed_at, created_at
+		FROM operator_kyc_documents
+		WHERE operator_id = $1
+		ORDER BY created_at DESC
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []KYCDocument{}
+	for rows.Next() {
+		var d KYCDocument
+		if err := rows.Scan(
+			&d.ID, &d.OperatorID, &d.DocumentType, &d.DocumentNumber, &d.OriginalFilename,
+			&d.Status, &d.RejectionReason, &d.ReviewedAt, &d.CreatedAt,
+		); err != nil {
+			log.Printf("[ERROR] Failed to scan kyc document: %v", err)
+			return nil, err
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// auditEntry starts an audit entry for the request's user
+func auditEntry(c *gin.Context, action, entityType, entityID string) audit.Entry {
+	role := c.GetString("operator_role")
+	if c.GetBool("admin") {
+		role = "admin"
+	}
+
+	return audit.Entry{
+		ActorID:    c.GetString("user_id"),
+		ActorRole:  role,
+		OperatorID: c.GetString("operator_id"),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RequestID:  c.GetString("request_id"),
+	}
+}
@@ -0,0 +1,61 @@
+// backend/internal/handlers/ledger.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Mvoii/zurura/internal/services/ledger"
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerHandler struct {
+	ledger *ledger.Service
+}
+
+func NewLedgerHandler(ledgerService *ledger.Service) *LedgerHandler {
+	return &LedgerHandler{
+		ledger: ledgerService,
+	}
+}
+
+// Reconcile checks pass balances against the ledger
+func (h *LedgerHandler) Reconcile(c *gin.Context) {
+	report, err := h.ledger.Reconcile(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ListAccounts returns ledger accounts with balances derived from their postings
+func (h *LedgerHandler) ListAccounts(c *gin.Context) {
+	limit, offset := pagination(c)
+
+	accounts, err := h.ledger.ListAccounts(c.Request.Context(), c.Query("prefix"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// OperatorRevenue returns the fares the ledger holds for the staff member's operator
+func (h *LedgerHandler) OperatorRevenue(c *gin.Context) {
+	account := ledger.OperatorAccount(c.GetString("operator_id"))
+	balance, err := h.ledger.AccountBalance(c.Request.Context(), account)
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch operator revenue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revenue"})
+		return
+	}
+
+	// a liability, credit negative
+	c.JSON(http.StatusOK, gin.H{
+		"account":    account,
+		"fares_owed": -balance,
+	})
+}
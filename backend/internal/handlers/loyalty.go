@@ -0,0 +1,141 @@
+// backend/internal/handlers/loyalty.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	loyaltyerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/loyalty"
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	loyaltyService *loyalty.Service
+}
+
+func NewLoyaltyHandler(ls *loyalty.Service) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		loyaltyService: ls,
+	}
+}
+
+// LoyaltyProgrammeRequest is the body for creating or replacing a programme
+type LoyaltyProgrammeRequest struct {
+	Name              string               `json:"name" binding:"required"`
+	PointsPerRide     int                  `json:"points_per_ride"`
+	PointsPerShilling float64              `json:"points_per_shilling"`
+	RedemptionValue   float64              `json:"redemption_value" binding:"required"`
+	MinRedeemPoints   int                  `json:"min_redeem_points"`
+	MaxRedeemPercent  float64              `json:"max_redeem_percent"`
+	Active            *bool                `json:"active"`
+	RouteIDs          []string             `json:"route_ids"`
+	Tiers             []models.LoyaltyTier `json:"tiers"`
+}
+
+func (r LoyaltyProgrammeRequest) input(operatorID string) loyalty.ProgrammeInput {
+	active := true
+	if r.Active != nil {
+		active = *r.Active
+	}
+	return loyalty.ProgrammeInput{
+		OperatorID:        operatorID,
+		Name:              r.Name,
+		PointsPerRide:     r.PointsPerRide,
+		PointsPerShilling: r.PointsPerShilling,
+		RedemptionValue:   r.RedemptionValue,
+		MinRedeemPoints:   r.MinRedeemPoints,
+		MaxRedeemPercent:  r.MaxRedeemPercent,
+		Active:            active,
+		RouteIDs:          r.RouteIDs,
+		Tiers:             r.Tiers,
+	}
+}
+
+// GetMyLoyalty returns the current user's points, tiers and points history
+func (h *LoyaltyHandler) GetMyLoyalty(c *gin.Context) {
+	userID := c.GetString("user_id")
+	limit, offset := pagination(c)
+
+	accounts, err := h.loyaltyService.Accounts(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty accounts"})
+		return
+	}
+	transactions, err := h.loyaltyService.Transactions(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts":     accounts,
+		"transactions": transactions,
+	})
+}
+
+// ListProgrammes returns the operator's or the platform's programmes
+func (h *LoyaltyHandler) ListProgrammes(c *gin.Context) {
+	programmes, err := h.loyaltyService.ListProgrammes(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty programmes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, programmes)
+}
+
+// CreateProgramme starts a loyalty programme for the operator, or the
+// platform programme when called by an admin
+func (h *LoyaltyHandler) CreateProgramme(c *gin.Context) {
+	var req LoyaltyProgrammeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	programme, err := h.loyaltyService.CreateProgramme(c.Request.Context(), req.input(c.GetString("operator_id")))
+	if err != nil {
+		loyaltyErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, programme)
+}
+
+// UpdateProgramme replaces a programme's rules, routes and tiers, or ends it
+// with "active": false
+func (h *LoyaltyHandler) UpdateProgramme(c *gin.Context) {
+	var req LoyaltyProgrammeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	programme, err := h.loyaltyService.UpdateProgramme(c.Request.Context(), c.Param("id"), req.input(c.GetString("operator_id")))
+	if err != nil {
+		loyaltyErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, programme)
+}
+
+func loyaltyErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*loyaltyerrors.LoyaltyError)
+	if !ok {
+		log.Printf("[ERROR] Loyalty error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "LOYALTY_PROGRAMME_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "LOYALTY_PROGRAMME_EXISTS":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
@@ -9,17 +9,39 @@ import (
 	"time"
 
 	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type OperatorHandler struct {
-	db *sql.DB
+	db    *sql.DB
+	audit *audit.Service
 }
 
-func NewOperatorHandler(db *sql.DB) *OperatorHandler {
-	return &OperatorHandler{db: db}
+func NewOperatorHandler(db *sql.DB, auditService *audit.Service) *OperatorHandler {
+	return &OperatorHandler{
+		db:    db,
+		audit: auditService,
+	}
+}
+
+// busState is the part of a bus the audit log tracks
+type busState struct {
+	RegistrationPlate string  `json:"registration_plate"`
+	Capacity          int     `json:"capacity"`
+	BusPhotoURL       *string `json:"bus_photo_url"`
+	Status            string  `json:"status"`
+}
+
+// assignmentState is the part of a route assignment the audit log tracks
+type assignmentState struct {
+	BusID     string    `json:"bus_id"`
+	RouteID   string    `json:"route_id"`
+	Status    string    `json:"status"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
 }
 
 // AddBusRequest - Request body for adding a bus
@@ -52,12 +74,8 @@ func (h *OperatorHandler) AddBus(c *gin.Context) {
 		return
 	}
 
-	// get op id from context set during auth
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
+	// operator resolved from the staff membership during auth
+	operatorID := c.GetString("operator_id")
 
 	/* 	_, err := h.db.Exec(`
 	   		INSERT INTO buses (
@@ -95,22 +113,6 @@ func (h *OperatorHandler) AddBus(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	var operatorID string
-	err = tx.QueryRow(`
-		SELECT id FROM bus_operators
-		WHERE user_id = $1
-	`, userID).Scan(&operatorID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[ERROR] Operator not found: %v", err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
-			return
-		}
-		log.Printf("[ERROR] Database error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
-
 	busID := uuid.New().String()
 	_, err = tx.Exec(`
 		INSERT INTO buses (
@@ -143,6 +145,19 @@ func (h *OperatorHandler) AddBus(c *gin.Context) {
 		}
 		return
 	}
+
+	entry := auditEntry(c, "bus.create", "bus", busID)
+	entry.After = busState{
+		RegistrationPlate: req.RegisterPlate,
+		Capacity:          req.Capacity,
+		BusPhotoURL:       &req.BusPhotoURL,
+		Status:            "active",
+	}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add bus"})
+		return
+	}
+
 	// Commit the transaction
 	if err := tx.Commit(); err != nil {
 		log.Printf("[ERROR] Failed to commit transaction: %v", err)
@@ -163,11 +178,7 @@ func (h *OperatorHandler) AddBus(c *gin.Context) {
 
 // update bus details
 func (h *OperatorHandler) UpdateBus(c *gin.Context) {
-	operatorID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
+	operatorID := c.GetString("operator_id")
 
 	busID := c.Param("id")
 	if _, err := uuid.Parse(busID); err != nil {
@@ -186,28 +197,47 @@ func (h *OperatorHandler) UpdateBus(c *gin.Context) {
 	}
 	if req.Capacity != nil && *req.Capacity <= 0 {
 		c.JSON(400, gin.H{"error": "capacity must be positive"})
+		return
 	}
 
-	// Verify bus belongs to operator
-	var busExists bool
-	err := h.db.QueryRow(`
-        SELECT EXISTS(
-            SELECT 1 FROM buses 
-            WHERE id = $1 AND operator_id = $2
-        )`, busID, operatorID).Scan(&busExists)
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Verify bus belongs to operator, keeping the old values for the audit log
+	var before busState
+	err = tx.QueryRow(`
+        SELECT registration_plate, capacity, bus_photo_url, status
+        FROM buses
+        WHERE id = $1 AND operator_id = $2
+        FOR UPDATE`, busID, operatorID).Scan(&before.RegistrationPlate, &before.Capacity, &before.BusPhotoURL, &before.Status)
 
-	if err != nil || !busExists {
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("[ERROR] Database error: %v", err)
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "bus not found"})
 		return
 	}
 
-	result, err := h.db.Exec(`
+	after := before
+	if req.Capacity != nil {
+		after.Capacity = *req.Capacity
+	}
+	if req.BusPhotoURL != "" {
+		after.BusPhotoURL = &req.BusPhotoURL
+	}
+
+	result, err := tx.Exec(`
 		UPDATE buses
 		SET
-			capacity = COALESCE($1, capacity),
-			bus_photo_url = COALESCE($2, bus_photo_url)
+			capacity = $1,
+			bus_photo_url = $2,
 			updated_at = NOW()
-		WHERE id = $3`, req.Capacity, req.BusPhotoURL, busID,
+		WHERE id = $3`, after.Capacity, after.BusPhotoURL, busID,
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -235,16 +265,30 @@ func (h *OperatorHandler) UpdateBus(c *gin.Context) {
 		return
 	}
 
+	entry := auditEntry(c, "bus.update", "bus", busID)
+	entry.Before = before
+	entry.After = after
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "update failed"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"id":            busID,
-		"capacity":      req.Capacity,
-		"bus_photo_url": req.BusPhotoURL,
+		"capacity":      after.Capacity,
+		"bus_photo_url": after.BusPhotoURL,
 	})
 }
 
 // list bus
 func (h *OperatorHandler) ListBuses(c *gin.Context) {
-	operatorID, _ := c.Get("user_id")
+	operatorID := c.GetString("operator_id")
 
 	rows, err := h.db.Query(`
 		SELECT
@@ -283,8 +327,8 @@ func (h *OperatorHandler) AssignBusToRoute(c *gin.Context) {
 	}
 
 	// Get operator IDs from context
-	userID, _ := c.Get("user_id")
-	/* operatorUserID, _ := c.Get("user_id") */
+	userID := c.GetString("user_id")
+	operatorID := c.GetString("operator_id")
 
 	// Start transaction
 	tx, err := h.db.Begin()
@@ -294,22 +338,6 @@ func (h *OperatorHandler) AssignBusToRoute(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
-	var operatorID string
-	err = tx.QueryRow(`
-		SELECT id FROM bus_operators 
-		WHERE user_id = $1
-	`, userID).Scan(&operatorID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[ERROR] Operator not found: %v", err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
-			return
-		}
-		log.Printf("[ERROR] Database error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
-
 	// Validate bus ownership and availability
 	var bus struct {
 		Capacity int
@@ -423,6 +451,19 @@ func (h *OperatorHandler) AssignBusToRoute(c *gin.Context) {
 		return
 	}
 
+	entry := auditEntry(c, "bus_assignment.create", "bus_assignment", assignmentID)
+	entry.After = assignmentState{
+		BusID:     req.BusID,
+		RouteID:   req.RouteID,
+		Status:    "active",
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create assignment"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.Printf("[ERROR] Failed to commit transaction: %v", err)
@@ -452,17 +493,8 @@ func (h *OperatorHandler) UpdateBusAssignment(c *gin.Context) {
 		return
 	}
 
-	// Get operator IDs from context
-	operatorUserID, _ := c.Get("user_id")
-	var operatorID string
-	err := h.db.QueryRow(`
-		SELECT operator_id FROM bus_route_assignments WHERE operator_user_id = $1
-	`, operatorUserID).Scan(&operatorID)
-	if err != nil {
-		log.Printf("[ERROR] Database error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
-	}
+	// Get operator ID from context
+	operatorID := c.GetString("operator_id")
 
 	// Start transaction
 	tx, err := h.db.Begin()
@@ -473,16 +505,13 @@ func (h *OperatorHandler) UpdateBusAssignment(c *gin.Context) {
 	defer tx.Rollback()
 
 	// Validate assignment exists and belongs to operator
-	var assignment struct {
-		BusID   string
-		RouteID string
-		Status  string
-	}
+	var assignment assignmentState
 	err = tx.QueryRow(`
-        SELECT bus_id, route_id, status
+        SELECT bus_id, route_id, status, start_date, end_date
         FROM bus_route_assignments
-        WHERE id = $1 AND operator_id = $2 AND operator_user_id = $3
-    `, assignmentID, operatorID, operatorUserID).Scan(&assignment.BusID, &assignment.RouteID, &assignment.Status)
+        WHERE id = $1 AND operator_id = $2
+        FOR UPDATE
+    `, assignmentID, operatorID).Scan(&assignment.BusID, &assignment.RouteID, &assignment.Status, &assignment.StartDate, &assignment.EndDate)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -563,6 +592,20 @@ func (h *OperatorHandler) UpdateBusAssignment(c *gin.Context) {
 		return
 	}
 
+	entry := auditEntry(c, "bus_assignment.update", "bus_assignment", assignmentID)
+	entry.Before = assignment
+	entry.After = assignmentState{
+		BusID:     assignment.BusID,
+		RouteID:   req.RouteID,
+		Status:    req.Status,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assignment"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		log.Printf("[ERROR] Failed to commit transaction: %v", err)
@@ -587,12 +630,7 @@ func (h *OperatorHandler) UpdateBusAssignment(c *gin.Context) {
 // GetBusAssignments retrieves all assignments for a bus
 func (h *OperatorHandler) GetBusAssignments(c *gin.Context) {
 	busID := c.Param("bus_id")
-	//userID, _ := c.Get("user_id")
-
-	var operatorID string
-	err := h.db.QueryRow(`
-		SELECT operator_id FROM buses WHERE id = $1
-	`, busID).Scan(&operatorID)
+	operatorID := c.GetString("operator_id")
 
 	rows, err := h.db.Query(`
         SELECT 
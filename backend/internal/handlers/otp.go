@@ -0,0 +1,208 @@
+// backend/internal/handlers/otp.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	autherrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/otp"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OTPHandler struct {
+	db         *sql.DB
+	otpService *otp.Service
+}
+
+func NewOTPHandler(db *sql.DB, otpService *otp.Service) *OTPHandler {
+	return &OTPHandler{
+		db:         db,
+		otpService: otpService,
+	}
+}
+
+type OTPRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+type OTPVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+	Code        string `json:"code" binding:"required,len=6,numeric"`
+	// only needed the first time a number is used
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	SchoolName string `json:"school_name"`
+}
+
+// RequestOTP sends a login code to the phone number
+func (h *OTPHandler) RequestOTP(c *gin.Context) {
+	var req OTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	phone, expiresAt, err := h.otpService.RequestCode(c.Request.Context(), req.PhoneNumber, c.ClientIP())
+	if err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":      "Code sent",
+		"phone_number": phone,
+		"expires_at":   expiresAt,
+	})
+}
+
+// VerifyOTP checks the code and logs the rider in, creating the account on first use
+func (h *OTPHandler) VerifyOTP(c *gin.Context) {
+	var req OTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	phone, err := otp.NormalizePhone(req.PhoneNumber)
+	if err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	var user struct {
+		ID        string
+		Email     sql.NullString
+		FirstName string
+		LastName  string
+	}
+	// only a number proven by an earlier code logs in to its account, one
+	// typed into a profile does not
+	err = h.db.QueryRow(`
+		SELECT id, email, first_name, last_name
+		FROM users
+		WHERE phone_number = $1 AND phone_verified_at IS NOT NULL
+	`, phone).Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName)
+
+	isNewUser := false
+	if err == sql.ErrNoRows {
+		isNewUser = true
+	} else if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// new numbers need a name before the code is used up
+	if isNewUser && (req.FirstName == "" || req.LastName == "") {
+		if _, err := h.otpService.VerifyCode(c.Request.Context(), phone, req.Code, false); err != nil {
+			respondOTPError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "first_name and last_name are required to sign up",
+			"signup_required": true,
+		})
+		return
+	}
+
+	if _, err := h.otpService.VerifyCode(c.Request.Context(), phone, req.Code, true); err != nil {
+		respondOTPError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if isNewUser {
+		var schoolName interface{}
+		if req.SchoolName != "" {
+			schoolName = req.SchoolName
+		}
+
+		user.ID = uuid.New().String()
+		user.FirstName = req.FirstName
+		user.LastName = req.LastName
+		if err := h.createPhoneUser(user.ID, phone, req.FirstName, req.LastName, schoolName); err != nil {
+			log.Printf("DATABASE ERROR: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+			return
+		}
+		status = http.StatusCreated
+	}
+
+	role, err := resolveRole(h.db, user.ID)
+	if err != nil {
+		log.Printf("Operator check failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	tokenStr, err := issueToken(user.ID, user.Email.String, role, time.Hour*24*7)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"token": tokenStr,
+		"user": gin.H{
+			"id":           user.ID,
+			"email":        user.Email.String,
+			"phone_number": phone,
+			"first_name":   user.FirstName,
+			"last_name":    user.LastName,
+			"role":         role,
+		},
+	})
+}
+
+// createPhoneUser signs up the owner of a number the code was sent to. An
+// account that has the number on its profile without verifying it loses it.
+func (h *OTPHandler) createPhoneUser(id, phone, firstName, lastName string, schoolName interface{}) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET phone_number = NULL, updated_at = NOW()
+		WHERE phone_number = $1 AND phone_verified_at IS NULL
+	`, phone)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO users (id, phone_number, first_name, last_name, school_name, phone_verified_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, id, phone, firstName, lastName, schoolName)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func respondOTPError(c *gin.Context, err error) {
+	switch e := err.(type) {
+	case *autherrors.AuthError:
+		switch e.Code {
+		case "INVALID_PHONE":
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		case "OTP_RATE_LIMITED":
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(e.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": e.Message})
+		case "OTP_INVALID", "OTP_TOO_MANY_ATTEMPTS":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": e.Message})
+		default:
+			log.Printf("[ERROR] Auth error: %v", e)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": e.Message})
+		}
+	default:
+		log.Printf("[ERROR] OTP error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,239 @@
+// backend/internal/handlers/passes.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	passerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/passes"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/gin-gonic/gin"
+)
+
+type PassHandler struct {
+	passService *passes.Service
+}
+
+func NewPassHandler(ps *passes.Service) *PassHandler {
+	return &PassHandler{
+		passService: ps,
+	}
+}
+
+// ListProducts returns the passes on sale
+func (h *PassHandler) ListProducts(c *gin.Context) {
+	products, err := h.passService.ListProducts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pass products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// PurchasePass buys a pass for the current user
+func (h *PassHandler) PurchasePass(c *gin.Context) {
+	var req struct {
+		ProductID     string `json:"product_id" binding:"required"`
+		PaymentMethod string `json:"payment_method" binding:"required"`
+		AutoRenew     bool   `json:"auto_renew"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pass, err := h.passService.Purchase(c.Request.Context(), passes.PurchaseRequest{
+		UserID:        c.GetString("user_id"),
+		ProductID:     req.ProductID,
+		PaymentMethod: payments.PaymentMethod(req.PaymentMethod),
+		AutoRenew:     req.AutoRenew,
+	})
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pass)
+}
+
+// TopUpPass adds credit to one of the user's passes
+func (h *PassHandler) TopUpPass(c *gin.Context) {
+	var req struct {
+		Amount        float64 `json:"amount" binding:"required"`
+		PaymentMethod string  `json:"payment_method" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pass, err := h.passService.TopUp(c.Request.Context(), c.GetString("user_id"), c.Param("id"),
+		req.Amount, payments.PaymentMethod(req.PaymentMethod))
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pass)
+}
+
+// ListPasses returns the user's passes
+func (h *PassHandler) ListPasses(c *gin.Context) {
+	list, err := h.passService.ListPasses(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch passes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetPass returns a pass with its transaction history
+func (h *PassHandler) GetPass(c *gin.Context) {
+	pass, transactions, err := h.passService.GetPass(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pass":         pass,
+		"transactions": transactions,
+	})
+}
+
+// SetAutoRenew turns automatic renewal on or off
+func (h *PassHandler) SetAutoRenew(c *gin.Context) {
+	var req struct {
+		AutoRenew     *bool  `json:"auto_renew" binding:"required"`
+		PaymentMethod string `json:"payment_method"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pass, err := h.passService.SetAutoRenew(c.Request.Context(), c.GetString("user_id"), c.Param("id"),
+		*req.AutoRenew, payments.PaymentMethod(req.PaymentMethod))
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pass)
+}
+
+// CancelPass cancels one of the user's passes
+func (h *PassHandler) CancelPass(c *gin.Context) {
+	pass, err := h.passService.Cancel(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pass)
+}
+
+type CreatePassProductRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Description  string     `json:"description"`
+	PassType     string     `json:"pass_type" binding:"required"`
+	Price        float64    `json:"price" binding:"required"`
+	Credit       float64    `json:"credit"`
+	ValidityDays int        `json:"validity_days" binding:"required"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Eligibility  string     `json:"eligibility"`
+	SchoolNames  []string   `json:"school_names"`
+	RouteIDs     []string   `json:"route_ids"`
+}
+
+// CreateProduct puts a pass on sale, for the operator on operator routes and
+// for the platform on admin routes
+func (h *PassHandler) CreateProduct(c *gin.Context) {
+	var req CreatePassProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.passService.CreateProduct(c.Request.Context(), passes.ProductInput{
+		OperatorID:   c.GetString("operator_id"),
+		Name:         req.Name,
+		Description:  req.Description,
+		PassType:     req.PassType,
+		Price:        req.Price,
+		Credit:       req.Credit,
+		ValidityDays: req.ValidityDays,
+		EndsAt:       req.EndsAt,
+		Eligibility:  req.Eligibility,
+		SchoolNames:  req.SchoolNames,
+		RouteIDs:     req.RouteIDs,
+	})
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// ListManagedProducts returns the operator's or the platform's products,
+// including ones taken off sale
+func (h *PassHandler) ListManagedProducts(c *gin.Context) {
+	products, err := h.passService.ListManagedProducts(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pass products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// UpdateProduct takes a product off sale or puts it back
+func (h *PassHandler) UpdateProduct(c *gin.Context) {
+	var req struct {
+		Active *bool `json:"active" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	product, err := h.passService.SetProductActive(c.Request.Context(), c.Param("id"), c.GetString("operator_id"), *req.Active)
+	if err != nil {
+		passErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func passErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*passerrors.PassError)
+	if !ok {
+		log.Printf("[ERROR] Pass error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "PASS_NOT_FOUND", "PASS_PRODUCT_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "PASS_NOT_ELIGIBLE":
+		c.JSON(http.StatusForbidden, gin.H{"error": e.Message})
+	case "PASS_NOT_ACTIVE", "PASS_NOT_RENEWABLE", "PASS_NO_BALANCE":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	case "PAYMENT_FAILED":
+		log.Printf("[ERROR] Pass payment failed: %v", e)
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
@@ -0,0 +1,81 @@
+// backend/internal/handlers/planner.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/planner"
+	"github.com/gin-gonic/gin"
+)
+
+type PlannerHandler struct {
+	planner *planner.Service
+}
+
+func NewPlannerHandler(ps *planner.Service) *PlannerHandler {
+	return &PlannerHandler{planner: ps}
+}
+
+// Plan finds door to door itineraries between two points across the whole
+// network, with walks to and between stops, changes and fares
+func (h *PlannerHandler) Plan(c *gin.Context) {
+	var req struct {
+		From     string `form:"from" binding:"required"` // lat,lng
+		To       string `form:"to" binding:"required"`
+		DepartAt string `form:"depart_at"` // RFC3339, now when empty
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, ok := parsePoint(req.From)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be lat,lng"})
+		return
+	}
+	to, ok := parsePoint(req.To)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be lat,lng"})
+		return
+	}
+
+	departAt := time.Now()
+	if req.DepartAt != "" {
+		var err error
+		if departAt, err = time.Parse(time.RFC3339, req.DepartAt); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time format"})
+			return
+		}
+	}
+
+	itineraries, err := h.planner.Plan(c.Request.Context(), from, to, departAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to plan journey: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan journey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"itineraries": itineraries})
+}
+
+// parsePoint reads a "lat,lng" query value
+func parsePoint(s string) (planner.Point, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return planner.Point{}, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return planner.Point{}, false
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return planner.Point{}, false
+	}
+	return planner.Point{Lat: lat, Lng: lng}, true
+}
@@ -0,0 +1,122 @@
+// backend/internal/handlers/promotions.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	promoerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/promotions"
+	"github.com/gin-gonic/gin"
+)
+
+type PromoHandler struct {
+	promoService *promotions.Service
+}
+
+func NewPromoHandler(ps *promotions.Service) *PromoHandler {
+	return &PromoHandler{
+		promoService: ps,
+	}
+}
+
+// CreatePromoRequest is the body for creating a promo code
+type CreatePromoRequest struct {
+	Code              string     `json:"code" binding:"required"`
+	Description       string     `json:"description"`
+	DiscountType      string     `json:"discount_type" binding:"required"`
+	DiscountValue     float64    `json:"discount_value" binding:"required"`
+	MaxDiscount       *float64   `json:"max_discount"`
+	MinFare           *float64   `json:"min_fare"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	MaxRedemptions    *int       `json:"max_redemptions"`
+	MaxPerUser        *int       `json:"max_per_user"`
+	FirstRideOnly     bool       `json:"first_ride_only"`
+	StacksWithLoyalty bool       `json:"stacks_with_loyalty"`
+	RouteIDs          []string   `json:"route_ids"`
+}
+
+// ListPromos returns the operator's or the platform's promo codes
+func (h *PromoHandler) ListPromos(c *gin.Context) {
+	promos, err := h.promoService.ListPromos(c.Request.Context(), c.GetString("operator_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promo codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promos)
+}
+
+// CreatePromo adds a promo code for the operator's buses, or for every bus
+// when called by an admin
+func (h *PromoHandler) CreatePromo(c *gin.Context) {
+	var req CreatePromoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo, err := h.promoService.CreatePromo(c.Request.Context(), promotions.PromoInput{
+		OperatorID:        c.GetString("operator_id"),
+		CreatedBy:         c.GetString("user_id"),
+		Code:              req.Code,
+		Description:       req.Description,
+		DiscountType:      req.DiscountType,
+		DiscountValue:     req.DiscountValue,
+		MaxDiscount:       req.MaxDiscount,
+		MinFare:           req.MinFare,
+		StartsAt:          req.StartsAt,
+		EndsAt:            req.EndsAt,
+		MaxRedemptions:    req.MaxRedemptions,
+		MaxPerUser:        req.MaxPerUser,
+		FirstRideOnly:     req.FirstRideOnly,
+		StacksWithLoyalty: req.StacksWithLoyalty,
+		RouteIDs:          req.RouteIDs,
+	})
+	if err != nil {
+		promoErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promo)
+}
+
+// UpdatePromo pauses a promo code or resumes it
+func (h *PromoHandler) UpdatePromo(c *gin.Context) {
+	var req struct {
+		Active *bool `json:"active" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	promo, err := h.promoService.SetPromoActive(c.Request.Context(), c.Param("id"), c.GetString("operator_id"), *req.Active)
+	if err != nil {
+		promoErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promo)
+}
+
+func promoErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*promoerrors.PromoError)
+	if !ok {
+		log.Printf("[ERROR] Promo error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "PROMO_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "PROMO_CODE_TAKEN":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
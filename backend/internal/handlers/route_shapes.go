@@ -0,0 +1,76 @@
+// backend/internal/handlers/route_shapes.go
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/gin-gonic/gin"
+)
+
+// maxShapeBody is the largest GeoJSON body a route shape is read from
+const maxShapeBody = 2 << 20
+
+type RouteShapeHandler struct {
+	routes *routes.Service
+}
+
+func NewRouteShapeHandler(rs *routes.Service) *RouteShapeHandler {
+	return &RouteShapeHandler{routes: rs}
+}
+
+// SetShape sets a route's shape from a GeoJSON LineString
+func (h *RouteShapeHandler) SetShape(c *gin.Context) {
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxShapeBody+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(raw) > maxShapeBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "The shape is too large"})
+		return
+	}
+
+	routeID := c.Param("route_id")
+	shape, err := h.routes.SetShape(c.Request.Context(), routeID, raw, auditEntry(c, "route.shape_set", "route", routeID))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shape)
+}
+
+// RecordShape builds a route's shape from the recorded locations of a
+// reference trip
+func (h *RouteShapeHandler) RecordShape(c *gin.Context) {
+	var req struct {
+		ScheduleID string `json:"schedule_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routeID := c.Param("route_id")
+	shape, err := h.routes.RecordShape(c.Request.Context(), routeID, req.ScheduleID,
+		auditEntry(c, "route.shape_set", "route", routeID))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shape)
+}
+
+// DeleteShape removes a route's shape
+func (h *RouteShapeHandler) DeleteShape(c *gin.Context) {
+	routeID := c.Param("route_id")
+	if err := h.routes.DeleteShape(c.Request.Context(), routeID, auditEntry(c, "route.shape_deleted", "route", routeID)); err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Route shape deleted"})
+}
@@ -0,0 +1,214 @@
+// backend/internal/handlers/route_versions.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	routeerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/gin-gonic/gin"
+)
+
+type RouteVersionHandler struct {
+	routes *routes.Service
+}
+
+func NewRouteVersionHandler(rs *routes.Service) *RouteVersionHandler {
+	return &RouteVersionHandler{routes: rs}
+}
+
+// UpdateRoute changes a route's name, description, origin or destination
+func (h *RouteVersionHandler) UpdateRoute(c *gin.Context) {
+	var req struct {
+		RouteName   string  `json:"route_name"`
+		Description *string `json:"description"`
+		Origin      string  `json:"origin"`
+		Destination string  `json:"destination"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routeID := c.Param("route_id")
+	route, err := h.routes.UpdateRoute(c.Request.Context(), routeID, routes.RouteInput{
+		RouteName:   req.RouteName,
+		Description: req.Description,
+		Origin:      req.Origin,
+		Destination: req.Destination,
+	}, auditEntry(c, "route.updated", "route", routeID))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, route)
+}
+
+// ListVersions lists a route's versions, newest first
+func (h *RouteVersionHandler) ListVersions(c *gin.Context) {
+	versions, err := h.routes.Versions(c.Request.Context(), c.Param("route_id"), c.GetString("operator_id"))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// CreateDraft starts a draft version from the route's published stops
+func (h *RouteVersionHandler) CreateDraft(c *gin.Context) {
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	// the body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	routeID := c.Param("route_id")
+	version, err := h.routes.CreateDraft(c.Request.Context(), routeID, req.Notes,
+		auditEntry(c, "route.draft_created", "route", routeID))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// GetVersion returns one of a route's versions with its stops
+func (h *RouteVersionHandler) GetVersion(c *gin.Context) {
+	number, ok := versionNumber(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.routes.Version(c.Request.Context(), c.Param("route_id"), number, c.GetString("operator_id"))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// DeleteDraft discards a draft version
+func (h *RouteVersionHandler) DeleteDraft(c *gin.Context) {
+	number, ok := versionNumber(c)
+	if !ok {
+		return
+	}
+
+	if err := h.routes.DeleteDraft(c.Request.Context(), c.Param("route_id"), number, c.GetString("operator_id")); err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft deleted"})
+}
+
+// ReplaceStops sets a draft's stops: their order, travel times, timetables
+// and whether they are called at
+func (h *RouteVersionHandler) ReplaceStops(c *gin.Context) {
+	number, ok := versionNumber(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Stops []struct {
+			StopID     string   `json:"stop_id" binding:"required"`
+			TravelTime int      `json:"travel_time"`
+			Timetable  []string `json:"timetable"`
+			Active     *bool    `json:"active"`
+		} `json:"stops" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stops := make([]routes.StopInput, len(req.Stops))
+	for i, st := range req.Stops {
+		stops[i] = routes.StopInput{
+			StopID:     st.StopID,
+			TravelTime: st.TravelTime,
+			Timetable:  st.Timetable,
+			Active:     st.Active == nil || *st.Active,
+		}
+	}
+
+	version, err := h.routes.ReplaceStops(c.Request.Context(), c.Param("route_id"), number, stops, c.GetString("operator_id"))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// Publish makes a draft the route's current version
+func (h *RouteVersionHandler) Publish(c *gin.Context) {
+	number, ok := versionNumber(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		EffectiveFrom *time.Time `json:"effective_from"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	routeID := c.Param("route_id")
+	publication, err := h.routes.Publish(c.Request.Context(), routeID, number, effectiveFrom,
+		auditEntry(c, "route.version_published", "route", routeID))
+	if err != nil {
+		routeErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, publication)
+}
+
+func versionNumber(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusNotFound, gin.H{"error": routeerrors.ErrRouteVersionNotFound.Message})
+		return 0, false
+	}
+	return number, true
+}
+
+func routeErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*routeerrors.RouteError)
+	if !ok {
+		log.Printf("[ERROR] Route error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "ROUTE_NOT_FOUND", "VERSION_NOT_FOUND", "SHAPE_NOT_FOUND", "TRIP_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "DRAFT_EXISTS", "NOT_DRAFT", "ROUTE_NAME_TAKEN":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
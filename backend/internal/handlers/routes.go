@@ -6,11 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
+	routeerrors "github.com/Mvoii/zurura/internal/errors"
 	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/planner"
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/Mvoii/zurura/internal/services/stops"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -18,11 +23,12 @@ import (
 )
 
 type RouteHandler struct {
-	db *sql.DB
+	db      *sql.DB
+	planner *planner.Service // reloads its network when routes change, may be nil
 }
 
-func NewRouteHandler(db *sql.DB) *RouteHandler {
-	return &RouteHandler{db: db}
+func NewRouteHandler(db *sql.DB, ps *planner.Service) *RouteHandler {
+	return &RouteHandler{db: db, planner: ps}
 }
 
 type AddStopRequest struct {
@@ -70,9 +76,14 @@ func (h *RouteHandler) GetRouteDetails(c *gin.Context) {
 			s.updated_at, 
 			rs.stop_order, 
 			rs.timetable, 
-			EXTRACT(EPOCH FROM rs.estimated_arrival_time)::INT AS eta_minutes
+			EXTRACT(EPOCH FROM rs.estimated_arrival_time)::INT AS eta_minutes,
+			-- where the stop snaps onto the route's shape, the planar fraction
+			-- along it is close enough to scale its length by
+			ST_LineLocatePoint(sh.geometry::geometry, s.geolocation::geometry) * sh.length_meters,
+			ST_Distance(sh.geometry, s.geolocation)
 		FROM route_bus_stops rs
 		JOIN bus_stops s ON rs.bus_stop_id = s.id
+		LEFT JOIN route_shapes sh ON sh.route_id = rs.route_id
 		WHERE rs.route_id = $1
 		ORDER BY rs.stop_order
 	`, routeID)
@@ -86,6 +97,7 @@ func (h *RouteHandler) GetRouteDetails(c *gin.Context) {
 	for rows.Next() {
 		var stop models.RouteBusStop
 		var timetable []string
+		var along, offset sql.NullFloat64
 		stop.StopDetails = models.BusStop{}
 
 		// Make sure the number of fields matches the query
@@ -99,6 +111,8 @@ func (h *RouteHandler) GetRouteDetails(c *gin.Context) {
 			&stop.StopOrder,
 			pq.Array(&timetable),
 			&stop.TravelTime,
+			&along,
+			&offset,
 		)
 
 		if err != nil {
@@ -107,6 +121,10 @@ func (h *RouteHandler) GetRouteDetails(c *gin.Context) {
 		}
 
 		stop.Timetable = timetable
+		if along.Valid {
+			d, o := math.Round(along.Float64), math.Round(offset.Float64)
+			stop.DistanceAlong, stop.SnapOffset = &d, &o
+		}
 		stops = append(stops, stop)
 		log.Printf("[DEBUG] Stop: %v", stop)
 	}
@@ -123,52 +141,24 @@ func (h *RouteHandler) GetRouteDetails(c *gin.Context) {
 		stops = []models.RouteBusStop{}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"route": route,
-		"stops": stops,
-	})
-}
-
-func (h *RouteHandler) FindNearbyStops(c *gin.Context) {
-	lat, _ := strconv.ParseFloat(c.Query("lat"), 64)
-	lng, _ := strconv.ParseFloat(c.Query("lng"), 64)
-	radius, _ := strconv.Atoi(c.DefaultQuery("radius", "500")) //meters
-
-	rows, err := h.db.Query(`
-		SELECT
-			id, name, landmark_description, latitude, longitude, created_at, updated_at, ST_DISTANCE(geolocation, $1) AS distance
-		FEOM bus_stops
-		WHERE ST_DWithin(geolocation, $1, $2)
-		ORDER BY distance
-		LIMIT 20
-	`, fmt.Sprintf("SRID=4326;POINT(%f %f)", lng, lat), // postgis fmt
-		radius,
-	)
-
+	shape, err := routes.Shape(c.Request.Context(), h.db, routeID)
 	if err != nil {
-		log.Printf("[ERROR] %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch near stops"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch route shape"})
 		return
 	}
-
-	var stops []models.BusStop
-	for rows.Next() {
-		var stop models.BusStop
-		var distance float64
-		rows.Scan(
-			&stop.ID,
-			&stop.Name,
-			&stop.LandmarkDescription,
-			&stop.Latitude,
-			&stop.Longitude,
-			&stop.CreatedAt,
-			&stop.UpdatedAt,
-			&distance,
-		)
-		stops = append(stops, stop)
+	along := make([]*float64, len(stops))
+	for i := range stops {
+		along[i] = stops[i].DistanceAlong
+	}
+	for i, d := range routes.StopSpacing(along) {
+		stops[i].DistanceFromPrevious = d
 	}
 
-	c.JSON(http.StatusOK, stops)
+	c.JSON(http.StatusOK, gin.H{
+		"route": route,
+		"stops": stops,
+		"shape": shape,
+	})
 }
 
 // create route
@@ -181,16 +171,43 @@ func (h *RouteHandler) CreateRoute(c *gin.Context) {
 
 	routeID := uuid.New().String()
 
-	_, err := h.db.Exec(`
-		INSERT INTO bus_routes (id, route_name, description, origin, destination)
-		VALUES ($1, $2, $3, $4, $5)`,
-		routeID, route.RouteName, route.Description, route.Origin, route.Destination)
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO bus_routes (id, route_name, description, origin, destination, operator_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		routeID, route.RouteName, route.Description, route.Origin, route.Destination, c.GetString("operator_id"))
 
 	if err != nil {
 		log.Printf("[ERROR] %v", err)
 		c.JSON(http.StatusConflict, gin.H{"error": "route name already exists"})
 		return
 	}
+
+	// a new route starts on its first version, stops are added to it
+	versionID := uuid.New().String()
+	_, err = tx.Exec(`
+		INSERT INTO route_versions (id, route_id, version, status, created_by, published_by, published_at, effective_from)
+		VALUES ($1, $2, 1, 'published', NULLIF($3, '')::UUID, NULLIF($3, '')::UUID, NOW(), NOW())`,
+		versionID, routeID, c.GetString("user_id"))
+	if err == nil {
+		_, err = tx.Exec(`UPDATE bus_routes SET current_version_id = $1 WHERE id = $2`, versionID, routeID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create route"})
+		return
+	}
+	h.planner.Invalidate()
 	c.JSON(http.StatusCreated, gin.H{
 		"id":          routeID,
 		"route_name":  route.RouteName,
@@ -227,13 +244,22 @@ func (h *RouteHandler) AddStopToRoute(c *gin.Context) {
 	defer tx.Rollback()
 
 	log.Printf("check route exist")
-	// Check if route exists
-	var routeExists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM bus_routes WHERE id = $1)", routeID).Scan(&routeExists)
-	if err != nil || !routeExists {
+	// Check if route exists and belongs to the operator, legacy routes have no owner
+	var versioned bool
+	err = tx.QueryRow(`
+		SELECT current_version_id IS NOT NULL FROM bus_routes
+		WHERE id = $1 AND (operator_id = $2 OR operator_id IS NULL)`,
+		routeID, c.GetString("operator_id")).Scan(&versioned)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "route not found"})
 		return
 	}
+	// trips and bookings are sold against the published version, so its
+	// stops only change by publishing a draft
+	if versioned {
+		c.JSON(http.StatusConflict, gin.H{"error": "route stops are versioned; add the stop to a draft version and publish it"})
+		return
+	}
 
 	var StopID string
 	/* if req.StopID != "" {
@@ -304,6 +330,7 @@ func (h *RouteHandler) AddStopToRoute(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add stop to route"})
 		return
 	}
+
 	err = tx.Commit()
 	log.Printf("committed transaction")
 
@@ -316,6 +343,7 @@ func (h *RouteHandler) AddStopToRoute(c *gin.Context) {
 		}
 		return
 	}
+	h.planner.Invalidate()
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "stop added to route",
@@ -567,6 +595,7 @@ func (h *RouteHandler) GetRouteByName(c *gin.Context) {
 	for rows.Next() {
 		var stop models.RouteBusStop
 		var timetable []string
+		var along, offset sql.NullFloat64
 		stop.StopDetails = models.BusStop{}
 
 		err := rows.Scan(
@@ -579,6 +608,8 @@ func (h *RouteHandler) GetRouteByName(c *gin.Context) {
 			&stop.StopOrder,
 			pq.Array(&timetable),
 			&stop.TravelTime,
+			&along,
+			&offset,
 		)
 
 		if err != nil {
@@ -586,6 +617,10 @@ func (h *RouteHandler) GetRouteByName(c *gin.Context) {
 			continue
 		}
 		stop.Timetable = timetable
+		if along.Valid {
+			d, o := math.Round(along.Float64), math.Round(offset.Float64)
+			stop.DistanceAlong, stop.SnapOffset = &d, &o
+		}
 		stops = append(stops, stop)
 	}
 
@@ -681,7 +716,21 @@ func (h *RouteHandler) GetNearbyBusesbyStop(c *gin.Context) {
 
 	log.Printf("[DEBUG] stop name: %s, route id: %s", stopName, routeID)
 
-	pattern := "%" + stopName + "%"
+	// the route's stops the name could mean, with the stop directory's matching
+	matches, err := stops.Search(c.Request.Context(), h.db, stopName, stops.SearchOptions{RouteID: routeID, MinScore: stops.ResolveScore})
+	if err != nil {
+		var stopErr *routeerrors.StopError
+		if errors.As(err, &stopErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": stopErr.Message})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch buses"})
+		return
+	}
+	stopIDs := []string{}
+	for _, m := range matches {
+		stopIDs = append(stopIDs, m.ID)
+	}
 
 	/// DEBUG
 	log.Printf("[DEBUG] assignment window for route %s:", routeID)
@@ -704,14 +753,14 @@ func (h *RouteHandler) GetNearbyBusesbyStop(c *gin.Context) {
 		JOIN bus_stops bs ON rbs.bus_stop_id = bs.id
 		JOIN bus_routes br ON bra.route_id = br.id
 		WHERE bra.route_id = $1
-		AND bs.name ILIKE $2
+		AND bs.id = ANY($2)
 		AND b.status IN ('active','assigned')
 		AND NOW() BETWEEN bra.start_date AND bra.end_date
 		AND b.current_occupancy < b.capacity
 		ORDER BY RANDOM()
 		LIMIT 3
 	`
-	rows, err := h.db.Query(query, routeID, pattern)
+	rows, err := h.db.Query(query, routeID, pq.Array(stopIDs))
 	if err != nil {
 		log.Printf("[ERROR] fetching rows: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch buses"})
@@ -60,6 +60,16 @@ func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
 		return
 	}
 
+	// the bus must belong to the operator
+	var busOwned bool
+	err = h.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM buses WHERE id = $1 AND operator_id = $2)
+	`, req.BusID, c.GetString("operator_id")).Scan(&busOwned)
+	if err != nil || !busOwned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bus not found or not owned by operator"})
+		return
+	}
+
 	// Check for conflicts
 	var conflict string
 	err = h.db.QueryRow(`
@@ -79,8 +89,8 @@ func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
 	// Create schedule
 	_, err = h.db.Exec(`
 		INSERT INTO schedules 
-		(route_id, bus_id, driver_id, departure_time)
-		VALUES ($1, $2, $3, $4)
+		(route_id, bus_id, driver_id, departure_time, route_version_id)
+		VALUES ($1, $2, $3, $4, (SELECT current_version_id FROM bus_routes WHERE id = $1))
 	`, req.RouteID, req.BusID, req.DriverID, departureTime)
 
 	if err != nil {
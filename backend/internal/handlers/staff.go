@@ -0,0 +1,532 @@
+// backend/internal/handlers/staff.go
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/rbac"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const inviteTTL = 7 * 24 * time.Hour
+
+// EmailSender queues an email for delivery, implemented by the notification service
+type EmailSender interface {
+	QueueEmail(email, subject, body string)
+}
+
+type StaffHandler struct {
+	db     *sql.DB
+	mailer EmailSender
+	audit  *audit.Service
+}
+
+func NewStaffHandler(db *sql.DB, mailer EmailSender, auditService *audit.Service) *StaffHandler {
+	return &StaffHandler{
+		db:     db,
+		mailer: mailer,
+		audit:  auditService,
+	}
+}
+
+type InviteStaffRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateStaffRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type StaffMember struct {
+	ID              string     `json:"id"`
+	UserID          *string    `json:"user_id"`
+	Email           string     `json:"email"`
+	FirstName       *string    `json:"first_name"`
+	LastName        *string    `json:"last_name"`
+	Role            string     `json:"role"`
+	Status          string     `json:"status"`
+	Permissions     []string   `json:"permissions"`
+	InviteExpiresAt *time.Time `json:"invite_expires_at,omitempty"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ListStaff returns the operator's staff and outstanding invitations
+func (h *StaffHandler) ListStaff(c *gin.Context) {
+	operatorID := c.GetString("operator_id")
+
+	rows, err := h.db.Query(`
+		SELECT m.id, m.user_id, m.email, u.first_name, u.last_name, m.role, m.status,
+			m.invite_expires_at, m.accepted_at, m.created_at
+		FROM operator_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.operator_id = $1 AND m.status <> 'revoked'
+		ORDER BY m.created_at
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	staff := []StaffMember{}
+	for rows.Next() {
+		var m StaffMember
+		if err := rows.Scan(
+			&m.ID, &m.UserID, &m.Email, &m.FirstName, &m.LastName, &m.Role, &m.Status,
+			&m.InviteExpiresAt, &m.AcceptedAt, &m.CreatedAt,
+		); err != nil {
+			log.Printf("[ERROR] Failed to scan staff member: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch staff"})
+			return
+		}
+		m.Permissions = permissionNames(rbac.Role(m.Role))
+		staff = append(staff, m)
+	}
+
+	c.JSON(http.StatusOK, staff)
+}
+
+// InviteStaff emails an invitation to join the operator with the given role
+func (h *StaffHandler) InviteStaff(c *gin.Context) {
+	var req InviteStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !rbac.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of owner, dispatcher, conductor, accountant"})
+		return
+	}
+	if !h.canGrant(c, rbac.Role(req.Role)) {
+		return
+	}
+
+	operatorID := c.GetString("operator_id")
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var exists bool
+	err := h.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM operator_members m
+			LEFT JOIN users u ON u.id = m.user_id
+			WHERE m.operator_id = $1
+			AND (LOWER(m.email) = $2 OR LOWER(u.email) = $2)
+			AND (m.status = 'active' OR (m.status = 'invited' AND m.invite_expires_at > NOW()))
+		)
+	`, operatorID, email).Scan(&exists)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "This email is already a member or has a pending invitation"})
+		return
+	}
+
+	token, err := generateInviteToken()
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate invite token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	memberID := uuid.New().String()
+	expiresAt := time.Now().Add(inviteTTL)
+	_, err = h.db.Exec(`
+		INSERT INTO operator_members (
+			id, operator_id, email, role, status, invite_token_hash, invite_expires_at, invited_by
+		) VALUES ($1, $2, $3, $4, 'invited', $5, $6, $7)
+	`, memberID, operatorID, email, req.Role, hashInviteToken(token), expiresAt, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to create invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	entry := auditEntry(c, "staff.invite", "operator_member", memberID)
+	entry.After = gin.H{"email": email, "role": req.Role, "status": "invited"}
+	if err := h.audit.Record(c.Request.Context(), nil, entry); err != nil {
+		log.Printf("[ERROR] Failed to audit invitation %s: %v", memberID, err)
+	}
+
+	var operatorName string
+	if err := h.db.QueryRow(`SELECT name FROM bus_operators WHERE id = $1`, operatorID).Scan(&operatorName); err != nil {
+		log.Printf("[ERROR] Failed to fetch operator name: %v", err)
+	}
+
+	h.mailer.QueueEmail(email, fmt.Sprintf("You have been invited to join %s on Zurura", operatorName), fmt.Sprintf(
+		"You have been invited to join %s as a %s.\n\nAccept the invitation here: %s/invitations/accept?token=%s\n\nThe link expires on %s.",
+		operatorName, req.Role, strings.TrimRight(os.Getenv("APP_URL"), "/"), token, expiresAt.Format("2 Jan 2006"),
+	))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                memberID,
+		"email":             email,
+		"role":              req.Role,
+		"status":            "invited",
+		"invite_expires_at": expiresAt,
+	})
+}
+
+// UpdateStaffRole changes a staff member's role
+func (h *StaffHandler) UpdateStaffRole(c *gin.Context) {
+	var req UpdateStaffRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !rbac.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of owner, dispatcher, conductor, accountant"})
+		return
+	}
+	if !h.canGrant(c, rbac.Role(req.Role)) {
+		return
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	owners, ok := h.lockOwners(c, tx)
+	if !ok {
+		return
+	}
+	currentRole, ok := h.lockMember(c, tx)
+	if !ok {
+		return
+	}
+	if currentRole == string(rbac.RoleOwner) && req.Role != string(rbac.RoleOwner) && !hasOtherOwner(c, owners) {
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE operator_members SET role = $1, updated_at = NOW() WHERE id = $2
+	`, req.Role, c.Param("member_id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to update staff role: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	entry := auditEntry(c, "staff.role_change", "operator_member", c.Param("member_id"))
+	entry.Before = gin.H{"role": currentRole}
+	entry.After = gin.H{"role": req.Role}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":          c.Param("member_id"),
+		"role":        req.Role,
+		"permissions": permissionNames(rbac.Role(req.Role)),
+	})
+}
+
+// RemoveStaff revokes a staff member's access or withdraws an invitation
+func (h *StaffHandler) RemoveStaff(c *gin.Context) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	owners, ok := h.lockOwners(c, tx)
+	if !ok {
+		return
+	}
+	currentRole, ok := h.lockMember(c, tx)
+	if !ok {
+		return
+	}
+	if currentRole == string(rbac.RoleOwner) {
+		if !h.canGrant(c, rbac.RoleOwner) || !hasOtherOwner(c, owners) {
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE operator_members
+		SET status = 'revoked', invite_token_hash = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, c.Param("member_id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to revoke staff member: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staff member"})
+		return
+	}
+
+	entry := auditEntry(c, "staff.remove", "operator_member", c.Param("member_id"))
+	entry.Before = gin.H{"role": currentRole}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove staff member"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Staff member removed"})
+}
+
+// AcceptInvitation adds the logged in user to the operator that invited them
+func (h *StaffHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var invite struct {
+		ID         string
+		OperatorID string
+		Email      string
+		Role       string
+		ExpiresAt  time.Time
+	}
+	err = tx.QueryRow(`
+		SELECT id, operator_id, email, role, invite_expires_at
+		FROM operator_members
+		WHERE invite_token_hash = $1 AND status = 'invited'
+		FOR UPDATE
+	`, hashInviteToken(req.Token)).Scan(&invite.ID, &invite.OperatorID, &invite.Email, &invite.Role, &invite.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+			return
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if time.Now().After(invite.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Invitation has expired"})
+		return
+	}
+
+	var userEmail sql.NullString
+	if err := tx.QueryRow(`SELECT email FROM users WHERE id = $1`, userID).Scan(&userEmail); err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if !strings.EqualFold(userEmail.String, invite.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation was sent to a different email address"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE operator_members
+		SET user_id = $1, status = 'active', invite_token_hash = NULL, accepted_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`, userID, invite.ID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of this operator"})
+			return
+		}
+		log.Printf("[ERROR] Failed to accept invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	entry := auditEntry(c, "staff.join", "operator_member", invite.ID)
+	entry.OperatorID = invite.OperatorID
+	entry.ActorRole = invite.Role
+	entry.Before = gin.H{"status": "invited"}
+	entry.After = gin.H{"status": "active", "user_id": userID}
+	if err := h.audit.Record(c.Request.Context(), tx, entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit transaction: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Invitation accepted",
+		"operator_id": invite.OperatorID,
+		"role":        invite.Role,
+		"permissions": permissionNames(rbac.Role(invite.Role)),
+	})
+}
+
+// ListMemberships returns the operators the logged in user works for
+func (h *StaffHandler) ListMemberships(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT m.operator_id, o.name, m.role
+		FROM operator_members m
+		JOIN bus_operators o ON o.id = m.operator_id
+		WHERE m.user_id = $1 AND m.status = 'active'
+		ORDER BY (m.role = 'owner') DESC, m.accepted_at
+	`, c.GetString("user_id"))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	memberships := []gin.H{}
+	for rows.Next() {
+		var operatorID, name, role string
+		if err := rows.Scan(&operatorID, &name, &role); err != nil {
+			log.Printf("[ERROR] Failed to scan membership: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memberships"})
+			return
+		}
+		memberships = append(memberships, gin.H{
+			"operator_id":   operatorID,
+			"operator_name": name,
+			"role":          role,
+			"permissions":   permissionNames(rbac.Role(role)),
+		})
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// canGrant stops anyone but an owner from handing out or taking away the owner role
+func (h *StaffHandler) canGrant(c *gin.Context, role rbac.Role) bool {
+	if role == rbac.RoleOwner && c.GetString("operator_role") != string(rbac.RoleOwner) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can manage owners"})
+		return false
+	}
+	return true
+}
+
+// lockMember loads the member in the url for update, checking it belongs to the operator
+func (h *StaffHandler) lockMember(c *gin.Context, tx *sql.Tx) (string, bool) {
+	var role string
+	err := tx.QueryRow(`
+		SELECT role FROM operator_members
+		WHERE id = $1 AND operator_id = $2 AND status <> 'revoked'
+		FOR UPDATE
+	`, c.Param("member_id"), c.GetString("operator_id")).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Staff member not found"})
+			return "", false
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", false
+	}
+	return role, true
+}
+
+// lockOwners locks the operator's active owners. It runs before the member
+// being changed is locked, so two owners demoting or removing each other
+// take turns and the second one sees the first one's change.
+func (h *StaffHandler) lockOwners(c *gin.Context, tx *sql.Tx) ([]string, bool) {
+	rows, err := tx.Query(`
+		SELECT id FROM operator_members
+		WHERE operator_id = $1 AND role = 'owner' AND status = 'active'
+		ORDER BY id
+		FOR UPDATE
+	`, c.GetString("operator_id"))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	defer rows.Close()
+
+	var owners []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return nil, false
+		}
+		owners = append(owners, id)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	return owners, true
+}
+
+// hasOtherOwner makes sure an operator always keeps at least one active
+// owner, from the owners locked by lockOwners
+func hasOtherOwner(c *gin.Context, owners []string) bool {
+	for _, id := range owners {
+		if id != c.Param("member_id") {
+			return true
+		}
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "An operator must keep at least one owner"})
+	return false
+}
+
+func permissionNames(role rbac.Role) []string {
+	names := []string{}
+	for _, p := range rbac.Permissions(role) {
+		names = append(names, string(p))
+	}
+	return names
+}
+
+func generateInviteToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
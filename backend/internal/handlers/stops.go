@@ -0,0 +1,147 @@
+// backend/internal/handlers/stops.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	stoperrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/stops"
+	"github.com/gin-gonic/gin"
+)
+
+type StopHandler struct {
+	stops *stops.Service
+}
+
+func NewStopHandler(ss *stops.Service) *StopHandler {
+	return &StopHandler{stops: ss}
+}
+
+// SearchStops finds stops by name, alias or landmark, allowing for misspellings
+func (h *StopHandler) SearchStops(c *gin.Context) {
+	var req struct {
+		Query   string `form:"q" binding:"required"`
+		RouteID string `form:"route_id"`
+		Limit   int    `form:"limit"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matches, err := h.stops.Search(c.Request.Context(), req.Query, stops.SearchOptions{RouteID: req.RouteID, Limit: req.Limit})
+	if err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stops": matches})
+}
+
+// NearbyStops lists the stops nearest a point with the walk to each
+func (h *StopHandler) NearbyStops(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+		return
+	}
+	radius, err := strconv.Atoi(c.DefaultQuery("radius", "500")) // meters
+	if err != nil || radius <= 0 || radius > stops.MaxNearbyRadius {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "radius must be between 1 and 5000 meters"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	nearby, err := h.stops.Nearby(c.Request.Context(), lat, lng, radius, limit)
+	if err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nearby)
+}
+
+// GetStop returns a stop with its aliases and routes
+func (h *StopHandler) GetStop(c *gin.Context) {
+	stop, err := h.stops.Stop(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stop)
+}
+
+// UpdateStop sets the landmark description of a stop on one of the
+// operator's routes
+func (h *StopHandler) UpdateStop(c *gin.Context) {
+	var req struct {
+		LandmarkDescription string `json:"landmark_description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stop, err := h.stops.UpdateLandmark(c.Request.Context(), c.Param("id"), c.GetString("operator_id"), req.LandmarkDescription)
+	if err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stop)
+}
+
+// AddStopAlias gives a stop on one of the operator's routes another name
+func (h *StopHandler) AddStopAlias(c *gin.Context) {
+	var req struct {
+		Alias string `json:"alias" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := h.stops.AddAlias(c.Request.Context(), c.Param("id"), c.GetString("operator_id"), c.GetString("user_id"), req.Alias)
+	if err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// DeleteStopAlias removes one of a stop's aliases
+func (h *StopHandler) DeleteStopAlias(c *gin.Context) {
+	if err := h.stops.DeleteAlias(c.Request.Context(), c.Param("id"), c.Param("alias_id"), c.GetString("operator_id")); err != nil {
+		stopErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alias removed"})
+}
+
+func stopErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*stoperrors.StopError)
+	if !ok {
+		log.Printf("[ERROR] Stop error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "STOP_NOT_FOUND", "ALIAS_NOT_FOUND":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "ALIAS_EXISTS":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
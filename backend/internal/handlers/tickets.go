@@ -0,0 +1,160 @@
+// backend/internal/handlers/tickets.go
+package handlers
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"time"
+
+	ticketerrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/tickets"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSyncScans caps how many offline scans one sync can carry
+const maxSyncScans = 500
+
+type TicketHandler struct {
+	ticketService *tickets.Service
+}
+
+func NewTicketHandler(ts *tickets.Service) *TicketHandler {
+	return &TicketHandler{
+		ticketService: ts,
+	}
+}
+
+// ScanTicketRequest is one scan of a ticket's QR code
+type ScanTicketRequest struct {
+	Token      string     `json:"token" binding:"required"`
+	BusID      string     `json:"bus_id" binding:"required"`
+	ScheduleID string     `json:"schedule_id"`
+	DeviceID   string     `json:"device_id"`
+	ScannedAt  *time.Time `json:"scanned_at"`
+}
+
+func (r ScanTicketRequest) input() tickets.ScanInput {
+	in := tickets.ScanInput{
+		Token:      r.Token,
+		BusID:      r.BusID,
+		ScheduleID: r.ScheduleID,
+		DeviceID:   r.DeviceID,
+	}
+	if r.ScannedAt != nil {
+		in.ScannedAt = *r.ScannedAt
+	}
+	return in
+}
+
+// GetPublicKey returns the key tickets are verified with, for conductors'
+// devices to check tickets offline
+func (h *TicketHandler) GetPublicKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"algorithm":  "EdDSA",
+		"public_key": base64.StdEncoding.EncodeToString(h.ticketService.PublicKey()),
+	})
+}
+
+// GetTicket returns the signed e-ticket for one of the rider's bookings
+func (h *TicketHandler) GetTicket(c *gin.Context) {
+	ticket, err := h.ticketService.Issue(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		ticketErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// GetPassengerTickets returns a ticket for each passenger named on a booking,
+// or only the caller's own when they are one of its passengers
+func (h *TicketHandler) GetPassengerTickets(c *gin.Context) {
+	tickets, err := h.ticketService.PassengerTickets(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		ticketErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tickets": tickets})
+}
+
+// ScanTicket boards the rider holding a ticket on one of the operator's buses
+func (h *TicketHandler) ScanTicket(c *gin.Context) {
+	var req ScanTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scan, err := h.ticketService.Scan(c.Request.Context(), req.input(), auditEntry(c, "", "", ""))
+	if err == ticketerrors.ErrTicketAlreadyUsed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":        err.(*ticketerrors.TicketError).Message,
+			"booking_id":   scan.BookingID,
+			"passenger_id": scan.PassengerID,
+			"boarded_at":   scan.BoardedAt,
+		})
+		return
+	}
+	if err != nil {
+		ticketErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, scan)
+}
+
+// SyncScans applies scans a device collected while offline
+func (h *TicketHandler) SyncScans(c *gin.Context) {
+	var req struct {
+		Scans []ScanTicketRequest `json:"scans" binding:"required,dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Scans) > maxSyncScans {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many scans, sync at most 500 at a time"})
+		return
+	}
+
+	scans := make([]tickets.ScanInput, len(req.Scans))
+	for i, s := range req.Scans {
+		scans[i] = s.input()
+	}
+	results := h.ticketService.Sync(c.Request.Context(), scans, auditEntry(c, "", "", ""))
+
+	counts := map[string]int{"boarded": 0, "duplicate": 0, "rejected": 0}
+	for _, r := range results {
+		counts[r.Result]++
+	}
+	if results == nil {
+		results = []models.TicketSyncResult{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"results":   results,
+		"boarded":   counts["boarded"],
+		"duplicate": counts["duplicate"],
+		"rejected":  counts["rejected"],
+	})
+}
+
+func ticketErrorResponse(c *gin.Context, err error) {
+	e, ok := err.(*ticketerrors.TicketError)
+	if !ok {
+		log.Printf("[ERROR] Ticket error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	switch e.Code {
+	case "TICKET_NOT_FOUND", "NO_PASSENGER_TICKETS":
+		c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+	case "TICKET_UNAVAILABLE", "TICKET_NOT_BOARDABLE", "TICKET_ALREADY_USED":
+		c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+	}
+}
@@ -11,9 +11,12 @@ import (
 	"strings"
 	//"time"
 
+	autherrors "github.com/Mvoii/zurura/internal/errors"
 	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/otp"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type UserHandler struct {
@@ -40,7 +43,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 	var profilePhotoURL sql.NullString
 	
 	query := `
-		SELECT id, email, first_name, last_name, phone_number, profile_photo_url, school_name, created_at, updated_at
+		SELECT id, COALESCE(email, ''), first_name, last_name, COALESCE(phone_number, ''), profile_photo_url, COALESCE(school_name, ''), created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -114,8 +117,16 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	if req.PhoneNumber != "" {
+		// stored in E.164, the form OTP login looks riders up by
+		phone, err := otp.NormalizePhone(req.PhoneNumber)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": autherrors.ErrInvalidPhone.Message})
+			return
+		}
+		// a new number is unverified until a code sent to it is used
 		query += fmt.Sprintf(", phone_number = $%d", paramCount)
-		params = append(params, req.PhoneNumber)
+		query += fmt.Sprintf(", phone_verified_at = CASE WHEN phone_number IS DISTINCT FROM $%d THEN NULL ELSE phone_verified_at END", paramCount)
+		params = append(params, phone)
 		paramCount++
 	}
 
@@ -137,6 +148,10 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 
 	// Execute update query
 	_, err = tx.Exec(query, params...)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Phone number is already in use"})
+		return
+	}
 	if err != nil {
 		log.Printf("[ERROR] Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
@@ -157,7 +172,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	var rideCount sql.NullInt32
 	
 	fetchQuery := `
-		SELECT id, email, first_name, last_name, school_name, 
+		SELECT id, COALESCE(email, ''), first_name, last_name, COALESCE(school_name, ''), 
 		       phone_number, profile_photo_url, ride_count, 
 		       created_at, updated_at
 		FROM users
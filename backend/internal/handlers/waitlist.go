@@ -0,0 +1,131 @@
+// backend/internal/handlers/waitlist.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	waitlisterrors "github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistHandler struct {
+	bookingService *booking.BookingService
+}
+
+func NewWaitlistHandler(bs *booking.BookingService) *WaitlistHandler {
+	return &WaitlistHandler{bookingService: bs}
+}
+
+// JoinWaitlist puts the rider on a full trip's waitlist
+func (h *WaitlistHandler) JoinWaitlist(c *gin.Context) {
+	var req struct {
+		ScheduleID        string `json:"schedule_id" binding:"required"`
+		BoardingStopName  string `json:"boarding_stop_name" binding:"required"`
+		AlightingStopName string `json:"alighting_stop_name" binding:"required"`
+		SeatCount         int    `json:"seat_count"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.SeatCount == 0 {
+		req.SeatCount = 1
+	}
+
+	entry, err := h.bookingService.JoinWaitlist(c.Request.Context(), booking.WaitlistRequest{
+		UserID:            c.GetString("user_id"),
+		ScheduleID:        req.ScheduleID,
+		BoardingStopName:  req.BoardingStopName,
+		AlightingStopName: req.AlightingStopName,
+		SeatCount:         req.SeatCount,
+	})
+	if err != nil {
+		waitlistErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// ListWaitlist returns the rider's waitlist entries with their place in line
+func (h *WaitlistHandler) ListWaitlist(c *gin.Context) {
+	entries, err := h.bookingService.UserWaitlist(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		log.Printf("[ERROR] Failed to fetch waitlist: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch waitlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waitlist": entries})
+}
+
+// AcceptOffer books the seats offered to the rider, paying as CreateBooking does
+func (h *WaitlistHandler) AcceptOffer(c *gin.Context) {
+	var req struct {
+		PaymentMethod string `json:"payment_method" binding:"required"`
+		RedeemPoints  int    `json:"redeem_points"`
+		PromoCode     string `json:"promo_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking, err := h.bookingService.AcceptWaitlistOffer(c.Request.Context(), c.Param("id"), booking.CreateBookingRequest{
+		UserID:        c.GetString("user_id"),
+		PaymentMethod: payments.PaymentMethod(req.PaymentMethod),
+		RedeemPoints:  req.RedeemPoints,
+		PromoCode:     req.PromoCode,
+	})
+	if err != nil {
+		if _, ok := err.(*waitlisterrors.WaitlistError); ok {
+			waitlistErrorResponse(c, err)
+			return
+		}
+		createBookingErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, booking)
+}
+
+// LeaveWaitlist takes the rider off a waitlist, passing on any seats offered
+func (h *WaitlistHandler) LeaveWaitlist(c *gin.Context) {
+	if err := h.bookingService.LeaveWaitlist(c.Request.Context(), c.Param("id"), c.GetString("user_id")); err != nil {
+		waitlistErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left the waitlist"})
+}
+
+func waitlistErrorResponse(c *gin.Context, err error) {
+	switch e := err.(type) {
+	case *waitlisterrors.WaitlistError:
+		switch e.Code {
+		case "WAITLIST_ENTRY_NOT_FOUND":
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+		case "ALREADY_WAITLISTED", "SEATS_AVAILABLE", "NO_OFFER", "WAITLIST_CLOSED", "TRIP_DEPARTED":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		case "OFFER_EXPIRED":
+			c.JSON(http.StatusGone, gin.H{"error": e.Message})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		}
+	case *waitlisterrors.BookingError:
+		switch e.Code {
+		case "SCHEDULE_NOT_FOUND", "ROUTE_NOT_FOUND":
+			c.JSON(http.StatusNotFound, gin.H{"error": e.Message})
+		case "SCHEDULE_CANCELLED", "OPERATOR_UNAVAILABLE":
+			c.JSON(http.StatusConflict, gin.H{"error": e.Message})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": e.Message})
+		}
+	default:
+		log.Printf("[ERROR] Waitlist error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,119 @@
+// backend/internal/middleware/idempotency.go
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/idempotency"
+	"github.com/gin-gonic/gin"
+)
+
+// Idempotency makes retries of a request sent with an Idempotency-Key header
+// safe: the first response is kept and replayed for retries with the same key
+// and body, and reusing the key for a different request is a conflict. Keys
+// are per user, so it must run after AuthRequired.
+func Idempotency(svc *idempotency.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		scope := c.GetString("user_id")
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.Path, body)
+		started, rec, err := svc.Claim(c.Request.Context(), scope, key, fingerprint)
+		if err != nil {
+			// fail open like the rate limiter, the request runs unprotected
+			log.Printf("[ERROR] Idempotency check failed: %v", err)
+			c.Next()
+			return
+		}
+
+		if !started {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case !rec.Completed:
+				c.Header("Retry-After", "1")
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(rec.StatusCode, rec.ContentType, rec.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		// a request waiting on a payment can run past the lock timeout, so
+		// the key is refreshed until it finishes
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(idempotency.RefreshInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					svc.Refresh(context.Background(), scope, key)
+				}
+			}
+		}()
+
+		ctx, charged := idempotency.TrackCharges(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// the client may have gone away, which is why it will retry, so the
+		// response is stored regardless of the request's context. A server
+		// error is retried unless the rider may already have been charged.
+		ctx = context.Background()
+		if status := recorder.Status(); status >= http.StatusInternalServerError && !charged() {
+			svc.Release(ctx, scope, key)
+		} else {
+			svc.Complete(ctx, scope, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
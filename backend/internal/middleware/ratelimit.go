@@ -0,0 +1,132 @@
+// backend/internal/middleware/ratelimit.go
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/otp"
+	"github.com/Mvoii/zurura/internal/services/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig sets the limits for one group of routes
+type RateLimitConfig struct {
+	Name       string          // bucket namespace, e.g. "login"
+	PerIP      ratelimit.Limit // zero value disables the check
+	PerAccount ratelimit.Limit
+	// AccountKey identifies the account a request is for, empty skips the account check
+	AccountKey func(c *gin.Context) string
+}
+
+// RateLimit applies per ip and per account token buckets and sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+func RateLimit(store ratelimit.Store, cfg RateLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var results []ratelimit.Result
+
+		if cfg.PerIP.Enabled() {
+			key := fmt.Sprintf("%s:ip:%s", cfg.Name, c.ClientIP())
+			result, err := store.Take(c.Request.Context(), key, cfg.PerIP)
+			if err != nil {
+				// fail open, the limiter must not take the api down
+				log.Printf("[ERROR] Rate limiter failed: %v", err)
+				c.Next()
+				return
+			}
+			results = append(results, result)
+		}
+
+		if cfg.PerAccount.Enabled() && cfg.AccountKey != nil {
+			if account := cfg.AccountKey(c); account != "" {
+				key := fmt.Sprintf("%s:account:%s", cfg.Name, account)
+				result, err := store.Take(c.Request.Context(), key, cfg.PerAccount)
+				if err != nil {
+					log.Printf("[ERROR] Rate limiter failed: %v", err)
+					c.Next()
+					return
+				}
+				results = append(results, result)
+			}
+		}
+
+		if len(results) == 0 {
+			c.Next()
+			return
+		}
+
+		// report the bucket closest to running out
+		tightest := results[0]
+		for _, r := range results[1:] {
+			if !r.Allowed || (tightest.Allowed && r.Remaining < tightest.Remaining) {
+				tightest = r
+			}
+		}
+
+		c.Header("RateLimit-Limit", fmt.Sprintf("%d", tightest.Limit))
+		c.Header("RateLimit-Remaining", fmt.Sprintf("%d", tightest.Remaining))
+		c.Header("RateLimit-Reset", fmt.Sprintf("%d", ceilSeconds(tightest.Reset)))
+
+		if !tightest.Allowed {
+			c.Header("Retry-After", fmt.Sprintf("%d", ceilSeconds(tightest.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please slow down"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AccountFromJSON reads the account from a field of the json body, leaving the body readable
+func AccountFromJSON(field string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return ""
+		}
+
+		value, _ := payload[field].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
+
+// PhoneFromJSON reads a phone number from a field of the json body, in the
+// E.164 form the otp service stores, so every spelling of a number shares
+// one limit
+func PhoneFromJSON(field string) func(c *gin.Context) string {
+	account := AccountFromJSON(field)
+	return func(c *gin.Context) string {
+		raw := account(c)
+		if phone, err := otp.NormalizePhone(raw); err == nil {
+			return phone
+		}
+		return raw
+	}
+}
+
+// AccountFromUser uses the authenticated user, so it must run after AuthRequired
+func AccountFromUser(c *gin.Context) string {
+	return c.GetString("user_id")
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
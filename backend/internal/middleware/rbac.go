@@ -0,0 +1,124 @@
+// backend/internal/middleware/rbac.go
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Mvoii/zurura/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OperatorMemberRequired resolves which operator the user works for and their
+// role there, setting operator_id, operator_role and operator_status. Users on several operators
+// pick one with the X-Operator-ID header. It must run after AuthRequired.
+func OperatorMemberRequired(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			c.Abort()
+			return
+		}
+
+		var requested interface{}
+		if operatorID := c.GetHeader("X-Operator-ID"); operatorID != "" {
+			if _, err := uuid.Parse(operatorID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "X-Operator-ID must be an operator id"})
+				c.Abort()
+				return
+			}
+			requested = operatorID
+		}
+
+		// owners first, so an owner who is also staff elsewhere lands on their own operator
+		var operatorID, role, operatorStatus string
+		err := db.QueryRow(`
+			SELECT m.operator_id, m.role, o.status
+			FROM operator_members m
+			JOIN bus_operators o ON o.id = m.operator_id
+			WHERE m.user_id = $1
+			AND m.status = 'active'
+			AND ($2::uuid IS NULL OR m.operator_id = $2::uuid)
+			ORDER BY (m.role = 'owner') DESC, m.accepted_at
+			LIMIT 1
+		`, userID, requested).Scan(&operatorID, &role, &operatorStatus)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("[ERROR] Operator membership lookup failed: %v", err)
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "operator privileges required"})
+			c.Abort()
+			return
+		}
+
+		c.Set("operator_id", operatorID)
+		c.Set("operator_role", role)
+		c.Set("operator_status", operatorStatus)
+		c.Next()
+	}
+}
+
+// OperatorActiveRequired blocks operators that are pending review, rejected or
+// suspended. It must run after OperatorMemberRequired.
+func OperatorActiveRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.GetString("operator_status") {
+		case "active":
+			c.Next()
+		case "pending":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "Operator account is pending verification",
+				"operator_status": "pending",
+			})
+			c.Abort()
+		default:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "Operator account is not active",
+				"operator_status": c.GetString("operator_status"),
+			})
+			c.Abort()
+		}
+	}
+}
+
+// AdminRequired checks the user is a platform admin. The role is read from the
+// database rather than the token so revoking it takes effect immediately.
+func AdminRequired(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var isAdmin bool
+		err := db.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin')
+		`, c.GetString("user_id")).Scan(&isAdmin)
+		if err != nil {
+			log.Printf("[ERROR] Admin check failed: %v", err)
+		}
+		if err != nil || !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin privileges required"})
+			c.Abort()
+			return
+		}
+
+		c.Set("admin", true)
+		c.Next()
+	}
+}
+
+// PermissionRequired checks the operator role set by OperatorMemberRequired grants perm
+func PermissionRequired(perm rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := rbac.Role(c.GetString("operator_role"))
+		if !rbac.Can(role, perm) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Requires '%s' permission, current role: %s", perm, role),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,28 @@
+// backend/internal/middleware/request_id.go
+package middleware
+
+import (
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestID tags every request with an id, taken from the X-Request-ID header
+// when the caller sends a sensible one, and echoes it back. The id, client ip
+// and user agent are also put on the request context for the audit log.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(
+			audit.WithRequest(c.Request.Context(), requestID, c.ClientIP(), c.Request.UserAgent()),
+		)
+
+		c.Next()
+	}
+}
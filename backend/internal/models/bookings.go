@@ -14,14 +14,76 @@ type Booking struct {
 	AlightingStopName string    `json:"alighting_stop_name" db:"alighting_stop_name"`
 	Seats           SeatMap   `json:"seats" db:"seats"`
 	Fare            float64   `json:"fare" db:"fare"`
+	RefundedAmount  float64   `json:"refunded_amount" db:"refunded_amount"`
 	BookingTime     time.Time `json:"booking_time" db:"booking_time"`
 	Status          string    `json:"status" db:"status"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	ExpiresAt       time.Time `json:"expires_at" db:"expires_at"`
-	BoardedAt       time.Time `json:"boarded_at" db:"boarded_at"`
+	ScheduleID      *string   `json:"schedule_id" db:"schedule_id"`
+	DepartsAt       *time.Time `json:"departs_at" db:"departs_at"`
+	BoardedAt       *time.Time `json:"boarded_at" db:"boarded_at"`
+	LineItems       []BookingLineItem `json:"line_items" db:"-"`
+	Passengers      []BookingPassenger `json:"passengers,omitempty" db:"-"`
+	JourneyID       *string   `json:"journey_id,omitempty" db:"journey_id"` // set on the legs of a multi-leg journey
+	JourneyLeg      int       `json:"journey_leg,omitempty" db:"journey_leg"`
+}
+
+// BookingPassenger is the named rider in one of a booking's seats, for
+// bookings made for a group or on someone else's behalf
+type BookingPassenger struct {
+	ID          string     `json:"id" db:"id"`
+	BookingID   string     `json:"booking_id" db:"booking_id"`
+	Name        string     `json:"name" db:"name"`
+	SeatNumber  *string    `json:"seat_number" db:"seat_number"`
+	Child       bool       `json:"child" db:"is_child"`
+	UserID      *string    `json:"user_id" db:"user_id"` // set for a registered rider
+	Status      string     `json:"status" db:"status"`   // active or cancelled
+	BoardedAt   *time.Time `json:"boarded_at" db:"boarded_at"`
+	CancelledAt *time.Time `json:"cancelled_at" db:"cancelled_at"`
+}
+
+// BookingLineItem is one part of a booking's fare, discounts are negative
+type BookingLineItem struct {
+	Type        string  `json:"type" db:"type"`
+	Description string  `json:"description" db:"description"`
+	Amount      float64 `json:"amount" db:"amount"`
+	Points      int     `json:"points,omitempty" db:"points"`
+}
+
+// BookingCancellation is one cancellation of some or all of a booking's seats
+type BookingCancellation struct {
+	ID             string    `json:"id" db:"id"`
+	BookingID      string    `json:"booking_id" db:"booking_id"`
+	Seats          int       `json:"seats" db:"seats"`
+	SeatNumbers    []string  `json:"seat_numbers" db:"seat_numbers"`
+	RefundPercent  float64   `json:"refund_percent" db:"refund_percent"`
+	RefundAmount   float64   `json:"refund_amount" db:"refund_amount"`
+	Reason         string    `json:"reason" db:"reason"` // rider, operator or no_show
+	PassengerIDs   []string  `json:"passenger_ids,omitempty" db:"passenger_ids"`
+	SeatsRemaining int       `json:"seats_remaining" db:"-"`
+	Status         string    `json:"status" db:"-"` // the booking's status afterwards
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	// the later legs of a journey cancelled along with this one
+	Connections []BookingCancellation `json:"connections,omitempty" db:"-"`
 }
 
 type SeatMap struct {
 	SeatNumbers []string `json:"seat_numbers"`
 	Count       int      `json:"count"`
 }
+
+// SeatHold reserves seats on a bus while the rider checks out. A booking
+// made with the hold's ID takes its seats; otherwise they are released at
+// ExpiresAt.
+type SeatHold struct {
+	ID          string    `json:"id" db:"id"`
+	UserID      string    `json:"user_id" db:"user_id"`
+	BusID       string    `json:"bus_id" db:"bus_id"`
+	ScheduleID  *string   `json:"schedule_id" db:"schedule_id"`
+	Seats       int       `json:"seats" db:"seats"`
+	SeatNumbers []string  `json:"seat_numbers" db:"seat_numbers"`
+	Status      string    `json:"status" db:"status"` // held, booked, released or expired
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+	BookingID   *string   `json:"booking_id" db:"booking_id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -3,13 +3,45 @@ package models
 import "time"
 
 type BusPass struct {
-	ID             string    `json:"id" db:"id"`
-	UserID         string    `json:"user_id" db:"user_id"`
-	PassType       string    `json:"pass_type" db:"pass_type"`
-	Balance        float64   `json:"balance" db:"balance"`
-	Fee            float64   `json:"fee" db:"fee"`
-	Status         string    `json:"status" db:"status"`
-	ExpirationDate time.Time `json:"expiration_date" db:"expiration_date"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	ProductID      *string    `json:"product_id" db:"product_id"`
+	PassType       string     `json:"pass_type" db:"pass_type"`
+	Balance        float64    `json:"balance" db:"balance"`
+	Fee            float64    `json:"fee" db:"fee"`
+	Status         string     `json:"status" db:"status"`
+	AutoRenew      bool       `json:"auto_renew" db:"auto_renew"`
+	PaymentMethod  *string    `json:"payment_method" db:"payment_method"`
+	ExpirationDate time.Time  `json:"expiration_date" db:"expiration_date"`
+	CancelledAt    *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type PassProduct struct {
+	ID           string     `json:"id" db:"id"`
+	OperatorID   *string    `json:"operator_id" db:"operator_id"`
+	Name         string     `json:"name" db:"name"`
+	Description  *string    `json:"description" db:"description"`
+	PassType     string     `json:"pass_type" db:"pass_type"`
+	Price        float64    `json:"price" db:"price"`
+	Credit       float64    `json:"credit" db:"credit"`
+	ValidityDays int        `json:"validity_days" db:"validity_days"`
+	EndsAt       *time.Time `json:"ends_at" db:"ends_at"`
+	Eligibility  string     `json:"eligibility" db:"eligibility"`
+	SchoolNames  []string   `json:"school_names" db:"school_names"`
+	RouteIDs     []string   `json:"route_ids" db:"-"`
+	Active       bool       `json:"active" db:"active"`
+}
+
+type PassTransaction struct {
+	ID           string    `json:"id" db:"id"`
+	PassID       string    `json:"pass_id" db:"pass_id"`
+	Type         string    `json:"type" db:"type"`
+	Amount       float64   `json:"amount" db:"amount"`
+	BalanceAfter float64   `json:"balance_after" db:"balance_after"`
+	PaymentID    *string   `json:"payment_id,omitempty" db:"payment_id"`
+	BookingID    *string   `json:"booking_id,omitempty" db:"booking_id"`
+	Description  *string   `json:"description,omitempty" db:"description"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
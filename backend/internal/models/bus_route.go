@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type BusRoute struct {
 	ID          string    `json:"id" db:"id"`
@@ -21,4 +24,54 @@ type RouteBusStop struct {
 	StopDetails BusStop   `json:"stop_details"`
 	TravelTime  int       `json:"travel_time" db:"estimated_arrival_time"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// along the route's shape when it has one
+	DistanceAlong        *float64 `json:"distance_along_meters,omitempty"`
+	DistanceFromPrevious *float64 `json:"distance_from_previous_meters,omitempty"`
+	SnapOffset           *float64 `json:"snap_offset_meters,omitempty"` // from the stop to the shape
+}
+
+// RouteVersion is one edit of a route's stops. Drafts are edited and then
+// published, superseding the version before them; trips keep the version
+// they run and bookings the version they were sold against.
+type RouteVersion struct {
+	ID            string             `json:"id" db:"id"`
+	RouteID       string             `json:"route_id" db:"route_id"`
+	Version       int                `json:"version" db:"version"`
+	Status        string             `json:"status" db:"status"` // draft, published, superseded
+	Notes         string             `json:"notes" db:"notes"`
+	CreatedBy     *string            `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+	PublishedBy   *string            `json:"published_by" db:"published_by"`
+	PublishedAt   *time.Time         `json:"published_at" db:"published_at"`
+	EffectiveFrom *time.Time         `json:"effective_from" db:"effective_from"`
+	Stops         []RouteVersionStop `json:"stops,omitempty" db:"-"`
+}
+
+// RouteVersionStop is a stop of a route version, in order
+type RouteVersionStop struct {
+	BusStopID  string   `json:"bus_stop_id" db:"bus_stop_id"`
+	Name       string   `json:"name" db:"name"`
+	StopOrder  int      `json:"stop_order" db:"stop_order"`
+	TravelTime int      `json:"travel_time" db:"estimated_arrival_time"` // minutes from route start
+	Timetable  []string `json:"timetable" db:"timetable"`
+	Active     bool     `json:"active" db:"is_active"`
+}
+
+// RoutePublication is the outcome of publishing a route version
+type RoutePublication struct {
+	Version        RouteVersion `json:"version"`
+	SchedulesMoved int          `json:"schedules_moved"` // upcoming trips now running the version
+	RidersNotified int          `json:"riders_notified"`
+}
+
+// RouteShape is the path a route's buses drive, as a GeoJSON LineString
+type RouteShape struct {
+	RouteID          string          `json:"route_id" db:"route_id"`
+	Geometry         json.RawMessage `json:"geometry" db:"geometry"`
+	LengthMeters     float64         `json:"length_meters" db:"length_meters"`
+	Source           string          `json:"source" db:"source"` // geojson, trace
+	SourceScheduleID *string         `json:"source_schedule_id,omitempty" db:"source_schedule_id"`
+	CreatedBy        *string         `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
 }
@@ -12,3 +12,44 @@ type BusStop struct {
 	CreatedAt           time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
+
+// BusStopAlias is another name riders know a stop by
+type BusStopAlias struct {
+	ID        string    `json:"id" db:"id"`
+	BusStopID string    `json:"bus_stop_id" db:"bus_stop_id"`
+	Alias     string    `json:"alias" db:"alias"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// StopRoute is a route calling at a stop
+type StopRoute struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	StopOrder int    `json:"stop_order"`
+}
+
+// StopDetails is a stop in the stop directory with its aliases and routes
+type StopDetails struct {
+	BusStop
+	Aliases []BusStopAlias `json:"aliases"`
+	Routes  []StopRoute    `json:"routes"`
+}
+
+// StopMatch is a stop found by name, alias or landmark, with what matched
+// and how closely, from 0 to 1
+type StopMatch struct {
+	BusStop
+	MatchedOn string   `json:"matched_on"` // name, alias or landmark
+	Matched   string   `json:"matched"`
+	Score     float64  `json:"score"`
+	Routes    []string `json:"routes"` // names of the routes calling at the stop
+}
+
+// NearbyStop is a stop near a point, with the distance on foot
+type NearbyStop struct {
+	BusStop
+	DistanceMeters int      `json:"distance_meters"` // in a straight line
+	WalkingMeters  int      `json:"walking_meters"`
+	WalkingMinutes int      `json:"walking_minutes"`
+	Routes         []string `json:"routes"`
+}
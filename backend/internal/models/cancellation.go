@@ -0,0 +1,37 @@
+// backend/internal/models/cancellation.go
+package models
+
+import "time"
+
+type CancellationPolicy struct {
+	ID                  string             `json:"id" db:"id"`
+	OperatorID          string             `json:"operator_id" db:"operator_id"`
+	RouteID             *string            `json:"route_id" db:"route_id"`
+	Name                string             `json:"name" db:"name"`
+	Rules               []CancellationRule `json:"rules" db:"-"`
+	NoShowRefundPercent float64            `json:"no_show_refund_percent" db:"no_show_refund_percent"`
+	NoShowGraceMinutes  int                `json:"no_show_grace_minutes" db:"no_show_grace_minutes"`
+	Active              bool               `json:"active" db:"active"`
+	CreatedAt           time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// CancellationRule refunds RefundPercent when cancelling at least
+// MinMinutesBefore departure, negative for after departure
+type CancellationRule struct {
+	MinMinutesBefore int     `json:"min_minutes_before" db:"min_minutes_before"`
+	RefundPercent    float64 `json:"refund_percent" db:"refund_percent"`
+}
+
+// CancellationPreview is what cancelling seats would refund right now
+type CancellationPreview struct {
+	BookingID      string     `json:"booking_id"`
+	Seats          int        `json:"seats"`
+	SeatNumbers    []string   `json:"seat_numbers"`
+	SeatsRemaining int        `json:"seats_remaining"`
+	DepartsAt      *time.Time `json:"departs_at"`
+	Policy         *string    `json:"policy"` // nil when the operator has no policy
+	SeatValue      float64    `json:"seat_value"`
+	RefundPercent  float64    `json:"refund_percent"`
+	RefundAmount   float64    `json:"refund_amount"`
+}
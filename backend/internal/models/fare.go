@@ -0,0 +1,51 @@
+// backend/internal/models/fare.go
+package models
+
+import "time"
+
+type StopFare struct {
+	FromStopID string  `json:"from_stop_id" db:"from_stop_id"`
+	ToStopID   string  `json:"to_stop_id" db:"to_stop_id"`
+	Fare       float64 `json:"fare" db:"fare"`
+}
+
+type FareMultiplier struct {
+	ID         string     `json:"id" db:"id"`
+	OperatorID *string    `json:"operator_id" db:"operator_id"`
+	RouteID    *string    `json:"route_id" db:"route_id"`
+	Name       string     `json:"name" db:"name"`
+	Multiplier float64    `json:"multiplier" db:"multiplier"`
+	DaysOfWeek []int      `json:"days_of_week" db:"days_of_week"`
+	StartTime  *string    `json:"start_time" db:"start_time"` // HH:MM local time
+	EndTime    *string    `json:"end_time" db:"end_time"`
+	StartsAt   *time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at" db:"ends_at"`
+	Active     bool       `json:"active" db:"active"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type FareOverride struct {
+	ID         string     `json:"id" db:"id"`
+	OperatorID string     `json:"operator_id" db:"operator_id"`
+	RouteID    string     `json:"route_id" db:"route_id"`
+	FromStopID *string    `json:"from_stop_id" db:"from_stop_id"`
+	ToStopID   *string    `json:"to_stop_id" db:"to_stop_id"`
+	Fare       float64    `json:"fare" db:"fare"`
+	Reason     *string    `json:"reason" db:"reason"`
+	StartsAt   time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at" db:"ends_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type FareQuote struct {
+	ID                string            `json:"quote_id" db:"id"`
+	Token             string            `json:"quote_token" db:"-"`
+	BusID             string            `json:"bus_id" db:"bus_id"`
+	RouteID           string            `json:"route_id" db:"route_id"`
+	BoardingStopName  string            `json:"boarding_stop_name" db:"boarding_stop_name"`
+	AlightingStopName string            `json:"alighting_stop_name" db:"alighting_stop_name"`
+	Seats             int               `json:"seats" db:"seats"`
+	LineItems         []BookingLineItem `json:"line_items" db:"line_items"`
+	Total             float64           `json:"total" db:"total"`
+	ExpiresAt         time.Time         `json:"expires_at" db:"expires_at"`
+}
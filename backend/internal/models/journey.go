@@ -0,0 +1,77 @@
+// backend/internal/models/journey.go
+package models
+
+import "time"
+
+// Journey is a trip across transfers booked with one payment. Each leg is a
+// booking of its own, in the order they are ridden.
+type Journey struct {
+	ID            string    `json:"id" db:"id"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	Seats         int       `json:"seats" db:"seats"`
+	Fare          float64   `json:"fare" db:"fare"`
+	PaymentMethod string    `json:"payment_method" db:"payment_method"`
+	TransactionID string    `json:"transaction_id" db:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	Legs          []Booking `json:"legs" db:"-"`
+}
+
+// Itinerary is one way of making a trip, riding each leg in turn and
+// changing buses at the stops between them
+type Itinerary struct {
+	Legs      []ItineraryLeg `json:"legs"`
+	DepartsAt time.Time      `json:"departs_at"`
+	ArrivesAt time.Time      `json:"arrives_at"`
+	Transfers int            `json:"transfers"`
+}
+
+// ItineraryLeg is a ride on one scheduled trip between two of its stops
+type ItineraryLeg struct {
+	RouteID           string    `json:"route_id"`
+	RouteName         string    `json:"route_name"`
+	BusID             string    `json:"bus_id"`
+	ScheduleID        string    `json:"schedule_id"`
+	BoardingStopName  string    `json:"boarding_stop_name"`
+	AlightingStopName string    `json:"alighting_stop_name"`
+	DepartsAt         time.Time `json:"departs_at"`
+	ArrivesAt         time.Time `json:"arrives_at"`
+	TransferWait      int       `json:"transfer_wait_minutes,omitempty"` // minutes waited at the stop before boarding
+}
+
+// PlannedItinerary is a door to door trip from the journey planner: walking
+// to a stop, riding one or more buses and walking on to the destination
+type PlannedItinerary struct {
+	Legs        []PlannedLeg `json:"legs"`
+	DepartsAt   time.Time    `json:"departs_at"`
+	ArrivesAt   time.Time    `json:"arrives_at"`
+	Transfers   int          `json:"transfers"`
+	WalkMeters  int          `json:"walk_meters"`
+	Fare        float64      `json:"fare"`
+	DurationMin int          `json:"duration_minutes"`
+}
+
+// PlannedLeg is a walk or a bus ride in a planned itinerary. Bus legs carry
+// what POST /journeys needs to book them.
+type PlannedLeg struct {
+	Mode       string      `json:"mode"` // "walk" or "bus"
+	From       PlannedStop `json:"from"`
+	To         PlannedStop `json:"to"`
+	DepartsAt  time.Time   `json:"departs_at"`
+	ArrivesAt  time.Time   `json:"arrives_at"`
+	Meters     int         `json:"distance_meters,omitempty"` // walk legs
+	RouteID    string      `json:"route_id,omitempty"`
+	RouteName  string      `json:"route_name,omitempty"`
+	BusID      string      `json:"bus_id,omitempty"`
+	ScheduleID string      `json:"schedule_id,omitempty"`
+	Stops      int         `json:"stops,omitempty"` // stops ridden past boarding
+	Fare       float64     `json:"fare,omitempty"`
+}
+
+// PlannedStop is where a planned leg starts or ends, a bus stop or the
+// rider's own origin or destination
+type PlannedStop struct {
+	StopID    string  `json:"stop_id,omitempty"`
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
@@ -0,0 +1,50 @@
+// backend/internal/models/loyalty.go
+package models
+
+import "time"
+
+type LoyaltyProgramme struct {
+	ID                string        `json:"id" db:"id"`
+	OperatorID        *string       `json:"operator_id" db:"operator_id"`
+	Name              string        `json:"name" db:"name"`
+	PointsPerRide     int           `json:"points_per_ride" db:"points_per_ride"`
+	PointsPerShilling float64       `json:"points_per_shilling" db:"points_per_shilling"`
+	RedemptionValue   float64       `json:"redemption_value" db:"redemption_value"`
+	MinRedeemPoints   int           `json:"min_redeem_points" db:"min_redeem_points"`
+	MaxRedeemPercent  float64       `json:"max_redeem_percent" db:"max_redeem_percent"`
+	Active            bool          `json:"active" db:"active"`
+	RouteIDs          []string      `json:"route_ids" db:"-"`
+	Tiers             []LoyaltyTier `json:"tiers" db:"-"`
+	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+type LoyaltyTier struct {
+	ID              string  `json:"id" db:"id"`
+	Name            string  `json:"name" db:"name"`
+	ThresholdPoints int     `json:"threshold_points" db:"threshold_points"`
+	EarnMultiplier  float64 `json:"earn_multiplier" db:"earn_multiplier"`
+	DiscountPercent float64 `json:"discount_percent" db:"discount_percent"`
+}
+
+type LoyaltyAccount struct {
+	ProgrammeID     string       `json:"programme_id" db:"programme_id"`
+	ProgrammeName   string       `json:"programme_name" db:"programme_name"`
+	OperatorID      *string      `json:"operator_id" db:"operator_id"`
+	PointsBalance   int          `json:"points_balance" db:"points_balance"`
+	LifetimePoints  int          `json:"lifetime_points" db:"lifetime_points"`
+	Rides           int          `json:"rides" db:"rides"`
+	RedemptionValue float64      `json:"redemption_value" db:"redemption_value"`
+	Tier            *LoyaltyTier `json:"tier" db:"-"`
+	NextTier        *LoyaltyTier `json:"next_tier,omitempty" db:"-"`
+}
+
+type LoyaltyTransaction struct {
+	ID           string    `json:"id" db:"id"`
+	ProgrammeID  string    `json:"programme_id" db:"programme_id"`
+	BookingID    *string   `json:"booking_id,omitempty" db:"booking_id"`
+	Type         string    `json:"type" db:"type"`
+	Points       int       `json:"points" db:"points"`
+	BalanceAfter int       `json:"balance_after" db:"balance_after"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,25 @@
+// backend/internal/models/promo.go
+package models
+
+import "time"
+
+type PromoCode struct {
+	ID                string     `json:"id" db:"id"`
+	OperatorID        *string    `json:"operator_id" db:"operator_id"`
+	Code              string     `json:"code" db:"code"`
+	Description       *string    `json:"description" db:"description"`
+	DiscountType      string     `json:"discount_type" db:"discount_type"`
+	DiscountValue     float64    `json:"discount_value" db:"discount_value"`
+	MaxDiscount       *float64   `json:"max_discount" db:"max_discount"`
+	MinFare           *float64   `json:"min_fare" db:"min_fare"`
+	StartsAt          time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at" db:"ends_at"`
+	MaxRedemptions    *int       `json:"max_redemptions" db:"max_redemptions"`
+	MaxPerUser        *int       `json:"max_per_user" db:"max_per_user"`
+	RedemptionCount   int        `json:"redemption_count" db:"redemption_count"`
+	FirstRideOnly     bool       `json:"first_ride_only" db:"first_ride_only"`
+	StacksWithLoyalty bool       `json:"stacks_with_loyalty" db:"stacks_with_loyalty"`
+	Active            bool       `json:"active" db:"active"`
+	RouteIDs          []string   `json:"route_ids" db:"-"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,43 @@
+// backend/internal/models/ticket.go
+package models
+
+import "time"
+
+// Ticket is a booking's signed e-ticket, shown to the conductor as a QR code
+type Ticket struct {
+	BookingID   string     `json:"booking_id"`
+	BusID       string     `json:"bus_id"`
+	ScheduleID  *string    `json:"schedule_id"`
+	Seats       int        `json:"seats"`
+	SeatNumbers []string   `json:"seat_numbers"`
+	PassengerID *string    `json:"passenger_id,omitempty"` // set on a named passenger's own ticket
+	Passenger   string     `json:"passenger,omitempty"`
+	DepartsAt   *time.Time `json:"departs_at"`
+	Token       string     `json:"token"` // the QR code's content
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
+
+// TicketScan is one scan of a ticket at boarding
+type TicketScan struct {
+	ID          string    `json:"id" db:"id"`
+	BookingID   string    `json:"booking_id" db:"booking_id"`
+	PassengerID *string   `json:"passenger_id,omitempty" db:"passenger_id"`
+	BusID       string    `json:"bus_id" db:"bus_id"`
+	ScannedBy   string    `json:"scanned_by" db:"scanned_by"`
+	DeviceID    *string   `json:"device_id" db:"device_id"`
+	Result      string    `json:"result" db:"result"` // boarded or duplicate
+	Offline     bool      `json:"offline" db:"offline"`
+	Seats       int       `json:"seats" db:"-"`
+	ScannedAt   time.Time `json:"scanned_at" db:"scanned_at"`
+	ReceivedAt  time.Time `json:"received_at" db:"received_at"`
+	BoardedAt   time.Time `json:"boarded_at" db:"-"` // when the first scan boarded the rider
+}
+
+// TicketSyncResult is what became of one scan in a batch collected offline
+type TicketSyncResult struct {
+	Index     int         `json:"index"`
+	BookingID string      `json:"booking_id,omitempty"`
+	Result    string      `json:"result"` // boarded, duplicate or rejected
+	Error     string      `json:"error,omitempty"`
+	Scan      *TicketScan `json:"scan,omitempty"`
+}
@@ -0,0 +1,23 @@
+// backend/internal/models/waitlist.go
+package models
+
+import "time"
+
+// WaitlistEntry is a rider waiting for seats on a full trip. When seats free
+// up the entry is offered them, held until OfferExpiresAt.
+type WaitlistEntry struct {
+	ID                string     `json:"id" db:"id"`
+	ScheduleID        string     `json:"schedule_id" db:"schedule_id"`
+	BusID             string     `json:"bus_id" db:"bus_id"`
+	UserID            string     `json:"user_id" db:"user_id"`
+	Seats             int        `json:"seats" db:"seats"`
+	BoardingStopName  string     `json:"boarding_stop_name" db:"boarding_stop_name"`
+	AlightingStopName string     `json:"alighting_stop_name" db:"alighting_stop_name"`
+	Status            string     `json:"status" db:"status"`        // waiting, offered, booked, expired, left or cancelled
+	Position          int        `json:"position,omitempty" db:"-"` // 1 for the next rider offered seats, while waiting
+	DepartureTime     time.Time  `json:"departure_time" db:"-"`
+	OfferedAt         *time.Time `json:"offered_at" db:"offered_at"`
+	OfferExpiresAt    *time.Time `json:"offer_expires_at" db:"offer_expires_at"`
+	BookingID         *string    `json:"booking_id" db:"booking_id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,68 @@
+// backend/internal/rbac/rbac.go
+package rbac
+
+// Role is a staff member's role within an operator
+type Role string
+
+const (
+	RoleOwner      Role = "owner"
+	RoleDispatcher Role = "dispatcher"
+	RoleConductor  Role = "conductor"
+	RoleAccountant Role = "accountant"
+)
+
+// Permission is an action on an operator's resources
+type Permission string
+
+const (
+	PermManageBuses     Permission = "manage_buses"
+	PermManageRoutes    Permission = "manage_routes"
+	PermManageSchedules Permission = "manage_schedules"
+	PermViewRevenue     Permission = "view_revenue"
+	PermIssueRefunds    Permission = "issue_refunds"
+	PermManageStaff     Permission = "manage_staff"
+	PermValidateTickets Permission = "validate_tickets"
+	// upload verification documents for the platform admins
+	PermManageVerification Permission = "manage_verification"
+	PermViewAudit          Permission = "view_audit"
+	// passes and other prices riders pay
+	PermManagePricing Permission = "manage_pricing"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleOwner: {
+		PermManageBuses, PermManageRoutes, PermManageSchedules, PermViewRevenue,
+		PermIssueRefunds, PermManageStaff, PermValidateTickets, PermManageVerification,
+		PermViewAudit, PermManagePricing,
+	},
+	RoleDispatcher: {
+		PermManageBuses, PermManageRoutes, PermManageSchedules, PermValidateTickets,
+	},
+	RoleConductor: {
+		PermValidateTickets,
+	},
+	RoleAccountant: {
+		PermViewRevenue, PermIssueRefunds, PermViewAudit, PermManagePricing,
+	},
+}
+
+// ValidRole reports whether r is a known role
+func ValidRole(r string) bool {
+	_, ok := rolePermissions[Role(r)]
+	return ok
+}
+
+// Can reports whether the role grants the permission
+func Can(r Role, p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions lists what the role grants
+func Permissions(r Role) []Permission {
+	return append([]Permission(nil), rolePermissions[r]...)
+}
@@ -0,0 +1,188 @@
+// backend/internal/services/audit/audit.go
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PlatformChain is the chain for entries that belong to no operator, e.g. user role changes
+const PlatformChain = "platform"
+
+// Entry describes one change
+type Entry struct {
+	ActorID    string // empty for system actions
+	ActorRole  string // e.g. admin, owner, dispatcher, rider
+	OperatorID string // operator the entity belongs to, also picks the hash chain
+	Action     string // e.g. operator.approve
+	EntityType string
+	EntityID   string
+	Before     interface{} // state before the change, nil on create
+	After      interface{} // state after the change, nil on delete
+	// filled from the request context when empty
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+// Log is a stored entry
+type Log struct {
+	ID         string    `json:"id"`
+	Seq        int64     `json:"seq"`
+	ChainKey   string    `json:"chain_key"`
+	ActorID    string    `json:"actor_id,omitempty"`
+	ActorRole  string    `json:"actor_role,omitempty"`
+	OperatorID string    `json:"operator_id,omitempty"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id,omitempty"`
+	Before     RawJSON   `json:"before"`
+	After      RawJSON   `json:"after"`
+	Diff       RawJSON   `json:"diff"`
+	IP         string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewAuditService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+type requestMetaKey struct{}
+
+type requestMeta struct {
+	requestID string
+	ip        string
+	userAgent string
+}
+
+// WithRequest stores request details on the context so services that only
+// see a context can still attribute their entries
+func WithRequest(ctx context.Context, requestID, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, requestMeta{
+		requestID: requestID,
+		ip:        ip,
+		userAgent: userAgent,
+	})
+}
+
+// Record appends the entry to its chain. Pass the transaction making the change
+// so the entry is only kept if the change is; with a nil tx the entry is
+// written in its own transaction.
+func (s *Service) Record(ctx context.Context, tx *sql.Tx, e Entry) error {
+	if tx != nil {
+		return s.record(ctx, tx, e)
+	}
+
+	own, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer own.Rollback()
+
+	if err := s.record(ctx, own, e); err != nil {
+		return err
+	}
+	if err := own.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) record(ctx context.Context, tx *sql.Tx, e Entry) error {
+	if meta, ok := ctx.Value(requestMetaKey{}).(requestMeta); ok {
+		if e.RequestID == "" {
+			e.RequestID = meta.requestID
+		}
+		if e.IP == "" {
+			e.IP = meta.ip
+		}
+		if e.UserAgent == "" {
+			e.UserAgent = meta.userAgent
+		}
+	}
+
+	before, err := canonicalValue(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := canonicalValue(e.After)
+	if err != nil {
+		return err
+	}
+	diff, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	l := Log{
+		ChainKey:   e.OperatorID,
+		ActorID:    e.ActorID,
+		ActorRole:  e.ActorRole,
+		OperatorID: e.OperatorID,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Before:     before,
+		After:      after,
+		Diff:       diff,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		// postgres keeps microseconds, the hash must survive the round trip
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if l.ChainKey == "" {
+		l.ChainKey = PlatformChain
+	}
+
+	// one writer per chain at a time, released when the transaction ends
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, chainLockClass, l.ChainKey); err != nil {
+		log.Printf("[ERROR] Failed to lock audit chain: %v", err)
+		return fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT seq, hash FROM audit_logs
+		WHERE chain_key = $1
+		ORDER BY seq DESC
+		LIMIT 1
+	`, l.ChainKey).Scan(&l.Seq, &l.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[ERROR] Failed to read audit chain head: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	l.Seq++
+	l.Hash = computeHash(l)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (
+			chain_key, seq, user_id, actor_role, operator_id, action, entity_type, entity_id,
+			old_data, new_data, diff, ip_address, user_agent, request_id, prev_hash, hash, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	`, l.ChainKey, l.Seq, nullable(l.ActorID), nullable(l.ActorRole), nullable(l.OperatorID), l.Action,
+		l.EntityType, nullable(l.EntityID), l.Before.value(), l.After.value(), l.Diff.value(),
+		nullable(l.IP), nullable(l.UserAgent), nullable(l.RequestID), l.PrevHash, l.Hash, l.CreatedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to write audit entry: %v", err)
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,235 @@
+// backend/internal/services/audit/chain.go
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+)
+
+// advisory lock namespace for audit chains
+const chainLockClass = 7301
+
+// RawJSON is canonical json: object keys sorted, no insignificant whitespace.
+// Postgres reformats jsonb, so values are canonicalised again when read back.
+type RawJSON json.RawMessage
+
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+func (r RawJSON) value() interface{} {
+	if len(r) == 0 {
+		return nil
+	}
+	return []byte(r)
+}
+
+// canonicalValue encodes v as canonical json, nil stays empty
+func canonicalValue(v interface{}) (RawJSON, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit state: %w", err)
+	}
+	return canonicalize(data)
+}
+
+// canonicalize re-encodes json so equal values always give equal bytes
+func canonicalize(data []byte) (RawJSON, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode audit state: %w", err)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit state: %w", err)
+	}
+	return out, nil
+}
+
+type change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Diff lists the top level fields that differ between two json objects as
+// {"field": {"from": ..., "to": ...}}. Values that are not objects are
+// compared whole under the "value" key.
+func Diff(before, after RawJSON) (RawJSON, error) {
+	var b, a interface{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, fmt.Errorf("failed to decode audit state: %w", err)
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, fmt.Errorf("failed to decode audit state: %w", err)
+		}
+	}
+
+	changes := map[string]change{}
+	bObj, bIsObj := b.(map[string]interface{})
+	aObj, aIsObj := a.(map[string]interface{})
+	if (bIsObj || b == nil) && (aIsObj || a == nil) {
+		for k, bv := range bObj {
+			if av, ok := aObj[k]; !ok || !reflect.DeepEqual(av, bv) {
+				changes[k] = change{From: bv, To: aObj[k]}
+			}
+		}
+		for k, av := range aObj {
+			if _, ok := bObj[k]; !ok {
+				changes[k] = change{From: nil, To: av}
+			}
+		}
+	} else if !reflect.DeepEqual(a, b) {
+		changes["value"] = change{From: b, To: a}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	return canonicalValue(changes)
+}
+
+// computeHash covers every stored field and the previous hash, so editing,
+// removing or reordering entries breaks the chain
+func computeHash(l Log) string {
+	fields, _ := json.Marshal([]interface{}{
+		l.ChainKey,
+		l.Seq,
+		l.PrevHash,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+		l.ActorID,
+		l.ActorRole,
+		l.OperatorID,
+		l.Action,
+		l.EntityType,
+		l.EntityID,
+		string(l.Before),
+		string(l.After),
+		string(l.Diff),
+		l.IP,
+		l.UserAgent,
+		l.RequestID,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyResult reports whether a chain is intact
+type VerifyResult struct {
+	ChainKey string `json:"chain_key"`
+	Entries  int64  `json:"entries"`
+	Valid    bool   `json:"valid"`
+	// first entry that does not check out
+	BrokenAtSeq int64  `json:"broken_at_seq,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	HeadHash    string `json:"head_hash,omitempty"`
+}
+
+// Verify walks a chain from the start, recomputing every hash
+func (s *Service) Verify(ctx context.Context, chainKey string) (*VerifyResult, error) {
+	rows, err := s.db.QueryContext(ctx, selectLogs+`
+		WHERE chain_key = $1
+		ORDER BY seq
+	`, chainKey)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	result := &VerifyResult{ChainKey: chainKey, Valid: true}
+	var prevHash string
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries++
+
+		reason := ""
+		switch {
+		case l.Seq != result.Entries:
+			reason = fmt.Sprintf("expected entry %d, found %d", result.Entries, l.Seq)
+		case l.PrevHash != prevHash:
+			reason = "previous hash does not match"
+		case computeHash(*l) != l.Hash:
+			reason = "entry contents do not match its hash"
+		}
+		if reason != "" {
+			result.Valid = false
+			result.BrokenAtSeq = result.Entries
+			result.Reason = reason
+			return result, nil
+		}
+
+		prevHash = l.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	result.HeadHash = prevHash
+	return result, nil
+}
+
+const selectLogs = `
+	SELECT id, seq, chain_key, user_id, actor_role, operator_id, action, entity_type, entity_id,
+		old_data::text, new_data::text, diff::text, ip_address, user_agent, request_id,
+		prev_hash, hash, created_at
+	FROM audit_logs
+`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLog(row scanner) (*Log, error) {
+	var l Log
+	var actorID, actorRole, operatorID, entityID, before, after, diff, ip, ua, requestID sql.NullString
+	err := row.Scan(
+		&l.ID, &l.Seq, &l.ChainKey, &actorID, &actorRole, &operatorID, &l.Action, &l.EntityType, &entityID,
+		&before, &after, &diff, &ip, &ua, &requestID, &l.PrevHash, &l.Hash, &l.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to scan audit entry: %v", err)
+		return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+	}
+
+	l.ActorID = actorID.String
+	l.ActorRole = actorRole.String
+	l.OperatorID = operatorID.String
+	l.EntityID = entityID.String
+	l.IP = ip.String
+	l.UserAgent = ua.String
+	l.RequestID = requestID.String
+
+	if l.Before, err = canonicalize([]byte(before.String)); err != nil {
+		return nil, err
+	}
+	if l.After, err = canonicalize([]byte(after.String)); err != nil {
+		return nil, err
+	}
+	if l.Diff, err = canonicalize([]byte(diff.String)); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
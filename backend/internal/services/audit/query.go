@@ -0,0 +1,167 @@
+// backend/internal/services/audit/query.go
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExportRows caps a single export
+const maxExportRows = 100000
+
+// Filter narrows a query, zero values match everything
+type Filter struct {
+	ChainKey   string
+	EntityType string
+	EntityID   string
+	Action     string // exact, or a prefix ending in "." such as "bus."
+	ActorID    string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+func (f Filter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	// entries from before chaining have no chain and are not returned
+	conds = append(conds, "chain_key IS NOT NULL")
+	if f.ChainKey != "" {
+		add("chain_key = $%d", f.ChainKey)
+	}
+	if f.EntityType != "" {
+		add("entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID != "" {
+		add("entity_id = $%d", f.EntityID)
+	}
+	if strings.HasSuffix(f.Action, ".") {
+		add("action LIKE $%d || '%%'", f.Action)
+	} else if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.ActorID != "" {
+		add("user_id::text = $%d", f.ActorID)
+	}
+	if f.RequestID != "" {
+		add("request_id = $%d", f.RequestID)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To)
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Query returns matching entries, newest first
+func (s *Service) Query(ctx context.Context, f Filter) ([]Log, error) {
+	if f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 100
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	where, args := f.where()
+	args = append(args, f.Limit, f.Offset)
+	rows, err := s.db.QueryContext(ctx, selectLogs+where+fmt.Sprintf(`
+		ORDER BY created_at DESC, seq DESC
+		LIMIT $%d OFFSET $%d
+	`, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []Log{}
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, *l)
+	}
+	return logs, rows.Err()
+}
+
+var exportColumns = []string{
+	"id", "chain_key", "seq", "created_at", "actor_id", "actor_role", "operator_id", "action",
+	"entity_type", "entity_id", "before", "after", "diff", "ip_address", "user_agent", "request_id",
+	"prev_hash", "hash",
+}
+
+// Export writes matching entries oldest first as csv or newline delimited json
+func (s *Service) Export(ctx context.Context, f Filter, format string, w io.Writer) error {
+	where, args := f.where()
+	args = append(args, maxExportRows)
+	rows, err := s.db.QueryContext(ctx, selectLogs+where+fmt.Sprintf(`
+		ORDER BY chain_key, seq
+		LIMIT $%d
+	`, len(args)), args...)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	var cw *csv.Writer
+	var enc *json.Encoder
+	if format == "csv" {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+	} else {
+		enc = json.NewEncoder(w)
+	}
+
+	for rows.Next() {
+		l, err := scanLog(rows)
+		if err != nil {
+			return err
+		}
+
+		if enc != nil {
+			if err := enc.Encode(l); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := cw.Write([]string{
+			l.ID, l.ChainKey, strconv.FormatInt(l.Seq, 10), l.CreatedAt.UTC().Format(time.RFC3339Nano),
+			l.ActorID, l.ActorRole, l.OperatorID, l.Action, l.EntityType, l.EntityID,
+			string(l.Before), string(l.After), string(l.Diff), l.IP, l.UserAgent, l.RequestID,
+			l.PrevHash, l.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
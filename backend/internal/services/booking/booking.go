@@ -7,24 +7,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/Mvoii/zurura/internal/errors"
 	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/cancellation"
+	"github.com/Mvoii/zurura/internal/services/fares"
+	"github.com/Mvoii/zurura/internal/services/idempotency"
+	"github.com/Mvoii/zurura/internal/services/ledger"
+	"github.com/Mvoii/zurura/internal/services/lifecycle"
+	"github.com/Mvoii/zurura/internal/services/loyalty"
 	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/Mvoii/zurura/internal/services/promotions"
+	"github.com/Mvoii/zurura/internal/services/stops"
 )
 
+// Notifier delivers a stored user notification, implemented by the notification service
+type Notifier interface {
+	Send(userID string, msgType models.NotificationType, message string) error
+}
+
 type BookingService struct {
 	db             *sql.DB
 	paymentService payments.PaymentService
+	audit          *audit.Service
+	cancellation   *cancellation.Service
+	fares          *fares.Service
+	ledger         *ledger.Service
+	loyalty        *loyalty.Service
+	promotions     *promotions.Service
+	notifier       Notifier
 }
 
-func NewBookingService(db *sql.DB, ps payments.PaymentService) *BookingService {
+func NewBookingService(db *sql.DB, ps payments.PaymentService, notifier Notifier) *BookingService {
 	return &BookingService{
 		db:             db,
 		paymentService: ps,
+		audit:          audit.NewAuditService(db),
+		cancellation:   cancellation.NewCancellationService(db),
+		fares:          fares.NewFareService(db),
+		ledger:         ledger.NewLedgerService(db),
+		loyalty:        loyalty.NewLoyaltyService(db),
+		promotions:     promotions.NewPromotionService(db),
+		notifier:       notifier,
 	}
 }
 
@@ -36,6 +66,17 @@ type CreateBookingRequest struct {
 	SeatCount         int
 	PaymentMethod     payments.PaymentMethod
 	UserID            string
+	RedeemPoints      int         // loyalty points to spend against the fare
+	PromoCode         string      // optional, matched case-insensitively
+	QuoteToken        string      // optional, books at a quoted fare while it is locked
+	HoldID            string      // optional, books the seats of one of the rider's seat holds
+	Passengers        []Passenger // optional, who rides in each seat when booking for others
+
+	scheduleID string                    // the trip booked, the bus's next departure when empty
+	seatsHeld  bool                      // the seats are already taken out of the bus's occupancy
+	paid       *payments.PaymentResponse // paid for together with the other legs of a journey
+	journeyID  string
+	journeyLeg int
 }
 
 func (s *BookingService) CreateBooking(ctx context.Context, req CreateBookingRequest) (*models.Booking, error) {
@@ -45,37 +86,229 @@ func (s *BookingService) CreateBooking(ctx context.Context, req CreateBookingReq
 	}
 	defer tx.Rollback()
 
-	// 1. Validate seat availability
+	booking, priced, err := s.createBooking(ctx, tx, req)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		s.releasePromo(ctx, priced)
+		return nil, err
+	}
+
+	return booking, nil
+}
+
+// createBooking books and pays for req in tx. The booking it priced is
+// returned even when booking fails, so the caller can hand back its promo
+// code use with releasePromo if tx does not commit.
+func (s *BookingService) createBooking(ctx context.Context, tx *sql.Tx, req CreateBookingRequest) (*models.Booking, *pricedBooking, error) {
+	priced, err := s.priceBooking(ctx, tx, &req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.claimPriced(ctx, tx, req, priced); err != nil {
+		return nil, priced, err
+	}
+	booking, err := s.bookPriced(ctx, tx, req, priced)
+	return booking, priced, err
+}
+
+// pricedBooking is a booking's fare worked out but not yet paid for
+type pricedBooking struct {
+	hold       *models.SeatHold
+	passengers []Passenger
+	trip       fares.Trip
+	fareItems  []models.BookingLineItem
+	lineItems  []models.BookingLineItem
+	baseFare   float64
+	promo      *promotions.Promotion
+	discounts  *loyalty.Application
+	fare       float64
+}
+
+// priceBooking checks the seats asked for and works out what they cost. The
+// request is filled in from the seat hold and passengers it names.
+func (s *BookingService) priceBooking(ctx context.Context, tx *sql.Tx, req *CreateBookingRequest) (*pricedBooking, error) {
+	// 1. Validate seat availability, seats already held are the rider's
+	var hold *models.SeatHold
+	if req.HoldID != "" {
+		var err error
+		if hold, err = s.useSeatHold(ctx, tx, req); err != nil {
+			return nil, err
+		}
+	}
 	/* if err := s.validateSeatAvailability(ctx, tx, req.BusID, req.SeatNumbers); err != nil {
 		return nil, err
 	} */
+	passengers, err := PassengerSeats(req.Passengers, req.SeatCount, req.SeatNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if len(passengers) > 0 {
+		req.SeatCount = len(passengers)
+		if len(req.SeatNumbers) == 0 && passengers[0].SeatNumber != "" {
+			// seats picked for the passengers
+			for _, p := range passengers {
+				req.SeatNumbers = append(req.SeatNumbers, p.SeatNumber)
+			}
+		}
+	}
+	// seats picked without holding them beforehand
+	if hold == nil && !req.seatsHeld && len(req.SeatNumbers) > 0 {
+		if _, err := SeatSelection(req.SeatCount, req.SeatNumbers); err != nil {
+			return nil, err
+		}
+		req.SeatCount = len(req.SeatNumbers)
+		scheduleID, _, err := nextDeparture(ctx, tx, req.BusID, req.scheduleID)
+		if err != nil {
+			return nil, err
+		}
+		var schedule string
+		if scheduleID != nil {
+			schedule = *scheduleID
+		}
+		if err := s.checkSeatNumbers(ctx, tx, req.BusID, schedule, req.SeatNumbers); err != nil {
+			return nil, err
+		}
+	}
 
 	// 2. Calculate fare
-	fare, err := s.calculateFare(ctx, tx, req.BusID, req.SeatCount)
+	trip, err := s.resolveTrip(ctx, tx, req.BusID, req.BoardingStopName, req.AlightingStopName, req.SeatCount)
 	if err != nil {
 		return nil, err
 	}
-
-	// 3. Process payment
-	paymentResp, err := s.processPayment(ctx, req, fare)
+	fareItems, err := s.calculateFare(ctx, tx, trip, req.QuoteToken)
 	if err != nil {
 		return nil, err
 	}
+	baseFare := fareTotal(fareItems)
+
+	lineItems := append([]models.BookingLineItem{}, fareItems...)
 
-	// 4. Create booking record
-	booking, err := s.createBookingRecord(ctx, tx, req, fare, paymentResp)
+	// 3. Apply a promo code, loyalty then applies to what is left
+	var promo *promotions.Promotion
+	if req.PromoCode != "" {
+		promo, err = s.promotions.Apply(ctx, tx, req.PromoCode, req.UserID, req.BusID, baseFare)
+		if err != nil {
+			return nil, err
+		}
+		if !promo.StacksWithLoyalty && req.RedeemPoints > 0 {
+			return nil, errors.ErrPromoNotStackable
+		}
+		lineItems = append(lineItems, promo.LineItem)
+	}
+
+	// 4. Apply loyalty discounts and redeemed points
+	discounts, err := s.loyalty.Apply(ctx, tx, req.UserID, req.BusID, fareTotal(lineItems), req.RedeemPoints)
 	if err != nil {
 		return nil, err
 	}
+	if promo == nil || promo.StacksWithLoyalty {
+		lineItems = append(lineItems, discounts.LineItems...)
+	}
 
-	// 5. Update bus occupancy
-	if err := s.updateBusOccupancy(ctx, tx, req.BusID, len(req.SeatNumbers)); err != nil {
-		return nil, err
+	return &pricedBooking{
+		hold:       hold,
+		passengers: passengers,
+		trip:       trip,
+		fareItems:  fareItems,
+		lineItems:  lineItems,
+		baseFare:   baseFare,
+		promo:      promo,
+		discounts:  discounts,
+		fare:       fareTotal(lineItems),
+	}, nil
+}
+
+// claimPriced takes the seats and promo code use a priced booking needs
+// before it is paid for, so a booking on a full bus or over a promo's caps
+// fails without the rider being charged. The promo code use is reserved
+// outside tx and must be handed back with releasePromo if tx does not commit.
+func (s *BookingService) claimPriced(ctx context.Context, tx *sql.Tx, req CreateBookingRequest, priced *pricedBooking) error {
+	if !req.seatsHeld {
+		if err := s.updateBusOccupancy(ctx, tx, req.BusID, req.SeatCount); err != nil {
+			return err
+		}
 	}
+	return s.promotions.Reserve(ctx, priced.promo, req.UserID)
+}
 
-	if err := tx.Commit(); err != nil {
+// releasePromo hands back the promo code use claimPriced reserved for a
+// booking that was not made
+func (s *BookingService) releasePromo(ctx context.Context, priced *pricedBooking) {
+	if priced == nil {
+		return
+	}
+	if err := s.promotions.Release(ctx, priced.promo); err != nil {
+		log.Printf("[ERROR] Promo code use %s was not handed back: %v", priced.promo.RedemptionID, err)
+	}
+}
+
+// bookPriced pays for a priced booking, claimed with claimPriced, and
+// records it
+func (s *BookingService) bookPriced(ctx context.Context, tx *sql.Tx, req CreateBookingRequest, priced *pricedBooking) (*models.Booking, error) {
+	hold, passengers, trip := priced.hold, priced.passengers, priced.trip
+	fareItems, lineItems, baseFare := priced.fareItems, priced.lineItems, priced.baseFare
+	promo, discounts, fare := priced.promo, priced.discounts, priced.fare
+
+	// 5. Process payment
+	var paymentResp *payments.PaymentResponse
+	var err error
+	switch {
+	case req.paid != nil:
+		// the journey's single payment, this leg's share of it
+		paid := *req.paid
+		paid.Amount = fare
+		paymentResp = &paid
+	case fare == 0:
+		paymentResp = freeRide(req.PaymentMethod)
+	default:
+		paymentResp, err = s.processPayment(ctx, tx, req, fare)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if paymentResp.BusPassID != "" && paymentResp.Amount == 0 {
+		// covered by an unlimited pass, the ride is still booked and recorded
+		// but no discount applies and no points or promo codes are used
+		lineItems = append(fareItems, models.BookingLineItem{
+			Type:        "unlimited_pass",
+			Description: "Covered by unlimited pass",
+			Amount:      -baseFare,
+		})
+		discounts.PointsRedeemed = 0
+		if err := s.promotions.Release(ctx, promo); err != nil {
+			return nil, err
+		}
+		promo = nil
+		fare = 0
+	}
+
+	// 6. Create booking record
+	booking, err := s.createBookingRecord(ctx, tx, req, trip, fare, lineItems, discounts.ProgrammeID, paymentResp)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loyalty.Redeem(ctx, tx, req.UserID, booking.ID, discounts); err != nil {
+		return nil, err
+	}
+	if err := s.promotions.Record(ctx, tx, promo, booking.ID); err != nil {
 		return nil, err
 	}
+	if len(passengers) > 0 {
+		if booking.Passengers, err = s.addPassengers(ctx, tx, booking.ID, passengers); err != nil {
+			return nil, err
+		}
+	}
+	if hold != nil {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE seat_holds SET status = 'booked', booking_id = $1, updated_at = NOW() WHERE id = $2
+		`, booking.ID, hold.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to update seat hold: %v", err)
+			return nil, fmt.Errorf("failed to update seat hold: %w", err)
+		}
+	}
 
 	return booking, nil
 }
@@ -150,80 +383,151 @@ func (s *BookingService) CreateBooking(ctx context.Context, req CreateBookingReq
 	return nil
 }
  */
-func (s *BookingService) calculateFare(ctx context.Context, tx *sql.Tx, busID string, seatCount int) (float64, error) {
-	// Get base fare from bus route using the bus_route_assignments table
-	var baseFare float64
+// resolveTrip works out the route and stops a booking or quote is for
+func (s *BookingService) resolveTrip(ctx context.Context, tx *sql.Tx, busID, boardingStop, alightingStop string, seatCount int) (fares.Trip, error) {
+	trip := fares.Trip{BusID: busID, Seats: seatCount, At: time.Now()}
+
 	err := tx.QueryRowContext(ctx, `
-		SELECT r.base_fare
+		SELECT bra.route_id, b.operator_id
 		FROM buses b
 		JOIN bus_route_assignments bra ON b.id = bra.bus_id
-		JOIN bus_routes r ON bra.route_id = r.id
 		WHERE b.id = $1
 		AND bra.status = 'active'
-	`, busID).Scan(&baseFare)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			log.Printf("[ERROR] No fare information found for bus %s", busID)
-			return 0, &errors.BookingError{
-				Code:    "ROUTE_NOT_FOUND",
-				Message: "Could not find fare information for this bus",
-			}
+	`, busID).Scan(&trip.RouteID, &trip.OperatorID)
+	if err == sql.ErrNoRows {
+		log.Printf("[ERROR] No fare information found for bus %s", busID)
+		return trip, &errors.BookingError{
+			Code:    "ROUTE_NOT_FOUND",
+			Message: "Could not find fare information for this bus",
 		}
+	}
+	if err != nil {
 		log.Printf("[ERROR] Database error: %v", err)
-		return 0, fmt.Errorf("database error: %w", err)
+		return trip, fmt.Errorf("database error: %w", err)
 	}
 
-	// Validate that base fare is greater than zero
-	if baseFare <= 0 {
-		log.Printf("[ERROR] Invalid base fare: %f", baseFare)
-		return 0, &errors.BookingError{
-			Code:    "INVALID_FARE",
-			Message: "The fare for this route is not properly configured",
-		}
+	// Resolve boarding and alighting stops
+	trip.BoardingStopID, trip.BoardingStopName, _, _, err = s.resolvesStop(ctx, tx, trip.RouteID, boardingStop)
+	if err != nil {
+		log.Printf("[ERROR] Failed to resolve boarding stop: %v", err)
+		return trip, err
 	}
+	trip.AlightingStopID, trip.AlightingStopName, _, _, err = s.resolvesStop(ctx, tx, trip.RouteID, alightingStop)
+	if err != nil {
+		log.Printf("[ERROR] Failed to resolve alighting stop: %v", err)
+		return trip, err
+	}
+	return trip, nil
+}
 
-	// Calculate total fare
-	totalFare := baseFare * float64(seatCount)
-
-	// Check for any applicable discounts
-	var discount float64
-	err = tx.QueryRowContext(ctx, `
-		SELECT discount_percentage
-		FROM loyalty_discounts
-		WHERE user_id = $1
-		AND expires_at > NOW()
-	`, ctx.Value("user_id")).Scan(&discount)
+// calculateFare returns the fare's line items, from the quote token when one
+// is given and still locked, otherwise from the fare engine
+func (s *BookingService) calculateFare(ctx context.Context, tx *sql.Tx, trip fares.Trip, quoteToken string) ([]models.BookingLineItem, error) {
+	if err := s.checkBookable(ctx, tx, trip.BusID); err != nil {
+		return nil, err
+	}
 
-	if err == nil && discount > 0 {
-		// Apply discount if available
-		totalFare = totalFare * (1 - discount/100)
+	if quoteToken != "" {
+		return s.fares.LockedQuote(quoteToken, trip)
 	}
+	return s.fares.Price(ctx, tx, trip)
+}
 
-	// Ensure final fare is never zero or negative
-	if totalFare <= 0 {
-		log.Printf("[ERROR] Calculated fare is zero or negative after discounts: %f", totalFare)
-		return 0, &errors.BookingError{
-			Code:    "INVALID_FARE",
-			Message: "Cannot process a booking with zero or negative fare",
+// checkBookable rejects buses of operators under review or suspended
+func (s *BookingService) checkBookable(ctx context.Context, tx *sql.Tx, busID string) error {
+	var operatorStatus string
+	err := tx.QueryRowContext(ctx, `
+		SELECT o.status
+		FROM buses b
+		JOIN bus_operators o ON b.operator_id = o.id
+		WHERE b.id = $1
+	`, busID).Scan(&operatorStatus)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	if err == nil && operatorStatus != "active" {
+		return &errors.BookingError{
+			Code:    "OPERATOR_UNAVAILABLE",
+			Message: "This bus is not accepting bookings",
 		}
 	}
+	return nil
+}
+
+// QuoteFare prices a trip and returns a quote token locking the price for
+// fares.QuoteLockPeriod
+func (s *BookingService) QuoteFare(ctx context.Context, busID, boardingStop, alightingStop string, seatCount int) (*models.FareQuote, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	trip, err := s.resolveTrip(ctx, tx, busID, boardingStop, alightingStop, seatCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBookable(ctx, tx, busID); err != nil {
+		return nil, err
+	}
+
+	return s.fares.Quote(ctx, tx, trip)
+}
+
+// freeRide stands in for a payment when discounts cover the whole fare
+func freeRide(method payments.PaymentMethod) *payments.PaymentResponse {
+	return &payments.PaymentResponse{
+		TransactionID: fmt.Sprintf("FREE_%d", time.Now().UnixNano()),
+		Status:        payments.PaymentStatusCompleted,
+		Amount:        0,
+		Timestamp:     time.Now(),
+		PaymentMethod: method,
+	}
+}
 
-	return totalFare, nil
+// fareTotal is what the rider pays for a booking's line items
+func fareTotal(items []models.BookingLineItem) float64 {
+	var total float64
+	for _, item := range items {
+		total += item.Amount
+	}
+	return math.Round(total*100) / 100
 }
 
-func (s *BookingService) processPayment(ctx context.Context, req CreateBookingRequest, amount float64) (*payments.PaymentResponse, error) {
+func (s *BookingService) processPayment(ctx context.Context, tx *sql.Tx, req CreateBookingRequest, amount float64) (*payments.PaymentResponse, error) {
 	// Handle different payment methods
 	switch req.PaymentMethod {
 	case payments.PaymentMethodBusPass:
-		// Check bus pass balance and get pass ID
+		// an unlimited pass covering this bus rides the holder for free
+		if req.SeatCount <= 1 {
+			passID, err := s.findUnlimitedPass(ctx, tx, req.UserID, req.BusID)
+			if err != nil {
+				return nil, err
+			}
+			if passID != "" {
+				return &payments.PaymentResponse{
+					TransactionID: fmt.Sprintf("PASS_%d", time.Now().UnixNano()),
+					Status:        payments.PaymentStatusCompleted,
+					Amount:        0,
+					Timestamp:     time.Now(),
+					PaymentMethod: payments.PaymentMethodBusPass,
+					Metadata:      map[string]interface{}{"unlimited_pass": true, "list_fare": amount},
+					BusPassID:     passID,
+				}, nil
+			}
+		}
+
+		// Check bus pass balance and get pass ID, the fare is taken from it
+		// through the ledger when the booking is written
 		var passID string
 		var balance float64
-		err := s.db.QueryRowContext(ctx, `
+		err := tx.QueryRowContext(ctx, `
 			SELECT id, balance
 			FROM bus_passes
 			WHERE user_id = $1
 			AND status = 'active'
+			AND pass_type <> 'unlimited'
 			AND expiration_date > NOW()
 			ORDER BY expiration_date DESC
 			LIMIT 1
@@ -243,18 +547,6 @@ func (s *BookingService) processPayment(ctx context.Context, req CreateBookingRe
 			return nil, errors.ErrInsufficientBalance
 		}
 
-		// Deduct from bus pass
-		_, err = s.db.ExecContext(ctx, `
-			UPDATE bus_passes
-			SET balance = balance - $1
-			WHERE id = $2
-		`, amount, passID)
-
-		if err != nil {
-			log.Printf("[ERROR] Database error: %v", err)
-			return nil, fmt.Errorf("failed to update bus pass balance: %w", err)
-		}
-
 		return &payments.PaymentResponse{
 			TransactionID: fmt.Sprintf("PASS_%d", time.Now().UnixNano()),
 			Status:        payments.PaymentStatusCompleted,
@@ -264,6 +556,23 @@ func (s *BookingService) processPayment(ctx context.Context, req CreateBookingRe
 			BusPassID:     passID,
 		}, nil
 
+	case payments.PaymentMethodInvoice:
+		// group bookings can be billed to the booker and settled later
+		if req.SeatCount < GroupInvoiceMinSeats {
+			return nil, &errors.BookingError{
+				Code:    "INVOICE_NOT_ALLOWED",
+				Message: fmt.Sprintf("Only bookings of %d or more seats can be paid by invoice", GroupInvoiceMinSeats),
+			}
+		}
+
+		return &payments.PaymentResponse{
+			TransactionID: fmt.Sprintf("INV_%d", time.Now().UnixNano()),
+			Status:        payments.PaymentStatusInvoiced,
+			Amount:        amount,
+			Timestamp:     time.Now(),
+			PaymentMethod: payments.PaymentMethodInvoice,
+		}, nil
+
 	default:
 		// Process through payment gateway
 		paymentReq := payments.PaymentRequest{
@@ -274,11 +583,48 @@ func (s *BookingService) processPayment(ctx context.Context, req CreateBookingRe
 			Description:   "Bus booking payment",
 		}
 
+		idempotency.Charged(ctx)
 		return s.paymentService.ProcessPayment(ctx, paymentReq)
 	}
 }
 
-func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, req CreateBookingRequest, fare float64, payment *payments.PaymentResponse) (*models.Booking, error) {
+// findUnlimitedPass returns the rider's unlimited pass that covers the bus, or
+// an empty id. Operator passes only cover that operator's buses and passes
+// scoped to routes only cover buses assigned to one of them.
+func (s *BookingService) findUnlimitedPass(ctx context.Context, tx *sql.Tx, userID, busID string) (string, error) {
+	var passID string
+	err := tx.QueryRowContext(ctx, `
+		SELECT bp.id
+		FROM bus_passes bp
+		JOIN pass_products pp ON pp.id = bp.product_id
+		JOIN buses b ON b.id = $2
+		WHERE bp.user_id = $1
+		AND bp.pass_type = 'unlimited'
+		AND bp.status = 'active'
+		AND bp.expiration_date > NOW()
+		AND (pp.operator_id IS NULL OR pp.operator_id = b.operator_id)
+		AND (
+			NOT EXISTS (SELECT 1 FROM pass_product_routes r WHERE r.product_id = pp.id)
+			OR EXISTS (
+				SELECT 1 FROM pass_product_routes r
+				JOIN bus_route_assignments a ON a.route_id = r.route_id
+				WHERE r.product_id = pp.id AND a.bus_id = b.id AND a.status = 'active'
+			)
+		)
+		ORDER BY bp.expiration_date DESC
+		LIMIT 1
+	`, userID, busID).Scan(&passID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	return passID, nil
+}
+
+func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, req CreateBookingRequest, trip fares.Trip, fare float64, lineItems []models.BookingLineItem, programmeID string, payment *payments.PaymentResponse) (*models.Booking, error) {
 	bookingID := uuid.New().String()
 	now := time.Now()
 
@@ -294,62 +640,56 @@ func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, re
 		return nil, fmt.Errorf("failed to marshal seats to JSON: %w", err)
 	}
 
-	// Get route_id for the bus from the bus_route_assignments table
-	var routeID string
-	err = tx.QueryRowContext(ctx, `
-		SELECT route_id 
-		FROM bus_route_assignments 
-		WHERE bus_id = $1
-		AND status = 'active'
-	`, req.BusID).Scan(&routeID)
-	if err != nil {
-		log.Printf("[ERROR] Database error: %v", err)
-		return nil, fmt.Errorf("failed to get route_id: %w", err)
-	}
+	routeID := trip.RouteID
 
-	// Resolve boarding and alighting stops
-	BoardingStopID, _, _, _, err := s.resolvesStop(ctx, tx, routeID, req.BoardingStopName)
-	if err != nil {
-		log.Printf("[ERROR] Failed to resolve boarding stop: %v", err)
-		return nil, err
+	// a gateway payment still being confirmed holds the seats until it expires
+	status := lifecycle.StatusConfirmed
+	if payment.Status == payments.PaymentStatusPending {
+		status = lifecycle.StatusPendingPayment
 	}
-	AlightingStopID, _, _, _, err := s.resolvesStop(ctx, tx, routeID, req.AlightingStopName)
+
+	// the bus's next departure unless the trip was picked, which cancellation
+	// policies count from
+	scheduleID, departsAt, err := nextDeparture(ctx, tx, req.BusID, req.scheduleID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to resolve alighting stop: %v", err)
 		return nil, err
 	}
 
-	// prep uuid params: nil if empty, or the real uuid string if not empty
-	var boardingIDParam interface{}
-	if BoardingStopID != "" {
-		boardingIDParam = BoardingStopID
-	}
-	var alightIDParam interface{}
-	if BoardingStopID != "" {
-		alightIDParam = AlightingStopID
+	// legs of a journey are numbered from 1
+	var journeyID *string
+	var journeyLeg interface{}
+	if req.journeyID != "" {
+		journeyID, journeyLeg = &req.journeyID, req.journeyLeg
 	}
 
 	// Create booking record with JSONB data - remove updated_at
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO bookings (
 			id, user_id, bus_id, route_id, boarding_stop_id, alighting_stop_id, seats, fare, payment_method,
-			status, created_at, expires_at, boarding_stop_name, alighting_stop_name
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			status, created_at, expires_at, boarding_stop_name, alighting_stop_name, loyalty_programme_id,
+			schedule_id, departs_at, journey_id, journey_leg, route_version_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19,
+			(SELECT route_version_id FROM schedules WHERE id = $16))
 	`,
 		bookingID,
 		req.UserID,
 		req.BusID,
 		routeID,
-		boardingIDParam,
-		alightIDParam,
+		nullable(trip.BoardingStopID),
+		nullable(trip.AlightingStopID),
 		seatsJSON,
 		fare,
 		payment.PaymentMethod,
-		"confirmed",
+		status,
 		now,
 		now.Add(15*time.Minute),
 		req.BoardingStopName,
 		req.AlightingStopName,
+		nullable(programmeID),
+		scheduleID,
+		departsAt,
+		journeyID,
+		journeyLeg,
 		// updated_at is not needed here
 	)
 
@@ -357,6 +697,24 @@ func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, re
 		log.Printf("[ERROR] Database error: %v", err)
 		return nil, fmt.Errorf("failed to create booking record: %w", err)
 	}
+	if err := lifecycle.Start(ctx, tx, bookingID, status, audit.Entry{ActorID: req.UserID, ActorRole: "rider"}); err != nil {
+		return nil, err
+	}
+
+	for i, item := range lineItems {
+		var points interface{}
+		if item.Points != 0 {
+			points = item.Points
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO booking_line_items (booking_id, position, type, description, amount, points)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, bookingID, i, item.Type, item.Description, item.Amount, points)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("failed to record fare line item: %w", err)
+		}
+	}
 
 	// Create payment record
 	paymentMetadata := map[string]interface{}{
@@ -377,13 +735,14 @@ func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, re
 		busPassID = payment.BusPassID
 	}
 
+	paymentID := uuid.New().String()
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO payments (
 			id, booking_id, user_id, amount, payment_method, payment_status,
 			transaction_id, currency, metadata, created_at, updated_at, bus_pass_id
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`,
-		uuid.New().String(),
+		paymentID,
 		bookingID,
 		req.UserID,
 		fare,
@@ -402,180 +761,759 @@ func (s *BookingService) createBookingRecord(ctx context.Context, tx *sql.Tx, re
 		return nil, fmt.Errorf("failed to create payment record: %w", err)
 	}
 
+	if payment.BusPassID != "" {
+		// taken in the same transaction as the booking so a failed booking never charges the pass
+		var operatorID string
+		err = tx.QueryRowContext(ctx, `SELECT operator_id FROM buses WHERE id = $1`, req.BusID).Scan(&operatorID)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+
+		if fare == 0 {
+			// unlimited pass, nothing moves in the ledger
+			if err := s.recordPassTransaction(ctx, tx, payment.BusPassID, req.UserID, "ride", 0, 0, paymentID, bookingID, ""); err != nil {
+				return nil, err
+			}
+		} else {
+			posted, err := s.ledger.PayFare(ctx, tx, payment.BusPassID, operatorID, fare, bookingID, paymentID)
+			if err != nil {
+				return nil, err
+			}
+			if err := s.recordPassTransaction(ctx, tx, payment.BusPassID, req.UserID, "ride", -fare, posted.PassBalance, paymentID, bookingID, posted.EntryID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = s.recordAudit(ctx, tx, req.UserID, req.BusID, "payment."+string(payment.Status), "payment", paymentID, nil, map[string]interface{}{
+		"booking_id":     bookingID,
+		"amount":         fare,
+		"payment_method": payment.PaymentMethod,
+		"payment_status": payment.Status,
+		"transaction_id": payment.TransactionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.Booking{
 		ID:                bookingID,
 		UserID:            req.UserID,
 		BusID:             req.BusID,
 		RouteID:           routeID,
-		BoardingStopID:    BoardingStopID,
-		AlightingStopID:   AlightingStopID,
+		BoardingStopID:    trip.BoardingStopID,
+		AlightingStopID:   trip.AlightingStopID,
 		BoardingStopName:  req.BoardingStopName,
 		AlightingStopName: req.AlightingStopName,
 		Seats:             seats,
 		Fare:              fare,
-		Status:            "confirmed",
+		Status:            status,
 		CreatedAt:         now,
 		ExpiresAt:         now.Add(15 * time.Minute),
+		ScheduleID:        scheduleID,
+		DepartsAt:         departsAt,
+		LineItems:         lineItems,
+		JourneyID:         journeyID,
+		JourneyLeg:        req.journeyLeg,
 	}, nil
 }
 
+// nextDeparture finds the bus's next scheduled departure, or checks that the
+// given schedule is still to depart. Both are nil when there is none.
+func nextDeparture(ctx context.Context, tx *sql.Tx, busID, scheduleID string) (*string, *time.Time, error) {
+	var id string
+	var departure time.Time
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, departure_time FROM schedules
+		WHERE bus_id = $1 AND departure_time > NOW() AND cancelled_at IS NULL
+		AND ($2 = '' OR id::TEXT = $2)
+		ORDER BY departure_time
+		LIMIT 1
+	`, busID, scheduleID).Scan(&id, &departure)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	return &id, &departure, nil
+}
+
+// updateBusOccupancy takes seats on the bus, or fails with
+// ErrSeatsUnavailable when it does not have that many left
 func (s *BookingService) updateBusOccupancy(ctx context.Context, tx *sql.Tx, busID string, seatCount int) error {
-	_, err := tx.ExecContext(ctx, `
+	result, err := tx.ExecContext(ctx, `
 		UPDATE buses
 		SET current_occupancy = current_occupancy + $1,
 			updated_at = NOW()
-		WHERE id = $2
+		WHERE id = $2 AND current_occupancy + $1 <= capacity
 	`, seatCount, busID)
 
 	if err != nil {
 		log.Printf("[ERROR] Database error: %v", err)
 		return fmt.Errorf("failed to update bus occupancy: %w", err)
 	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return errors.ErrSeatsUnavailable
+	}
 
 	return nil
 }
 
+// CancelBooking cancels all of a booking's remaining seats
 func (s *BookingService) CancelBooking(ctx context.Context, bookingID string, userID string) error {
+	_, err := s.CancelSeats(ctx, bookingID, userID, 0, nil, nil)
+	return err
+}
+
+// CancelSeats cancels some of a booking's seats, by seat number or by count,
+// and refunds their share of the payment under the operator's cancellation
+// policy. A count of 0 with no seat numbers cancels every remaining seat.
+// On a booking with named passengers, passengerIDs picks whose seats are
+// cancelled instead. Once no seats remain the booking is cancelled, and its redeemed loyalty
+// points and promo code use are given back.
+func (s *BookingService) CancelSeats(ctx context.Context, bookingID, userID string, seatCount int, seatNumbers, passengerIDs []string) (*models.BookingCancellation, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		log.Printf("[ERROR] Failed to begin transaction for booking cancellation: %v", err)
-		return fmt.Errorf("database error: %w", err)
+		return nil, fmt.Errorf("database error: %w", err)
 	}
 	defer tx.Rollback()
 
-	// 1. Validate booking exists and belongs to user
-	var booking struct {
-		Status        string
-		PaymentID     string
-		Amount        float64
-		PaymentMethod string
+	plan, err := s.planCancellation(ctx, tx, bookingID, userID, seatCount, seatNumbers, passengerIDs, "rider", time.Now())
+	if err != nil {
+		return nil, err
 	}
-	err = tx.QueryRowContext(ctx, `
-		SELECT b.status, p.id, p.amount, p.payment_method
+
+	result, err := s.applyCancellation(ctx, tx, plan, audit.Entry{
+		ActorID:    userID,
+		ActorRole:  "rider",
+		OperatorID: plan.operatorID,
+	}, lifecycle.StatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	// a journey's later legs cannot be ridden without this one
+	var buses []string
+	result.Connections, buses, err = s.cancelConnections(ctx, tx, plan, audit.Entry{
+		ActorID:    userID,
+		ActorRole:  "rider",
+		OperatorID: plan.operatorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the freed seats go to the trip's waitlist first
+	offers, err := s.offerSeats(ctx, tx, plan.busID)
+	if err != nil {
+		return nil, err
+	}
+	for _, busID := range buses {
+		more, err := s.offerSeats(ctx, tx, busID)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, more...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit cancellation transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("[INFO] Cancelled %d seats of booking %s for user %s", plan.cancelled.Count, bookingID, userID)
+	s.sendRefunds(ctx)
+	s.notifyOffers(offers)
+	return result, nil
+}
+
+// PreviewCancellation works out what CancelSeats would refund right now
+// without cancelling anything
+func (s *BookingService) PreviewCancellation(ctx context.Context, bookingID, userID string, seatCount int, seatNumbers, passengerIDs []string) (*models.CancellationPreview, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	plan, err := s.planCancellation(ctx, tx, bookingID, userID, seatCount, seatNumbers, passengerIDs, "rider", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.CancellationPreview{
+		BookingID:      bookingID,
+		Seats:          plan.cancelled.Count,
+		SeatNumbers:    plan.cancelled.SeatNumbers,
+		SeatsRemaining: plan.remaining.Count,
+		DepartsAt:      plan.departsAt,
+		SeatValue:      plan.seatValue,
+		RefundPercent:  plan.percent,
+		RefundAmount:   plan.refund,
+	}
+	if plan.policy != nil {
+		preview.Policy = &plan.policy.Name
+	}
+	return preview, nil
+}
+
+// cancellationPlan is a cancellation worked out but not yet made
+type cancellationPlan struct {
+	bookingID  string
+	userID     string
+	status     string
+	busID      string
+	operatorID string
+	departsAt  *time.Time
+	reason     string // rider, operator or no_show
+
+	seats      models.SeatMap
+	remaining  models.SeatMap
+	cancelled  models.SeatMap
+	passengers []string // the named passengers whose seats are cancelled
+
+	paymentID      string
+	paymentMethod  string
+	paymentStatus  string
+	amount         float64
+	paymentRefunds float64
+
+	policy    *models.CancellationPolicy
+	seatValue float64 // the cancelled seats' share of the payment
+	percent   float64
+	refund    float64
+}
+
+// planCancellation locks the booking and works out which seats are cancelled
+// and what they refund. userID limits it to the rider's own bookings, empty
+// for cancellations made by the operator or on the rider's behalf.
+// passengerIDs picks seats by the passengers named on the booking.
+func (s *BookingService) planCancellation(ctx context.Context, tx *sql.Tx, bookingID, userID string, seatCount int, seatNumbers, passengerIDs []string, reason string, at time.Time) (*cancellationPlan, error) {
+	plan := &cancellationPlan{bookingID: bookingID, reason: reason}
+	var seatsJSON []byte
+	var routeID string
+	var departsAt sql.NullTime
+	var cancelledValue float64
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT b.user_id, b.status, b.bus_id, COALESCE(b.route_id::TEXT, ''), bu.operator_id, b.departs_at, b.seats,
+			p.id, p.amount, p.payment_method, p.payment_status, p.refunded_amount,
+			COALESCE((SELECT SUM(seat_value) FROM booking_cancellations WHERE booking_id = b.id), 0)
 		FROM bookings b
+		JOIN buses bu ON bu.id = b.bus_id
 		LEFT JOIN payments p ON b.id = p.booking_id
-		WHERE b.id = $1 AND b.user_id = $2
-	`, bookingID, userID).Scan(&booking.Status, &booking.PaymentID, &booking.Amount, &booking.PaymentMethod)
+		WHERE b.id = $1 AND ($2 = '' OR b.user_id::TEXT = $2)
+		FOR UPDATE OF b
+	`, bookingID, userID).Scan(&plan.userID, &plan.status, &plan.busID, &routeID, &plan.operatorID, &departsAt, &seatsJSON,
+		&plan.paymentID, &plan.amount, &plan.paymentMethod, &plan.paymentStatus, &plan.paymentRefunds, &cancelledValue)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("[ERROR] Booking not found or does not belong to user: %v", err)
-			return &errors.BookingError{
+			return nil, &errors.BookingError{
 				Code:    "BOOKING_NOT_FOUND",
 				Message: "Booking not found or does not belong to user",
 			}
 		}
 		log.Printf("[ERROR] Database error while fetching booking: %v", err)
-		return fmt.Errorf("database error: %w", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if departsAt.Valid {
+		plan.departsAt = &departsAt.Time
 	}
 
-	// 2. Validate booking can be cancelled
-	if booking.Status == "cancelled" {
-		log.Printf("[ERROR] Booking is already cancelled")
-		return &errors.BookingError{
+	switch plan.status {
+	case lifecycle.StatusCancelled:
+		return nil, &errors.BookingError{
 			Code:    "ALREADY_CANCELLED",
 			Message: "Booking is already cancelled",
 		}
-	}
-
-	if booking.Status == "completed" {
-		log.Printf("[ERROR] Booking is already completed")
-		return &errors.BookingError{
+	case lifecycle.StatusCompleted:
+		return nil, &errors.BookingError{
 			Code:    "CANNOT_CANCEL_COMPLETED",
 			Message: "Cannot cancel a completed booking",
 		}
+	case lifecycle.StatusNoShow:
+		return nil, &errors.BookingError{
+			Code:    "INVALID_BOOKING_STATUS",
+			Message: "Cannot cancel a booking that was marked as a no-show",
+		}
+	case lifecycle.StatusBoarded:
+		return nil, &errors.BookingError{
+			Code:    "INVALID_BOOKING_STATUS",
+			Message: "Cannot cancel a booking once the rider has boarded",
+		}
+	}
+	if !lifecycle.CanTransition(plan.status, lifecycle.StatusCancelled) {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_BOOKING_STATUS",
+			Message: fmt.Sprintf("Cannot cancel a %s booking", plan.status),
+		}
+	}
+
+	if err := json.Unmarshal(seatsJSON, &plan.seats); err != nil {
+		log.Printf("[ERROR] Failed to read booking seats: %v", err)
+		return nil, fmt.Errorf("failed to read booking seats: %w", err)
+	}
+	if len(passengerIDs) > 0 && (seatCount != 0 || len(seatNumbers) > 0) {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: "Choose seats or passengers to cancel, not both",
+		}
+	}
+	passengers, err := passengerSelection(ctx, tx, bookingID, passengerIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(passengerIDs) > 0 {
+		seatCount, seatNumbers = PassengerCancellation(passengers)
+	}
+	plan.remaining, plan.cancelled, err = SplitSeats(plan.seats, seatCount, seatNumbers)
+	if err != nil {
+		return nil, err
+	}
+	if plan.passengers, err = CancelledPassengers(passengers, plan.cancelled, plan.remaining.Count == 0 || len(passengerIDs) > 0); err != nil {
+		return nil, err
+	}
+	if plan.cancelled.SeatNumbers == nil {
+		plan.cancelled.SeatNumbers = []string{}
+	}
+	if plan.status == lifecycle.StatusPendingPayment && plan.remaining.Count > 0 {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: "A booking awaiting payment can only be cancelled in full",
+		}
+	}
+
+	plan.policy, err = s.cancellation.PolicyFor(ctx, tx, plan.operatorID, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// value withheld by earlier cancellations stays withheld, and nothing has
+	// been paid for a booking awaiting payment
+	if plan.paymentID != "" && plan.status != lifecycle.StatusPendingPayment {
+		plan.seatValue = SeatRefund(plan.amount-cancelledValue, plan.seats.Count, plan.cancelled.Count)
+	}
+	switch reason {
+	case "operator":
+		plan.percent = 100
+	case "no_show":
+		plan.percent = 0
+		if plan.policy != nil {
+			plan.percent = plan.policy.NoShowRefundPercent
+		}
+	default:
+		plan.percent = cancellation.RefundPercent(plan.policy, plan.departsAt, at)
+	}
+	plan.refund = cancellation.Refund(plan.seatValue, plan.percent)
+	return plan, nil
+}
+
+// applyCancellation makes a planned cancellation. The booking ends up in
+// finalStatus once no seats remain; actor is who made the cancellation.
+func (s *BookingService) applyCancellation(ctx context.Context, tx *sql.Tx, plan *cancellationPlan, actor audit.Entry, finalStatus string) (*models.BookingCancellation, error) {
+	allCancelled := plan.remaining.Count == 0
+	record := func(action, entityType, entityID string, before, after interface{}) error {
+		entry := actor
+		entry.Action = action
+		entry.EntityType = entityType
+		entry.EntityID = entityID
+		entry.Before = before
+		entry.After = after
+		return s.audit.Record(ctx, tx, entry)
 	}
 
-	// 3. Process refund if payment was made
-	if booking.PaymentID != "" {
-		if err := s.processRefund(ctx, tx, booking.PaymentID, booking.Amount, booking.PaymentMethod); err != nil {
+	// 1. Refund the cancelled seats' share of the payment, or call off a
+	// payment that has not gone through yet
+	if plan.status == lifecycle.StatusPendingPayment {
+		if err := s.abandonPayment(ctx, tx, plan.paymentID, payments.PaymentStatusFailed, true, actor); err != nil {
+			return nil, err
+		}
+	} else if plan.paymentID != "" && plan.refund > 0 {
+		fully := math.Round((plan.amount-plan.paymentRefunds-plan.refund)*100) <= 0
+		if err := s.processRefund(ctx, tx, plan.bookingID, plan.paymentID, plan.refund, plan.paymentMethod, fully); err != nil {
 			log.Printf("[ERROR] Failed to process refund: %v", err)
-			return err
+			return nil, err
+		}
+
+		status := payments.PaymentStatusPartiallyRefunded
+		if fully {
+			status = payments.PaymentStatusRefunded
+		}
+		err := record("payment.refunded", "payment", plan.paymentID,
+			map[string]interface{}{"payment_status": plan.paymentStatus, "amount": plan.amount, "refunded_amount": plan.paymentRefunds},
+			map[string]interface{}{"payment_status": status, "amount": plan.amount, "refunded_amount": plan.paymentRefunds + plan.refund},
+		)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// 4. Update booking status
+	if allCancelled && finalStatus == lifecycle.StatusCancelled {
+		// redeemed loyalty points go back to the rider and the promo use is freed
+		if err := s.loyalty.Reverse(ctx, tx, plan.bookingID); err != nil {
+			return nil, err
+		}
+		if err := s.promotions.Reverse(ctx, tx, plan.bookingID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Update booking seats and status
+	remainingJSON, err := json.Marshal(plan.remaining)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal seats to JSON: %w", err)
+	}
 	_, err = tx.ExecContext(ctx, `
 		UPDATE bookings
-		SET status = 'cancelled'
-		WHERE id = $1
-	`, bookingID)
+		SET seats = $1, refunded_amount = refunded_amount + $2
+		WHERE id = $3
+	`, remainingJSON, plan.refund, plan.bookingID)
 
 	if err != nil {
-		log.Printf("[ERROR] Failed to update booking status: %v", err)
-		return fmt.Errorf("failed to update booking status: %w", err)
+		log.Printf("[ERROR] Failed to update booking seats: %v", err)
+		return nil, fmt.Errorf("failed to update booking seats: %w", err)
+	}
+	if err := cancelPassengers(ctx, tx, plan.passengers, time.Now()); err != nil {
+		return nil, err
 	}
 
-	// 5. Update bus occupancy - fixed JSONB query
-	var seatCount int
+	status := plan.status
+	if allCancelled {
+		if err := lifecycle.Transition(ctx, tx, plan.bookingID, plan.status, finalStatus, actor, plan.reason); err != nil {
+			return nil, err
+		}
+		status = finalStatus
+	}
+
+	result := &models.BookingCancellation{
+		BookingID:      plan.bookingID,
+		Seats:          plan.cancelled.Count,
+		SeatNumbers:    plan.cancelled.SeatNumbers,
+		RefundPercent:  plan.percent,
+		RefundAmount:   plan.refund,
+		Reason:         plan.reason,
+		SeatsRemaining: plan.remaining.Count,
+		Status:         status,
+		PassengerIDs:   plan.passengers,
+	}
+	var policyID string
+	if plan.policy != nil {
+		policyID = plan.policy.ID
+	}
 	err = tx.QueryRowContext(ctx, `
-		SELECT jsonb_array_length(seats->'seat_numbers')
-		FROM bookings
-		WHERE id = $1
-	`, bookingID).Scan(&seatCount)
+		INSERT INTO booking_cancellations (booking_id, seats, seat_numbers, seat_value, refund_percent, refund_amount, reason, policy_id, cancelled_by, passenger_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`, plan.bookingID, plan.cancelled.Count, pq.StringArray(plan.cancelled.SeatNumbers), plan.seatValue, plan.percent,
+		plan.refund, plan.reason, nullable(policyID), nullable(actor.ActorID), pq.Array(plan.passengers)).Scan(&result.ID, &result.CreatedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record booking cancellation: %v", err)
+		return nil, fmt.Errorf("failed to record booking cancellation: %w", err)
+	}
 
+	action := "booking.seats_cancelled"
+	if allCancelled {
+		action = "booking." + finalStatus
+	}
+	err = record(action, "booking", plan.bookingID,
+		map[string]interface{}{"status": plan.status, "seats": plan.seats.Count},
+		map[string]interface{}{"status": status, "seats": plan.remaining.Count, "refund_percent": plan.percent, "refund_amount": plan.refund},
+	)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get seat count: %v", err)
-		return fmt.Errorf("failed to get seat count: %w", err)
+		return nil, err
 	}
 
+	// 3. Release the cancelled seats on the bus
 	_, err = tx.ExecContext(ctx, `
-		UPDATE buses b
-		SET current_occupancy = current_occupancy - $1,
+		UPDATE buses
+		SET current_occupancy = GREATEST(current_occupancy - $1, 0),
 			updated_at = NOW()
-		FROM bookings bk
-		WHERE bk.id = $2 AND b.id = bk.bus_id
-	`, seatCount, bookingID)
+		WHERE id = $2
+	`, plan.cancelled.Count, plan.busID)
 
 	if err != nil {
 		log.Printf("[ERROR] Failed to update bus occupancy: %v", err)
-		return fmt.Errorf("failed to update bus occupancy: %w", err)
+		return nil, fmt.Errorf("failed to update bus occupancy: %w", err)
+	}
+
+	return result, nil
+}
+
+// CancelTrip cancels one of the operator's scheduled departures and refunds
+// every booking on it in full, whatever the cancellation policy says.
+// It returns the number of bookings refunded.
+func (s *BookingService) CancelTrip(ctx context.Context, scheduleID, reason string, actor audit.Entry) (int, error) {
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		return 0, errors.ErrScheduleNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for trip cancellation: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var busID string
+	var departure time.Time
+	var cancelledAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT s.bus_id, s.departure_time, s.cancelled_at
+		FROM schedules s
+		JOIN buses bu ON bu.id = s.bus_id
+		WHERE s.id = $1 AND bu.operator_id = $2
+		FOR UPDATE OF s
+	`, scheduleID, actor.OperatorID).Scan(&busID, &departure, &cancelledAt)
+	if err == sql.ErrNoRows {
+		return 0, errors.ErrScheduleNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	if cancelledAt.Valid {
+		return 0, errors.ErrScheduleCancelled
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE schedules SET cancelled_at = NOW(), cancellation_reason = $1 WHERE id = $2
+	`, nullable(reason), scheduleID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to cancel schedule: %v", err)
+		return 0, fmt.Errorf("failed to cancel schedule: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM bookings WHERE schedule_id = $1 AND status IN ('confirmed', 'pending_payment')
+	`, scheduleID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	var bookingIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookingIDs = append(bookingIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var connectionBuses []string
+	for _, id := range bookingIDs {
+		plan, err := s.planCancellation(ctx, tx, id, "", 0, nil, nil, "operator", time.Now())
+		if e, ok := err.(*errors.BookingError); ok && e.Code == "ALREADY_CANCELLED" {
+			// a later leg of a journey already cancelled along with its first
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if _, err := s.applyCancellation(ctx, tx, plan, actor, lifecycle.StatusCancelled); err != nil {
+			return 0, err
+		}
+		// riders cannot make their connections, those legs are refunded in full too
+		_, buses, err := s.cancelConnections(ctx, tx, plan, actor)
+		if err != nil {
+			return 0, err
+		}
+		connectionBuses = append(connectionBuses, buses...)
+	}
+
+	// riders waiting for the trip are taken off its waitlist, and the bus's
+	// freed seats offered to those waiting for its next trip
+	if err := s.cancelWaitlist(ctx, tx, scheduleID, busID); err != nil {
+		return 0, err
+	}
+	offers, err := s.offerSeats(ctx, tx, busID)
+	if err != nil {
+		return 0, err
+	}
+	for _, connectionBus := range connectionBuses {
+		more, err := s.offerSeats(ctx, tx, connectionBus)
+		if err != nil {
+			return 0, err
+		}
+		offers = append(offers, more...)
+	}
+
+	entry := actor
+	entry.Action = "schedule.cancelled"
+	entry.EntityType = "schedule"
+	entry.EntityID = scheduleID
+	entry.Before = map[string]interface{}{"bus_id": busID, "departure_time": departure}
+	entry.After = map[string]interface{}{"bus_id": busID, "departure_time": departure, "reason": reason, "bookings_refunded": len(bookingIDs)}
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		log.Printf("[ERROR] Failed to commit cancellation transaction: %v", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		log.Printf("[ERROR] Failed to commit trip cancellation: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Printf("[INFO] Successfully cancelled booking %s for user %s", bookingID, userID)
-	return nil
+	log.Printf("[INFO] Cancelled schedule %s and refunded %d bookings", scheduleID, len(bookingIDs))
+	s.sendRefunds(ctx)
+	s.notifyOffers(offers)
+	return len(bookingIDs), nil
 }
 
-func (s *BookingService) processRefund(ctx context.Context, tx *sql.Tx, paymentID string, amount float64, paymentMethod string) error {
+// SplitSeats works out which of a booking's seats a cancellation is for.
+// Seat numbers must be on the booking; a count without seat numbers takes the
+// last seats booked, and neither takes every seat.
+func SplitSeats(seats models.SeatMap, count int, seatNumbers []string) (remaining, cancelled models.SeatMap, err error) {
+	invalid := func(message string) error {
+		return &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: message,
+		}
+	}
+
+	switch {
+	case len(seatNumbers) > 0:
+		if count != 0 && count != len(seatNumbers) {
+			return remaining, cancelled, invalid("Seat count does not match the seat numbers given")
+		}
+		pick := map[string]bool{}
+		for _, n := range seatNumbers {
+			pick[n] = true
+		}
+		for _, n := range seats.SeatNumbers {
+			if pick[n] {
+				cancelled.SeatNumbers = append(cancelled.SeatNumbers, n)
+				delete(pick, n)
+			} else {
+				remaining.SeatNumbers = append(remaining.SeatNumbers, n)
+			}
+		}
+		if len(pick) > 0 || len(cancelled.SeatNumbers) != len(seatNumbers) {
+			return models.SeatMap{}, models.SeatMap{}, invalid("Some of the seats are not on this booking")
+		}
+		cancelled.Count = len(cancelled.SeatNumbers)
+
+	case count > 0:
+		if count > seats.Count {
+			return remaining, cancelled, invalid(fmt.Sprintf("Only %d seats are left on this booking", seats.Count))
+		}
+		cancelled.Count = count
+		if len(seats.SeatNumbers) >= count {
+			split := len(seats.SeatNumbers) - count
+			remaining.SeatNumbers = append(remaining.SeatNumbers, seats.SeatNumbers[:split]...)
+			cancelled.SeatNumbers = append(cancelled.SeatNumbers, seats.SeatNumbers[split:]...)
+		}
+
+	case count < 0:
+		return remaining, cancelled, invalid("Seat count must be positive")
+
+	default:
+		cancelled = seats
+	}
+
+	if cancelled.Count == 0 {
+		return models.SeatMap{}, models.SeatMap{}, invalid("The booking has no seats left to cancel")
+	}
+	remaining.Count = seats.Count - cancelled.Count
+	return remaining, cancelled, nil
+}
+
+// SeatRefund is the share of what is left of a payment that cancelling some
+// of the remaining seats gives back. The last seats get all of it so rounding
+// never leaves anything behind.
+func SeatRefund(unrefunded float64, seatsLeft, cancelled int) float64 {
+	if unrefunded <= 0 || seatsLeft <= 0 {
+		return 0
+	}
+	if cancelled >= seatsLeft {
+		return math.Round(unrefunded*100) / 100
+	}
+	return math.Round(unrefunded*float64(cancelled)/float64(seatsLeft)*100) / 100
+}
+
+// processRefund gives amount of a payment back, fully marks the payment
+// refunded rather than partially refunded
+func (s *BookingService) processRefund(ctx context.Context, tx *sql.Tx, bookingID, paymentID string, amount float64, paymentMethod string, fully bool) error {
 	// Convert string to PaymentMethod type
 	method := payments.PaymentMethod(paymentMethod)
 
 	switch method {
 	case payments.PaymentMethodBusPass:
-		// Refund to bus pass
-		_, err := tx.ExecContext(ctx, `
-			UPDATE bus_passes bp
-			SET balance = balance + $1,
-				updated_at = NOW()
+		// Refund to the pass that paid, from the operator that was paid
+		var passID, userID, operatorID string
+		err := tx.QueryRowContext(ctx, `
+			SELECT p.bus_pass_id, p.user_id, bu.operator_id
 			FROM payments p
-			WHERE p.id = $2 AND p.user_id = bp.user_id
-		`, amount, paymentID)
+			JOIN bookings b ON b.id = p.booking_id
+			JOIN buses bu ON bu.id = b.bus_id
+			WHERE p.id = $1
+		`, paymentID).Scan(&passID, &userID, &operatorID)
 
 		if err != nil {
 			log.Printf("[ERROR] Database error: %v", err)
 			return fmt.Errorf("failed to refund bus pass: %w", err)
 		}
 
+		if amount == 0 {
+			// unlimited pass ride, there is nothing to give back
+			break
+		}
+
+		posted, err := s.ledger.RefundFare(ctx, tx, passID, operatorID, amount, bookingID, paymentID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to refund bus pass: %v", err)
+			return fmt.Errorf("failed to refund bus pass: %w", err)
+		}
+
+		if err := s.recordPassTransaction(ctx, tx, passID, userID, "refund", amount, posted.PassBalance, paymentID, bookingID, posted.EntryID); err != nil {
+			return err
+		}
+
+	case payments.PaymentMethodInvoice:
+		// nothing went through a gateway, the refund is credited against
+		// the invoice through refunded_amount
+
 	default:
-		// Process refund through payment gateway
-		if err := s.paymentService.RefundPayment(ctx, paymentID); err != nil {
-			log.Printf("[ERROR] Failed to process refund: %v", err)
+		if amount == 0 {
+			// free ride, nothing was charged
+			break
+		}
+
+		// Refund through the payment gateway, which knows the payment by its
+		// transaction id, once the cancellation commits
+		var transactionID string
+		err := tx.QueryRowContext(ctx, `SELECT transaction_id FROM payments WHERE id = $1`, paymentID).Scan(&transactionID)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
 			return fmt.Errorf("failed to process refund: %w", err)
 		}
+		if err := queueRefund(ctx, tx, bookingID, paymentID, transactionID, amount); err != nil {
+			return err
+		}
 	}
 
 	// Update payment status
+	status := payments.PaymentStatusPartiallyRefunded
+	if fully {
+		status = payments.PaymentStatusRefunded
+	}
 	_, err := tx.ExecContext(ctx, `
 		UPDATE payments
-		SET payment_status = 'refunded',
+		SET payment_status = $1,
+			refunded_amount = refunded_amount + $2,
 			updated_at = NOW()
-		WHERE id = $1
-	`, paymentID)
+		WHERE id = $3
+	`, status, amount, paymentID)
 
 	if err != nil {
 		log.Printf("[ERROR] Database error: %v", err)
@@ -586,25 +1524,13 @@ func (s *BookingService) processRefund(ctx context.Context, tx *sql.Tx, paymentI
 }
 
 func (s *BookingService) resolvesStop(ctx context.Context, tx *sql.Tx, routeID, stopName string) (stopID, name string, lat, lng *float64, err error) {
-	// real stops
-	const q1 = `
-		SELECT bs.id, bs.name, bs.latitude, bs.longitude
-		FROM route_bus_stops rbs
-		JOIN bus_stops bs ON rbs.bus_stop_id = bs.id
-		WHERE rbs.route_id = $1
-		AND bs.name ILIKE '%' || $2 || '%'
-		LIMIT 1
-	`
-	var rawLat, rawLng sql.NullFloat64
-	if scanErr := tx.QueryRowContext(ctx, q1, routeID, stopName).Scan(&stopID, &name, &rawLat, &rawLng); scanErr == nil {
-		if rawLat.Valid {
-			lat = &rawLat.Float64
-		}
-		if rawLng.Valid {
-			lng = &rawLng.Float64
-		}
-
-		return stopID, name, lat, lng, nil
+	// real stops, matched the way the stop directory searches
+	match, err := stops.Resolve(ctx, tx, routeID, stopName)
+	if err != nil {
+		return "", "", nil, nil, err
+	}
+	if match != nil {
+		return match.ID, match.Name, &match.Latitude, &match.Longitude, nil
 	}
 
 	// virtual origin or destination
@@ -612,14 +1538,15 @@ func (s *BookingService) resolvesStop(ctx context.Context, tx *sql.Tx, routeID,
 		SELECT origin AS name, NULL::float8 AS latitude, NULL::float8 AS longitude
 			FROM bus_routes
 		WHERE id = $1
-			AND LOWER(origin) = LOWER($2)
+			AND stop_search_key(origin) = stop_search_key($2)
 		UNION
 		SELECT destination AS name, NULL::float8 AS latitude, NULL::float8 AS longitude
 			FROM bus_routes
 		WHERE id = $1
-			AND LOWER(destination) = LOWER($2)
+			AND stop_search_key(destination) = stop_search_key($2)
 		LIMIT 1
 	`
+	var rawLat, rawLng sql.NullFloat64
 	if scanErr := tx.QueryRowContext(ctx, q2, routeID, stopName).Scan(&name, &rawLat, &rawLng); scanErr == nil {
 		// no real id, leave stop id empty
 		return "", name, nil, nil, nil
@@ -631,3 +1558,45 @@ func (s *BookingService) resolvesStop(ctx context.Context, tx *sql.Tx, routeID,
 		Message: fmt.Sprintf("Stop %s not found on this route", stopName),
 	}
 }
+
+// recordPassTransaction adds a ride or refund to the pass's history
+func (s *BookingService) recordPassTransaction(ctx context.Context, tx *sql.Tx, passID, userID, kind string, amount, balanceAfter float64, paymentID, bookingID, entryID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO pass_transactions (pass_id, user_id, type, amount, balance_after, payment_id, booking_id, journal_entry_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, passID, userID, kind, amount, balanceAfter, paymentID, bookingID, nullable(entryID))
+	if err != nil {
+		log.Printf("[ERROR] Failed to record pass transaction: %v", err)
+		return fmt.Errorf("failed to record pass transaction: %w", err)
+	}
+	return nil
+}
+
+// recordAudit writes a rider initiated transition to the audit chain of the
+// operator running the bus
+func (s *BookingService) recordAudit(ctx context.Context, tx *sql.Tx, userID, busID, action, entityType, entityID string, before, after interface{}) error {
+	var operatorID string
+	err := tx.QueryRowContext(ctx, `SELECT operator_id FROM buses WHERE id = $1`, busID).Scan(&operatorID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	return s.audit.Record(ctx, tx, audit.Entry{
+		ActorID:    userID,
+		ActorRole:  "rider",
+		OperatorID: operatorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,387 @@
+// backend/internal/services/booking/holds.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// SeatHoldTTL is how long seats are held for a rider checking out
+const SeatHoldTTL = 5 * time.Minute
+
+type SeatHoldRequest struct {
+	UserID      string
+	BusID       string
+	SeatCount   int
+	SeatNumbers []string // optional, reserves these seats in particular
+}
+
+// HoldSeats reserves seats on the bus's next trip for SeatHoldTTL while the
+// rider pays. The seats are taken out of the bus's occupancy straight away,
+// so nobody else can book them, and CreateBooking with the hold's ID books
+// them. A rider has one hold on a bus at a time; holding again releases the
+// earlier hold.
+func (s *BookingService) HoldSeats(ctx context.Context, req SeatHoldRequest) (*models.SeatHold, error) {
+	seatNumbers, err := SeatSelection(req.SeatCount, req.SeatNumbers)
+	if err != nil {
+		return nil, err
+	}
+	seats := req.SeatCount
+	if seats == 0 {
+		seats = len(seatNumbers)
+	}
+	if _, err := uuid.Parse(req.BusID); err != nil {
+		return nil, busNotFound()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var capacity int
+	err = tx.QueryRowContext(ctx, `
+		SELECT capacity FROM buses WHERE id = $1 AND status IN ('active', 'assigned')
+	`, req.BusID).Scan(&capacity)
+	if err == sql.ErrNoRows {
+		return nil, busNotFound()
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if seats > capacity {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: fmt.Sprintf("Cannot hold more seats than bus capacity (%d)", capacity),
+		}
+	}
+	if err := s.checkBookable(ctx, tx, req.BusID); err != nil {
+		return nil, err
+	}
+
+	// the rider's earlier hold on the bus goes back first
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, seats FROM seat_holds
+		WHERE user_id = $1 AND bus_id = $2 AND status = 'held'
+		FOR UPDATE
+	`, req.UserID, req.BusID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	released := 0
+	var previous []string
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan seat hold: %w", err)
+		}
+		previous = append(previous, id)
+		released += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(previous) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE seat_holds SET status = 'released', updated_at = NOW() WHERE id = ANY($1)
+		`, pq.Array(previous))
+		if err != nil {
+			log.Printf("[ERROR] Failed to release seat hold: %v", err)
+			return nil, fmt.Errorf("failed to release seat hold: %w", err)
+		}
+		if err := s.releaseSeats(ctx, tx, req.BusID, released); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduleID, _, err := nextDeparture(ctx, tx, req.BusID, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(seatNumbers) > 0 {
+		var schedule string
+		if scheduleID != nil {
+			schedule = *scheduleID
+		}
+		if err := s.checkSeatNumbers(ctx, tx, req.BusID, schedule, seatNumbers); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.updateBusOccupancy(ctx, tx, req.BusID, seats); err != nil {
+		return nil, err
+	}
+
+	hold := &models.SeatHold{
+		ID:          uuid.New().String(),
+		UserID:      req.UserID,
+		BusID:       req.BusID,
+		ScheduleID:  scheduleID,
+		Seats:       seats,
+		SeatNumbers: seatNumbers,
+		Status:      "held",
+		ExpiresAt:   time.Now().Add(SeatHoldTTL),
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO seat_holds (id, user_id, bus_id, schedule_id, seats, seat_numbers, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, hold.ID, hold.UserID, hold.BusID, scheduleID, hold.Seats, pq.StringArray(seatNumbers), hold.ExpiresAt).Scan(&hold.CreatedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to hold seats: %v", err)
+		return nil, fmt.Errorf("failed to hold seats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Held %d seats on bus %s for user %s until %s", seats, req.BusID, req.UserID, hold.ExpiresAt.Format(time.RFC3339))
+	return hold, nil
+}
+
+// ReleaseSeatHold gives up one of the rider's seat holds before it expires
+func (s *BookingService) ReleaseSeatHold(ctx context.Context, holdID, userID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	hold, err := lockSeatHold(ctx, tx, holdID, userID)
+	if err != nil {
+		return err
+	}
+	if hold.Status != "held" {
+		return seatHoldClosed(hold.Status)
+	}
+
+	offers, err := s.endSeatHold(ctx, tx, hold, "released")
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.notifyOffers(offers)
+	return nil
+}
+
+// ProcessSeatHolds releases the seats of holds that expired without being
+// booked, offering them to the bus's waitlist. It returns the number of
+// holds released.
+func (s *BookingService) ProcessSeatHolds(ctx context.Context) (int, error) {
+	due, err := s.dueBookings(ctx, `
+		SELECT id FROM seat_holds WHERE status = 'held' AND expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range due {
+		var offers []models.WaitlistEntry
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			hold, err := lockSeatHold(ctx, tx, id, "")
+			if err != nil {
+				return err
+			}
+			// booked or released in the meantime
+			if hold.Status != "held" {
+				return nil
+			}
+			if offers, err = s.endSeatHold(ctx, tx, hold, "expired"); err != nil {
+				return err
+			}
+			expired++
+			return nil
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to expire seat hold %s: %v", id, err)
+			continue
+		}
+		s.notifyOffers(offers)
+	}
+	return expired, nil
+}
+
+// useSeatHold checks the rider's hold can be booked and fills in the
+// booking request from it
+func (s *BookingService) useSeatHold(ctx context.Context, tx *sql.Tx, req *CreateBookingRequest) (*models.SeatHold, error) {
+	hold, err := lockSeatHold(ctx, tx, req.HoldID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	switch hold.Status {
+	case "held":
+		if !hold.ExpiresAt.After(time.Now()) {
+			return nil, errors.ErrSeatHoldExpired
+		}
+	case "expired":
+		return nil, errors.ErrSeatHoldExpired
+	default:
+		return nil, seatHoldClosed(hold.Status)
+	}
+	if (req.BusID != "" && req.BusID != hold.BusID) || (req.SeatCount != 0 && req.SeatCount != hold.Seats) {
+		return nil, errors.ErrSeatHoldMismatch
+	}
+
+	req.BusID = hold.BusID
+	req.SeatCount = hold.Seats
+	req.SeatNumbers = hold.SeatNumbers
+	req.scheduleID = ""
+	if hold.ScheduleID != nil {
+		req.scheduleID = *hold.ScheduleID
+	}
+	req.seatsHeld = true
+	return hold, nil
+}
+
+// endSeatHold closes a hold that was not booked and gives its seats back,
+// offering them to the bus's waitlist
+func (s *BookingService) endSeatHold(ctx context.Context, tx *sql.Tx, hold *models.SeatHold, status string) ([]models.WaitlistEntry, error) {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE seat_holds SET status = $1, updated_at = NOW() WHERE id = $2
+	`, status, hold.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update seat hold: %v", err)
+		return nil, fmt.Errorf("failed to update seat hold: %w", err)
+	}
+	if err := s.releaseSeats(ctx, tx, hold.BusID, hold.Seats); err != nil {
+		return nil, err
+	}
+	return s.offerSeats(ctx, tx, hold.BusID)
+}
+
+// checkSeatNumbers rejects seats another rider holds or has booked on the
+// trip. The bus row stays locked until the caller commits, so two riders
+// can't both pass the check for the same seat.
+func (s *BookingService) checkSeatNumbers(ctx context.Context, tx *sql.Tx, busID, scheduleID string, seatNumbers []string) error {
+	var locked string
+	err := tx.QueryRowContext(ctx, `SELECT id FROM buses WHERE id = $1 FOR UPDATE`, busID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return busNotFound()
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	var taken string
+	err = tx.QueryRowContext(ctx, `
+		SELECT n FROM unnest($3::TEXT[]) n
+		WHERE EXISTS (
+			SELECT 1 FROM seat_holds h
+			WHERE h.bus_id = $1 AND h.status = 'held' AND h.expires_at > NOW() AND n = ANY(h.seat_numbers)
+		) OR EXISTS (
+			SELECT 1 FROM bookings b
+			WHERE b.bus_id = $1 AND COALESCE(b.schedule_id::TEXT, '') = $2
+			AND b.status IN ('pending_payment', 'confirmed', 'boarded')
+			AND b.seats->'seat_numbers' ? n
+		)
+		LIMIT 1
+	`, busID, scheduleID, pq.StringArray(seatNumbers)).Scan(&taken)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	return &errors.BookingError{
+		Code:    "SEATS_UNAVAILABLE",
+		Message: fmt.Sprintf("Seat %s is already taken", taken),
+	}
+}
+
+// SeatSelection checks seats asked for by count or by seat number, and
+// returns the seat numbers with duplicates rejected
+func SeatSelection(count int, seatNumbers []string) ([]string, error) {
+	if count < 0 || (count == 0 && len(seatNumbers) == 0) {
+		return nil, errors.ErrInvalidSeatSelection
+	}
+	if len(seatNumbers) > 0 && count != 0 && count != len(seatNumbers) {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: "Seat count does not match the seat numbers given",
+		}
+	}
+
+	seen := map[string]bool{}
+	numbers := []string{}
+	for _, n := range seatNumbers {
+		if n == "" || seen[n] {
+			return nil, &errors.BookingError{
+				Code:    "INVALID_SEAT_SELECTION",
+				Message: fmt.Sprintf("Seat %q is not a valid seat selection", n),
+			}
+		}
+		seen[n] = true
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+// lockSeatHold locks a seat hold, limited to the rider's own when userID is
+// given
+func lockSeatHold(ctx context.Context, tx *sql.Tx, holdID, userID string) (*models.SeatHold, error) {
+	if _, err := uuid.Parse(holdID); err != nil {
+		return nil, errors.ErrSeatHoldNotFound
+	}
+
+	var h models.SeatHold
+	var scheduleID, bookingID sql.NullString
+	var seatNumbers pq.StringArray
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, user_id, bus_id, schedule_id, seats, seat_numbers, status, expires_at, booking_id, created_at
+		FROM seat_holds
+		WHERE id = $1 AND ($2 = '' OR user_id::TEXT = $2)
+		FOR UPDATE
+	`, holdID, userID).Scan(&h.ID, &h.UserID, &h.BusID, &scheduleID, &h.Seats, &seatNumbers, &h.Status,
+		&h.ExpiresAt, &bookingID, &h.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrSeatHoldNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if scheduleID.Valid {
+		h.ScheduleID = &scheduleID.String
+	}
+	if bookingID.Valid {
+		h.BookingID = &bookingID.String
+	}
+	h.SeatNumbers = []string(seatNumbers)
+	return &h, nil
+}
+
+func seatHoldClosed(status string) error {
+	return &errors.BookingError{
+		Code:    "SEAT_HOLD_CLOSED",
+		Message: fmt.Sprintf("Seat hold is already %s", status),
+	}
+}
+
+func busNotFound() error {
+	return &errors.BookingError{
+		Code:    "BUS_NOT_FOUND",
+		Message: "Bus not found or inactive",
+	}
+}
@@ -0,0 +1,451 @@
+// backend/internal/services/booking/journeys.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/lifecycle"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/Mvoii/zurura/internal/services/stops"
+)
+
+const (
+	// MinTransferTime is the least time a rider is given to change buses
+	MinTransferTime = 5 * time.Minute
+	// MaxTransferWait is the longest a rider is asked to wait for the next leg
+	MaxTransferWait = 45 * time.Minute
+	// MaxJourneyLegs is the most legs a journey can be booked with
+	MaxJourneyLegs = 3
+
+	// planningHorizon is how far after the departure time trips are searched
+	planningHorizon = 6 * time.Hour
+	maxItineraries  = 10
+)
+
+// JourneyLegRequest is one leg of a journey, a trip from PlanJourneys
+type JourneyLegRequest struct {
+	BusID             string
+	ScheduleID        string
+	BoardingStopName  string
+	AlightingStopName string
+}
+
+// JourneyRequest books every leg of a journey for the same seats with one
+// payment
+type JourneyRequest struct {
+	UserID        string
+	Legs          []JourneyLegRequest
+	SeatCount     int
+	PaymentMethod payments.PaymentMethod
+}
+
+// stopTimes is when each upcoming trip reaches each of its stops, from its
+// departure and the stop's time from the start of the route version it runs
+const stopTimes = `
+	WITH stop_times AS (
+		SELECT s.id AS schedule_id, s.bus_id, s.route_id, r.route_name, ss.bus_stop_id, bs.name AS stop_name,
+			ss.stop_order, s.departure_time + COALESCE(ss.estimated_arrival_time, INTERVAL '0') AS at
+		FROM schedules s
+		JOIN bus_routes r ON r.id = s.route_id
+		JOIN schedule_stops ss ON ss.schedule_id = s.id
+		JOIN bus_stops bs ON bs.id = ss.bus_stop_id
+		WHERE s.cancelled_at IS NULL
+		AND s.departure_time > $3::TIMESTAMPTZ - INTERVAL '3 hours'
+		AND s.departure_time < $3::TIMESTAMPTZ + make_interval(secs => $4)
+	)`
+
+// PlanJourneys finds the scheduled trips from one stop to another leaving
+// after departAfter, riding straight through or changing once at a stop two
+// routes share. The best itineraries come first: earliest arrival, then
+// fewest transfers, then latest departure.
+func (s *BookingService) PlanJourneys(ctx context.Context, from, to string, departAfter time.Time) ([]models.Itinerary, error) {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if stops.SearchKey(from) == "" || stops.SearchKey(to) == "" {
+		return nil, invalidJourney("Choose two different stops")
+	}
+	var err error
+	if from, err = directoryStop(ctx, s.db, from); err != nil {
+		return nil, err
+	}
+	if to, err = directoryStop(ctx, s.db, to); err != nil {
+		return nil, err
+	}
+	if stops.SearchKey(from) == stops.SearchKey(to) {
+		return nil, invalidJourney("Choose two different stops")
+	}
+	horizon := planningHorizon.Seconds()
+
+	itineraries := []models.Itinerary{}
+	rows, err := s.db.QueryContext(ctx, stopTimes+`
+		SELECT a.route_id, a.route_name, a.bus_id, a.schedule_id, a.stop_name, b.stop_name, a.at, b.at
+		FROM stop_times a
+		JOIN stop_times b ON b.schedule_id = a.schedule_id AND b.stop_order > a.stop_order
+		WHERE stop_search_key(a.stop_name) = stop_search_key($1) AND stop_search_key(b.stop_name) = stop_search_key($2) AND a.at >= $3
+	`, from, to, departAfter, horizon)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for rows.Next() {
+		var leg models.ItineraryLeg
+		if err := rows.Scan(&leg.RouteID, &leg.RouteName, &leg.BusID, &leg.ScheduleID, &leg.BoardingStopName,
+			&leg.AlightingStopName, &leg.DepartsAt, &leg.ArrivesAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan itinerary: %w", err)
+		}
+		itineraries = append(itineraries, itinerary(leg))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, stopTimes+`
+		SELECT a.route_id, a.route_name, a.bus_id, a.schedule_id, a.stop_name, t1.stop_name, a.at, t1.at,
+			t2.route_id, t2.route_name, t2.bus_id, t2.schedule_id, t2.stop_name, b.stop_name, t2.at, b.at
+		FROM stop_times a
+		JOIN stop_times t1 ON t1.schedule_id = a.schedule_id AND t1.stop_order > a.stop_order
+		JOIN stop_times t2 ON t2.bus_stop_id = t1.bus_stop_id AND t2.route_id <> a.route_id
+			AND t2.at >= t1.at + make_interval(secs => $5) AND t2.at <= t1.at + make_interval(secs => $6)
+		JOIN stop_times b ON b.schedule_id = t2.schedule_id AND b.stop_order > t2.stop_order
+		WHERE stop_search_key(a.stop_name) = stop_search_key($1) AND stop_search_key(b.stop_name) = stop_search_key($2) AND a.at >= $3
+		AND stop_search_key(t1.stop_name) <> stop_search_key($2)
+	`, from, to, departAfter, horizon, MinTransferTime.Seconds(), MaxTransferWait.Seconds())
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var first, second models.ItineraryLeg
+		if err := rows.Scan(&first.RouteID, &first.RouteName, &first.BusID, &first.ScheduleID, &first.BoardingStopName,
+			&first.AlightingStopName, &first.DepartsAt, &first.ArrivesAt,
+			&second.RouteID, &second.RouteName, &second.BusID, &second.ScheduleID, &second.BoardingStopName,
+			&second.AlightingStopName, &second.DepartsAt, &second.ArrivesAt); err != nil {
+			return nil, fmt.Errorf("failed to scan itinerary: %w", err)
+		}
+		second.TransferWait = int(second.DepartsAt.Sub(first.ArrivesAt).Minutes())
+		itineraries = append(itineraries, itinerary(first, second))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return RankItineraries(itineraries, maxItineraries), nil
+}
+
+// directoryStop is the name of the stop a rider's stop name stands for in
+// the stop directory, or the name as given when none matches closely enough
+func directoryStop(ctx context.Context, q stops.Queryer, name string) (string, error) {
+	matches, err := stops.Search(ctx, q, name, stops.SearchOptions{Limit: 1, MinScore: stops.ResolveScore})
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return name, nil
+	}
+	return matches[0].Name, nil
+}
+
+func itinerary(legs ...models.ItineraryLeg) models.Itinerary {
+	return models.Itinerary{
+		Legs:      legs,
+		DepartsAt: legs[0].DepartsAt,
+		ArrivesAt: legs[len(legs)-1].ArrivesAt,
+		Transfers: len(legs) - 1,
+	}
+}
+
+// RankItineraries orders itineraries best first and keeps at most limit of
+// them. The same trips ridden through different transfer stops are only
+// kept once, arriving earliest.
+func RankItineraries(itineraries []models.Itinerary, limit int) []models.Itinerary {
+	ranked := append([]models.Itinerary{}, itineraries...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if !a.ArrivesAt.Equal(b.ArrivesAt) {
+			return a.ArrivesAt.Before(b.ArrivesAt)
+		}
+		if a.Transfers != b.Transfers {
+			return a.Transfers < b.Transfers
+		}
+		return a.DepartsAt.After(b.DepartsAt)
+	})
+
+	seen := map[string]bool{}
+	result := []models.Itinerary{}
+	for _, it := range ranked {
+		trips := make([]string, len(it.Legs))
+		for i, leg := range it.Legs {
+			trips[i] = leg.ScheduleID
+		}
+		key := strings.Join(trips, ",")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, it)
+		if len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+// CheckTransfers checks that each leg of a journey starts where the one
+// before it ends, with time to change buses and not too long a wait, and
+// fills in each leg's wait
+func CheckTransfers(legs []models.ItineraryLeg) error {
+	for i := 1; i < len(legs); i++ {
+		prev, leg := legs[i-1], &legs[i]
+		if !strings.EqualFold(prev.AlightingStopName, leg.BoardingStopName) {
+			return invalidJourney(fmt.Sprintf("Leg %d must start at %s, where leg %d ends", i+1, prev.AlightingStopName, i))
+		}
+		wait := leg.DepartsAt.Sub(prev.ArrivesAt)
+		if wait < MinTransferTime {
+			return invalidJourney(fmt.Sprintf("Leg %d leaves %s too soon after leg %d arrives", i+1, leg.BoardingStopName, i))
+		}
+		if wait > MaxTransferWait {
+			return invalidJourney(fmt.Sprintf("Leg %d leaves %s more than %d minutes after leg %d arrives",
+				i+1, leg.BoardingStopName, int(MaxTransferWait.Minutes()), i))
+		}
+		leg.TransferWait = int(wait.Minutes())
+	}
+	return nil
+}
+
+// BookJourney books every leg of a journey in one go, or none of them: if
+// any leg cannot be booked the whole journey fails. Card and mobile money
+// payments are taken once for the whole fare and shared between the legs;
+// bus passes pay for each leg from the pass as it is booked.
+func (s *BookingService) BookJourney(ctx context.Context, req JourneyRequest) (*models.Journey, error) {
+	if len(req.Legs) < 2 || len(req.Legs) > MaxJourneyLegs {
+		return nil, invalidJourney(fmt.Sprintf("A journey has 2 to %d legs", MaxJourneyLegs))
+	}
+	if req.SeatCount == 0 {
+		req.SeatCount = 1
+	}
+	if req.SeatCount < 0 {
+		return nil, invalidJourney("Seat count must be positive")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for journey booking: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Check the legs connect
+	legs := make([]models.ItineraryLeg, len(req.Legs))
+	for i, leg := range req.Legs {
+		if legs[i], err = s.journeyLeg(ctx, tx, leg); err != nil {
+			return nil, legError(i, err)
+		}
+	}
+	if err := CheckTransfers(legs); err != nil {
+		return nil, err
+	}
+
+	// 2. Price each leg
+	journey := &models.Journey{
+		ID:            uuid.New().String(),
+		UserID:        req.UserID,
+		Seats:         req.SeatCount,
+		PaymentMethod: string(req.PaymentMethod),
+		CreatedAt:     time.Now(),
+	}
+	bookings := make([]CreateBookingRequest, len(req.Legs))
+	priced := make([]*pricedBooking, len(req.Legs))
+	committed := false
+	defer func() {
+		if !committed {
+			for _, p := range priced {
+				s.releasePromo(ctx, p)
+			}
+		}
+	}()
+	var total float64
+	for i, leg := range req.Legs {
+		bookings[i] = CreateBookingRequest{
+			BusID:             leg.BusID,
+			BoardingStopName:  leg.BoardingStopName,
+			AlightingStopName: leg.AlightingStopName,
+			SeatCount:         req.SeatCount,
+			PaymentMethod:     req.PaymentMethod,
+			UserID:            req.UserID,
+			scheduleID:        leg.ScheduleID,
+			journeyID:         journey.ID,
+			journeyLeg:        i + 1,
+		}
+		if priced[i], err = s.priceBooking(ctx, tx, &bookings[i]); err != nil {
+			return nil, legError(i, err)
+		}
+		total += priced[i].fare
+	}
+	for i := range bookings {
+		if err := s.claimPriced(ctx, tx, bookings[i], priced[i]); err != nil {
+			return nil, legError(i, err)
+		}
+	}
+	journey.Fare = fareTotal([]models.BookingLineItem{{Amount: total}})
+
+	// 3. Take one payment for the whole journey
+	if req.PaymentMethod != payments.PaymentMethodBusPass {
+		paid := freeRide(req.PaymentMethod)
+		if journey.Fare > 0 {
+			if paid, err = s.processPayment(ctx, tx, bookings[0], journey.Fare); err != nil {
+				return nil, err
+			}
+		}
+		journey.TransactionID = paid.TransactionID
+		for i := range bookings {
+			bookings[i].paid = paid
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO journeys (id, user_id, seats, fare, payment_method, transaction_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, journey.ID, journey.UserID, journey.Seats, journey.Fare, req.PaymentMethod, nullable(journey.TransactionID), journey.CreatedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create journey: %v", err)
+		return nil, fmt.Errorf("failed to create journey: %w", err)
+	}
+
+	// 4. Book each leg
+	for i := range bookings {
+		booking, err := s.bookPriced(ctx, tx, bookings[i], priced[i])
+		if err != nil {
+			return nil, legError(i, err)
+		}
+		journey.Legs = append(journey.Legs, *booking)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit journey booking: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return journey, nil
+}
+
+// journeyLeg checks that a leg's trip runs between its stops and works out
+// when it reaches them
+func (s *BookingService) journeyLeg(ctx context.Context, tx *sql.Tx, req JourneyLegRequest) (models.ItineraryLeg, error) {
+	leg := models.ItineraryLeg{
+		BusID:      req.BusID,
+		ScheduleID: req.ScheduleID,
+	}
+	if _, err := uuid.Parse(req.ScheduleID); err != nil {
+		return leg, errors.ErrScheduleNotFound
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT s.route_id, r.route_name, a.name, b.name,
+			s.departure_time + COALESCE(ra.estimated_arrival_time, INTERVAL '0'),
+			s.departure_time + COALESCE(rb.estimated_arrival_time, INTERVAL '0')
+		FROM schedules s
+		JOIN bus_routes r ON r.id = s.route_id
+		JOIN schedule_stops ra ON ra.schedule_id = s.id
+		JOIN bus_stops a ON a.id = ra.bus_stop_id
+		JOIN schedule_stops rb ON rb.schedule_id = s.id AND rb.stop_order > ra.stop_order
+		JOIN bus_stops b ON b.id = rb.bus_stop_id
+		WHERE s.id = $1 AND s.bus_id::TEXT = $2 AND s.cancelled_at IS NULL AND s.departure_time > NOW()
+		AND stop_search_key(a.name) = stop_search_key($3) AND stop_search_key(b.name) = stop_search_key($4)
+		LIMIT 1
+	`, req.ScheduleID, req.BusID, req.BoardingStopName, req.AlightingStopName).Scan(&leg.RouteID, &leg.RouteName,
+		&leg.BoardingStopName, &leg.AlightingStopName, &leg.DepartsAt, &leg.ArrivesAt)
+	if err == sql.ErrNoRows {
+		return leg, invalidJourney(fmt.Sprintf("The trip does not run from %s to %s", req.BoardingStopName, req.AlightingStopName))
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return leg, fmt.Errorf("database error: %w", err)
+	}
+	return leg, nil
+}
+
+// cancelConnections cancels the later legs of the journey a cancelled
+// booking is part of, which cannot be ridden without it. They lose as many
+// seats as it did, or all of them once it has none left.
+func (s *BookingService) cancelConnections(ctx context.Context, tx *sql.Tx, plan *cancellationPlan, actor audit.Entry) ([]models.BookingCancellation, []string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT l.id, (l.seats->>'count')::INT
+		FROM bookings b
+		JOIN bookings l ON l.journey_id = b.journey_id AND l.journey_leg > b.journey_leg
+		WHERE b.id = $1 AND l.status IN ('confirmed', 'pending_payment')
+		ORDER BY l.journey_leg
+	`, plan.bookingID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	type connection struct {
+		id    string
+		seats int
+	}
+	var connections []connection
+	for rows.Next() {
+		var c connection
+		if err := rows.Scan(&c.id, &c.seats); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan journey leg: %w", err)
+		}
+		connections = append(connections, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var cancelled []models.BookingCancellation
+	var buses []string
+	for _, c := range connections {
+		count := plan.cancelled.Count
+		if plan.remaining.Count == 0 || count >= c.seats {
+			count = 0
+		}
+		legPlan, err := s.planCancellation(ctx, tx, c.id, "", count, nil, nil, plan.reason, time.Now())
+		if err != nil {
+			return nil, nil, err
+		}
+		result, err := s.applyCancellation(ctx, tx, legPlan, actor, lifecycle.StatusCancelled)
+		if err != nil {
+			return nil, nil, err
+		}
+		cancelled = append(cancelled, *result)
+		buses = append(buses, legPlan.busID)
+	}
+	return cancelled, buses, nil
+}
+
+// legError says which leg of a journey could not be booked
+func legError(i int, err error) error {
+	if e, ok := err.(*errors.BookingError); ok {
+		return &errors.BookingError{
+			Code:    e.Code,
+			Message: fmt.Sprintf("Leg %d: %s", i+1, e.Message),
+			Err:     e.Err,
+		}
+	}
+	return err
+}
+
+func invalidJourney(message string) error {
+	return &errors.BookingError{
+		Code:    "INVALID_JOURNEY",
+		Message: message,
+	}
+}
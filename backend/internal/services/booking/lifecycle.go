@@ -0,0 +1,387 @@
+// backend/internal/services/booking/lifecycle.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/cancellation"
+	"github.com/Mvoii/zurura/internal/services/lifecycle"
+	"github.com/Mvoii/zurura/internal/services/payments"
+)
+
+// DefaultTripDuration is how long a trip is taken to last when its route has
+// no estimated duration
+const DefaultTripDuration = 2 * time.Hour
+
+// system is the actor for transitions made by the background jobs
+var system = audit.Entry{ActorRole: "system"}
+
+// BookingHistory returns the status changes of one of the rider's bookings
+func (s *BookingService) BookingHistory(ctx context.Context, bookingID, userID string) ([]lifecycle.Change, error) {
+	if _, err := uuid.Parse(bookingID); err != nil {
+		return nil, errors.ErrBookingNotFound
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM bookings WHERE id = $1 AND user_id = $2)
+	`, bookingID, userID).Scan(&exists)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.ErrBookingNotFound
+	}
+
+	return lifecycle.History(ctx, s.db, bookingID)
+}
+
+// CompleteBooking marks a boarded booking on one of the operator's buses as
+// ridden. The rider's ride count goes up and the ride earns loyalty points,
+// which are returned.
+func (s *BookingService) CompleteBooking(ctx context.Context, bookingID string, actor audit.Entry) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for booking completion: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID, status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT b.user_id, b.status
+		FROM bookings b
+		JOIN buses bu ON bu.id = b.bus_id
+		WHERE b.id = $1 AND bu.operator_id = $2
+		FOR UPDATE OF b
+	`, bookingID, actor.OperatorID).Scan(&userID, &status)
+	if err == sql.ErrNoRows {
+		return 0, &errors.BookingError{
+			Code:    "BOOKING_NOT_FOUND",
+			Message: "Booking not found",
+		}
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error while fetching booking: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	points, err := s.completeBooking(ctx, tx, bookingID, userID, status, actor)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit completion transaction: %v", err)
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return points, nil
+}
+
+// completeBooking moves a locked booking to completed, counts the ride and
+// awards its loyalty points
+func (s *BookingService) completeBooking(ctx context.Context, tx *sql.Tx, bookingID, userID, status string, actor audit.Entry) (int, error) {
+	if err := lifecycle.Transition(ctx, tx, bookingID, status, lifecycle.StatusCompleted, actor, ""); err != nil {
+		return 0, err
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE bookings SET completed_at = NOW() WHERE id = $1`, bookingID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update booking status: %v", err)
+		return 0, fmt.Errorf("failed to update booking status: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET ride_count = ride_count + 1 WHERE id = $1`, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update ride count: %v", err)
+		return 0, fmt.Errorf("failed to update ride count: %w", err)
+	}
+
+	points, err := s.loyalty.Award(ctx, tx, bookingID)
+	if err != nil {
+		return 0, err
+	}
+
+	actor.Action = "booking.completed"
+	actor.EntityType = "booking"
+	actor.EntityID = bookingID
+	actor.Before = map[string]interface{}{"status": status}
+	actor.After = map[string]interface{}{"status": lifecycle.StatusCompleted, "points_earned": points}
+	if err := s.audit.Record(ctx, tx, actor); err != nil {
+		return 0, err
+	}
+	return points, nil
+}
+
+// ProcessCompletions completes boarded bookings once their trip has ended,
+// the departure plus the route's estimated duration, or the time the rider
+// boarded when the departure is not known. It returns the number completed.
+func (s *BookingService) ProcessCompletions(ctx context.Context) (int, error) {
+	due, err := s.dueBookings(ctx, `
+		SELECT b.id
+		FROM bookings b
+		LEFT JOIN bus_routes r ON r.id = b.route_id
+		WHERE b.status = 'boarded'
+		AND COALESCE(b.departs_at, b.boarded_at) + COALESCE(r.estimated_duration, $1 * INTERVAL '1 second') < NOW()
+	`, DefaultTripDuration.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	completed := 0
+	for _, id := range due {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			var userID, status string
+			err := tx.QueryRowContext(ctx, `
+				SELECT user_id, status FROM bookings WHERE id = $1 FOR UPDATE
+			`, id).Scan(&userID, &status)
+			if err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+			// completed by the operator in the meantime
+			if status != lifecycle.StatusBoarded {
+				return nil
+			}
+			if _, err := s.completeBooking(ctx, tx, id, userID, status, system); err != nil {
+				return err
+			}
+			completed++
+			return nil
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to complete booking %s: %v", id, err)
+		}
+	}
+	return completed, nil
+}
+
+// ProcessPendingPayments settles bookings awaiting payment: they are
+// confirmed once the gateway reports the payment completed, and expire when
+// it failed or was not completed before the booking's hold ran out. It
+// returns the number of bookings settled either way.
+func (s *BookingService) ProcessPendingPayments(ctx context.Context) (int, error) {
+	due, err := s.dueBookings(ctx, `
+		SELECT id FROM bookings WHERE status = 'pending_payment'
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, id := range due {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			ok, err := s.settlePayment(ctx, tx, id)
+			if ok {
+				settled++
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to settle payment for booking %s: %v", id, err)
+		}
+	}
+	return settled, nil
+}
+
+func (s *BookingService) settlePayment(ctx context.Context, tx *sql.Tx, bookingID string) (bool, error) {
+	var status, busID, paymentID, transactionID string
+	var seats int
+	var expiresAt time.Time
+	err := tx.QueryRowContext(ctx, `
+		SELECT b.status, b.bus_id, (b.seats->>'count')::INT, b.expires_at, p.id, p.transaction_id
+		FROM bookings b
+		JOIN payments p ON p.booking_id = b.id
+		WHERE b.id = $1
+		FOR UPDATE OF b
+	`, bookingID).Scan(&status, &busID, &seats, &expiresAt, &paymentID, &transactionID)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	if status != lifecycle.StatusPendingPayment {
+		return false, nil
+	}
+
+	paymentStatus, err := s.paymentService.GetPaymentStatus(ctx, transactionID)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case paymentStatus == payments.PaymentStatusCompleted:
+		if err := lifecycle.Transition(ctx, tx, bookingID, status, lifecycle.StatusConfirmed, system, "payment completed"); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE payments SET payment_status = $1, updated_at = NOW() WHERE id = $2
+		`, payments.PaymentStatusCompleted, paymentID)
+		if err != nil {
+			return false, fmt.Errorf("failed to update payment status: %w", err)
+		}
+
+	case paymentStatus == payments.PaymentStatusFailed || time.Now().After(expiresAt):
+		reason, abandoned, cancel := "payment failed", payments.PaymentStatusFailed, false
+		if paymentStatus != payments.PaymentStatusFailed {
+			reason, abandoned, cancel = "payment not completed in time", payments.PaymentStatusExpired, true
+		}
+		if err := s.abandonPayment(ctx, tx, paymentID, abandoned, cancel, system); err != nil {
+			return false, err
+		}
+		if err := lifecycle.Transition(ctx, tx, bookingID, status, lifecycle.StatusExpired, system, reason); err != nil {
+			return false, err
+		}
+		// the hold on the seats, redeemed points and the promo code use are released
+		if err := s.loyalty.Reverse(ctx, tx, bookingID); err != nil {
+			return false, err
+		}
+		if err := s.promotions.Reverse(ctx, tx, bookingID); err != nil {
+			return false, err
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE buses
+			SET current_occupancy = GREATEST(current_occupancy - $1, 0),
+				updated_at = NOW()
+			WHERE id = $2
+		`, seats, busID)
+		if err != nil {
+			return false, fmt.Errorf("failed to update bus occupancy: %w", err)
+		}
+
+	default:
+		// still pending and within the hold
+		return false, nil
+	}
+	return true, nil
+}
+
+// abandonPayment calls off a payment the gateway has not completed and
+// marks it with status. cancel is false when the gateway already failed it.
+func (s *BookingService) abandonPayment(ctx context.Context, tx *sql.Tx, paymentID string, status payments.PaymentStatus, cancel bool, actor audit.Entry) error {
+	if cancel {
+		var transactionID string
+		err := tx.QueryRowContext(ctx, `SELECT transaction_id FROM payments WHERE id = $1`, paymentID).Scan(&transactionID)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return fmt.Errorf("database error: %w", err)
+		}
+		if err := s.paymentService.CancelPayment(ctx, transactionID); err != nil {
+			log.Printf("[ERROR] Failed to cancel payment: %v", err)
+			return fmt.Errorf("failed to cancel payment: %w", err)
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payments SET payment_status = $1, updated_at = NOW() WHERE id = $2
+	`, status, paymentID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+
+	actor.Action = "payment." + string(status)
+	actor.EntityType = "payment"
+	actor.EntityID = paymentID
+	actor.Before = map[string]interface{}{"payment_status": payments.PaymentStatusPending}
+	actor.After = map[string]interface{}{"payment_status": status}
+	return s.audit.Record(ctx, tx, actor)
+}
+
+// ProcessNoShows marks confirmed bookings whose departure has passed without
+// the rider boarding as no-shows, once the policy's grace period is over, and
+// refunds what the policy gives no-shows. Bookings on operators without a
+// policy are left alone. It returns the number of bookings marked.
+func (s *BookingService) ProcessNoShows(ctx context.Context) (int, error) {
+	due, err := s.dueBookings(ctx, `
+		SELECT id FROM bookings
+		WHERE status = 'confirmed'
+		AND departs_at < NOW() AND departs_at > NOW() - INTERVAL '1 day'
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, id := range due {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			ok, err := s.markNoShow(ctx, tx, id)
+			if ok {
+				marked++
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to process no-show for booking %s: %v", id, err)
+		}
+	}
+	return marked, nil
+}
+
+func (s *BookingService) markNoShow(ctx context.Context, tx *sql.Tx, bookingID string) (bool, error) {
+	// another worker may have got there first, or the rider boarded meanwhile
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM bookings WHERE id = $1 FOR UPDATE`, bookingID).Scan(&status)
+	if err != nil {
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	if status != lifecycle.StatusConfirmed {
+		return false, nil
+	}
+
+	now := time.Now()
+	plan, err := s.planCancellation(ctx, tx, bookingID, "", 0, nil, nil, "no_show", now)
+	if err != nil {
+		return false, err
+	}
+	if plan.departsAt == nil || !cancellation.NoShowDue(plan.policy, *plan.departsAt, now) {
+		return false, nil
+	}
+
+	actor := system
+	actor.OperatorID = plan.operatorID
+	if _, err := s.applyCancellation(ctx, tx, plan, actor, lifecycle.StatusNoShow); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// dueBookings returns the ids a background job has to work through
+func (s *BookingService) dueBookings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// inTx runs fn in a transaction of its own, committed when fn succeeds
+func (s *BookingService) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,322 @@
+// backend/internal/services/booking/passengers.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// GroupInvoiceMinSeats is the number of seats from which a booking can be
+// paid by invoice
+const GroupInvoiceMinSeats = 10
+
+// Passenger names who rides in one of a booking's seats
+type Passenger struct {
+	Name       string
+	SeatNumber string // optional
+	Child      bool
+	UserID     string // optional, a registered rider
+}
+
+// PassengerSeats checks a booking's named passengers against the seats
+// booked. Every seat has one passenger. Passengers either all have seat
+// numbers or none do; without them they take the booking's seat numbers in
+// order. The passengers are returned with their names trimmed and seats
+// filled in.
+func PassengerSeats(passengers []Passenger, count int, seatNumbers []string) ([]Passenger, error) {
+	if len(passengers) == 0 {
+		return nil, nil
+	}
+	invalid := func(message string) error {
+		return &errors.BookingError{
+			Code:    "INVALID_PASSENGERS",
+			Message: message,
+		}
+	}
+	if count != 0 && count != len(passengers) {
+		return nil, invalid(fmt.Sprintf("%d seats need %d passengers, one per seat", count, count))
+	}
+
+	result := make([]Passenger, len(passengers))
+	numbered := 0
+	users := map[string]bool{}
+	for i, p := range passengers {
+		p.Name = strings.TrimSpace(p.Name)
+		p.SeatNumber = strings.TrimSpace(p.SeatNumber)
+		if p.Name == "" {
+			return nil, invalid("Every passenger needs a name")
+		}
+		if len(p.Name) > 100 {
+			return nil, invalid("Passenger names can be at most 100 characters")
+		}
+		if p.SeatNumber != "" {
+			numbered++
+		}
+		if p.UserID != "" {
+			if users[p.UserID] {
+				return nil, invalid("A rider can only be a passenger once on a booking")
+			}
+			users[p.UserID] = true
+		}
+		result[i] = p
+	}
+
+	switch {
+	case numbered == 0 && len(seatNumbers) > 0:
+		if len(seatNumbers) != len(result) {
+			return nil, invalid("Each seat needs one passenger")
+		}
+		for i := range result {
+			result[i].SeatNumber = seatNumbers[i]
+		}
+	case numbered == 0:
+	case numbered < len(result):
+		return nil, invalid("Give every passenger a seat number, or none")
+	default:
+		seats := map[string]bool{}
+		for _, p := range result {
+			if seats[p.SeatNumber] {
+				return nil, invalid(fmt.Sprintf("Seat %s is given to more than one passenger", p.SeatNumber))
+			}
+			seats[p.SeatNumber] = true
+		}
+		if len(seatNumbers) > 0 {
+			if len(seatNumbers) != len(result) {
+				return nil, invalid("Each seat needs one passenger")
+			}
+			for _, n := range seatNumbers {
+				if !seats[n] {
+					return nil, invalid(fmt.Sprintf("No passenger is in seat %s", n))
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// addPassengers records who rides in the booking's seats
+func (s *BookingService) addPassengers(ctx context.Context, tx *sql.Tx, bookingID string, passengers []Passenger) ([]models.BookingPassenger, error) {
+	added := []models.BookingPassenger{}
+	for i, p := range passengers {
+		if p.UserID != "" {
+			if _, err := uuid.Parse(p.UserID); err != nil {
+				return nil, passengerNotFound()
+			}
+		}
+
+		passenger := models.BookingPassenger{
+			ID:        uuid.New().String(),
+			BookingID: bookingID,
+			Name:      p.Name,
+			Child:     p.Child,
+			Status:    "active",
+		}
+		if p.SeatNumber != "" {
+			seat := p.SeatNumber
+			passenger.SeatNumber = &seat
+		}
+		if p.UserID != "" {
+			user := p.UserID
+			passenger.UserID = &user
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO booking_passengers (id, booking_id, position, name, seat_number, is_child, user_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, passenger.ID, bookingID, i, p.Name, nullable(p.SeatNumber), p.Child, nullable(p.UserID))
+		if err != nil {
+			// foreign_key_violation, the rider does not exist
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return nil, passengerNotFound()
+			}
+			log.Printf("[ERROR] Failed to add booking passenger: %v", err)
+			return nil, fmt.Errorf("failed to add booking passenger: %w", err)
+		}
+		added = append(added, passenger)
+	}
+	return added, nil
+}
+
+// passengerSelection locks the booking's active passengers, or only those
+// in passengerIDs when any are given
+func passengerSelection(ctx context.Context, tx *sql.Tx, bookingID string, passengerIDs []string) ([]models.BookingPassenger, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, booking_id, name, seat_number, is_child, user_id, status, boarded_at, cancelled_at
+		FROM booking_passengers
+		WHERE booking_id = $1 AND status = 'active'
+		ORDER BY position
+		FOR UPDATE
+	`, bookingID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	passengers := []models.BookingPassenger{}
+	for rows.Next() {
+		var p models.BookingPassenger
+		if err := scanPassenger(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan booking passenger: %w", err)
+		}
+		passengers = append(passengers, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(passengerIDs) == 0 {
+		return passengers, nil
+	}
+
+	byID := map[string]models.BookingPassenger{}
+	for _, p := range passengers {
+		byID[p.ID] = p
+	}
+	selected := []models.BookingPassenger{}
+	seen := map[string]bool{}
+	for _, id := range passengerIDs {
+		p, ok := byID[id]
+		if !ok || seen[id] {
+			return nil, &errors.BookingError{
+				Code:    "INVALID_SEAT_SELECTION",
+				Message: fmt.Sprintf("Passenger %s is not on the booking", id),
+			}
+		}
+		seen[id] = true
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+// PassengerCancellation is the seat count or seat numbers that cancelling
+// the given passengers takes off their booking
+func PassengerCancellation(passengers []models.BookingPassenger) (int, []string) {
+	seatNumbers := []string{}
+	for _, p := range passengers {
+		if p.SeatNumber == nil {
+			return len(passengers), nil
+		}
+		seatNumbers = append(seatNumbers, *p.SeatNumber)
+	}
+	return 0, seatNumbers
+}
+
+// CancelledPassengers returns the ids of the passengers whose seats a
+// cancellation takes. passengers are the booking's active passengers, or the
+// ones asked for when whole is set; whole also covers cancelling every seat.
+// Otherwise the cancelled seats must be the passengers' numbered seats.
+func CancelledPassengers(passengers []models.BookingPassenger, cancelled models.SeatMap, whole bool) ([]string, error) {
+	if len(passengers) == 0 {
+		return nil, nil
+	}
+	ids := []string{}
+	if whole {
+		for _, p := range passengers {
+			ids = append(ids, p.ID)
+		}
+		return ids, nil
+	}
+
+	bySeat := map[string]string{}
+	for _, p := range passengers {
+		if p.SeatNumber != nil {
+			bySeat[*p.SeatNumber] = p.ID
+		}
+	}
+	for _, n := range cancelled.SeatNumbers {
+		id, ok := bySeat[n]
+		if !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != cancelled.Count {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: "Choose the passengers to cancel",
+		}
+	}
+	return ids, nil
+}
+
+// BookingPassengers returns the passengers named on one of the rider's bookings
+func (s *BookingService) BookingPassengers(ctx context.Context, bookingID, userID string) ([]models.BookingPassenger, error) {
+	if _, err := uuid.Parse(bookingID); err != nil {
+		return nil, errors.ErrBookingNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.booking_id, p.name, p.seat_number, p.is_child, p.user_id, p.status, p.boarded_at, p.cancelled_at
+		FROM booking_passengers p
+		JOIN bookings b ON b.id = p.booking_id
+		WHERE p.booking_id = $1 AND b.user_id = $2
+		ORDER BY p.position
+	`, bookingID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	passengers := []models.BookingPassenger{}
+	for rows.Next() {
+		var p models.BookingPassenger
+		if err := scanPassenger(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan booking passenger: %w", err)
+		}
+		passengers = append(passengers, p)
+	}
+	return passengers, rows.Err()
+}
+
+func scanPassenger(row scanner, p *models.BookingPassenger) error {
+	var seatNumber, userID sql.NullString
+	var boardedAt, cancelledAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.BookingID, &p.Name, &seatNumber, &p.Child, &userID, &p.Status, &boardedAt, &cancelledAt); err != nil {
+		return err
+	}
+	if seatNumber.Valid {
+		p.SeatNumber = &seatNumber.String
+	}
+	if userID.Valid {
+		p.UserID = &userID.String
+	}
+	if boardedAt.Valid {
+		p.BoardedAt = &boardedAt.Time
+	}
+	if cancelledAt.Valid {
+		p.CancelledAt = &cancelledAt.Time
+	}
+	return nil
+}
+
+// cancelPassengers takes cancelled passengers off a booking
+func cancelPassengers(ctx context.Context, tx *sql.Tx, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		UPDATE booking_passengers SET status = 'cancelled', cancelled_at = $1 WHERE id = ANY($2)
+	`, at, pq.Array(ids))
+	if err != nil {
+		log.Printf("[ERROR] Failed to cancel booking passengers: %v", err)
+		return fmt.Errorf("failed to cancel booking passengers: %w", err)
+	}
+	return nil
+}
+
+func passengerNotFound() error {
+	return &errors.BookingError{
+		Code:    "INVALID_PASSENGERS",
+		Message: "A passenger's rider account was not found",
+	}
+}
@@ -0,0 +1,109 @@
+// backend/internal/services/booking/refunds.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// maxRefundAttempts is how often a gateway refund is tried before it is
+	// left failed for someone to look at
+	maxRefundAttempts = 10
+	// refundRetryDelay is the wait after a first failed attempt, doubling
+	// with each one after
+	refundRetryDelay = time.Minute
+)
+
+// queueRefund records a refund owed through the payment gateway. It is sent
+// by sendRefunds once the cancellation commits, never from inside it.
+func queueRefund(ctx context.Context, tx *sql.Tx, bookingID, paymentID, transactionID string, amount float64) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO gateway_refunds (payment_id, booking_id, transaction_id, amount)
+		VALUES ($1, $2, $3, $4)
+	`, paymentID, nullable(bookingID), transactionID, amount)
+	if err != nil {
+		log.Printf("[ERROR] Failed to queue refund: %v", err)
+		return fmt.Errorf("failed to queue refund: %w", err)
+	}
+	return nil
+}
+
+// ProcessRefunds sends the gateway refunds that are due. Each is locked
+// while it is sent, so two workers never send the same one, and a failed
+// attempt is retried later with a growing delay. It returns the number sent.
+func (s *BookingService) ProcessRefunds(ctx context.Context) (int, error) {
+	due, err := s.dueBookings(ctx, `
+		SELECT id FROM gateway_refunds
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY created_at
+		LIMIT 100
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, id := range due {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			var transactionID string
+			var amount float64
+			var attempts int
+			err := tx.QueryRowContext(ctx, `
+				SELECT transaction_id, amount, attempts
+				FROM gateway_refunds
+				WHERE id = $1 AND status = 'pending'
+				FOR UPDATE SKIP LOCKED
+			`, id).Scan(&transactionID, &amount, &attempts)
+			if err == sql.ErrNoRows {
+				// sent by another worker meanwhile
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+
+			if refundErr := s.paymentService.RefundPayment(ctx, transactionID, amount); refundErr != nil {
+				log.Printf("[ERROR] Failed to send refund %s: %v", id, refundErr)
+				attempts++
+				status := "pending"
+				if attempts >= maxRefundAttempts {
+					status = "failed"
+				}
+				_, err := tx.ExecContext(ctx, `
+					UPDATE gateway_refunds
+					SET status = $1, attempts = $2, last_error = $3,
+						next_attempt_at = NOW() + make_interval(secs => $4), updated_at = NOW()
+					WHERE id = $5
+				`, status, attempts, refundErr.Error(), (refundRetryDelay << uint(attempts-1)).Seconds(), id)
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				UPDATE gateway_refunds
+				SET status = 'sent', attempts = attempts + 1, sent_at = NOW(), updated_at = NOW()
+				WHERE id = $1
+			`, id)
+			if err != nil {
+				return err
+			}
+			sent++
+			return nil
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to process refund %s: %v", id, err)
+		}
+	}
+	return sent, nil
+}
+
+// sendRefunds sends the refunds a committed cancellation queued, those that
+// fail are retried by ProcessRefunds
+func (s *BookingService) sendRefunds(ctx context.Context) {
+	if _, err := s.ProcessRefunds(ctx); err != nil {
+		log.Printf("[ERROR] Failed to send refunds: %v", err)
+	}
+}
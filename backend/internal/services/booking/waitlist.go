@@ -0,0 +1,561 @@
+// backend/internal/services/booking/waitlist.go
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// WaitlistOfferWindow is how long seats offered to a waitlisted rider are
+// held for them before the offer passes to the next rider
+const WaitlistOfferWindow = 10 * time.Minute
+
+type WaitlistRequest struct {
+	UserID            string
+	ScheduleID        string
+	BoardingStopName  string
+	AlightingStopName string
+	SeatCount         int
+}
+
+const waitlistColumns = `w.id, w.schedule_id, w.bus_id, w.user_id, w.seats, w.boarding_stop_name,
+	w.alighting_stop_name, w.status, s.departure_time, w.offered_at, w.offer_expires_at, w.booking_id, w.created_at,
+	CASE WHEN w.status = 'waiting' THEN (
+		SELECT COUNT(*) FROM waitlist_entries o
+		WHERE o.schedule_id = w.schedule_id AND o.status = 'waiting' AND o.created_at <= w.created_at
+	) ELSE 0 END`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWaitlistEntry(row scanner, e *models.WaitlistEntry) error {
+	var offeredAt, offerExpiresAt sql.NullTime
+	var bookingID sql.NullString
+	err := row.Scan(
+		&e.ID, &e.ScheduleID, &e.BusID, &e.UserID, &e.Seats, &e.BoardingStopName,
+		&e.AlightingStopName, &e.Status, &e.DepartureTime, &offeredAt, &offerExpiresAt, &bookingID, &e.CreatedAt,
+		&e.Position,
+	)
+	if err != nil {
+		return err
+	}
+	if offeredAt.Valid {
+		e.OfferedAt = &offeredAt.Time
+	}
+	if offerExpiresAt.Valid {
+		e.OfferExpiresAt = &offerExpiresAt.Time
+	}
+	if bookingID.Valid {
+		e.BookingID = &bookingID.String
+	}
+	return nil
+}
+
+// JoinWaitlist puts the rider on a full trip's waitlist. Riders are offered
+// freed seats in the order they joined.
+func (s *BookingService) JoinWaitlist(ctx context.Context, req WaitlistRequest) (*models.WaitlistEntry, error) {
+	if req.SeatCount < 1 {
+		return nil, errors.ErrInvalidSeatSelection
+	}
+	if _, err := uuid.Parse(req.ScheduleID); err != nil {
+		return nil, errors.ErrScheduleNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var busID, nextID string
+	var departure time.Time
+	var cancelledAt sql.NullTime
+	var capacity, occupancy int
+	err = tx.QueryRowContext(ctx, `
+		SELECT s.bus_id, s.departure_time, s.cancelled_at, b.capacity, b.current_occupancy,
+			COALESCE((
+				SELECT n.id::TEXT FROM schedules n
+				WHERE n.bus_id = s.bus_id AND n.departure_time > NOW() AND n.cancelled_at IS NULL
+				ORDER BY n.departure_time
+				LIMIT 1
+			), '')
+		FROM schedules s
+		JOIN buses b ON b.id = s.bus_id
+		WHERE s.id = $1
+	`, req.ScheduleID).Scan(&busID, &departure, &cancelledAt, &capacity, &occupancy, &nextID)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrScheduleNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if cancelledAt.Valid {
+		return nil, errors.ErrScheduleCancelled
+	}
+	if !departure.After(time.Now()) {
+		return nil, &errors.WaitlistError{
+			Code:    "TRIP_DEPARTED",
+			Message: "This trip has already departed",
+		}
+	}
+	if req.SeatCount > capacity {
+		return nil, &errors.BookingError{
+			Code:    "INVALID_SEAT_SELECTION",
+			Message: fmt.Sprintf("Cannot wait for more seats than bus capacity (%d)", capacity),
+		}
+	}
+	// bookings are taken for the bus's next trip, so that is the one whose
+	// seats can be checked
+	if nextID == req.ScheduleID && capacity-occupancy >= req.SeatCount {
+		return nil, errors.ErrWaitlistSeatsAvailable
+	}
+
+	// the stops are checked now so the offer can be booked as it is
+	trip, err := s.resolveTrip(ctx, tx, busID, req.BoardingStopName, req.AlightingStopName, req.SeatCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkBookable(ctx, tx, busID); err != nil {
+		return nil, err
+	}
+
+	entryID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO waitlist_entries (id, schedule_id, bus_id, user_id, seats, boarding_stop_name, alighting_stop_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entryID, req.ScheduleID, busID, req.UserID, req.SeatCount, trip.BoardingStopName, trip.AlightingStopName)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrAlreadyWaitlisted
+		}
+		log.Printf("[ERROR] Failed to join waitlist: %v", err)
+		return nil, fmt.Errorf("failed to join waitlist: %w", err)
+	}
+
+	entry, err := waitlistEntry(ctx, tx, entryID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User %s joined the waitlist for schedule %s at position %d", req.UserID, req.ScheduleID, entry.Position)
+	return entry, nil
+}
+
+// UserWaitlist returns the rider's waitlist entries, latest first
+func (s *BookingService) UserWaitlist(ctx context.Context, userID string) ([]models.WaitlistEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist_entries w
+		JOIN schedules s ON s.id = w.schedule_id
+		WHERE w.user_id = $1
+		ORDER BY w.created_at DESC
+		LIMIT 50
+	`, userID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		var e models.WaitlistEntry
+		if err := scanWaitlistEntry(rows, &e); err != nil {
+			log.Printf("[ERROR] Failed to scan waitlist entry: %v", err)
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// LeaveWaitlist takes the rider off a waitlist. Seats they were offered
+// pass to the next rider.
+func (s *BookingService) LeaveWaitlist(ctx context.Context, entryID, userID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entry, err := lockWaitlistEntry(ctx, tx, entryID, userID)
+	if err != nil {
+		return err
+	}
+	if entry.Status != "waiting" && entry.Status != "offered" {
+		return waitlistClosed(entry.Status)
+	}
+
+	offers, err := s.closeWaitlistEntry(ctx, tx, entry, "left")
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.notifyOffers(offers)
+	return nil
+}
+
+// AcceptWaitlistOffer books the seats the rider was offered while the offer
+// holds. The booking is made as CreateBooking would, with req giving how the
+// rider pays; the trip, stops and seats come from the waitlist entry.
+func (s *BookingService) AcceptWaitlistOffer(ctx context.Context, entryID string, req CreateBookingRequest) (*models.Booking, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := lockWaitlistEntry(ctx, tx, entryID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	switch entry.Status {
+	case "offered":
+		if entry.OfferExpiresAt == nil || !entry.OfferExpiresAt.After(time.Now()) {
+			return nil, errors.ErrWaitlistOfferExpired
+		}
+	case "waiting":
+		return nil, errors.ErrWaitlistNoOffer
+	case "expired":
+		return nil, errors.ErrWaitlistOfferExpired
+	default:
+		return nil, waitlistClosed(entry.Status)
+	}
+
+	req.BusID = entry.BusID
+	req.BoardingStopName = entry.BoardingStopName
+	req.AlightingStopName = entry.AlightingStopName
+	req.SeatNumbers = nil
+	req.SeatCount = entry.Seats
+	req.QuoteToken = ""
+	req.scheduleID = entry.ScheduleID
+	req.seatsHeld = true
+
+	booking, priced, err := s.createBooking(ctx, tx, req)
+	if err != nil {
+		s.releasePromo(ctx, priced)
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = 'booked', booking_id = $1, updated_at = NOW() WHERE id = $2
+	`, booking.ID, entry.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update waitlist entry: %v", err)
+		s.releasePromo(ctx, priced)
+		return nil, fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.releasePromo(ctx, priced)
+		return nil, err
+	}
+
+	log.Printf("[INFO] Waitlist entry %s booked as %s", entry.ID, booking.ID)
+	return booking, nil
+}
+
+// ProcessWaitlist passes lapsed offers on to the next rider, drops riders
+// whose trip left without them and offers seats that have freed up, e.g.
+// from bookings whose payment expired. It returns the number of offers made.
+func (s *BookingService) ProcessWaitlist(ctx context.Context) (int, error) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE waitlist_entries w
+		SET status = 'expired', updated_at = NOW()
+		FROM schedules s
+		WHERE s.id = w.schedule_id AND w.status = 'waiting' AND s.departure_time <= NOW()
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	lapsed, err := s.dueBookings(ctx, `
+		SELECT id FROM waitlist_entries WHERE status = 'offered' AND offer_expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	offered := 0
+	for _, id := range lapsed {
+		var offers []models.WaitlistEntry
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			entry, err := lockWaitlistEntry(ctx, tx, id, "")
+			if err != nil {
+				return err
+			}
+			// accepted or left in the meantime
+			if entry.Status != "offered" {
+				return nil
+			}
+			offers, err = s.closeWaitlistEntry(ctx, tx, entry, "expired")
+			return err
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to expire waitlist offer %s: %v", id, err)
+			continue
+		}
+		s.notifyOffers(offers)
+		offered += len(offers)
+	}
+
+	buses, err := s.dueBookings(ctx, `
+		SELECT DISTINCT bus_id FROM waitlist_entries WHERE status = 'waiting'
+	`)
+	if err != nil {
+		return offered, err
+	}
+	for _, busID := range buses {
+		var offers []models.WaitlistEntry
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			var err error
+			offers, err = s.offerSeats(ctx, tx, busID)
+			return err
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to offer waitlisted seats on bus %s: %v", busID, err)
+			continue
+		}
+		s.notifyOffers(offers)
+		offered += len(offers)
+	}
+	return offered, nil
+}
+
+// WaitlistOffers returns how many riders at the head of a waitlist, wanting
+// the given numbers of seats in the order they joined, can be offered seats
+// out of free. Nobody is passed over for a rider behind them who wants fewer.
+func WaitlistOffers(free int, wanted []int) int {
+	n := 0
+	for _, seats := range wanted {
+		if seats > free {
+			break
+		}
+		free -= seats
+		n++
+	}
+	return n
+}
+
+// offerSeats offers the bus's free seats to the riders waiting for its next
+// trip, holding them for WaitlistOfferWindow. The offers made are returned
+// for the caller to notify once the transaction commits.
+func (s *BookingService) offerSeats(ctx context.Context, tx *sql.Tx, busID string) ([]models.WaitlistEntry, error) {
+	var capacity, occupancy int
+	err := tx.QueryRowContext(ctx, `
+		SELECT capacity, current_occupancy FROM buses WHERE id = $1 FOR UPDATE
+	`, busID).Scan(&capacity, &occupancy)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if occupancy >= capacity {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist_entries w
+		JOIN schedules s ON s.id = w.schedule_id
+		WHERE w.status = 'waiting' AND w.schedule_id = (
+			SELECT id FROM schedules
+			WHERE bus_id = $1 AND departure_time > NOW() AND cancelled_at IS NULL
+			ORDER BY departure_time
+			LIMIT 1
+		)
+		ORDER BY w.created_at
+		FOR UPDATE OF w
+	`, busID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	var waiting []models.WaitlistEntry
+	var wanted []int
+	for rows.Next() {
+		var e models.WaitlistEntry
+		if err := scanWaitlistEntry(rows, &e); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan waitlist entry: %w", err)
+		}
+		waiting = append(waiting, e)
+		wanted = append(wanted, e.Seats)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	offers := waiting[:WaitlistOffers(capacity-occupancy, wanted)]
+	now := time.Now()
+	expires := now.Add(WaitlistOfferWindow)
+	seats := 0
+	for i := range offers {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE waitlist_entries
+			SET status = 'offered', offered_at = $1, offer_expires_at = $2, updated_at = NOW()
+			WHERE id = $3
+		`, now, expires, offers[i].ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to offer waitlisted seats: %v", err)
+			return nil, fmt.Errorf("failed to offer waitlisted seats: %w", err)
+		}
+		offers[i].Status = "offered"
+		offers[i].Position = 0
+		offers[i].OfferedAt = &now
+		offers[i].OfferExpiresAt = &expires
+		seats += offers[i].Seats
+	}
+	if seats > 0 {
+		// held for the riders offered them until they book or the offer lapses
+		if err := s.updateBusOccupancy(ctx, tx, busID, seats); err != nil {
+			return nil, err
+		}
+	}
+	return offers, nil
+}
+
+// closeWaitlistEntry takes a waiting or offered entry off the waitlist.
+// Seats held for an offer are released and offered to the next riders.
+func (s *BookingService) closeWaitlistEntry(ctx context.Context, tx *sql.Tx, entry *models.WaitlistEntry, status string) ([]models.WaitlistEntry, error) {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = $1, updated_at = NOW() WHERE id = $2
+	`, status, entry.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update waitlist entry: %v", err)
+		return nil, fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+	if entry.Status != "offered" {
+		return nil, nil
+	}
+
+	if err := s.releaseSeats(ctx, tx, entry.BusID, entry.Seats); err != nil {
+		return nil, err
+	}
+	return s.offerSeats(ctx, tx, entry.BusID)
+}
+
+// cancelWaitlist closes the waitlist of a cancelled trip, releasing seats
+// held for offers on it
+func (s *BookingService) cancelWaitlist(ctx context.Context, tx *sql.Tx, scheduleID, busID string) error {
+	var held int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(seats), 0) FROM waitlist_entries WHERE schedule_id = $1 AND status = 'offered'
+	`, scheduleID).Scan(&held)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE waitlist_entries SET status = 'cancelled', updated_at = NOW()
+		WHERE schedule_id = $1 AND status IN ('waiting', 'offered')
+	`, scheduleID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to close waitlist: %v", err)
+		return fmt.Errorf("failed to close waitlist: %w", err)
+	}
+	if held == 0 {
+		return nil
+	}
+	return s.releaseSeats(ctx, tx, busID, held)
+}
+
+func (s *BookingService) releaseSeats(ctx context.Context, tx *sql.Tx, busID string, seats int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE buses
+		SET current_occupancy = GREATEST(current_occupancy - $1, 0),
+			updated_at = NOW()
+		WHERE id = $2
+	`, seats, busID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update bus occupancy: %v", err)
+		return fmt.Errorf("failed to update bus occupancy: %w", err)
+	}
+	return nil
+}
+
+// notifyOffers tells riders about the seats they were offered
+func (s *BookingService) notifyOffers(offers []models.WaitlistEntry) {
+	if s.notifier == nil {
+		return
+	}
+	for _, o := range offers {
+		message := fmt.Sprintf(
+			"%d seat(s) have freed up on your %s trip from %s. They are held for you until %s, confirm your booking before then.",
+			o.Seats, o.DepartureTime.Format("2 Jan 2006 15:04"), o.BoardingStopName, o.OfferExpiresAt.Format("15:04"),
+		)
+		if err := s.notifier.Send(o.UserID, models.NotificationBooking, message); err != nil {
+			log.Printf("[ERROR] Failed to send waitlist offer notification: %v", err)
+		}
+	}
+}
+
+// waitlistEntry loads one of the rider's waitlist entries
+func waitlistEntry(ctx context.Context, tx *sql.Tx, entryID, userID string) (*models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := scanWaitlistEntry(tx.QueryRowContext(ctx, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist_entries w
+		JOIN schedules s ON s.id = w.schedule_id
+		WHERE w.id = $1 AND w.user_id = $2
+	`, entryID, userID), &e)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrWaitlistEntryNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &e, nil
+}
+
+// lockWaitlistEntry locks a waitlist entry, limited to the rider's own when
+// userID is given
+func lockWaitlistEntry(ctx context.Context, tx *sql.Tx, entryID, userID string) (*models.WaitlistEntry, error) {
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, errors.ErrWaitlistEntryNotFound
+	}
+
+	var e models.WaitlistEntry
+	err := scanWaitlistEntry(tx.QueryRowContext(ctx, `
+		SELECT `+waitlistColumns+`
+		FROM waitlist_entries w
+		JOIN schedules s ON s.id = w.schedule_id
+		WHERE w.id = $1 AND ($2 = '' OR w.user_id::TEXT = $2)
+		FOR UPDATE OF w
+	`, entryID, userID), &e)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrWaitlistEntryNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &e, nil
+}
+
+func waitlistClosed(status string) error {
+	return &errors.WaitlistError{
+		Code:    "WAITLIST_CLOSED",
+		Message: fmt.Sprintf("This waitlist entry is already %s", status),
+	}
+}
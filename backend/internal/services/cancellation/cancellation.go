@@ -0,0 +1,97 @@
+// backend/internal/services/cancellation/cancellation.go
+package cancellation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// Service keeps operators' cancellation policies and works out refunds
+type Service struct {
+	db *sql.DB
+}
+
+func NewCancellationService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// PolicyFor returns the active policy for the route, the operator's policy for
+// all its routes when the route has none, or nil when the operator has none
+func (s *Service) PolicyFor(ctx context.Context, tx *sql.Tx, operatorID, routeID string) (*models.CancellationPolicy, error) {
+	var p models.CancellationPolicy
+	err := scanPolicy(tx.QueryRowContext(ctx, `
+		SELECT `+policyColumns+`
+		FROM cancellation_policies p
+		WHERE p.active = TRUE AND p.operator_id = $1
+		AND (p.route_id IS NULL OR p.route_id = $2)
+		ORDER BY p.route_id IS NULL
+		LIMIT 1
+	`, operatorID, routeID), &p)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.Rules, err = policyRules(ctx, tx, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RefundPercent is the share of the fare a rider cancelling at the given time
+// gets back. Without a policy everything is refunded. Without a known
+// departure the rule for the earliest cancellations applies. Otherwise the
+// rule with the latest cutoff that has not passed applies, and nothing is
+// refunded once every cutoff has passed.
+func RefundPercent(policy *models.CancellationPolicy, departsAt *time.Time, at time.Time) float64 {
+	if policy == nil {
+		return 100
+	}
+
+	var best *models.CancellationRule
+	for i := range policy.Rules {
+		r := &policy.Rules[i]
+		if departsAt == nil {
+			if best == nil || r.MinMinutesBefore > best.MinMinutesBefore {
+				best = r
+			}
+			continue
+		}
+		minutesBefore := int(math.Floor(departsAt.Sub(at).Minutes()))
+		if minutesBefore >= r.MinMinutesBefore && (best == nil || r.MinMinutesBefore > best.MinMinutesBefore) {
+			best = r
+		}
+	}
+
+	if best == nil {
+		return 0
+	}
+	return best.RefundPercent
+}
+
+// NoShowDue reports whether a rider who has not boarded counts as a no-show
+// under the policy. Operators without a policy do not have no-show rules.
+func NoShowDue(policy *models.CancellationPolicy, departsAt time.Time, at time.Time) bool {
+	if policy == nil {
+		return false
+	}
+	return !at.Before(departsAt.Add(time.Duration(policy.NoShowGraceMinutes) * time.Minute))
+}
+
+// Refund applies a refund percentage to an amount, in cents
+func Refund(amount, percent float64) float64 {
+	return math.Round(amount*percent) / 100
+}
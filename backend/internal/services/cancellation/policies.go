@@ -0,0 +1,289 @@
+// backend/internal/services/cancellation/policies.go
+package cancellation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// PolicyInput describes a cancellation policy to create or replace
+type PolicyInput struct {
+	OperatorID          string
+	RouteID             string // empty for all the operator's routes
+	Name                string
+	Rules               []models.CancellationRule
+	NoShowRefundPercent float64
+	NoShowGraceMinutes  int
+	Active              bool
+}
+
+const policyColumns = `p.id, p.operator_id, p.route_id, p.name, p.no_show_refund_percent,
+	p.no_show_grace_minutes, p.active, p.created_at, p.updated_at`
+
+func scanPolicy(row scanner, p *models.CancellationPolicy) error {
+	return row.Scan(
+		&p.ID, &p.OperatorID, &p.RouteID, &p.Name, &p.NoShowRefundPercent,
+		&p.NoShowGraceMinutes, &p.Active, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func policyRules(ctx context.Context, q queryer, policyID string) ([]models.CancellationRule, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT min_minutes_before, refund_percent
+		FROM cancellation_policy_rules
+		WHERE policy_id = $1
+		ORDER BY min_minutes_before DESC
+	`, policyID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.CancellationRule{}
+	for rows.Next() {
+		var r models.CancellationRule
+		if err := rows.Scan(&r.MinMinutesBefore, &r.RefundPercent); err != nil {
+			return nil, fmt.Errorf("failed to scan cancellation rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// ListPolicies returns the operator's cancellation policies, including
+// inactive ones
+func (s *Service) ListPolicies(ctx context.Context, operatorID string) ([]models.CancellationPolicy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+policyColumns+`
+		FROM cancellation_policies p
+		WHERE p.operator_id = $1
+		ORDER BY p.active DESC, p.created_at DESC
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.CancellationPolicy{}
+	for rows.Next() {
+		var p models.CancellationPolicy
+		if err := scanPolicy(rows, &p); err != nil {
+			log.Printf("[ERROR] Failed to scan cancellation policy: %v", err)
+			return nil, fmt.Errorf("failed to scan cancellation policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range policies {
+		if policies[i].Rules, err = policyRules(ctx, s.db, policies[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return policies, nil
+}
+
+// CreatePolicy adds a policy for one of the operator's routes, or for all of
+// them. A route or the operator as a whole has one active policy at a time.
+func (s *Service) CreatePolicy(ctx context.Context, in PolicyInput) (*models.CancellationPolicy, error) {
+	in.Active = true
+	if err := s.validatePolicy(ctx, &in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var routeID interface{}
+	if in.RouteID != "" {
+		routeID = in.RouteID
+	}
+
+	policyID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO cancellation_policies (id, operator_id, route_id, name, no_show_refund_percent, no_show_grace_minutes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, policyID, in.OperatorID, routeID, in.Name, in.NoShowRefundPercent, in.NoShowGraceMinutes)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrCancellationPolicyExists
+		}
+		log.Printf("[ERROR] Failed to create cancellation policy: %v", err)
+		return nil, fmt.Errorf("failed to create cancellation policy: %w", err)
+	}
+
+	if err := saveRules(ctx, tx, policyID, in.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Created cancellation policy %s (%s)", policyID, in.Name)
+	return s.managedPolicy(ctx, policyID, in.OperatorID)
+}
+
+// UpdatePolicy replaces a policy's rules. Cancellations already made keep
+// the refund they got.
+func (s *Service) UpdatePolicy(ctx context.Context, policyID string, in PolicyInput) (*models.CancellationPolicy, error) {
+	existing, err := s.managedPolicy(ctx, policyID, in.OperatorID)
+	if err != nil {
+		return nil, err
+	}
+	// the scope of a policy does not change
+	in.RouteID = ""
+	if existing.RouteID != nil {
+		in.RouteID = *existing.RouteID
+	}
+	if err := s.validatePolicy(ctx, &in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE cancellation_policies
+		SET name = $1, no_show_refund_percent = $2, no_show_grace_minutes = $3, active = $4, updated_at = NOW()
+		WHERE id = $5
+	`, in.Name, in.NoShowRefundPercent, in.NoShowGraceMinutes, in.Active, policyID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrCancellationPolicyExists
+		}
+		log.Printf("[ERROR] Failed to update cancellation policy: %v", err)
+		return nil, fmt.Errorf("failed to update cancellation policy: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cancellation_policy_rules WHERE policy_id = $1`, policyID); err != nil {
+		log.Printf("[ERROR] Failed to update cancellation policy: %v", err)
+		return nil, fmt.Errorf("failed to update cancellation policy: %w", err)
+	}
+	if err := saveRules(ctx, tx, policyID, in.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.managedPolicy(ctx, policyID, in.OperatorID)
+}
+
+func saveRules(ctx context.Context, tx *sql.Tx, policyID string, rules []models.CancellationRule) error {
+	for _, r := range rules {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO cancellation_policy_rules (policy_id, min_minutes_before, refund_percent)
+			VALUES ($1, $2, $3)
+		`, policyID, r.MinMinutesBefore, r.RefundPercent)
+		if err != nil {
+			log.Printf("[ERROR] Failed to save cancellation rule: %v", err)
+			return fmt.Errorf("failed to save cancellation rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// managedPolicy loads one of the operator's policies
+func (s *Service) managedPolicy(ctx context.Context, policyID, operatorID string) (*models.CancellationPolicy, error) {
+	if _, err := uuid.Parse(policyID); err != nil {
+		return nil, errors.ErrCancellationPolicyNotFound
+	}
+
+	var p models.CancellationPolicy
+	err := scanPolicy(s.db.QueryRowContext(ctx, `
+		SELECT `+policyColumns+`
+		FROM cancellation_policies p
+		WHERE p.id = $1 AND p.operator_id = $2
+	`, policyID, operatorID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrCancellationPolicyNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.Rules, err = policyRules(ctx, s.db, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *Service) validatePolicy(ctx context.Context, in *PolicyInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return invalidPolicy("name is required")
+	}
+	if len(in.Rules) == 0 {
+		return invalidPolicy("a policy needs at least one rule")
+	}
+	if in.NoShowRefundPercent < 0 || in.NoShowRefundPercent > 100 {
+		return invalidPolicy("no_show_refund_percent must be between 0 and 100")
+	}
+	if in.NoShowGraceMinutes < 0 {
+		return invalidPolicy("no_show_grace_minutes cannot be negative")
+	}
+
+	seen := map[int]bool{}
+	for _, r := range in.Rules {
+		if r.RefundPercent < 0 || r.RefundPercent > 100 {
+			return invalidPolicy("refund_percent must be between 0 and 100")
+		}
+		if seen[r.MinMinutesBefore] {
+			return invalidPolicy(fmt.Sprintf("more than one rule for %d minutes before departure", r.MinMinutesBefore))
+		}
+		seen[r.MinMinutesBefore] = true
+	}
+	sort.Slice(in.Rules, func(i, j int) bool {
+		return in.Rules[i].MinMinutesBefore > in.Rules[j].MinMinutesBefore
+	})
+
+	if in.RouteID != "" {
+		if _, err := uuid.Parse(in.RouteID); err != nil {
+			return invalidPolicy("route not found")
+		}
+		var routeOperator sql.NullString
+		err := s.db.QueryRowContext(ctx, `SELECT operator_id FROM bus_routes WHERE id = $1`, in.RouteID).Scan(&routeOperator)
+		if err == sql.ErrNoRows || (err == nil && routeOperator.String != in.OperatorID) {
+			return invalidPolicy("route not found")
+		}
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return fmt.Errorf("database error: %w", err)
+		}
+	}
+	return nil
+}
+
+func invalidPolicy(message string) error {
+	return &errors.CancellationError{
+		Code:    "INVALID_POLICY",
+		Message: message,
+	}
+}
@@ -0,0 +1,186 @@
+// backend/internal/services/fares/fares.go
+package fares
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// QuoteLockPeriod is how long a quoted fare is honoured by bookings
+const QuoteLockPeriod = 5 * time.Minute
+
+// Trip is what a fare is priced for
+type Trip struct {
+	BusID             string
+	RouteID           string
+	OperatorID        string
+	BoardingStopID    string // empty for a route's origin or destination without a stop
+	AlightingStopID   string
+	BoardingStopName  string
+	AlightingStopName string
+	Seats             int
+	At                time.Time
+}
+
+// Fare is worked out by the engine's rules in turn
+type Fare struct {
+	PerSeat     float64
+	Description string
+	Multiplier  *models.FareMultiplier // nil when no time of day multiplier applies
+}
+
+// Queryer is satisfied by *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Rule adjusts a fare, e.g. sets it from a fare table or applies a peak
+// multiplier. Rules run in the order they are given to the engine.
+type Rule interface {
+	Apply(ctx context.Context, q Queryer, trip Trip, fare *Fare) error
+}
+
+// Engine prices trips by running its rules
+type Engine struct {
+	rules []Rule
+}
+
+func NewFareEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// DefaultRules price from the route's base fare, then the stop-to-stop fare
+// table, then time of day multipliers, and finally operator overrides
+func DefaultRules() []Rule {
+	return []Rule{BaseFareRule{}, StopFareRule{}, MultiplierRule{}, OverrideRule{}}
+}
+
+// Price returns the fare's line items for the trip
+func (e *Engine) Price(ctx context.Context, q Queryer, trip Trip) ([]models.BookingLineItem, error) {
+	fare := &Fare{}
+	for _, rule := range e.rules {
+		if err := rule.Apply(ctx, q, trip, fare); err != nil {
+			return nil, err
+		}
+	}
+
+	if fare.PerSeat <= 0 {
+		log.Printf("[ERROR] Invalid fare for bus %s: %f", trip.BusID, fare.PerSeat)
+		return nil, &errors.BookingError{
+			Code:    "INVALID_FARE",
+			Message: "The fare for this route is not properly configured",
+		}
+	}
+
+	return LineItems(*fare, trip.Seats), nil
+}
+
+// LineItems turns a worked out fare into the booking's fare line items
+func LineItems(fare Fare, seats int) []models.BookingLineItem {
+	base := roundCents(fare.PerSeat * float64(seats))
+	items := []models.BookingLineItem{{
+		Type:        "base_fare",
+		Description: fmt.Sprintf("%s x %d", fare.Description, seats),
+		Amount:      base,
+	}}
+
+	if m := fare.Multiplier; m != nil && m.Multiplier != 1 {
+		items = append(items, models.BookingLineItem{
+			Type:        "fare_multiplier",
+			Description: fmt.Sprintf("%s (x%g)", m.Name, m.Multiplier),
+			Amount:      roundCents(base * (m.Multiplier - 1)),
+		})
+	}
+	return items
+}
+
+// Service prices trips and signs the quotes riders are shown
+type Service struct {
+	db     *sql.DB
+	engine *Engine
+	quotes *QuoteSigner
+}
+
+func NewFareService(db *sql.DB) *Service {
+	secret := os.Getenv("QUOTE_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+
+	return &Service{
+		db:     db,
+		engine: NewFareEngine(DefaultRules()...),
+		quotes: NewQuoteSigner(secret),
+	}
+}
+
+// Price returns the current fare for the trip
+func (s *Service) Price(ctx context.Context, q Queryer, trip Trip) ([]models.BookingLineItem, error) {
+	return s.engine.Price(ctx, q, trip)
+}
+
+// Quote prices the trip and signs the price into a token, so a booking made
+// within the lock period pays it even if fares change in between
+func (s *Service) Quote(ctx context.Context, q Queryer, trip Trip) (*models.FareQuote, error) {
+	items, err := s.engine.Price(ctx, q, trip)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &models.FareQuote{
+		ID:                uuid.New().String(),
+		BusID:             trip.BusID,
+		RouteID:           trip.RouteID,
+		BoardingStopName:  trip.BoardingStopName,
+		AlightingStopName: trip.AlightingStopName,
+		Seats:             trip.Seats,
+		LineItems:         items,
+		Total:             total(items),
+		ExpiresAt:         time.Now().Add(QuoteLockPeriod).Truncate(time.Second),
+	}
+	if quote.Token, err = s.quotes.Sign(quote); err != nil {
+		log.Printf("[ERROR] Failed to sign fare quote: %v", err)
+		return nil, err
+	}
+	return quote, nil
+}
+
+// LockedQuote returns the line items of a quote token that is still locked
+// and was made for the trip
+func (s *Service) LockedQuote(token string, trip Trip) ([]models.BookingLineItem, error) {
+	quote, err := s.quotes.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if quote.BusID != trip.BusID || quote.RouteID != trip.RouteID || quote.Seats != trip.Seats ||
+		!strings.EqualFold(quote.BoardingStopName, trip.BoardingStopName) ||
+		!strings.EqualFold(quote.AlightingStopName, trip.AlightingStopName) {
+		return nil, errors.ErrQuoteMismatch
+	}
+	return quote.LineItems, nil
+}
+
+func total(items []models.BookingLineItem) float64 {
+	var sum float64
+	for _, item := range items {
+		sum += item.Amount
+	}
+	return roundCents(sum)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,380 @@
+// backend/internal/services/fares/manage.go
+package fares
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// MultiplierInput describes a time of day multiplier to add
+type MultiplierInput struct {
+	OperatorID string // empty for platform multipliers
+	RouteID    string // optional, operator multipliers only
+	Name       string
+	Multiplier float64
+	DaysOfWeek []int
+	StartTime  string // HH:MM, empty for all day
+	EndTime    string
+	StartsAt   *time.Time
+	EndsAt     *time.Time
+}
+
+// OverrideInput describes an operator's fixed fare
+type OverrideInput struct {
+	OperatorID string
+	CreatedBy  string
+	RouteID    string
+	FromStopID string // both empty for the whole route
+	ToStopID   string
+	Fare       float64
+	Reason     string
+	StartsAt   *time.Time
+	EndsAt     *time.Time
+}
+
+// StopFares returns the fare table of one of the operator's routes
+func (s *Service) StopFares(ctx context.Context, routeID, operatorID string) ([]models.StopFare, error) {
+	if err := s.ownedRoute(ctx, s.db, routeID, operatorID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT from_stop_id, to_stop_id, fare
+		FROM route_stop_fares
+		WHERE route_id = $1
+		ORDER BY from_stop_id, to_stop_id
+	`, routeID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	fares := []models.StopFare{}
+	for rows.Next() {
+		var f models.StopFare
+		if err := rows.Scan(&f.FromStopID, &f.ToStopID, &f.Fare); err != nil {
+			return nil, fmt.Errorf("failed to scan stop fare: %w", err)
+		}
+		fares = append(fares, f)
+	}
+	return fares, rows.Err()
+}
+
+// ReplaceStopFares sets the route's fare table. Every stop must be on the route.
+func (s *Service) ReplaceStopFares(ctx context.Context, routeID, operatorID string, fares []models.StopFare) ([]models.StopFare, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.ownedRoute(ctx, tx, routeID, operatorID); err != nil {
+		return nil, err
+	}
+
+	onRoute := map[string]bool{}
+	rows, err := tx.QueryContext(ctx, `SELECT bus_stop_id FROM route_bus_stops WHERE route_id = $1`, routeID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	for rows.Next() {
+		var stopID string
+		if err := rows.Scan(&stopID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan route stop: %w", err)
+		}
+		onRoute[stopID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, f := range fares {
+		if !onRoute[f.FromStopID] || !onRoute[f.ToStopID] {
+			return nil, invalidFareRule("every stop in the fare table must be on the route")
+		}
+		if f.FromStopID == f.ToStopID {
+			return nil, invalidFareRule("a fare needs two different stops")
+		}
+		if f.Fare <= 0 {
+			return nil, invalidFareRule("fares must be positive")
+		}
+		key := f.FromStopID + "/" + f.ToStopID
+		if seen[key] {
+			return nil, invalidFareRule("each stop pair can only be priced once per direction")
+		}
+		seen[key] = true
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM route_stop_fares WHERE route_id = $1`, routeID); err != nil {
+		log.Printf("[ERROR] Failed to replace fare table: %v", err)
+		return nil, fmt.Errorf("failed to replace fare table: %w", err)
+	}
+	for _, f := range fares {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO route_stop_fares (route_id, from_stop_id, to_stop_id, fare) VALUES ($1, $2, $3, $4)
+		`, routeID, f.FromStopID, f.ToStopID, f.Fare)
+		if err != nil {
+			log.Printf("[ERROR] Failed to replace fare table: %v", err)
+			return nil, fmt.Errorf("failed to replace fare table: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.StopFares(ctx, routeID, operatorID)
+}
+
+// ListMultipliers returns an operator's multipliers, or the platform's when
+// operatorID is empty
+func (s *Service) ListMultipliers(ctx context.Context, operatorID string) ([]models.FareMultiplier, error) {
+	return listMultipliers(ctx, s.db, `
+		WHERE COALESCE(m.operator_id::TEXT, '') = $1
+		ORDER BY m.active DESC, m.created_at DESC
+	`, operatorID)
+}
+
+// CreateMultiplier adds a time of day multiplier
+func (s *Service) CreateMultiplier(ctx context.Context, in MultiplierInput) (*models.FareMultiplier, error) {
+	if err := validateMultiplier(&in); err != nil {
+		return nil, err
+	}
+	if in.RouteID != "" {
+		if err := s.ownedRoute(ctx, s.db, in.RouteID, in.OperatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	var days pq.Int64Array
+	for _, d := range in.DaysOfWeek {
+		days = append(days, int64(d))
+	}
+
+	id := uuid.New().String()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO fare_multipliers (
+			id, operator_id, route_id, name, multiplier, days_of_week, start_time, end_time, starts_at, ends_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7::TIME, $8::TIME, $9, $10)
+	`, id, nullable(in.OperatorID), nullable(in.RouteID), in.Name, in.Multiplier, days,
+		nullable(in.StartTime), nullable(in.EndTime), in.StartsAt, in.EndsAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create fare multiplier: %v", err)
+		return nil, fmt.Errorf("failed to create fare multiplier: %w", err)
+	}
+
+	return s.managedMultiplier(ctx, id, in.OperatorID)
+}
+
+// DeactivateMultiplier stops a multiplier from applying
+func (s *Service) DeactivateMultiplier(ctx context.Context, multiplierID, operatorID string) (*models.FareMultiplier, error) {
+	if _, err := s.managedMultiplier(ctx, multiplierID, operatorID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE fare_multipliers SET active = FALSE WHERE id = $1`, multiplierID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update fare multiplier: %v", err)
+		return nil, fmt.Errorf("failed to update fare multiplier: %w", err)
+	}
+	return s.managedMultiplier(ctx, multiplierID, operatorID)
+}
+
+func (s *Service) managedMultiplier(ctx context.Context, multiplierID, operatorID string) (*models.FareMultiplier, error) {
+	if _, err := uuid.Parse(multiplierID); err != nil {
+		return nil, errors.ErrFareMultiplierNotFound
+	}
+
+	multipliers, err := listMultipliers(ctx, s.db, `
+		WHERE m.id = $1 AND COALESCE(m.operator_id::TEXT, '') = $2
+	`, multiplierID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(multipliers) == 0 {
+		return nil, errors.ErrFareMultiplierNotFound
+	}
+	return &multipliers[0], nil
+}
+
+// ListOverrides returns the operator's fare overrides that have not ended
+func (s *Service) ListOverrides(ctx context.Context, operatorID string) ([]models.FareOverride, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, operator_id, route_id, from_stop_id, to_stop_id, fare, reason, starts_at, ends_at, created_at
+		FROM fare_overrides
+		WHERE operator_id = $1 AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY starts_at
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.FareOverride{}
+	for rows.Next() {
+		var o models.FareOverride
+		err := rows.Scan(&o.ID, &o.OperatorID, &o.RouteID, &o.FromStopID, &o.ToStopID, &o.Fare,
+			&o.Reason, &o.StartsAt, &o.EndsAt, &o.CreatedAt)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scan fare override: %v", err)
+			return nil, fmt.Errorf("failed to scan fare override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// CreateOverride sets a fixed fare on one of the operator's routes, or on a
+// stop pair of it, for a period
+func (s *Service) CreateOverride(ctx context.Context, in OverrideInput) (*models.FareOverride, error) {
+	if in.Fare <= 0 {
+		return nil, invalidFareRule("fare must be positive")
+	}
+	if (in.FromStopID == "") != (in.ToStopID == "") {
+		return nil, invalidFareRule("set both from_stop_id and to_stop_id, or neither")
+	}
+	startsAt := time.Now()
+	if in.StartsAt != nil {
+		startsAt = *in.StartsAt
+	}
+	if in.EndsAt != nil && !in.EndsAt.After(startsAt) {
+		return nil, invalidFareRule("ends_at must be after starts_at")
+	}
+	if err := s.ownedRoute(ctx, s.db, in.RouteID, in.OperatorID); err != nil {
+		return nil, err
+	}
+
+	if in.FromStopID != "" {
+		var onRoute int
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM route_bus_stops
+			WHERE route_id = $1 AND bus_stop_id::TEXT IN ($2, $3)
+		`, in.RouteID, in.FromStopID, in.ToStopID).Scan(&onRoute)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if onRoute != 2 {
+			return nil, invalidFareRule("both stops must be on the route")
+		}
+	}
+
+	var o models.FareOverride
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO fare_overrides (operator_id, route_id, from_stop_id, to_stop_id, fare, reason, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, operator_id, route_id, from_stop_id, to_stop_id, fare, reason, starts_at, ends_at, created_at
+	`, in.OperatorID, in.RouteID, nullable(in.FromStopID), nullable(in.ToStopID), in.Fare,
+		nullable(strings.TrimSpace(in.Reason)), startsAt, in.EndsAt, nullable(in.CreatedBy)).Scan(
+		&o.ID, &o.OperatorID, &o.RouteID, &o.FromStopID, &o.ToStopID, &o.Fare, &o.Reason, &o.StartsAt, &o.EndsAt, &o.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create fare override: %v", err)
+		return nil, fmt.Errorf("failed to create fare override: %w", err)
+	}
+	return &o, nil
+}
+
+// DeleteOverride removes one of the operator's fare overrides
+func (s *Service) DeleteOverride(ctx context.Context, overrideID, operatorID string) error {
+	if _, err := uuid.Parse(overrideID); err != nil {
+		return errors.ErrFareOverrideNotFound
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM fare_overrides WHERE id = $1 AND operator_id = $2`, overrideID, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete fare override: %v", err)
+		return fmt.Errorf("failed to delete fare override: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.ErrFareOverrideNotFound
+	}
+	return nil
+}
+
+// ownedRoute checks the route belongs to the operator
+func (s *Service) ownedRoute(ctx context.Context, q Queryer, routeID, operatorID string) error {
+	if _, err := uuid.Parse(routeID); err != nil {
+		return errors.ErrFareRouteNotFound
+	}
+
+	var owner sql.NullString
+	err := q.QueryRowContext(ctx, `SELECT operator_id FROM bus_routes WHERE id = $1`, routeID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner.String != operatorID) {
+		return errors.ErrFareRouteNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
+
+func validateMultiplier(in *MultiplierInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return invalidFareRule("name is required")
+	}
+	if in.Multiplier <= 0 || in.Multiplier > 5 {
+		return invalidFareRule("multiplier must be above 0 and at most 5")
+	}
+	if in.OperatorID == "" && in.RouteID != "" {
+		return invalidFareRule("route_id only applies to operator multipliers")
+	}
+	for _, d := range in.DaysOfWeek {
+		if d < 0 || d > 6 {
+			return invalidFareRule("days_of_week are 0 (Sunday) to 6 (Saturday)")
+		}
+	}
+
+	if (in.StartTime == "") != (in.EndTime == "") {
+		return invalidFareRule("set both start_time and end_time, or neither")
+	}
+	if in.StartTime != "" {
+		start, err := ClockMinutes(in.StartTime)
+		if err != nil {
+			return invalidFareRule("start_time must be HH:MM")
+		}
+		end, err := ClockMinutes(in.EndTime)
+		if err != nil {
+			return invalidFareRule("end_time must be HH:MM")
+		}
+		if start == end {
+			return invalidFareRule("start_time and end_time cannot be the same")
+		}
+	}
+
+	if in.StartsAt != nil && in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+		return invalidFareRule("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+func invalidFareRule(message string) error {
+	return &errors.FareError{
+		Code:    "INVALID_FARE_RULE",
+		Message: message,
+	}
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
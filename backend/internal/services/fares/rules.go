@@ -0,0 +1,240 @@
+// backend/internal/services/fares/rules.go
+package fares
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// EAT is the time zone multiplier schedules are written in. Kenya does not
+// observe daylight saving.
+var EAT = time.FixedZone("EAT", 3*60*60)
+
+// BaseFareRule starts from the route's flat base fare
+type BaseFareRule struct{}
+
+func (BaseFareRule) Apply(ctx context.Context, q Queryer, trip Trip, fare *Fare) error {
+	err := q.QueryRowContext(ctx, `SELECT base_fare FROM bus_routes WHERE id = $1`, trip.RouteID).Scan(&fare.PerSeat)
+	if err == sql.ErrNoRows {
+		return &errors.BookingError{
+			Code:    "ROUTE_NOT_FOUND",
+			Message: "Could not find fare information for this bus",
+		}
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	fare.Description = "Base fare"
+	return nil
+}
+
+// StopFareRule prices the trip from the route's stop-to-stop fare table when
+// it has the stop pair, in either direction unless the reverse has its own fare
+type StopFareRule struct{}
+
+func (StopFareRule) Apply(ctx context.Context, q Queryer, trip Trip, fare *Fare) error {
+	if trip.BoardingStopID == "" || trip.AlightingStopID == "" {
+		return nil
+	}
+
+	var perSeat float64
+	err := q.QueryRowContext(ctx, `
+		SELECT fare
+		FROM route_stop_fares
+		WHERE route_id = $1
+		AND ((from_stop_id = $2 AND to_stop_id = $3) OR (from_stop_id = $3 AND to_stop_id = $2))
+		ORDER BY from_stop_id = $2 DESC
+		LIMIT 1
+	`, trip.RouteID, trip.BoardingStopID, trip.AlightingStopID).Scan(&perSeat)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	fare.PerSeat = perSeat
+	fare.Description = fmt.Sprintf("Fare %s to %s", trip.BoardingStopName, trip.AlightingStopName)
+	return nil
+}
+
+// MultiplierRule applies the time of day multiplier in force when the trip
+// is priced
+type MultiplierRule struct{}
+
+func (MultiplierRule) Apply(ctx context.Context, q Queryer, trip Trip, fare *Fare) error {
+	multipliers, err := listMultipliers(ctx, q, `
+		WHERE m.active = TRUE
+		AND (m.operator_id IS NULL OR m.operator_id = $1)
+		AND (m.route_id IS NULL OR m.route_id = $2)
+	`, trip.OperatorID, trip.RouteID)
+	if err != nil {
+		return err
+	}
+
+	fare.Multiplier = PickMultiplier(multipliers, trip.At)
+	return nil
+}
+
+// OverrideRule replaces the fare with the operator's fixed fare for the route
+// or stop pair while one is in force. Multipliers do not apply on top.
+type OverrideRule struct{}
+
+func (OverrideRule) Apply(ctx context.Context, q Queryer, trip Trip, fare *Fare) error {
+	var perSeat float64
+	var reason sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT fare, reason
+		FROM fare_overrides
+		WHERE route_id = $1 AND operator_id = $2
+		AND starts_at <= $3 AND (ends_at IS NULL OR ends_at > $3)
+		AND (
+			from_stop_id IS NULL
+			OR (from_stop_id::TEXT = $4 AND to_stop_id::TEXT = $5)
+			OR (from_stop_id::TEXT = $5 AND to_stop_id::TEXT = $4)
+		)
+		ORDER BY from_stop_id IS NULL, created_at DESC
+		LIMIT 1
+	`, trip.RouteID, trip.OperatorID, trip.At, trip.BoardingStopID, trip.AlightingStopID).Scan(&perSeat, &reason)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	fare.PerSeat = perSeat
+	fare.Description = "Operator fare"
+	if reason.Valid && reason.String != "" {
+		fare.Description = reason.String
+	}
+	fare.Multiplier = nil
+	return nil
+}
+
+const multiplierColumns = `m.id, m.operator_id, m.route_id, m.name, m.multiplier, m.days_of_week,
+	to_char(m.start_time, 'HH24:MI'), to_char(m.end_time, 'HH24:MI'), m.starts_at, m.ends_at, m.active, m.created_at`
+
+func listMultipliers(ctx context.Context, q Queryer, where string, args ...interface{}) ([]models.FareMultiplier, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+multiplierColumns+` FROM fare_multipliers m `+where, args...)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	multipliers := []models.FareMultiplier{}
+	for rows.Next() {
+		var m models.FareMultiplier
+		var days pq.Int64Array
+		err := rows.Scan(&m.ID, &m.OperatorID, &m.RouteID, &m.Name, &m.Multiplier, &days,
+			&m.StartTime, &m.EndTime, &m.StartsAt, &m.EndsAt, &m.Active, &m.CreatedAt)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scan fare multiplier: %v", err)
+			return nil, fmt.Errorf("failed to scan fare multiplier: %w", err)
+		}
+		for _, d := range days {
+			m.DaysOfWeek = append(m.DaysOfWeek, int(d))
+		}
+		multipliers = append(multipliers, m)
+	}
+	return multipliers, rows.Err()
+}
+
+// MultiplierApplies reports whether the multiplier's schedule covers at. Day
+// and time of day are read in EAT, and a time window that ends before it
+// starts runs past midnight.
+func MultiplierApplies(m models.FareMultiplier, at time.Time) bool {
+	if !m.Active {
+		return false
+	}
+	if m.StartsAt != nil && at.Before(*m.StartsAt) {
+		return false
+	}
+	if m.EndsAt != nil && !at.Before(*m.EndsAt) {
+		return false
+	}
+
+	local := at.In(EAT)
+	if len(m.DaysOfWeek) > 0 {
+		found := false
+		for _, d := range m.DaysOfWeek {
+			if d == int(local.Weekday()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if m.StartTime == nil || m.EndTime == nil {
+		return true
+	}
+	start, err1 := ClockMinutes(*m.StartTime)
+	end, err2 := ClockMinutes(*m.EndTime)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	now := local.Hour()*60 + local.Minute()
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// PickMultiplier returns the multiplier in force at the given time. A route
+// multiplier wins over an operator-wide one, which wins over the platform's;
+// within a scope the highest applies.
+func PickMultiplier(multipliers []models.FareMultiplier, at time.Time) *models.FareMultiplier {
+	var best *models.FareMultiplier
+	bestScope := -1
+	for i := range multipliers {
+		m := &multipliers[i]
+		if !MultiplierApplies(*m, at) {
+			continue
+		}
+		scope := 0
+		if m.OperatorID != nil {
+			scope = 1
+		}
+		if m.RouteID != nil {
+			scope = 2
+		}
+		if scope > bestScope || (scope == bestScope && m.Multiplier > best.Multiplier) {
+			best, bestScope = m, scope
+		}
+	}
+	return best
+}
+
+// ClockMinutes parses a HH:MM time of day into minutes after midnight
+func ClockMinutes(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time of day %q", clock)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,103 @@
+// backend/internal/services/fares/tokens.go
+package fares
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+const quoteAudience = "fare_quote"
+
+// QuoteSigner signs fare quotes into short-lived tokens and verifies them.
+// The token carries the whole fare breakdown, so a booking can honour the
+// quoted price without the quote being stored.
+type QuoteSigner struct {
+	key []byte
+}
+
+// NewQuoteSigner derives the signing key from secret so quote tokens can
+// never pass as login tokens signed with the same secret, or the reverse
+func NewQuoteSigner(secret string) *QuoteSigner {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(quoteAudience))
+	return &QuoteSigner{key: mac.Sum(nil)}
+}
+
+type quoteClaims struct {
+	BusID             string                   `json:"bus_id"`
+	RouteID           string                   `json:"route_id"`
+	BoardingStopName  string                   `json:"boarding_stop_name"`
+	AlightingStopName string                   `json:"alighting_stop_name"`
+	Seats             int                      `json:"seats"`
+	LineItems         []models.BookingLineItem `json:"line_items"`
+	Total             float64                  `json:"total"`
+	jwt.RegisteredClaims
+}
+
+// Sign returns the quote's token
+func (s *QuoteSigner) Sign(quote *models.FareQuote) (string, error) {
+	claims := quoteClaims{
+		BusID:             quote.BusID,
+		RouteID:           quote.RouteID,
+		BoardingStopName:  quote.BoardingStopName,
+		AlightingStopName: quote.AlightingStopName,
+		Seats:             quote.Seats,
+		LineItems:         quote.LineItems,
+		Total:             quote.Total,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        quote.ID,
+			Audience:  jwt.ClaimStrings{quoteAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(quote.ExpiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign fare quote: %w", err)
+	}
+	return token, nil
+}
+
+// Verify returns the quote in a token signed by Sign. It fails with
+// ErrQuoteExpired once the lock period is over and ErrQuoteInvalid when the
+// token is malformed or has been changed.
+func (s *QuoteSigner) Verify(token string) (*models.FareQuote, error) {
+	var claims quoteClaims
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return s.key, nil
+	})
+	if err != nil {
+		// only report expiry for tokens that are otherwise genuine
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors == jwt.ValidationErrorExpired {
+			return nil, errors.ErrQuoteExpired
+		}
+		return nil, errors.ErrQuoteInvalid
+	}
+	if !parsed.Valid || !claims.VerifyAudience(quoteAudience, true) || claims.ExpiresAt == nil ||
+		claims.Total != total(claims.LineItems) {
+		return nil, errors.ErrQuoteInvalid
+	}
+
+	return &models.FareQuote{
+		ID:                claims.ID,
+		BusID:             claims.BusID,
+		RouteID:           claims.RouteID,
+		BoardingStopName:  claims.BoardingStopName,
+		AlightingStopName: claims.AlightingStopName,
+		Seats:             claims.Seats,
+		LineItems:         claims.LineItems,
+		Total:             claims.Total,
+		ExpiresAt:         claims.ExpiresAt.Time,
+	}, nil
+}
@@ -0,0 +1,165 @@
+// backend/internal/services/idempotency/idempotency.go
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// KeyTTL is how long a key's response is kept for replays
+const KeyTTL = 24 * time.Hour
+
+// lockTimeout is how long a key may go without being refreshed before a
+// retry can take it over, e.g. when the server died halfway through
+const lockTimeout = time.Minute
+
+// RefreshInterval is how often a running request refreshes its key, well
+// within lockTimeout so a slow payment keeps it
+const RefreshInterval = lockTimeout / 3
+
+// Record is what is kept for a key
+type Record struct {
+	Fingerprint string
+	Completed   bool // false while the first request is still running
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewIdempotencyService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Fingerprint identifies a request so a key reused for a different one can
+// be told apart from a retry
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type chargesKey struct{}
+
+// TrackCharges returns a context on which Charged records a payment, and a
+// func reporting whether one was made
+func TrackCharges(ctx context.Context) (context.Context, func() bool) {
+	charged := new(int32)
+	return context.WithValue(ctx, chargesKey{}, charged), func() bool {
+		return atomic.LoadInt32(charged) == 1
+	}
+}
+
+// Charged records that the request is about to charge the rider through the
+// gateway. A server error after it is kept for retries rather than released,
+// so a retry can't charge them again.
+func Charged(ctx context.Context) {
+	if charged, ok := ctx.Value(chargesKey{}).(*int32); ok {
+		atomic.StoreInt32(charged, 1)
+	}
+}
+
+// Claim reserves the key for a request. When it returns true the caller runs
+// the request and must Complete or Release the key; otherwise the record
+// already kept for the key is returned.
+func (s *Service) Claim(ctx context.Context, scope, key, fingerprint string) (bool, *Record, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (scope, key, fingerprint)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint, locked_at = NOW()
+		WHERE idempotency_keys.status_code IS NULL
+		AND idempotency_keys.locked_at < NOW() - $4 * INTERVAL '1 second'
+	`, scope, key, fingerprint, lockTimeout.Seconds())
+	if err != nil {
+		log.Printf("[ERROR] Failed to claim idempotency key: %v", err)
+		return false, nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return true, nil, nil
+	}
+
+	var rec Record
+	var status sql.NullInt64
+	var contentType sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT fingerprint, status_code, content_type, response_body
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&rec.Fingerprint, &status, &contentType, &rec.Body)
+	if err == sql.ErrNoRows {
+		// purged in between, try again
+		return s.Claim(ctx, scope, key, fingerprint)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return false, nil, fmt.Errorf("database error: %w", err)
+	}
+
+	rec.Completed = status.Valid
+	rec.StatusCode = int(status.Int64)
+	rec.ContentType = contentType.String
+	return false, &rec, nil
+}
+
+// Complete keeps the response to replay for retries with the key
+func (s *Service) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5, completed_at = NOW()
+		WHERE scope = $1 AND key = $2
+	`, scope, key, statusCode, contentType, body)
+	if err != nil {
+		log.Printf("[ERROR] Failed to store idempotent response: %v", err)
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Refresh keeps the key claimed while its request is still running
+func (s *Service) Refresh(ctx context.Context, scope, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET locked_at = NOW()
+		WHERE scope = $1 AND key = $2 AND status_code IS NULL
+	`, scope, key)
+	if err != nil {
+		log.Printf("[ERROR] Failed to refresh idempotency key: %v", err)
+		return fmt.Errorf("failed to refresh idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release frees the key so the request can be retried, for server errors
+// from requests that charged nothing
+func (s *Service) Release(ctx context.Context, scope, key string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2 AND status_code IS NULL
+	`, scope, key)
+	if err != nil {
+		log.Printf("[ERROR] Failed to release idempotency key: %v", err)
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes keys older than KeyTTL
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE created_at < NOW() - $1 * INTERVAL '1 second'
+	`, KeyTTL.Seconds())
+	if err != nil {
+		log.Printf("[ERROR] Failed to purge idempotency keys: %v", err)
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,276 @@
+// backend/internal/services/ledger/ledger.go
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/payments"
+)
+
+// system accounts
+const (
+	OpeningBalancesAccount = "equity:opening_balances"
+	PassDiscountsAccount   = "expense:pass_discounts" // credit given above the price paid
+	PassFeesAccount        = "revenue:pass_fees"      // price paid above the credit given
+)
+
+// PassAccount holds the ride credit owed to a pass holder
+func PassAccount(passID string) string {
+	return "pass:" + passID
+}
+
+// OperatorAccount holds fares owed to an operator
+func OperatorAccount(operatorID string) string {
+	return "operator:" + operatorID
+}
+
+// ClearingAccount holds money collected by a payment provider but not yet settled
+func ClearingAccount(method payments.PaymentMethod) string {
+	return "clearing:" + string(method)
+}
+
+func accountType(code string) string {
+	switch {
+	case strings.HasPrefix(code, "clearing:"):
+		return "asset"
+	case strings.HasPrefix(code, "equity:"):
+		return "equity"
+	case strings.HasPrefix(code, "revenue:"):
+		return "revenue"
+	case strings.HasPrefix(code, "expense:"):
+		return "expense"
+	default:
+		// pass and operator accounts are money held for someone else
+		return "liability"
+	}
+}
+
+// Cents converts a shilling amount to the ledger's minor units
+func Cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Amount converts cents back to shillings
+func Amount(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// Posting is one line of an entry, debits positive and credits negative
+type Posting struct {
+	Account string
+	Amount  int64
+}
+
+// Entry is a balanced set of postings
+type Entry struct {
+	Kind        string
+	Description string
+	PassID      string // the pass whose cached balance the entry changes, if any
+	BookingID   string
+	PaymentID   string
+	Postings    []Posting
+}
+
+// Result of posting an entry
+type Result struct {
+	EntryID     string
+	PassBalance float64 // cached pass balance after the entry, when PassID is set
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewLedgerService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Post writes the entry in tx and moves the cached pass balance with it.
+// A pass can never go below zero, ErrInsufficientBalance is returned instead.
+func (s *Service) Post(ctx context.Context, tx *sql.Tx, e Entry) (*Result, error) {
+	if err := validate(e); err != nil {
+		return nil, err
+	}
+
+	result := &Result{EntryID: uuid.New().String()}
+
+	if e.PassID != "" {
+		// pass accounts are liabilities, credits (negative) add to the balance
+		var change int64
+		for _, p := range e.Postings {
+			if p.Account == PassAccount(e.PassID) {
+				change -= p.Amount
+			}
+		}
+
+		// the row lock serialises spending from the same pass
+		err := tx.QueryRowContext(ctx, `
+			UPDATE bus_passes
+			SET balance = ROUND((balance + $1)::NUMERIC, 2), updated_at = NOW()
+			WHERE id = $2 AND balance + $1 >= 0
+			RETURNING balance
+		`, Amount(change), e.PassID).Scan(&result.PassBalance)
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrInsufficientBalance
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to update pass balance: %v", err)
+			return nil, fmt.Errorf("failed to update pass balance: %w", err)
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO journal_entries (id, kind, description, pass_id, booking_id, payment_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, result.EntryID, e.Kind, nullable(e.Description), nullable(e.PassID), nullable(e.BookingID), nullable(e.PaymentID))
+	if err != nil {
+		log.Printf("[ERROR] Failed to create journal entry: %v", err)
+		return nil, fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	for _, p := range e.Postings {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO ledger_accounts (code, type) VALUES ($1, $2)
+			ON CONFLICT (code) DO NOTHING
+		`, p.Account, accountType(p.Account))
+		if err != nil {
+			log.Printf("[ERROR] Failed to open ledger account: %v", err)
+			return nil, fmt.Errorf("failed to open ledger account: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_postings (entry_id, account_code, amount)
+			VALUES ($1, $2, $3)
+		`, result.EntryID, p.Account, p.Amount)
+		if err != nil {
+			log.Printf("[ERROR] Failed to write posting: %v", err)
+			return nil, fmt.Errorf("failed to write posting: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// PayFare moves a fare from a pass to the operator running the bus
+func (s *Service) PayFare(ctx context.Context, tx *sql.Tx, passID, operatorID string, fare float64, bookingID, paymentID string) (*Result, error) {
+	cents := Cents(fare)
+	return s.Post(ctx, tx, Entry{
+		Kind:      "ride",
+		PassID:    passID,
+		BookingID: bookingID,
+		PaymentID: paymentID,
+		Postings: []Posting{
+			{Account: PassAccount(passID), Amount: cents},
+			{Account: OperatorAccount(operatorID), Amount: -cents},
+		},
+	})
+}
+
+// RefundFare reverses a fare back to the pass
+func (s *Service) RefundFare(ctx context.Context, tx *sql.Tx, passID, operatorID string, amount float64, bookingID, paymentID string) (*Result, error) {
+	cents := Cents(amount)
+	return s.Post(ctx, tx, Entry{
+		Kind:      "refund",
+		PassID:    passID,
+		BookingID: bookingID,
+		PaymentID: paymentID,
+		Postings: []Posting{
+			{Account: OperatorAccount(operatorID), Amount: cents},
+			{Account: PassAccount(passID), Amount: -cents},
+		},
+	})
+}
+
+// LoadPass records money paid for a pass and the credit it bought. When the
+// two differ the gap is booked as a discount, or as a fee to feeAccount
+// (PassFeesAccount when empty). Unlimited passes carry no credit, so their
+// whole price is a fee.
+func (s *Service) LoadPass(ctx context.Context, tx *sql.Tx, kind, passID string, method payments.PaymentMethod, paid, credit float64, feeAccount, paymentID, description string) (*Result, error) {
+	if feeAccount == "" {
+		feeAccount = PassFeesAccount
+	}
+
+	paidCents, creditCents := Cents(paid), Cents(credit)
+
+	postings := []Posting{
+		{Account: ClearingAccount(method), Amount: paidCents},
+	}
+	if creditCents != 0 {
+		postings = append(postings, Posting{Account: PassAccount(passID), Amount: -creditCents})
+	}
+	switch gap := creditCents - paidCents; {
+	case gap > 0:
+		postings = append(postings, Posting{Account: PassDiscountsAccount, Amount: gap})
+	case gap < 0:
+		postings = append(postings, Posting{Account: feeAccount, Amount: gap})
+	}
+
+	return s.Post(ctx, tx, Entry{
+		Kind:        kind,
+		Description: description,
+		PassID:      passID,
+		PaymentID:   paymentID,
+		Postings:    postings,
+	})
+}
+
+// AccountBalance returns an account's balance derived from its postings,
+// debit positive
+func (s *Service) AccountBalance(ctx context.Context, code string) (float64, error) {
+	var cents int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE account_code = $1
+	`, code).Scan(&cents)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return Amount(cents), nil
+}
+
+func validate(e Entry) error {
+	if len(e.Postings) < 2 {
+		return fmt.Errorf("journal entry %q needs at least two postings", e.Kind)
+	}
+
+	var total int64
+	accounts := make([]string, 0, len(e.Postings))
+	for _, p := range e.Postings {
+		if p.Amount == 0 {
+			return fmt.Errorf("journal entry %q has a zero posting to %s", e.Kind, p.Account)
+		}
+		if strings.HasPrefix(p.Account, "pass:") && p.Account != PassAccount(e.PassID) {
+			// the cached balance of any other pass would drift
+			return fmt.Errorf("journal entry %q posts to %s without naming the pass", e.Kind, p.Account)
+		}
+		total += p.Amount
+		accounts = append(accounts, p.Account)
+	}
+	if total != 0 {
+		return fmt.Errorf("journal entry %q does not balance (off by %d cents)", e.Kind, total)
+	}
+
+	sort.Strings(accounts)
+	for i := 1; i < len(accounts); i++ {
+		if accounts[i] == accounts[i-1] {
+			return fmt.Errorf("journal entry %q posts to %s twice", e.Kind, accounts[i])
+		}
+	}
+	return nil
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
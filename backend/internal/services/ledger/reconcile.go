@@ -0,0 +1,141 @@
+// backend/internal/services/ledger/reconcile.go
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Drift is a pass whose cached balance disagrees with its ledger account
+type Drift struct {
+	PassID  string  `json:"pass_id"`
+	Account string  `json:"account"`
+	Cached  float64 `json:"cached_balance"`
+	Ledger  float64 `json:"ledger_balance"`
+}
+
+// Report is the outcome of a reconciliation run
+type Report struct {
+	CheckedAt         time.Time `json:"checked_at"`
+	PassesChecked     int       `json:"passes_checked"`
+	Drift             []Drift   `json:"drift"`
+	UnbalancedEntries []string  `json:"unbalanced_entries"`
+	TrialBalance      float64   `json:"trial_balance"` // sum of every posting, always zero in a healthy ledger
+	OK                bool      `json:"ok"`
+}
+
+// AccountSummary is an account with its derived balance
+type AccountSummary struct {
+	Code    string  `json:"code"`
+	Type    string  `json:"type"`
+	Balance float64 `json:"balance"`
+}
+
+// Reconcile checks every pass balance against its postings and that the
+// ledger as a whole balances
+func (s *Service) Reconcile(ctx context.Context) (*Report, error) {
+	report := &Report{
+		CheckedAt:         time.Now(),
+		Drift:             []Drift{},
+		UnbalancedEntries: []string{},
+	}
+
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bus_passes`).Scan(&report.PassesChecked)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT bp.id, ROUND((bp.balance * 100)::NUMERIC)::BIGINT, COALESCE(-SUM(lp.amount), 0)
+		FROM bus_passes bp
+		LEFT JOIN ledger_postings lp ON lp.account_code = 'pass:' || bp.id::TEXT
+		GROUP BY bp.id, bp.balance
+		HAVING ROUND((bp.balance * 100)::NUMERIC)::BIGINT <> COALESCE(-SUM(lp.amount), 0)
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d Drift
+		var cached, ledger int64
+		if err := rows.Scan(&d.PassID, &cached, &ledger); err != nil {
+			return nil, fmt.Errorf("failed to scan drift: %w", err)
+		}
+		d.Account = PassAccount(d.PassID)
+		d.Cached = Amount(cached)
+		d.Ledger = Amount(ledger)
+		report.Drift = append(report.Drift, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.db.QueryContext(ctx, `
+		SELECT entry_id FROM ledger_postings
+		GROUP BY entry_id
+		HAVING SUM(amount) <> 0
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer entries.Close()
+
+	for entries.Next() {
+		var id string
+		if err := entries.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		report.UnbalancedEntries = append(report.UnbalancedEntries, id)
+	}
+	if err := entries.Err(); err != nil {
+		return nil, err
+	}
+
+	var total int64
+	err = s.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(amount), 0) FROM ledger_postings`).Scan(&total)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	report.TrialBalance = Amount(total)
+
+	report.OK = len(report.Drift) == 0 && len(report.UnbalancedEntries) == 0 && total == 0
+	return report, nil
+}
+
+// ListAccounts returns every account with its balance derived from postings
+func (s *Service) ListAccounts(ctx context.Context, prefix string, limit, offset int) ([]AccountSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.code, a.type, COALESCE(SUM(lp.amount), 0)
+		FROM ledger_accounts a
+		LEFT JOIN ledger_postings lp ON lp.account_code = a.code
+		WHERE a.code LIKE $1 || '%'
+		GROUP BY a.code, a.type
+		ORDER BY a.code
+		LIMIT $2 OFFSET $3
+	`, prefix, limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []AccountSummary{}
+	for rows.Next() {
+		var a AccountSummary
+		var cents int64
+		if err := rows.Scan(&a.Code, &a.Type, &cents); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		a.Balance = Amount(cents)
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
@@ -0,0 +1,136 @@
+// backend/internal/services/lifecycle/lifecycle.go
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/audit"
+)
+
+// Booking statuses
+const (
+	StatusPendingPayment = "pending_payment"
+	StatusConfirmed      = "confirmed"
+	StatusBoarded        = "boarded"
+	StatusCompleted      = "completed"
+	StatusExpired        = "expired"
+	StatusCancelled      = "cancelled"
+	StatusNoShow         = "no_show"
+)
+
+// transitions lists the statuses a booking can move to from each status.
+// Expired, cancelled, completed and no-show bookings are final. Only a
+// boarded booking can be completed, one that never boarded is a no-show.
+var transitions = map[string][]string{
+	StatusPendingPayment: {StatusConfirmed, StatusExpired, StatusCancelled},
+	StatusConfirmed:      {StatusBoarded, StatusCancelled, StatusNoShow},
+	StatusBoarded:        {StatusCompleted},
+}
+
+// CanTransition reports whether a booking can move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Final reports whether no transition leaves the status
+func Final(status string) bool {
+	return len(transitions[status]) == 0
+}
+
+// Change is one row of a booking's status history
+type Change struct {
+	FromStatus *string   `json:"from_status"` // nil for the status the booking was created in
+	ToStatus   string    `json:"to_status"`
+	ActorID    *string   `json:"actor_id"`
+	ActorRole  *string   `json:"actor_role"`
+	Reason     *string   `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Start records the status a new booking was created in
+func Start(ctx context.Context, tx *sql.Tx, bookingID, status string, actor audit.Entry) error {
+	return record(ctx, tx, bookingID, "", status, actor, "")
+}
+
+// Transition moves a booking from one status to another and records it in
+// the booking's history. It fails with INVALID_BOOKING_STATUS when the
+// transition is not allowed or the booking is no longer in the from status.
+func Transition(ctx context.Context, tx *sql.Tx, bookingID, from, to string, actor audit.Entry, reason string) error {
+	if !CanTransition(from, to) {
+		return invalidTransition(from, to)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE bookings SET status = $1 WHERE id = $2 AND status = $3
+	`, to, bookingID, from)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update booking status: %v", err)
+		return fmt.Errorf("failed to update booking status: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return invalidTransition(from, to)
+	}
+
+	return record(ctx, tx, bookingID, from, to, actor, reason)
+}
+
+func record(ctx context.Context, tx *sql.Tx, bookingID, from, to string, actor audit.Entry, reason string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, actor_id, actor_role, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, bookingID, nullable(from), to, nullable(actor.ActorID), nullable(actor.ActorRole), nullable(reason))
+	if err != nil {
+		log.Printf("[ERROR] Failed to record booking status change: %v", err)
+		return fmt.Errorf("failed to record booking status change: %w", err)
+	}
+	return nil
+}
+
+// History returns a booking's status changes, oldest first
+func History(ctx context.Context, db *sql.DB, bookingID string) ([]Change, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT from_status, to_status, actor_id, actor_role, reason, created_at
+		FROM booking_status_history
+		WHERE booking_id = $1
+		ORDER BY created_at, id
+	`, bookingID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	history := []Change{}
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.FromStatus, &c.ToStatus, &c.ActorID, &c.ActorRole, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan booking status change: %w", err)
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+func invalidTransition(from, to string) error {
+	return &errors.BookingError{
+		Code:    "INVALID_BOOKING_STATUS",
+		Message: fmt.Sprintf("A %s booking cannot become %s", from, to),
+	}
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
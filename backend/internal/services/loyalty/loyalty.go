@@ -0,0 +1,428 @@
+// backend/internal/services/loyalty/loyalty.go
+package loyalty
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// Service runs loyalty programmes: riders earn points on completed rides,
+// climb tiers on their lifetime points and redeem points against fares
+type Service struct {
+	db *sql.DB
+}
+
+func NewLoyaltyService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Application is what a programme does to one booking's fare
+type Application struct {
+	ProgrammeID    string // empty when no programme covers the bus
+	LineItems      []models.BookingLineItem
+	PointsRedeemed int
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Apply prices a fare under the programme covering the bus. The rider's
+// account stays locked until the booking transaction ends so the same points
+// cannot be spent twice.
+func (s *Service) Apply(ctx context.Context, tx *sql.Tx, userID, busID string, fare float64, redeemPoints int) (*Application, error) {
+	if redeemPoints < 0 {
+		return nil, invalidRedemption("redeem_points cannot be negative")
+	}
+
+	programmeID, err := s.programmeFor(ctx, tx, busID)
+	if err != nil {
+		return nil, err
+	}
+	if programmeID == "" {
+		if redeemPoints > 0 {
+			return nil, errors.ErrNoLoyaltyProgramme
+		}
+		return &Application{}, nil
+	}
+
+	programme, err := getProgramme(ctx, tx, programmeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance, lifetime int
+	err = tx.QueryRowContext(ctx, `
+		SELECT points_balance, lifetime_points
+		FROM loyalty_accounts
+		WHERE user_id = $1 AND programme_id = $2
+		FOR UPDATE
+	`, userID, programmeID).Scan(&balance, &lifetime)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	items, points, err := Discounts(*programme, TierFor(programme.Tiers, lifetime), fare, balance, redeemPoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Application{
+		ProgrammeID:    programmeID,
+		LineItems:      items,
+		PointsRedeemed: points,
+	}, nil
+}
+
+// programmeFor picks the operator's programme when it covers the bus's
+// route, falling back to the platform programme
+func (s *Service) programmeFor(ctx context.Context, tx *sql.Tx, busID string) (string, error) {
+	var programmeID string
+	err := tx.QueryRowContext(ctx, `
+		SELECT p.id
+		FROM loyalty_programmes p
+		JOIN buses b ON b.id = $1
+		WHERE p.active = TRUE
+		AND (
+			p.operator_id IS NULL
+			OR (
+				p.operator_id = b.operator_id
+				AND (
+					NOT EXISTS (SELECT 1 FROM loyalty_programme_routes r WHERE r.programme_id = p.id)
+					OR EXISTS (
+						SELECT 1 FROM loyalty_programme_routes r
+						JOIN bus_route_assignments a ON a.route_id = r.route_id
+						WHERE r.programme_id = p.id AND a.bus_id = b.id AND a.status = 'active'
+					)
+				)
+			)
+		)
+		ORDER BY p.operator_id IS NULL
+		LIMIT 1
+	`, busID).Scan(&programmeID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	return programmeID, nil
+}
+
+// Redeem takes the points an application spent from the rider's account
+func (s *Service) Redeem(ctx context.Context, tx *sql.Tx, userID, bookingID string, app *Application) error {
+	if app == nil || app.PointsRedeemed == 0 {
+		return nil
+	}
+
+	var balance int
+	err := tx.QueryRowContext(ctx, `
+		UPDATE loyalty_accounts
+		SET points_balance = points_balance - $1, updated_at = NOW()
+		WHERE user_id = $2 AND programme_id = $3 AND points_balance >= $1
+		RETURNING points_balance
+	`, app.PointsRedeemed, userID, app.ProgrammeID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return errors.ErrInsufficientPoints
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to redeem points: %v", err)
+		return fmt.Errorf("failed to redeem points: %w", err)
+	}
+
+	return recordTransaction(ctx, tx, userID, app.ProgrammeID, bookingID, "redeem", -app.PointsRedeemed, balance)
+}
+
+// Reverse gives back the points a cancelled booking redeemed
+func (s *Service) Reverse(ctx context.Context, tx *sql.Tx, bookingID string) error {
+	var userID, programmeID string
+	var points int
+	err := tx.QueryRowContext(ctx, `
+		SELECT user_id, programme_id, -points
+		FROM loyalty_transactions
+		WHERE booking_id = $1 AND type = 'redeem'
+		AND NOT EXISTS (
+			SELECT 1 FROM loyalty_transactions WHERE booking_id = $1 AND type = 'reverse'
+		)
+	`, bookingID).Scan(&userID, &programmeID, &points)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	var balance int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE loyalty_accounts
+		SET points_balance = points_balance + $1, updated_at = NOW()
+		WHERE user_id = $2 AND programme_id = $3
+		RETURNING points_balance
+	`, points, userID, programmeID).Scan(&balance)
+	if err != nil {
+		log.Printf("[ERROR] Failed to return redeemed points: %v", err)
+		return fmt.Errorf("failed to return redeemed points: %w", err)
+	}
+
+	return recordTransaction(ctx, tx, userID, programmeID, bookingID, "reverse", points, balance)
+}
+
+// Award credits the points a completed booking earns in the programme it was
+// made under and moves the rider up a tier when they reach it. It returns
+// the points earned.
+func (s *Service) Award(ctx context.Context, tx *sql.Tx, bookingID string) (int, error) {
+	var userID string
+	var programmeID sql.NullString
+	var fare float64
+	err := tx.QueryRowContext(ctx, `
+		SELECT user_id, loyalty_programme_id, fare FROM bookings WHERE id = $1
+	`, bookingID).Scan(&userID, &programmeID, &fare)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	if !programmeID.Valid {
+		return 0, nil
+	}
+
+	programme, err := getProgramme(ctx, tx, programmeID.String)
+	if err != nil {
+		return 0, err
+	}
+	if !programme.Active {
+		// the programme has ended, rides no longer earn in it
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO loyalty_accounts (user_id, programme_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, programme.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to open loyalty account: %v", err)
+		return 0, fmt.Errorf("failed to open loyalty account: %w", err)
+	}
+
+	var lifetime int
+	err = tx.QueryRowContext(ctx, `
+		SELECT lifetime_points FROM loyalty_accounts
+		WHERE user_id = $1 AND programme_id = $2
+		FOR UPDATE
+	`, userID, programme.ID).Scan(&lifetime)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	points := PointsForRide(*programme, TierFor(programme.Tiers, lifetime), fare)
+
+	var tierID interface{}
+	if tier := TierFor(programme.Tiers, lifetime+points); tier != nil {
+		tierID = tier.ID
+	}
+
+	var balance int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE loyalty_accounts
+		SET points_balance = points_balance + $1,
+			lifetime_points = lifetime_points + $1,
+			rides = rides + 1,
+			tier_id = $2,
+			updated_at = NOW()
+		WHERE user_id = $3 AND programme_id = $4
+		RETURNING points_balance
+	`, points, tierID, userID, programme.ID).Scan(&balance)
+	if err != nil {
+		log.Printf("[ERROR] Failed to award points: %v", err)
+		return 0, fmt.Errorf("failed to award points: %w", err)
+	}
+
+	if err := recordTransaction(ctx, tx, userID, programme.ID, bookingID, "earn", points, balance); err != nil {
+		return 0, err
+	}
+	return points, nil
+}
+
+// Accounts returns the rider's standing in every programme they have joined
+func (s *Service) Accounts(ctx context.Context, userID string) ([]models.LoyaltyAccount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.programme_id, p.name, p.operator_id, a.points_balance, a.lifetime_points, a.rides, p.redemption_value
+		FROM loyalty_accounts a
+		JOIN loyalty_programmes p ON p.id = a.programme_id
+		WHERE a.user_id = $1
+		ORDER BY a.updated_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.LoyaltyAccount{}
+	for rows.Next() {
+		var a models.LoyaltyAccount
+		err := rows.Scan(&a.ProgrammeID, &a.ProgrammeName, &a.OperatorID, &a.PointsBalance, &a.LifetimePoints, &a.Rides, &a.RedemptionValue)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scan loyalty account: %v", err)
+			return nil, fmt.Errorf("failed to scan loyalty account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range accounts {
+		tiers, err := programmeTiers(ctx, s.db, accounts[i].ProgrammeID)
+		if err != nil {
+			return nil, err
+		}
+		accounts[i].Tier = TierFor(tiers, accounts[i].LifetimePoints)
+		accounts[i].NextTier = nextTier(tiers, accounts[i].LifetimePoints)
+	}
+	return accounts, nil
+}
+
+// Transactions returns the rider's points history, newest first
+func (s *Service) Transactions(ctx context.Context, userID string, limit, offset int) ([]models.LoyaltyTransaction, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, programme_id, booking_id, type, points, balance_after, created_at
+		FROM loyalty_transactions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.LoyaltyTransaction{}
+	for rows.Next() {
+		var t models.LoyaltyTransaction
+		if err := rows.Scan(&t.ID, &t.ProgrammeID, &t.BookingID, &t.Type, &t.Points, &t.BalanceAfter, &t.CreatedAt); err != nil {
+			log.Printf("[ERROR] Failed to scan loyalty transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan loyalty transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func recordTransaction(ctx context.Context, tx *sql.Tx, userID, programmeID, bookingID, kind string, points, balanceAfter int) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO loyalty_transactions (user_id, programme_id, booking_id, type, points, balance_after)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, userID, programmeID, bookingID, kind, points, balanceAfter)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record loyalty transaction: %v", err)
+		return fmt.Errorf("failed to record loyalty transaction: %w", err)
+	}
+	return nil
+}
+
+// TierFor returns the highest tier the lifetime points reach, or nil below
+// the first threshold
+func TierFor(tiers []models.LoyaltyTier, lifetimePoints int) *models.LoyaltyTier {
+	var best *models.LoyaltyTier
+	for i := range tiers {
+		if tiers[i].ThresholdPoints <= lifetimePoints && (best == nil || tiers[i].ThresholdPoints > best.ThresholdPoints) {
+			best = &tiers[i]
+		}
+	}
+	return best
+}
+
+func nextTier(tiers []models.LoyaltyTier, lifetimePoints int) *models.LoyaltyTier {
+	var next *models.LoyaltyTier
+	for i := range tiers {
+		if tiers[i].ThresholdPoints > lifetimePoints && (next == nil || tiers[i].ThresholdPoints < next.ThresholdPoints) {
+			next = &tiers[i]
+		}
+	}
+	return next
+}
+
+// PointsForRide is what a ride paying farePaid earns, the tier's multiplier
+// applies to both the per ride and the per shilling points
+func PointsForRide(p models.LoyaltyProgramme, tier *models.LoyaltyTier, farePaid float64) int {
+	multiplier := 1.0
+	if tier != nil {
+		multiplier = tier.EarnMultiplier
+	}
+	return int(math.Floor((float64(p.PointsPerRide) + farePaid*p.PointsPerShilling) * multiplier))
+}
+
+// Discounts returns the line items taken off a fare, the tier discount first
+// and then redeemed points, along with the points spent. Redemption is capped
+// at the programme's share of the fare, so fewer points than asked may be spent.
+func Discounts(p models.LoyaltyProgramme, tier *models.LoyaltyTier, fare float64, balance, redeemPoints int) ([]models.BookingLineItem, int, error) {
+	items := []models.BookingLineItem{}
+	remaining := fare
+
+	if tier != nil && tier.DiscountPercent > 0 {
+		discount := roundCents(fare * tier.DiscountPercent / 100)
+		if discount > 0 {
+			items = append(items, models.BookingLineItem{
+				Type:        "loyalty_tier_discount",
+				Description: fmt.Sprintf("%s tier discount (%g%%)", tier.Name, tier.DiscountPercent),
+				Amount:      -discount,
+			})
+			remaining -= discount
+		}
+	}
+
+	if redeemPoints == 0 {
+		return items, 0, nil
+	}
+	if redeemPoints > balance {
+		return nil, 0, errors.ErrInsufficientPoints
+	}
+	if redeemPoints < p.MinRedeemPoints {
+		return nil, 0, invalidRedemption(fmt.Sprintf("At least %d points must be redeemed at a time", p.MinRedeemPoints))
+	}
+
+	maxValue := roundCents(remaining * p.MaxRedeemPercent / 100)
+	points := redeemPoints
+	if usable := int(math.Floor(maxValue / p.RedemptionValue)); usable < points {
+		points = usable
+	}
+	if points <= 0 || points < p.MinRedeemPoints {
+		return nil, 0, invalidRedemption("This fare is too low to redeem points against")
+	}
+
+	items = append(items, models.BookingLineItem{
+		Type:        "loyalty_redemption",
+		Description: fmt.Sprintf("%d points redeemed", points),
+		Amount:      -roundCents(float64(points) * p.RedemptionValue),
+		Points:      points,
+	})
+	return items, points, nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func invalidRedemption(message string) error {
+	return &errors.BookingError{
+		Code:    "INVALID_REDEMPTION",
+		Message: message,
+	}
+}
@@ -0,0 +1,387 @@
+// backend/internal/services/loyalty/programmes.go
+package loyalty
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// ProgrammeInput describes a loyalty programme to create or replace
+type ProgrammeInput struct {
+	OperatorID        string // empty for the platform programme
+	Name              string
+	PointsPerRide     int
+	PointsPerShilling float64
+	RedemptionValue   float64
+	MinRedeemPoints   int
+	MaxRedeemPercent  float64
+	Active            bool
+	RouteIDs          []string
+	Tiers             []models.LoyaltyTier
+}
+
+const programmeColumns = `p.id, p.operator_id, p.name, p.points_per_ride, p.points_per_shilling,
+	p.redemption_value, p.min_redeem_points, p.max_redeem_percent, p.active, p.created_at, p.updated_at`
+
+func scanProgramme(row scanner, p *models.LoyaltyProgramme) error {
+	return row.Scan(
+		&p.ID, &p.OperatorID, &p.Name, &p.PointsPerRide, &p.PointsPerShilling,
+		&p.RedemptionValue, &p.MinRedeemPoints, &p.MaxRedeemPercent, &p.Active, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+// ListProgrammes returns an operator's programmes, or the platform's when
+// operatorID is empty, including ones that have ended
+func (s *Service) ListProgrammes(ctx context.Context, operatorID string) ([]models.LoyaltyProgramme, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+programmeColumns+`
+		FROM loyalty_programmes p
+		WHERE COALESCE(p.operator_id::TEXT, '') = $1
+		ORDER BY p.active DESC, p.created_at DESC
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	programmes := []models.LoyaltyProgramme{}
+	for rows.Next() {
+		var p models.LoyaltyProgramme
+		if err := scanProgramme(rows, &p); err != nil {
+			log.Printf("[ERROR] Failed to scan loyalty programme: %v", err)
+			return nil, fmt.Errorf("failed to scan loyalty programme: %w", err)
+		}
+		programmes = append(programmes, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range programmes {
+		if err := loadProgrammeDetails(ctx, s.db, &programmes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return programmes, nil
+}
+
+// CreateProgramme starts a programme. An operator or the platform runs one
+// programme at a time.
+func (s *Service) CreateProgramme(ctx context.Context, in ProgrammeInput) (*models.LoyaltyProgramme, error) {
+	if err := validateProgramme(&in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var operatorID interface{}
+	if in.OperatorID != "" {
+		operatorID = in.OperatorID
+	}
+
+	programmeID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO loyalty_programmes (
+			id, operator_id, name, points_per_ride, points_per_shilling,
+			redemption_value, min_redeem_points, max_redeem_percent
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, programmeID, operatorID, in.Name, in.PointsPerRide, in.PointsPerShilling,
+		in.RedemptionValue, in.MinRedeemPoints, in.MaxRedeemPercent)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, errors.ErrLoyaltyProgrammeExists
+		}
+		log.Printf("[ERROR] Failed to create loyalty programme: %v", err)
+		return nil, fmt.Errorf("failed to create loyalty programme: %w", err)
+	}
+
+	if err := saveScope(ctx, tx, programmeID, in); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Created loyalty programme %s (%s)", programmeID, in.Name)
+	return s.managedProgramme(ctx, programmeID, in.OperatorID)
+}
+
+// UpdateProgramme replaces a programme's rules, routes and tiers. Riders keep
+// their points and are placed in the new tiers by their lifetime points.
+func (s *Service) UpdateProgramme(ctx context.Context, programmeID string, in ProgrammeInput) (*models.LoyaltyProgramme, error) {
+	if _, err := s.managedProgramme(ctx, programmeID, in.OperatorID); err != nil {
+		return nil, err
+	}
+	if err := validateProgramme(&in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE loyalty_programmes
+		SET name = $1,
+			points_per_ride = $2,
+			points_per_shilling = $3,
+			redemption_value = $4,
+			min_redeem_points = $5,
+			max_redeem_percent = $6,
+			active = $7,
+			updated_at = NOW()
+		WHERE id = $8
+	`, in.Name, in.PointsPerRide, in.PointsPerShilling, in.RedemptionValue,
+		in.MinRedeemPoints, in.MaxRedeemPercent, in.Active, programmeID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, errors.ErrLoyaltyProgrammeExists
+		}
+		log.Printf("[ERROR] Failed to update loyalty programme: %v", err)
+		return nil, fmt.Errorf("failed to update loyalty programme: %w", err)
+	}
+
+	for _, table := range []string{"loyalty_programme_routes", "loyalty_tiers"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE programme_id = $1`, programmeID); err != nil {
+			log.Printf("[ERROR] Failed to update loyalty programme: %v", err)
+			return nil, fmt.Errorf("failed to update loyalty programme: %w", err)
+		}
+	}
+	if err := saveScope(ctx, tx, programmeID, in); err != nil {
+		return nil, err
+	}
+
+	// account tiers were cleared with the old tiers, place riders again
+	_, err = tx.ExecContext(ctx, `
+		UPDATE loyalty_accounts a
+		SET tier_id = (
+			SELECT t.id FROM loyalty_tiers t
+			WHERE t.programme_id = a.programme_id AND t.threshold_points <= a.lifetime_points
+			ORDER BY t.threshold_points DESC
+			LIMIT 1
+		)
+		WHERE a.programme_id = $1
+	`, programmeID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update loyalty tiers: %v", err)
+		return nil, fmt.Errorf("failed to update loyalty tiers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.managedProgramme(ctx, programmeID, in.OperatorID)
+}
+
+// saveScope writes the programme's routes and tiers. Operators can only
+// scope a programme to their own routes.
+func saveScope(ctx context.Context, tx *sql.Tx, programmeID string, in ProgrammeInput) error {
+	for _, routeID := range in.RouteIDs {
+		var routeOperator sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT operator_id FROM bus_routes WHERE id = $1`, routeID).Scan(&routeOperator)
+		if err == sql.ErrNoRows || (routeOperator.Valid && routeOperator.String != in.OperatorID) {
+			return invalidProgramme(fmt.Sprintf("route %s not found", routeID))
+		}
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO loyalty_programme_routes (programme_id, route_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, programmeID, routeID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scope loyalty programme: %v", err)
+			return fmt.Errorf("failed to scope loyalty programme: %w", err)
+		}
+	}
+
+	for _, tier := range in.Tiers {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO loyalty_tiers (programme_id, name, threshold_points, earn_multiplier, discount_percent)
+			VALUES ($1, $2, $3, $4, $5)
+		`, programmeID, tier.Name, tier.ThresholdPoints, tier.EarnMultiplier, tier.DiscountPercent)
+		if err != nil {
+			log.Printf("[ERROR] Failed to add loyalty tier: %v", err)
+			return fmt.Errorf("failed to add loyalty tier: %w", err)
+		}
+	}
+	return nil
+}
+
+// managedProgramme loads a programme run by the operator, or a platform
+// programme when operatorID is empty
+func (s *Service) managedProgramme(ctx context.Context, programmeID, operatorID string) (*models.LoyaltyProgramme, error) {
+	if _, err := uuid.Parse(programmeID); err != nil {
+		return nil, errors.ErrLoyaltyProgrammeNotFound
+	}
+
+	var p models.LoyaltyProgramme
+	err := scanProgramme(s.db.QueryRowContext(ctx, `
+		SELECT `+programmeColumns+`
+		FROM loyalty_programmes p
+		WHERE p.id = $1 AND COALESCE(p.operator_id::TEXT, '') = $2
+	`, programmeID, operatorID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrLoyaltyProgrammeNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := loadProgrammeDetails(ctx, s.db, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func getProgramme(ctx context.Context, q queryer, programmeID string) (*models.LoyaltyProgramme, error) {
+	var p models.LoyaltyProgramme
+	err := scanProgramme(q.QueryRowContext(ctx, `
+		SELECT `+programmeColumns+` FROM loyalty_programmes p WHERE p.id = $1
+	`, programmeID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrLoyaltyProgrammeNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.Tiers, err = programmeTiers(ctx, q, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func loadProgrammeDetails(ctx context.Context, q queryer, p *models.LoyaltyProgramme) error {
+	var err error
+	if p.Tiers, err = programmeTiers(ctx, q, p.ID); err != nil {
+		return err
+	}
+
+	var routeIDs pq.StringArray
+	err = q.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(route_id::TEXT), '{}') FROM loyalty_programme_routes WHERE programme_id = $1
+	`, p.ID).Scan(&routeIDs)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	p.RouteIDs = []string(routeIDs)
+	return nil
+}
+
+func programmeTiers(ctx context.Context, q queryer, programmeID string) ([]models.LoyaltyTier, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, name, threshold_points, earn_multiplier, discount_percent
+		FROM loyalty_tiers
+		WHERE programme_id = $1
+		ORDER BY threshold_points
+	`, programmeID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	tiers := []models.LoyaltyTier{}
+	for rows.Next() {
+		var t models.LoyaltyTier
+		if err := rows.Scan(&t.ID, &t.Name, &t.ThresholdPoints, &t.EarnMultiplier, &t.DiscountPercent); err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty tier: %w", err)
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, rows.Err()
+}
+
+func validateProgramme(in *ProgrammeInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return invalidProgramme("name is required")
+	}
+	if in.PointsPerRide < 0 || in.PointsPerShilling < 0 {
+		return invalidProgramme("points_per_ride and points_per_shilling cannot be negative")
+	}
+	if in.PointsPerRide == 0 && in.PointsPerShilling == 0 {
+		return invalidProgramme("rides must earn points, set points_per_ride or points_per_shilling")
+	}
+	if in.RedemptionValue <= 0 {
+		return invalidProgramme("redemption_value must be positive")
+	}
+	if in.MinRedeemPoints < 0 {
+		return invalidProgramme("min_redeem_points cannot be negative")
+	}
+	if in.MaxRedeemPercent == 0 {
+		in.MaxRedeemPercent = 50
+	}
+	if in.MaxRedeemPercent <= 0 || in.MaxRedeemPercent >= 100 {
+		return invalidProgramme("max_redeem_percent must be between 0 and 100")
+	}
+
+	if in.OperatorID == "" && len(in.RouteIDs) > 0 {
+		return invalidProgramme("route_ids only apply to operator programmes")
+	}
+	for _, routeID := range in.RouteIDs {
+		if _, err := uuid.Parse(routeID); err != nil {
+			return invalidProgramme(fmt.Sprintf("route %s not found", routeID))
+		}
+	}
+
+	thresholds := map[int]bool{}
+	for i := range in.Tiers {
+		tier := &in.Tiers[i]
+		tier.Name = strings.TrimSpace(tier.Name)
+		if tier.Name == "" {
+			return invalidProgramme("every tier needs a name")
+		}
+		if tier.ThresholdPoints < 0 || thresholds[tier.ThresholdPoints] {
+			return invalidProgramme("tier thresholds must be distinct and not negative")
+		}
+		thresholds[tier.ThresholdPoints] = true
+		if tier.EarnMultiplier == 0 {
+			tier.EarnMultiplier = 1
+		}
+		if tier.EarnMultiplier < 0 {
+			return invalidProgramme("earn_multiplier must be positive")
+		}
+		if tier.DiscountPercent < 0 || tier.DiscountPercent >= 100 {
+			return invalidProgramme("discount_percent must be at least 0 and below 100")
+		}
+	}
+	return nil
+}
+
+func invalidProgramme(message string) error {
+	return &errors.LoyaltyError{
+		Code:    "INVALID_PROGRAMME",
+		Message: message,
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -133,11 +133,58 @@ func (s *NotificationService) Send(userID string, msgType models.NotificationTyp
 			NotificationID: notificationID,
 			RetryCount:     0,
 		}
+	case models.NotificationPassExpiration:
+		s.smsQueue <- SMSMessage{
+			Phone:          user.Phone,
+			Content:        message,
+			NotificationID: notificationID,
+			RetryCount:     0,
+		}
+		s.emailQueue <- EmailMessage{
+			Email:          user.Email,
+			Subject:        "Bus Pass Expiry",
+			Body:           message,
+			NotificationID: notificationID,
+			RetryCount:     0,
+		}
+	case models.NotificationBooking:
+		s.smsQueue <- SMSMessage{
+			Phone:          user.Phone,
+			Content:        message,
+			NotificationID: notificationID,
+			RetryCount:     0,
+		}
+		s.emailQueue <- EmailMessage{
+			Email:          user.Email,
+			Subject:        "Booking Update",
+			Body:           message,
+			NotificationID: notificationID,
+			RetryCount:     0,
+		}
 	}
 
 	return nil
 }
 
+// QueueSMS sends a transactional sms that is not stored as a user notification, e.g. login codes
+func (s *NotificationService) QueueSMS(phone, content string) {
+	s.smsQueue <- SMSMessage{
+		Phone:      phone,
+		Content:    content,
+		RetryCount: 0,
+	}
+}
+
+// QueueEmail sends a transactional email that is not stored as a user notification, e.g. staff invitations
+func (s *NotificationService) QueueEmail(email, subject, body string) {
+	s.emailQueue <- EmailMessage{
+		Email:      email,
+		Subject:    subject,
+		Body:       body,
+		RetryCount: 0,
+	}
+}
+
 func (s *NotificationService) ProcessNotifications() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -164,6 +211,14 @@ func (s *NotificationService) ProcessNotifications() {
 }
 
 func (s *NotificationService) handleDeliveryResult(id string, err error, retryCount int, msg interface{}) {
+	if id == "" {
+		// transactional message, nothing to track
+		if err != nil && retryCount < maxRetries {
+			s.requeue(msg)
+		}
+		return
+	}
+
 	updateStmt := `
 	UPDATE notifications
 	SET delivery_attempts = delivery_attempts + 1,
@@ -189,14 +244,18 @@ func (s *NotificationService) handleDeliveryResult(id string, err error, retryCo
 
 	if err != nil && retryCount < maxRetries {
 		log.Printf("Retrying delivery (attempt %d/%d) for notification ID %s", retryCount+1, maxRetries, id)
-		switch v := msg.(type) {
-		case SMSMessage:
-			v.RetryCount++
-			s.smsQueue <- v
-		case EmailMessage:
-			v.RetryCount++
-			s.emailQueue <- v
-		}
+		s.requeue(msg)
+	}
+}
+
+func (s *NotificationService) requeue(msg interface{}) {
+	switch v := msg.(type) {
+	case SMSMessage:
+		v.RetryCount++
+		s.smsQueue <- v
+	case EmailMessage:
+		v.RetryCount++
+		s.emailQueue <- v
 	}
 }
 
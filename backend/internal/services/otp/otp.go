@@ -0,0 +1,292 @@
+// backend/internal/services/otp/otp.go
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+)
+
+const (
+	codeLength      = 6
+	codeTTL         = 5 * time.Minute
+	maxAttempts     = 5
+	resendInterval  = 60 * time.Second
+	maxCodesPerHour = 5
+)
+
+// advisory lock namespace for codes sent to a phone number
+const phoneLockClass = 7302
+
+// SMSSender queues an sms for delivery, implemented by the notification service
+type SMSSender interface {
+	QueueSMS(phone, content string)
+}
+
+type Service struct {
+	db     *sql.DB
+	sender SMSSender
+	secret []byte
+}
+
+func NewOTPService(db *sql.DB, sender SMSSender) *Service {
+	secret := os.Getenv("OTP_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+
+	return &Service{
+		db:     db,
+		sender: sender,
+		secret: []byte(secret),
+	}
+}
+
+// NormalizePhone converts a kenyan or international number to E.164,
+// e.g. "0712 345 678" and "254712345678" both become "+254712345678"
+func NormalizePhone(raw string) (string, error) {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			// separators
+		default:
+			return "", errors.ErrInvalidPhone
+		}
+	}
+	phone := b.String()
+
+	switch {
+	case strings.HasPrefix(phone, "+"):
+		// already international
+	case strings.HasPrefix(phone, "00"):
+		phone = "+" + phone[2:]
+	case strings.HasPrefix(phone, "254") && len(phone) == 12:
+		phone = "+" + phone
+	case strings.HasPrefix(phone, "0") && len(phone) == 10:
+		// local 07xx / 01xx
+		phone = "+254" + phone[1:]
+	case len(phone) == 9 && (phone[0] == '7' || phone[0] == '1'):
+		phone = "+254" + phone
+	default:
+		return "", errors.ErrInvalidPhone
+	}
+
+	digits := phone[1:]
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", errors.ErrInvalidPhone
+	}
+
+	// kenyan mobile numbers are +254 followed by 7xx or 1xx
+	if strings.HasPrefix(digits, "254") {
+		local := digits[3:]
+		if len(local) != 9 || (local[0] != '7' && local[0] != '1') {
+			return "", errors.ErrInvalidPhone
+		}
+	}
+
+	return phone, nil
+}
+
+// RequestCode generates a new code for the phone number and queues it by sms
+func (s *Service) RequestCode(ctx context.Context, rawPhone, requestIP string) (string, time.Time, error) {
+	phone, err := NormalizePhone(rawPhone)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	// requests for the number wait on each other, so they can't all pass
+	// the limits before any of them is stored
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, phoneLockClass, phone); err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", time.Time{}, fmt.Errorf("database error: %w", err)
+	}
+
+	var sentLastHour int
+	var lastSent sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), MAX(created_at)
+		FROM phone_otps
+		WHERE phone_number = $1
+		AND created_at > NOW() - INTERVAL '1 hour'
+	`, phone).Scan(&sentLastHour, &lastSent)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", time.Time{}, fmt.Errorf("database error: %w", err)
+	}
+
+	if lastSent.Valid && time.Since(lastSent.Time) < resendInterval {
+		return "", time.Time{}, &errors.AuthError{
+			Code:       "OTP_RATE_LIMITED",
+			Message:    "Please wait before requesting another code",
+			RetryAfter: resendInterval - time.Since(lastSent.Time),
+		}
+	}
+
+	if sentLastHour >= maxCodesPerHour {
+		return "", time.Time{}, &errors.AuthError{
+			Code:       "OTP_RATE_LIMITED",
+			Message:    "Too many codes requested, try again later",
+			RetryAfter: time.Hour,
+		}
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		log.Printf("[ERROR] Failed to generate otp: %v", err)
+		return "", time.Time{}, fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	expiresAt := time.Now().Add(codeTTL)
+
+	// a new code replaces any outstanding one
+	_, err = tx.ExecContext(ctx, `
+		UPDATE phone_otps
+		SET consumed_at = NOW()
+		WHERE phone_number = $1 AND consumed_at IS NULL
+	`, phone)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", time.Time{}, fmt.Errorf("database error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO phone_otps (phone_number, code_hash, max_attempts, expires_at, request_ip)
+		VALUES ($1, $2, $3, $4, $5)
+	`, phone, s.hashCode(phone, code), maxAttempts, expiresAt, requestIP)
+	if err != nil {
+		log.Printf("[ERROR] Failed to store otp: %v", err)
+		return "", time.Time{}, fmt.Errorf("failed to store code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.sender.QueueSMS(phone, fmt.Sprintf(
+		"Your Zurura code is %s. It expires in %d minutes. Do not share it with anyone.",
+		code, int(codeTTL.Minutes()),
+	))
+
+	return phone, expiresAt, nil
+}
+
+// VerifyCode checks the latest code for the phone number. A wrong code counts
+// against the attempt limit. When consume is false a correct code stays valid,
+// so the caller can ask for more details before using it.
+func (s *Service) VerifyCode(ctx context.Context, rawPhone, code string, consume bool) (string, error) {
+	phone, err := NormalizePhone(rawPhone)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var otp struct {
+		ID          string
+		CodeHash    string
+		Attempts    int
+		MaxAttempts int
+		ExpiresAt   time.Time
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, code_hash, attempts, max_attempts, expires_at
+		FROM phone_otps
+		WHERE phone_number = $1 AND consumed_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, phone).Scan(&otp.ID, &otp.CodeHash, &otp.Attempts, &otp.MaxAttempts, &otp.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.ErrOTPInvalid
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	if time.Now().After(otp.ExpiresAt) {
+		return "", errors.ErrOTPInvalid
+	}
+
+	if otp.Attempts >= otp.MaxAttempts {
+		return "", errors.ErrOTPTooManyAttempts
+	}
+
+	if !hmac.Equal([]byte(otp.CodeHash), []byte(s.hashCode(phone, code))) {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE phone_otps SET attempts = attempts + 1 WHERE id = $1
+		`, otp.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to record otp attempt: %v", err)
+			return "", fmt.Errorf("database error: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return "", fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		if otp.Attempts+1 >= otp.MaxAttempts {
+			return "", errors.ErrOTPTooManyAttempts
+		}
+		return "", errors.ErrOTPInvalid
+	}
+
+	if consume {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE phone_otps SET consumed_at = NOW() WHERE id = $1
+		`, otp.ID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to consume otp: %v", err)
+			return "", fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return phone, nil
+}
+
+func (s *Service) hashCode(phone, code string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(phone + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
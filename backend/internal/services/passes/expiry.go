@@ -0,0 +1,246 @@
+// backend/internal/services/passes/expiry.go
+package passes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/payments"
+)
+
+// ProcessExpirations reminds riders of passes about to expire, then renews or
+// expires passes that are due. It is safe to run from several replicas.
+func (s *Service) ProcessExpirations(ctx context.Context) error {
+	if err := s.sendReminders(ctx); err != nil {
+		return err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM bus_passes
+		WHERE status = 'active' AND expiration_date <= NOW()
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	var due []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pass: %w", err)
+		}
+		due = append(due, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range due {
+		if err := s.renewOrExpire(ctx, id); err != nil {
+			log.Printf("[ERROR] Failed to process expiry of pass %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+type expiringPass struct {
+	id         string
+	userID     string
+	name       string
+	expiration time.Time
+	autoRenew  bool
+}
+
+func (s *Service) sendReminders(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT bp.id, bp.user_id, COALESCE(p.name, 'bus pass'), bp.expiration_date, bp.auto_renew
+		FROM bus_passes bp
+		LEFT JOIN pass_products p ON p.id = bp.product_id
+		WHERE bp.status = 'active'
+		AND bp.expiry_notified_at IS NULL
+		AND bp.expiration_date > NOW()
+		AND bp.expiration_date <= $1
+	`, time.Now().Add(reminderWindow))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	var expiring []expiringPass
+	for rows.Next() {
+		var p expiringPass
+		if err := rows.Scan(&p.id, &p.userID, &p.name, &p.expiration, &p.autoRenew); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pass: %w", err)
+		}
+		expiring = append(expiring, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range expiring {
+		// claim the reminder first so a second replica does not send it again
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE bus_passes SET expiry_notified_at = NOW()
+			WHERE id = $1 AND expiry_notified_at IS NULL
+		`, p.id)
+		if err != nil {
+			log.Printf("[ERROR] Failed to mark pass reminder: %v", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("Your %s expires on %s.", p.name, p.expiration.Format("2 Jan 2006 15:04"))
+		if p.autoRenew {
+			message += " It will renew automatically."
+		} else {
+			message += " Renew or buy a new pass to keep riding."
+		}
+		if err := s.notifier.Send(p.userID, models.NotificationPassExpiration, message); err != nil {
+			log.Printf("[ERROR] Failed to send pass reminder: %v", err)
+		}
+	}
+	return nil
+}
+
+// renewOrExpire handles one due pass. The row stays locked while the renewal
+// is charged so no other worker can charge it twice.
+func (s *Service) renewOrExpire(ctx context.Context, passID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var balance float64
+	var expiration time.Time
+	var autoRenew bool
+	var method sql.NullString
+	var productID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id, balance, expiration_date, auto_renew, payment_method, product_id
+		FROM bus_passes
+		WHERE id = $1 AND status = 'active' AND expiration_date <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`, passID).Scan(&userID, &balance, &expiration, &autoRenew, &method, &productID)
+	if err == sql.ErrNoRows {
+		// handled elsewhere
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	if autoRenew && productID.Valid && method.Valid {
+		product, err := s.getProduct(ctx, productID.String)
+		if err == nil {
+			var renewed bool
+			renewed, err = s.renew(ctx, tx, passID, userID, expiration, payments.PaymentMethod(method.String), product)
+			if err != nil {
+				return err
+			}
+			if renewed {
+				return nil
+			}
+		} else {
+			log.Printf("[INFO] Product for pass %s is no longer sold, expiring it", passID)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE bus_passes SET status = 'expired', auto_renew = FALSE, updated_at = NOW()
+		WHERE id = $1
+	`, passID)
+	if err != nil {
+		return fmt.Errorf("failed to expire pass: %w", err)
+	}
+	if err := recordTransaction(ctx, tx, passID, userID, "expiry", 0, balance, "", "", ""); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	message := "Your bus pass has expired."
+	if autoRenew {
+		message = "We could not renew your bus pass and it has expired. Buy a new pass to keep riding."
+	}
+	if err := s.notifier.Send(userID, models.NotificationPassExpiration, message); err != nil {
+		log.Printf("[ERROR] Failed to send pass expiry notification: %v", err)
+	}
+	return nil
+}
+
+// renew charges the renewal and extends the pass, returning false when the
+// payment is declined or the product has ended so the caller expires it instead
+func (s *Service) renew(ctx context.Context, tx *sql.Tx, passID, userID string, expiration time.Time, method payments.PaymentMethod, product *models.PassProduct) (bool, error) {
+	newExpiration := RenewedExpiration(expiration, time.Now(), product)
+	if !newExpiration.After(time.Now()) {
+		return false, nil
+	}
+
+	payment, err := s.charge(ctx, userID, product.Price, method, "Bus pass renewal: "+product.Name)
+	if err != nil {
+		log.Printf("[INFO] Renewal of pass %s declined: %v", passID, err)
+		return false, nil
+	}
+	err = func() error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE bus_passes
+			SET fee = $1,
+				expiration_date = $2,
+				expiry_notified_at = NULL,
+				updated_at = NOW()
+			WHERE id = $3
+		`, product.Price, newExpiration, passID)
+		if err != nil {
+			return fmt.Errorf("failed to renew pass: %w", err)
+		}
+
+		paymentID, err := s.recordPayment(ctx, tx, userID, "system", passID, "pass_renewal", product.Price, payment)
+		if err != nil {
+			return err
+		}
+		posted, err := s.ledger.LoadPass(ctx, tx, "pass_renewal", passID, method, product.Price, product.Credit, feeAccount(product), paymentID, product.Name)
+		if err != nil {
+			return err
+		}
+		if err := recordTransaction(ctx, tx, passID, userID, "renewal", product.Credit, posted.PassBalance, paymentID, posted.EntryID, product.Name); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		if refundErr := s.paymentService.RefundPayment(ctx, payment.TransactionID, payment.Amount); refundErr != nil {
+			log.Printf("[ERROR] Failed to refund renewal payment %s: %v", payment.TransactionID, refundErr)
+		}
+		return false, err
+	}
+
+	message := fmt.Sprintf("Your %s has been renewed until %s.", product.Name, newExpiration.Format("2 Jan 2006"))
+	if err := s.notifier.Send(userID, models.NotificationPayment, message); err != nil {
+		log.Printf("[ERROR] Failed to send pass renewal notification: %v", err)
+	}
+	return true, nil
+}
+
+// RenewedExpiration extends from the old expiry so renewal periods stay
+// aligned, unless the pass lapsed so long ago that would end in the past
+func RenewedExpiration(expiration, now time.Time, product *models.PassProduct) time.Time {
+	if next := ExpiresAt(product, expiration); next.After(now) {
+		return next
+	}
+	return ExpiresAt(product, now)
+}
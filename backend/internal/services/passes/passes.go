@@ -0,0 +1,500 @@
+// backend/internal/services/passes/passes.go
+package passes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/idempotency"
+	"github.com/Mvoii/zurura/internal/services/ledger"
+	"github.com/Mvoii/zurura/internal/services/payments"
+)
+
+const (
+	// riders are reminded this long before a pass expires
+	reminderWindow = 3 * 24 * time.Hour
+
+	minTopUp = 50.0
+	maxTopUp = 10000.0
+
+	historyLimit = 50
+)
+
+// Notifier delivers a stored user notification, implemented by the notification service
+type Notifier interface {
+	Send(userID string, msgType models.NotificationType, message string) error
+}
+
+type Service struct {
+	db             *sql.DB
+	paymentService payments.PaymentService
+	audit          *audit.Service
+	ledger         *ledger.Service
+	notifier       Notifier
+}
+
+func NewPassService(db *sql.DB, ps payments.PaymentService, auditService *audit.Service, notifier Notifier) *Service {
+	return &Service{
+		db:             db,
+		paymentService: ps,
+		audit:          auditService,
+		ledger:         ledger.NewLedgerService(db),
+		notifier:       notifier,
+	}
+}
+
+type PurchaseRequest struct {
+	UserID        string
+	ProductID     string
+	PaymentMethod payments.PaymentMethod
+	AutoRenew     bool
+}
+
+// Purchase charges the rider for a product and issues the pass
+func (s *Service) Purchase(ctx context.Context, req PurchaseRequest) (*models.BusPass, error) {
+	product, err := s.getProduct(ctx, req.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	var schoolName sql.NullString
+	var studentVerifiedAt sql.NullTime
+	err = s.db.QueryRowContext(ctx, `
+		SELECT school_name, student_verified_at FROM users WHERE id = $1
+	`, req.UserID).Scan(&schoolName, &studentVerifiedAt)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !Eligible(*product, schoolName.String, studentVerifiedAt.Valid) {
+		return nil, errors.ErrPassNotEligible
+	}
+
+	payment, err := s.charge(ctx, req.UserID, product.Price, req.PaymentMethod, "Bus pass purchase: "+product.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	passID := uuid.New().String()
+	err = s.withRefund(ctx, payment, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO bus_passes (
+				id, user_id, product_id, pass_type, balance, fee, status,
+				auto_renew, payment_method, expiration_date
+			) VALUES ($1, $2, $3, $4, 0, $5, 'active', $6, $7, $8)
+		`, passID, req.UserID, product.ID, product.PassType, product.Price,
+			req.AutoRenew, req.PaymentMethod, ExpiresAt(product, time.Now()))
+		if err != nil {
+			log.Printf("[ERROR] Failed to create bus pass: %v", err)
+			return fmt.Errorf("failed to create bus pass: %w", err)
+		}
+
+		paymentID, err := s.recordPayment(ctx, tx, req.UserID, "rider", passID, "pass_purchase", product.Price, payment)
+		if err != nil {
+			return err
+		}
+
+		posted, err := s.ledger.LoadPass(ctx, tx, "pass_purchase", passID, req.PaymentMethod, product.Price, product.Credit, feeAccount(product), paymentID, product.Name)
+		if err != nil {
+			return err
+		}
+
+		return recordTransaction(ctx, tx, passID, req.UserID, "purchase", product.Credit, posted.PassBalance, paymentID, posted.EntryID, product.Name)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User %s bought pass %s (%s)", req.UserID, passID, product.Name)
+	return s.getPass(ctx, s.db, req.UserID, passID)
+}
+
+// TopUp charges the rider and adds the amount to an active pass
+func (s *Service) TopUp(ctx context.Context, userID, passID string, amount float64, method payments.PaymentMethod) (*models.BusPass, error) {
+	if amount < minTopUp || amount > maxTopUp {
+		return nil, errors.ErrInvalidTopUp
+	}
+
+	pass, err := s.getPass(ctx, s.db, userID, passID)
+	if err != nil {
+		return nil, err
+	}
+	if !usable(pass) {
+		return nil, errors.ErrPassNotActive
+	}
+	if pass.PassType == "unlimited" {
+		return nil, errors.ErrPassNoBalance
+	}
+
+	payment, err := s.charge(ctx, userID, amount, method, "Bus pass top-up")
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withRefund(ctx, payment, func(tx *sql.Tx) error {
+		// the pass may have expired or been cancelled while the payment went through
+		var id string
+		err := tx.QueryRowContext(ctx, `
+			SELECT id FROM bus_passes
+			WHERE id = $1 AND user_id = $2 AND status = 'active' AND expiration_date > NOW()
+			FOR UPDATE
+		`, passID, userID).Scan(&id)
+		if err == sql.ErrNoRows {
+			return errors.ErrPassNotActive
+		}
+		if err != nil {
+			log.Printf("[ERROR] Failed to top up pass: %v", err)
+			return fmt.Errorf("failed to top up pass: %w", err)
+		}
+
+		paymentID, err := s.recordPayment(ctx, tx, userID, "rider", passID, "pass_top_up", amount, payment)
+		if err != nil {
+			return err
+		}
+
+		posted, err := s.ledger.LoadPass(ctx, tx, "pass_top_up", passID, method, amount, amount, "", paymentID, "")
+		if err != nil {
+			return err
+		}
+
+		return recordTransaction(ctx, tx, passID, userID, "top_up", amount, posted.PassBalance, paymentID, posted.EntryID, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getPass(ctx, s.db, userID, passID)
+}
+
+// ListPasses returns all of the rider's passes, newest first
+func (s *Service) ListPasses(ctx context.Context, userID string) ([]models.BusPass, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+passColumns+`
+		FROM bus_passes
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	passes := []models.BusPass{}
+	for rows.Next() {
+		var p models.BusPass
+		if err := scanPass(rows, &p); err != nil {
+			log.Printf("[ERROR] Failed to scan bus pass: %v", err)
+			return nil, fmt.Errorf("failed to scan bus pass: %w", err)
+		}
+		passes = append(passes, p)
+	}
+	return passes, rows.Err()
+}
+
+// GetPass returns one of the rider's passes with its latest transactions
+func (s *Service) GetPass(ctx context.Context, userID, passID string) (*models.BusPass, []models.PassTransaction, error) {
+	pass, err := s.getPass(ctx, s.db, userID, passID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pass_id, type, amount, balance_after, payment_id, booking_id, description, created_at
+		FROM pass_transactions
+		WHERE pass_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, passID, historyLimit)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.PassTransaction{}
+	for rows.Next() {
+		var t models.PassTransaction
+		if err := rows.Scan(
+			&t.ID, &t.PassID, &t.Type, &t.Amount, &t.BalanceAfter,
+			&t.PaymentID, &t.BookingID, &t.Description, &t.CreatedAt,
+		); err != nil {
+			log.Printf("[ERROR] Failed to scan pass transaction: %v", err)
+			return nil, nil, fmt.Errorf("failed to scan pass transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	return pass, transactions, rows.Err()
+}
+
+// SetAutoRenew turns renewal on or off. Renewals are charged to method, or to
+// the method the pass was last paid with when method is empty.
+func (s *Service) SetAutoRenew(ctx context.Context, userID, passID string, autoRenew bool, method payments.PaymentMethod) (*models.BusPass, error) {
+	pass, err := s.getPass(ctx, s.db, userID, passID)
+	if err != nil {
+		return nil, err
+	}
+	if pass.Status != "active" {
+		return nil, errors.ErrPassNotActive
+	}
+
+	if autoRenew {
+		if pass.ProductID == nil {
+			return nil, errors.ErrPassNotRenewable
+		}
+		if method == "" && pass.PaymentMethod != nil {
+			method = payments.PaymentMethod(*pass.PaymentMethod)
+		}
+		if !chargeable(method) {
+			return nil, errors.ErrPassPaymentMethod
+		}
+	}
+
+	var paymentMethod interface{}
+	if method != "" {
+		paymentMethod = method
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE bus_passes
+		SET auto_renew = $1,
+			payment_method = COALESCE($2, payment_method),
+			updated_at = NOW()
+		WHERE id = $3 AND user_id = $4
+	`, autoRenew, paymentMethod, passID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update auto renew: %v", err)
+		return nil, fmt.Errorf("failed to update auto renew: %w", err)
+	}
+
+	return s.getPass(ctx, s.db, userID, passID)
+}
+
+// Cancel stops a pass from being used or renewed. Remaining balance stays on
+// the pass record but can no longer be spent.
+func (s *Service) Cancel(ctx context.Context, userID, passID string) (*models.BusPass, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var balance float64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE bus_passes
+		SET status = 'cancelled', auto_renew = FALSE, cancelled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'active'
+		RETURNING balance
+	`, passID, userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		// tell a missing pass apart from one that is already inactive
+		if _, err := s.getPass(ctx, tx, userID, passID); err != nil {
+			return nil, err
+		}
+		return nil, errors.ErrPassNotActive
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to cancel pass: %v", err)
+		return nil, fmt.Errorf("failed to cancel pass: %w", err)
+	}
+
+	if err := recordTransaction(ctx, tx, passID, userID, "cancellation", 0, balance, "", "", "Cancelled by rider"); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] User %s cancelled pass %s", userID, passID)
+	return s.getPass(ctx, s.db, userID, passID)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const passColumns = `id, user_id, product_id, pass_type, balance, fee, status, auto_renew,
+	payment_method, expiration_date, cancelled_at, created_at, updated_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPass(row scanner, p *models.BusPass) error {
+	return row.Scan(
+		&p.ID, &p.UserID, &p.ProductID, &p.PassType, &p.Balance, &p.Fee, &p.Status, &p.AutoRenew,
+		&p.PaymentMethod, &p.ExpirationDate, &p.CancelledAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+func (s *Service) getPass(ctx context.Context, q queryer, userID, passID string) (*models.BusPass, error) {
+	if _, err := uuid.Parse(passID); err != nil {
+		return nil, errors.ErrPassNotFound
+	}
+
+	var p models.BusPass
+	err := scanPass(q.QueryRowContext(ctx, `
+		SELECT `+passColumns+`
+		FROM bus_passes
+		WHERE id = $1 AND user_id = $2
+	`, passID, userID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPassNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &p, nil
+}
+
+// feeAccount is where the price of a product above its credit goes, to the
+// operator for operator passes
+func feeAccount(p *models.PassProduct) string {
+	if p.OperatorID != nil {
+		return ledger.OperatorAccount(*p.OperatorID)
+	}
+	return ledger.PassFeesAccount
+}
+
+func usable(p *models.BusPass) bool {
+	return p.Status == "active" && p.ExpirationDate.After(time.Now())
+}
+
+// chargeable reports whether a pass can be bought with the method, cash has
+// no one to collect it and a pass cannot pay for itself
+func chargeable(method payments.PaymentMethod) bool {
+	return method == payments.PaymentMethodMPesa || method == payments.PaymentMethodCard
+}
+
+func (s *Service) charge(ctx context.Context, userID string, amount float64, method payments.PaymentMethod, description string) (*payments.PaymentResponse, error) {
+	if !chargeable(method) {
+		return nil, errors.ErrPassPaymentMethod
+	}
+
+	idempotency.Charged(ctx)
+	resp, err := s.paymentService.ProcessPayment(ctx, payments.PaymentRequest{
+		Amount:        amount,
+		Currency:      "KES",
+		PaymentMethod: method,
+		UserID:        userID,
+		Description:   description,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Pass payment failed: %v", err)
+		return nil, &errors.PassError{
+			Code:    errors.ErrPassPaymentFailed.Code,
+			Message: errors.ErrPassPaymentFailed.Message,
+			Err:     err,
+		}
+	}
+	if resp.Status != payments.PaymentStatusCompleted {
+		log.Printf("[ERROR] Pass payment %s ended as %s", resp.TransactionID, resp.Status)
+		return nil, errors.ErrPassPaymentFailed
+	}
+	return resp, nil
+}
+
+// withRefund runs fn in a transaction and refunds the payment if it fails,
+// so riders are never charged for a pass that was not issued
+func (s *Service) withRefund(ctx context.Context, payment *payments.PaymentResponse, fn func(tx *sql.Tx) error) error {
+	err := func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		if refundErr := s.paymentService.RefundPayment(ctx, payment.TransactionID, payment.Amount); refundErr != nil {
+			log.Printf("[ERROR] Failed to refund pass payment %s: %v", payment.TransactionID, refundErr)
+		}
+	}
+	return err
+}
+
+// recordPayment stores a completed pass payment and audits it
+func (s *Service) recordPayment(ctx context.Context, tx *sql.Tx, userID, actorRole, passID, kind string, amount float64, payment *payments.PaymentResponse) (string, error) {
+	metadata, err := json.Marshal(map[string]interface{}{
+		"booking_type": kind,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata to JSON: %w", err)
+	}
+
+	paymentID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO payments (
+			id, user_id, amount, payment_method, payment_status,
+			transaction_id, currency, metadata, bus_pass_id
+		) VALUES ($1, $2, $3, $4, $5, $6, 'KES', $7, $8)
+	`, paymentID, userID, amount, payment.PaymentMethod, payment.Status, payment.TransactionID, metadata, passID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create payment record: %v", err)
+		return "", fmt.Errorf("failed to create payment record: %w", err)
+	}
+
+	actorID := userID
+	if actorRole == "system" {
+		actorID = ""
+	}
+	err = s.audit.Record(ctx, tx, audit.Entry{
+		ActorID:    actorID,
+		ActorRole:  actorRole,
+		Action:     "payment." + string(payment.Status),
+		EntityType: "payment",
+		EntityID:   paymentID,
+		After: map[string]interface{}{
+			"bus_pass_id":    passID,
+			"kind":           kind,
+			"amount":         amount,
+			"payment_method": payment.PaymentMethod,
+			"payment_status": payment.Status,
+			"transaction_id": payment.TransactionID,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return paymentID, nil
+}
+
+// recordTransaction adds an entry to the pass history shown to riders, linked
+// to the journal entry that moved the balance when there is one
+func recordTransaction(ctx context.Context, tx *sql.Tx, passID, userID, kind string, amount, balanceAfter float64, paymentID, entryID, description string) error {
+	var payment, entry, desc interface{}
+	if paymentID != "" {
+		payment = paymentID
+	}
+	if entryID != "" {
+		entry = entryID
+	}
+	if description != "" {
+		desc = description
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO pass_transactions (pass_id, user_id, type, amount, balance_after, payment_id, journal_entry_id, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, passID, userID, kind, amount, balanceAfter, payment, entry, desc)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record pass transaction: %v", err)
+		return fmt.Errorf("failed to record pass transaction: %w", err)
+	}
+	return nil
+}
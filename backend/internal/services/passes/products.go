@@ -0,0 +1,349 @@
+// backend/internal/services/passes/products.go
+package passes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// ProductInput describes a pass product to create
+type ProductInput struct {
+	OperatorID   string // empty for platform products
+	Name         string
+	Description  string
+	PassType     string
+	Price        float64
+	Credit       float64
+	ValidityDays int
+	EndsAt       *time.Time
+	Eligibility  string
+	SchoolNames  []string
+	RouteIDs     []string
+}
+
+const productColumns = `p.id, p.operator_id, p.name, p.description, p.pass_type, p.price, p.credit,
+	p.validity_days, p.ends_at, p.eligibility, p.school_names, p.active`
+
+func scanProduct(row scanner, p *models.PassProduct) error {
+	var schools pq.StringArray
+	err := row.Scan(
+		&p.ID, &p.OperatorID, &p.Name, &p.Description, &p.PassType, &p.Price, &p.Credit,
+		&p.ValidityDays, &p.EndsAt, &p.Eligibility, &schools, &p.Active,
+	)
+	p.SchoolNames = []string(schools)
+	if p.SchoolNames == nil {
+		p.SchoolNames = []string{}
+	}
+	return err
+}
+
+// ListProducts returns the passes riders can buy
+func (s *Service) ListProducts(ctx context.Context) ([]models.PassProduct, error) {
+	return s.listProducts(ctx, `
+		WHERE p.active = TRUE AND (p.ends_at IS NULL OR p.ends_at > NOW())
+		ORDER BY p.price
+	`)
+}
+
+// ListManagedProducts returns an operator's products, or platform products
+// when operatorID is empty, including ones no longer on sale
+func (s *Service) ListManagedProducts(ctx context.Context, operatorID string) ([]models.PassProduct, error) {
+	if operatorID == "" {
+		return s.listProducts(ctx, `WHERE p.operator_id IS NULL ORDER BY p.created_at DESC`)
+	}
+	return s.listProducts(ctx, `WHERE p.operator_id = $1 ORDER BY p.created_at DESC`, operatorID)
+}
+
+func (s *Service) listProducts(ctx context.Context, where string, args ...interface{}) ([]models.PassProduct, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+productColumns+` FROM pass_products p `+where, args...)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	products := []models.PassProduct{}
+	for rows.Next() {
+		var p models.PassProduct
+		if err := scanProduct(rows, &p); err != nil {
+			log.Printf("[ERROR] Failed to scan pass product: %v", err)
+			return nil, fmt.Errorf("failed to scan pass product: %w", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range products {
+		if products[i].RouteIDs, err = s.productRoutes(ctx, products[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return products, nil
+}
+
+func (s *Service) productRoutes(ctx context.Context, productID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT route_id FROM pass_product_routes WHERE product_id = $1`, productID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	routeIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan product route: %w", err)
+		}
+		routeIDs = append(routeIDs, id)
+	}
+	return routeIDs, rows.Err()
+}
+
+// getProduct returns a product that is on sale
+func (s *Service) getProduct(ctx context.Context, productID string) (*models.PassProduct, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, errors.ErrPassProductNotFound
+	}
+
+	var p models.PassProduct
+	err := scanProduct(s.db.QueryRowContext(ctx, `
+		SELECT `+productColumns+`
+		FROM pass_products p
+		WHERE p.id = $1 AND p.active = TRUE AND (p.ends_at IS NULL OR p.ends_at > NOW())
+	`, productID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPassProductNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateProduct puts a new pass on sale
+func (s *Service) CreateProduct(ctx context.Context, in ProductInput) (*models.PassProduct, error) {
+	if err := validateProduct(&in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, routeID := range in.RouteIDs {
+		// operators can only scope a pass to their own routes
+		var routeOperator sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT operator_id FROM bus_routes WHERE id = $1`, routeID).Scan(&routeOperator)
+		if err == sql.ErrNoRows || (in.OperatorID != "" && routeOperator.Valid && routeOperator.String != in.OperatorID) {
+			return nil, invalidProduct(fmt.Sprintf("route %s not found", routeID))
+		}
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	var operatorID, description interface{}
+	if in.OperatorID != "" {
+		operatorID = in.OperatorID
+	}
+	if in.Description != "" {
+		description = in.Description
+	}
+
+	productID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO pass_products (
+			id, operator_id, name, description, pass_type, price, credit,
+			validity_days, ends_at, eligibility, school_names
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, productID, operatorID, in.Name, description, in.PassType, in.Price, in.Credit,
+		in.ValidityDays, in.EndsAt, in.Eligibility, pq.Array(in.SchoolNames))
+	if err != nil {
+		log.Printf("[ERROR] Failed to create pass product: %v", err)
+		return nil, fmt.Errorf("failed to create pass product: %w", err)
+	}
+
+	for _, routeID := range in.RouteIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO pass_product_routes (product_id, route_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, productID, routeID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scope pass product: %v", err)
+			return nil, fmt.Errorf("failed to scope pass product: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Created pass product %s (%s)", productID, in.Name)
+	return s.managedProduct(ctx, productID, in.OperatorID)
+}
+
+// SetProductActive takes a product off sale or back on. Passes already sold
+// keep working but will not renew while it is off sale.
+func (s *Service) SetProductActive(ctx context.Context, productID, operatorID string, active bool) (*models.PassProduct, error) {
+	if _, err := s.managedProduct(ctx, productID, operatorID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE pass_products SET active = $1, updated_at = NOW() WHERE id = $2
+	`, active, productID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update pass product: %v", err)
+		return nil, fmt.Errorf("failed to update pass product: %w", err)
+	}
+
+	return s.managedProduct(ctx, productID, operatorID)
+}
+
+// managedProduct loads a product owned by the operator, or a platform product
+// when operatorID is empty
+func (s *Service) managedProduct(ctx context.Context, productID, operatorID string) (*models.PassProduct, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, errors.ErrPassProductNotFound
+	}
+
+	var p models.PassProduct
+	err := scanProduct(s.db.QueryRowContext(ctx, `
+		SELECT `+productColumns+`
+		FROM pass_products p
+		WHERE p.id = $1 AND COALESCE(p.operator_id::TEXT, '') = $2
+	`, productID, operatorID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPassProductNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.RouteIDs, err = s.productRoutes(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func validateProduct(in *ProductInput) error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return invalidProduct("name is required")
+	}
+
+	switch in.PassType {
+	case "prepaid", "subscription":
+	case "unlimited":
+		if in.Credit != 0 {
+			return invalidProduct("unlimited passes do not carry credit")
+		}
+	default:
+		return invalidProduct("pass_type must be prepaid, subscription or unlimited")
+	}
+
+	if in.Price <= 0 || in.Credit < 0 {
+		return invalidProduct("price must be positive and credit cannot be negative")
+	}
+	if in.ValidityDays <= 0 {
+		return invalidProduct("validity_days must be positive")
+	}
+	if in.EndsAt != nil && !in.EndsAt.After(time.Now()) {
+		return invalidProduct("ends_at must be in the future")
+	}
+
+	if in.Eligibility == "" {
+		in.Eligibility = "anyone"
+	}
+	switch in.Eligibility {
+	case "anyone":
+		if len(in.SchoolNames) > 0 {
+			return invalidProduct("school_names only apply to student passes")
+		}
+	case "student", "verified_student":
+	default:
+		return invalidProduct("eligibility must be anyone, student or verified_student")
+	}
+
+	schools := in.SchoolNames[:0]
+	for _, school := range in.SchoolNames {
+		if school = strings.TrimSpace(school); school != "" {
+			schools = append(schools, school)
+		}
+	}
+	in.SchoolNames = schools
+
+	for _, routeID := range in.RouteIDs {
+		if _, err := uuid.Parse(routeID); err != nil {
+			return invalidProduct(fmt.Sprintf("route %s not found", routeID))
+		}
+	}
+	return nil
+}
+
+func invalidProduct(message string) error {
+	return &errors.PassError{
+		Code:    "INVALID_PRODUCT",
+		Message: message,
+	}
+}
+
+// Eligible reports whether a rider may buy the product. Student products need
+// a school on the rider's profile, or a student verification by an admin for
+// verified_student products, and the school must be listed when the product
+// names schools.
+func Eligible(p models.PassProduct, schoolName string, studentVerified bool) bool {
+	switch p.Eligibility {
+	case "", "anyone":
+		return true
+	case "student":
+		if strings.TrimSpace(schoolName) == "" && !studentVerified {
+			return false
+		}
+	case "verified_student":
+		if !studentVerified {
+			return false
+		}
+	default:
+		return false
+	}
+
+	if len(p.SchoolNames) == 0 {
+		return true
+	}
+	for _, school := range p.SchoolNames {
+		if strings.EqualFold(strings.TrimSpace(school), strings.TrimSpace(schoolName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpiresAt is when a pass bought or renewed from the product at start runs
+// out, never later than the product's end date
+func ExpiresAt(p *models.PassProduct, start time.Time) time.Time {
+	expiry := start.AddDate(0, 0, p.ValidityDays)
+	if p.EndsAt != nil && p.EndsAt.Before(expiry) {
+		return *p.EndsAt
+	}
+	return expiry
+}
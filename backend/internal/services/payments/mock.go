@@ -59,10 +59,14 @@ func (m *MockPaymentService) VerifyPayment(ctx context.Context, transactionID st
 }
 
 // RefundPayment implements refund processing
-func (m *MockPaymentService) RefundPayment(ctx context.Context, transactionID string) error {
+func (m *MockPaymentService) RefundPayment(ctx context.Context, transactionID string, amount float64) error {
 	// Simulate network delay
 	time.Sleep(300 * time.Millisecond)
 
+	if amount <= 0 {
+		return errors.New("refund amount must be positive")
+	}
+
 	// Always succeed for mock implementation
 	return nil
 }
@@ -11,11 +11,13 @@ import (
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusCompleted PaymentStatus = "completed"
-	PaymentStatusFailed    PaymentStatus = "failed"
-	PaymentStatusRefunded  PaymentStatus = "refunded"
-	PaymentStatusExpired   PaymentStatus = "expired"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusCompleted         PaymentStatus = "completed"
+	PaymentStatusFailed            PaymentStatus = "failed"
+	PaymentStatusRefunded          PaymentStatus = "refunded"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentStatusExpired           PaymentStatus = "expired"
+	PaymentStatusInvoiced          PaymentStatus = "invoiced" // billed to the rider, paid later
 )
 
 // PaymentMethod represents the available payment methods
@@ -26,6 +28,7 @@ const (
 	PaymentMethodCash    PaymentMethod = "cash"
 	PaymentMethodBusPass PaymentMethod = "bus_pass"
 	PaymentMethodCard    PaymentMethod = "card"
+	PaymentMethodInvoice PaymentMethod = "invoice"
 )
 
 // PaymentRequest represents a payment request
@@ -59,8 +62,8 @@ type PaymentService interface {
 	// VerifyPayment verifies the status of a payment
 	VerifyPayment(ctx context.Context, transactionID string) (*PaymentResponse, error)
 
-	// RefundPayment processes a refund
-	RefundPayment(ctx context.Context, transactionID string) error
+	// RefundPayment refunds amount of a payment, which may be less than was paid
+	RefundPayment(ctx context.Context, transactionID string, amount float64) error
 
 	// GetPaymentStatus retrieves the current status of a payment
 	GetPaymentStatus(ctx context.Context, transactionID string) (PaymentStatus, error)
@@ -0,0 +1,186 @@
+// backend/internal/services/planner/network.go
+package planner
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/stops"
+)
+
+const (
+	// WalkSpeed is a rider's walking pace in metres per second
+	WalkSpeed = stops.WalkSpeed
+	// MaxAccessWalk is the furthest a rider is asked to walk to the first
+	// stop or on from the last one
+	MaxAccessWalk = 800.0
+	// MaxTransferWalk is the furthest a rider is asked to walk between stops
+	// to change buses
+	MaxTransferWalk = 400.0
+	// MaxDirectWalk is the furthest a trip is offered on foot alone
+	MaxDirectWalk = 2000.0
+
+	earthRadius = 6371000.0
+)
+
+// Point is a place on the map
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Stop is a bus stop in the network
+type Stop struct {
+	ID   string
+	Name string
+	Point
+}
+
+// Route is the stops a version of a route calls at in order, with the time
+// from the start of the route to each of them
+type Route struct {
+	ID         string
+	Version    string // route version the stops are from, empty for unversioned routes
+	Name       string
+	OperatorID string
+	BaseFare   float64
+	Stops      []string
+	Offsets    []time.Duration
+}
+
+// Key is what the network knows the route by, its version when it has one
+func (r *Route) Key() string {
+	if r.Version != "" {
+		return r.Version
+	}
+	return r.ID
+}
+
+// Trip is one scheduled run of a route, on the version of it the trip runs
+type Trip struct {
+	ScheduleID string
+	BusID      string
+	RouteID    string
+	Version    string
+	OperatorID string
+	Departure  time.Time
+}
+
+// Key is the key of the route the trip runs in the network
+func (t *Trip) Key() string {
+	if t.Version != "" {
+		return t.Version
+	}
+	return t.RouteID
+}
+
+// Footpath is a walk from one stop to another nearby
+type Footpath struct {
+	To     string
+	Meters float64
+}
+
+// routeStop is a route calling at a stop, at its index in the route
+type routeStop struct {
+	route string
+	index int
+}
+
+// Network is the stops and routes the planner searches, with the walks
+// between stops close enough to change buses on foot. It is read only once
+// built and safe to share.
+type Network struct {
+	Stops     map[string]Stop
+	Routes    map[string]*Route // by Key
+	routesAt  map[string][]routeStop
+	footpaths map[string][]Footpath
+	LoadedAt  time.Time
+}
+
+// NewNetwork indexes the routes by the stops they call at and works out the
+// footpaths between stops. Routes calling at unknown stops, or at fewer than
+// two, are left out.
+func NewNetwork(stopList []Stop, routes []Route) *Network {
+	n := &Network{
+		Stops:     make(map[string]Stop, len(stopList)),
+		Routes:    make(map[string]*Route, len(routes)),
+		routesAt:  map[string][]routeStop{},
+		footpaths: map[string][]Footpath{},
+		LoadedAt:  time.Now(),
+	}
+	for _, s := range stopList {
+		n.Stops[s.ID] = s
+	}
+
+	for i := range routes {
+		r := routes[i]
+		if len(r.Stops) < 2 || len(r.Offsets) != len(r.Stops) {
+			continue
+		}
+		known := true
+		for _, id := range r.Stops {
+			if _, ok := n.Stops[id]; !ok {
+				known = false
+				break
+			}
+		}
+		if !known {
+			continue
+		}
+		n.Routes[r.Key()] = &r
+		for idx, id := range r.Stops {
+			n.routesAt[id] = append(n.routesAt[id], routeStop{route: r.Key(), index: idx})
+		}
+	}
+
+	// only stops on a route can be changed between
+	served := make([]Stop, 0, len(n.routesAt))
+	for id := range n.routesAt {
+		served = append(served, n.Stops[id])
+	}
+	sort.Slice(served, func(i, j int) bool { return served[i].ID < served[j].ID })
+	for i, a := range served {
+		for _, b := range served[i+1:] {
+			meters := Distance(a.Point, b.Point)
+			if meters > MaxTransferWalk {
+				continue
+			}
+			n.footpaths[a.ID] = append(n.footpaths[a.ID], Footpath{To: b.ID, Meters: meters})
+			n.footpaths[b.ID] = append(n.footpaths[b.ID], Footpath{To: a.ID, Meters: meters})
+		}
+	}
+	return n
+}
+
+// StopsNear returns the served stops within radius metres of p, nearest first
+func (n *Network) StopsNear(p Point, radius float64) []Footpath {
+	near := []Footpath{}
+	for id := range n.routesAt {
+		if meters := Distance(p, n.Stops[id].Point); meters <= radius {
+			near = append(near, Footpath{To: id, Meters: meters})
+		}
+	}
+	sort.Slice(near, func(i, j int) bool {
+		if near[i].Meters != near[j].Meters {
+			return near[i].Meters < near[j].Meters
+		}
+		return near[i].To < near[j].To
+	})
+	return near
+}
+
+// Distance is the great circle distance between two points in metres
+func Distance(a, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// WalkTime is how long walking the distance takes, to the second
+func WalkTime(meters float64) time.Duration {
+	return time.Duration(math.Ceil(meters/WalkSpeed)) * time.Second
+}
@@ -0,0 +1,281 @@
+// backend/internal/services/planner/planner.go
+package planner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/fares"
+)
+
+const (
+	// NetworkMaxAge is how long a loaded network is used before it is
+	// reloaded, for changes made outside the route endpoints
+	NetworkMaxAge = 15 * time.Minute
+	// MaxItineraries is the most itineraries a plan returns
+	MaxItineraries = 5
+
+	// planningHorizon is how far after the departure time trips are searched
+	planningHorizon = 6 * time.Hour
+	// maxRouteDuration is how long before the departure time a trip can
+	// have left its first stop and still be caught further along
+	maxRouteDuration = 3 * time.Hour
+)
+
+type Service struct {
+	db    *sql.DB
+	fares *fares.Service
+
+	mu      sync.RWMutex
+	network *Network
+	stale   bool
+
+	// loading is held while the network is reloaded, so concurrent plans
+	// wait for one load rather than each running their own
+	loading sync.Mutex
+}
+
+func NewPlannerService(db *sql.DB, fs *fares.Service) *Service {
+	return &Service{db: db, fares: fs}
+}
+
+// Invalidate marks the network out of date after a route or its stops
+// change, the next plan reloads it
+func (s *Service) Invalidate() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.stale = true
+	s.mu.Unlock()
+}
+
+// Refresh loads the stops and routes from the database
+func (s *Service) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.stale = false
+	s.mu.Unlock()
+
+	n, err := s.load(ctx)
+	if err != nil {
+		s.Invalidate()
+		return err
+	}
+
+	s.mu.Lock()
+	s.network = n
+	s.mu.Unlock()
+	log.Printf("[LOG] journey planner network loaded: %d stops, %d routes", len(n.Stops), len(n.Routes))
+	return nil
+}
+
+// Network returns the loaded network, reloading it first when it was
+// invalidated or has aged out
+func (s *Service) Network(ctx context.Context) (*Network, error) {
+	s.mu.RLock()
+	n, stale := s.network, s.stale
+	s.mu.RUnlock()
+	if n != nil && !stale && time.Since(n.LoadedAt) < NetworkMaxAge {
+		return n, nil
+	}
+
+	s.loading.Lock()
+	defer s.loading.Unlock()
+
+	// another plan may have reloaded it while this one waited
+	s.mu.RLock()
+	n, stale = s.network, s.stale
+	s.mu.RUnlock()
+	if n != nil && !stale && time.Since(n.LoadedAt) < NetworkMaxAge {
+		return n, nil
+	}
+	if err := s.Refresh(ctx); err != nil {
+		if n != nil {
+			log.Printf("[ERROR] Failed to reload journey planner network, using the last one: %v", err)
+			return n, nil
+		}
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.network, nil
+}
+
+// Plan finds ranked door to door itineraries between two points leaving at
+// departAt or later, with the fare of each bus ride
+func (s *Service) Plan(ctx context.Context, from, to Point, departAt time.Time) ([]models.PlannedItinerary, error) {
+	n, err := s.Network(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := s.upcomingTrips(ctx, n, departAt)
+	if err != nil {
+		return nil, err
+	}
+
+	its := n.Plan(from, to, departAt, trips, MaxItineraries)
+	operators := map[string]string{}
+	for _, t := range trips {
+		operators[t.ScheduleID] = t.OperatorID
+	}
+	for i := range its {
+		its[i].Fare = 0
+		for j := range its[i].Legs {
+			leg := &its[i].Legs[j]
+			if leg.Mode != "bus" {
+				continue
+			}
+			leg.Fare = s.legFare(ctx, n, *leg, operators[leg.ScheduleID])
+			its[i].Fare += leg.Fare
+		}
+	}
+	return its, nil
+}
+
+// legFare prices one seat on a bus leg with the fare engine, or at the
+// route's base fare when the engine can't
+func (s *Service) legFare(ctx context.Context, n *Network, leg models.PlannedLeg, operatorID string) float64 {
+	items, err := s.fares.Price(ctx, s.db, fares.Trip{
+		BusID:             leg.BusID,
+		RouteID:           leg.RouteID,
+		OperatorID:        operatorID,
+		BoardingStopID:    leg.From.StopID,
+		AlightingStopID:   leg.To.StopID,
+		BoardingStopName:  leg.From.Name,
+		AlightingStopName: leg.To.Name,
+		Seats:             1,
+		At:                leg.DepartsAt,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to price planned leg on route %s: %v", leg.RouteID, err)
+		for _, r := range n.Routes {
+			if r.ID == leg.RouteID {
+				return r.BaseFare
+			}
+		}
+		return 0
+	}
+	fare := 0.0
+	for _, item := range items {
+		fare += item.Amount
+	}
+	return fare
+}
+
+// load reads every stop on a route and the routes' stops in order, for each
+// published route version and each older one upcoming trips still run
+func (s *Service) load(ctx context.Context) (*Network, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT bs.id, bs.name, bs.latitude, bs.longitude
+		FROM bus_stops bs
+		WHERE EXISTS (SELECT 1 FROM route_bus_stops rbs WHERE rbs.bus_stop_id = bs.id)
+		OR EXISTS (SELECT 1 FROM route_version_stops rvs WHERE rvs.bus_stop_id = bs.id AND rvs.is_active)
+	`)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	stops := []Stop{}
+	for rows.Next() {
+		var st Stop
+		if err := rows.Scan(&st.ID, &st.Name, &st.Lat, &st.Lng); err != nil {
+			return nil, fmt.Errorf("failed to scan bus stop: %w", err)
+		}
+		stops = append(stops, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT r.id, v.id::TEXT, r.route_name, COALESCE(r.operator_id::TEXT, ''), COALESCE(r.base_fare, 0),
+			rvs.bus_stop_id, EXTRACT(EPOCH FROM COALESCE(rvs.estimated_arrival_time, INTERVAL '0'))::BIGINT, rvs.stop_order
+		FROM route_versions v
+		JOIN bus_routes r ON r.id = v.route_id
+		JOIN route_version_stops rvs ON rvs.route_version_id = v.id AND rvs.is_active
+		WHERE v.status = 'published'
+		OR EXISTS (
+			SELECT 1 FROM schedules s
+			WHERE s.route_version_id = v.id AND s.cancelled_at IS NULL
+			AND s.departure_time > NOW() - make_interval(secs => $1)
+		)
+		UNION ALL
+		SELECT r.id, '', r.route_name, COALESCE(r.operator_id::TEXT, ''), COALESCE(r.base_fare, 0),
+			rbs.bus_stop_id, EXTRACT(EPOCH FROM COALESCE(rbs.estimated_arrival_time, INTERVAL '0'))::BIGINT, rbs.stop_order
+		FROM bus_routes r
+		JOIN route_bus_stops rbs ON rbs.route_id = r.id
+		WHERE r.current_version_id IS NULL
+		ORDER BY 1, 2, 8
+	`, maxRouteDuration.Seconds())
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []Route{}
+	for rows.Next() {
+		var r Route
+		var stopID string
+		var offset int64
+		var order int
+		if err := rows.Scan(&r.ID, &r.Version, &r.Name, &r.OperatorID, &r.BaseFare, &stopID, &offset, &order); err != nil {
+			return nil, fmt.Errorf("failed to scan route stop: %w", err)
+		}
+		if len(routes) == 0 || routes[len(routes)-1].Key() != r.Key() {
+			routes = append(routes, r)
+		}
+		last := &routes[len(routes)-1]
+		last.Stops = append(last.Stops, stopID)
+		last.Offsets = append(last.Offsets, time.Duration(offset)*time.Second)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewNetwork(stops, routes), nil
+}
+
+// upcomingTrips reads the scheduled runs of the network's routes that can be
+// caught between departAt and the planning horizon
+func (s *Service) upcomingTrips(ctx context.Context, n *Network, departAt time.Time) ([]Trip, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.bus_id, s.route_id, COALESCE(s.route_version_id::TEXT, ''), b.operator_id, s.departure_time
+		FROM schedules s
+		JOIN buses b ON b.id = s.bus_id
+		WHERE s.cancelled_at IS NULL
+		AND s.departure_time BETWEEN $1 AND $2
+		ORDER BY s.departure_time
+	`, departAt.Add(-maxRouteDuration), departAt.Add(planningHorizon))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	trips := []Trip{}
+	for rows.Next() {
+		var t Trip
+		var operatorID sql.NullString
+		if err := rows.Scan(&t.ScheduleID, &t.BusID, &t.RouteID, &t.Version, &operatorID, &t.Departure); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		r, ok := n.Routes[t.Key()]
+		if !ok {
+			continue
+		}
+		t.OperatorID = operatorID.String
+		if t.OperatorID == "" {
+			t.OperatorID = r.OperatorID
+		}
+		trips = append(trips, t)
+	}
+	return trips, rows.Err()
+}
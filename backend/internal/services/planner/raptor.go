@@ -0,0 +1,380 @@
+// backend/internal/services/planner/raptor.go
+package planner
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/booking"
+)
+
+const (
+	// MaxRides is the most buses an itinerary rides, two changes
+	MaxRides = 3
+	// searchesPerPlan is how many departures after the first the planner
+	// looks at for later options
+	searchesPerPlan = 5
+)
+
+const (
+	accessLabel = iota
+	rideLabel
+	walkLabel
+)
+
+// label is how a search round first reached a stop
+type label struct {
+	at   time.Time
+	kind int
+
+	// rides
+	trip     *Trip
+	board    string
+	boardIdx int
+	idx      int
+
+	// walks, from the origin or from another stop
+	from   string
+	meters float64
+}
+
+// timetable is the trips of each route, by departure
+type timetable map[string][]*Trip
+
+func newTimetable(n *Network, trips []Trip) timetable {
+	tt := timetable{}
+	for i := range trips {
+		t := &trips[i]
+		if _, ok := n.Routes[t.Key()]; ok {
+			tt[t.Key()] = append(tt[t.Key()], t)
+		}
+	}
+	for _, ts := range tt {
+		sort.Slice(ts, func(i, j int) bool { return ts[i].Departure.Before(ts[j].Departure) })
+	}
+	return tt
+}
+
+// Plan finds itineraries from one point to another leaving at departAt or
+// later, on the given trips. Each search is a round based (RAPTOR) scan of
+// the routes, one round per bus ridden, and keeps the fastest way there for
+// each number of rides. Later departures are searched for more options, and
+// the results ranked by arrival, then changes, then walking. Fares are not
+// filled in.
+func (n *Network) Plan(from, to Point, departAt time.Time, trips []Trip, limit int) []models.PlannedItinerary {
+	tt := newTimetable(n, trips)
+	found := []models.PlannedItinerary{}
+
+	var walk *models.PlannedItinerary
+	if meters := Distance(from, to); meters <= MaxDirectWalk {
+		w := walkOnly(from, to, departAt, meters)
+		walk = &w
+		found = append(found, w)
+	}
+
+	at := departAt
+	for i := 0; i < searchesPerPlan; i++ {
+		its := n.search(from, to, at, tt)
+		if len(its) == 0 {
+			break
+		}
+		// the next search leaves just after the earliest of these does
+		next := its[0].DepartsAt
+		for _, it := range its {
+			if walk != nil && !it.ArrivesAt.Before(walk.ArrivesAt) {
+				continue
+			}
+			found = append(found, it)
+			if it.DepartsAt.Before(next) {
+				next = it.DepartsAt
+			}
+		}
+		at = next.Add(time.Minute)
+	}
+	return Rank(found, limit)
+}
+
+// Rank orders itineraries by arrival, then changes, then walking, then the
+// latest departure, dropping those that ride the same trips as a better one
+func Rank(its []models.PlannedItinerary, limit int) []models.PlannedItinerary {
+	sort.SliceStable(its, func(i, j int) bool {
+		a, b := its[i], its[j]
+		if !a.ArrivesAt.Equal(b.ArrivesAt) {
+			return a.ArrivesAt.Before(b.ArrivesAt)
+		}
+		if a.Transfers != b.Transfers {
+			return a.Transfers < b.Transfers
+		}
+		if a.WalkMeters != b.WalkMeters {
+			return a.WalkMeters < b.WalkMeters
+		}
+		return a.DepartsAt.After(b.DepartsAt)
+	})
+
+	ranked := []models.PlannedItinerary{}
+	seen := map[string]bool{}
+	for _, it := range its {
+		key := []string{}
+		for _, leg := range it.Legs {
+			if leg.Mode == "bus" {
+				key = append(key, leg.ScheduleID+"/"+leg.From.StopID+"/"+leg.To.StopID)
+			}
+		}
+		k := strings.Join(key, ",")
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		ranked = append(ranked, it)
+		if limit > 0 && len(ranked) == limit {
+			break
+		}
+	}
+	return ranked
+}
+
+// search runs the rounds from one departure time and returns the fastest
+// itinerary for each number of buses ridden that beats those with fewer
+func (n *Network) search(from, to Point, departAt time.Time, tt timetable) []models.PlannedItinerary {
+	labels := []map[string]*label{{}}
+	best := map[string]time.Time{}
+	marked := map[string]bool{}
+
+	for _, fp := range n.StopsNear(from, MaxAccessWalk) {
+		at := departAt.Add(WalkTime(fp.Meters))
+		labels[0][fp.To] = &label{at: at, kind: accessLabel, meters: fp.Meters}
+		best[fp.To] = at
+		marked[fp.To] = true
+	}
+	egress := n.StopsNear(to, MaxAccessWalk)
+
+	// labelAt is the best way to a stop in round k or before, the latest
+	// round that improved on it
+	labelAt := func(k int, stop string) (*label, int) {
+		for ; k >= 0; k-- {
+			if l, ok := labels[k][stop]; ok {
+				return l, k
+			}
+		}
+		return nil, -1
+	}
+
+	its := []models.PlannedItinerary{}
+	var bestArrival time.Time
+	for k := 1; k <= MaxRides && len(marked) > 0; k++ {
+		labels = append(labels, map[string]*label{})
+
+		// each route is scanned from the first marked stop it calls at
+		queue := map[string]int{}
+		for stop := range marked {
+			for _, rs := range n.routesAt[stop] {
+				if idx, ok := queue[rs.route]; !ok || rs.index < idx {
+					queue[rs.route] = rs.index
+				}
+			}
+		}
+		routes := make([]string, 0, len(queue))
+		for id := range queue {
+			routes = append(routes, id)
+		}
+		sort.Strings(routes)
+
+		slack := time.Duration(0)
+		if k > 1 {
+			slack = booking.MinTransferTime
+		}
+
+		rode := []string{}
+		for _, id := range routes {
+			r := n.Routes[id]
+			trips := tt[id]
+			var cur *Trip
+			var board string
+			var boardIdx int
+			for i := queue[id]; i < len(r.Stops); i++ {
+				stop := r.Stops[i]
+				if cur != nil {
+					arr := cur.Departure.Add(r.Offsets[i])
+					if b, ok := best[stop]; !ok || arr.Before(b) {
+						if _, ok := labels[k][stop]; !ok {
+							rode = append(rode, stop)
+						}
+						labels[k][stop] = &label{at: arr, kind: rideLabel, trip: cur, board: board, boardIdx: boardIdx, idx: i}
+						best[stop] = arr
+					}
+				}
+
+				// catch an earlier trip here if the stop was reached in time
+				prev, _ := labelAt(k-1, stop)
+				if prev == nil {
+					continue
+				}
+				ready := prev.at.Add(slack)
+				t := earliestTrip(trips, r.Offsets[i], ready)
+				if t != nil && (cur == nil || t.Departure.Before(cur.Departure)) {
+					cur, board, boardIdx = t, stop, i
+				}
+			}
+		}
+
+		marked = map[string]bool{}
+		sort.Strings(rode)
+		for _, stop := range rode {
+			l := labels[k][stop]
+			if l.kind != rideLabel {
+				continue
+			}
+			marked[stop] = true
+			for _, fp := range n.footpaths[stop] {
+				at := l.at.Add(WalkTime(fp.Meters))
+				if b, ok := best[fp.To]; ok && !at.Before(b) {
+					continue
+				}
+				labels[k][fp.To] = &label{at: at, kind: walkLabel, from: stop, meters: fp.Meters}
+				best[fp.To] = at
+				marked[fp.To] = true
+			}
+		}
+
+		// the way off at the destination end that arrives first this round
+		var end *label
+		var endStop string
+		var endMeters float64
+		var arrival time.Time
+		for _, fp := range egress {
+			l, ok := labels[k][fp.To]
+			if !ok {
+				continue
+			}
+			at := l.at.Add(WalkTime(fp.Meters))
+			if end == nil || at.Before(arrival) {
+				end, endStop, endMeters, arrival = l, fp.To, fp.Meters, at
+			}
+		}
+		if end == nil || (!bestArrival.IsZero() && !arrival.Before(bestArrival)) {
+			continue
+		}
+		bestArrival = arrival
+
+		// walk the labels back to the origin
+		legs := []models.PlannedLeg{}
+		if endMeters > 0 {
+			stop := n.Stops[endStop]
+			legs = append(legs, models.PlannedLeg{
+				Mode:      "walk",
+				From:      plannedStop(stop),
+				To:        models.PlannedStop{Name: "Destination", Latitude: to.Lat, Longitude: to.Lng},
+				DepartsAt: end.at,
+				ArrivesAt: arrival,
+				Meters:    int(math.Round(endMeters)),
+			})
+		}
+		stop, round := endStop, k
+		for {
+			l := labels[round][stop]
+			switch l.kind {
+			case walkLabel:
+				prev := labels[round][l.from]
+				legs = append(legs, models.PlannedLeg{
+					Mode:      "walk",
+					From:      plannedStop(n.Stops[l.from]),
+					To:        plannedStop(n.Stops[stop]),
+					DepartsAt: prev.at,
+					ArrivesAt: l.at,
+					Meters:    int(math.Round(l.meters)),
+				})
+				stop = l.from
+				continue
+			case rideLabel:
+				r := n.Routes[l.trip.Key()]
+				legs = append(legs, models.PlannedLeg{
+					Mode:       "bus",
+					From:       plannedStop(n.Stops[l.board]),
+					To:         plannedStop(n.Stops[stop]),
+					DepartsAt:  l.trip.Departure.Add(r.Offsets[l.boardIdx]),
+					ArrivesAt:  l.at,
+					RouteID:    r.ID,
+					RouteName:  r.Name,
+					BusID:      l.trip.BusID,
+					ScheduleID: l.trip.ScheduleID,
+					Stops:      l.idx - l.boardIdx,
+				})
+				stop = l.board
+				_, round = labelAt(round-1, stop)
+				continue
+			}
+
+			// the walk from the origin, timed to reach the first bus as it leaves
+			if l.meters > 0 {
+				boards := legs[len(legs)-1].DepartsAt
+				legs = append(legs, models.PlannedLeg{
+					Mode:      "walk",
+					From:      models.PlannedStop{Name: "Origin", Latitude: from.Lat, Longitude: from.Lng},
+					To:        plannedStop(n.Stops[stop]),
+					DepartsAt: boards.Add(-WalkTime(l.meters)),
+					ArrivesAt: boards,
+					Meters:    int(math.Round(l.meters)),
+				})
+			}
+			break
+		}
+
+		for i, j := 0, len(legs)-1; i < j; i, j = i+1, j-1 {
+			legs[i], legs[j] = legs[j], legs[i]
+		}
+		its = append(its, itinerary(legs))
+	}
+	return its
+}
+
+// earliestTrip is the first trip reaching the stop at offset no earlier
+// than ready. A route's trips keep the same running times, so they reach
+// every stop in the order they depart.
+func earliestTrip(trips []*Trip, offset time.Duration, ready time.Time) *Trip {
+	i := sort.Search(len(trips), func(i int) bool {
+		return !trips[i].Departure.Add(offset).Before(ready)
+	})
+	if i == len(trips) {
+		return nil
+	}
+	return trips[i]
+}
+
+func walkOnly(from, to Point, departAt time.Time, meters float64) models.PlannedItinerary {
+	return itinerary([]models.PlannedLeg{{
+		Mode:      "walk",
+		From:      models.PlannedStop{Name: "Origin", Latitude: from.Lat, Longitude: from.Lng},
+		To:        models.PlannedStop{Name: "Destination", Latitude: to.Lat, Longitude: to.Lng},
+		DepartsAt: departAt,
+		ArrivesAt: departAt.Add(WalkTime(meters)),
+		Meters:    int(math.Round(meters)),
+	}})
+}
+
+// itinerary sums up the legs of a trip
+func itinerary(legs []models.PlannedLeg) models.PlannedItinerary {
+	it := models.PlannedItinerary{
+		Legs:      legs,
+		DepartsAt: legs[0].DepartsAt,
+		ArrivesAt: legs[len(legs)-1].ArrivesAt,
+	}
+	rides := 0
+	for _, leg := range legs {
+		if leg.Mode == "bus" {
+			rides++
+		}
+		it.WalkMeters += leg.Meters
+	}
+	if rides > 1 {
+		it.Transfers = rides - 1
+	}
+	it.DurationMin = int(math.Ceil(it.ArrivesAt.Sub(it.DepartsAt).Minutes()))
+	return it
+}
+
+func plannedStop(s Stop) models.PlannedStop {
+	return models.PlannedStop{StopID: s.ID, Name: s.Name, Latitude: s.Lat, Longitude: s.Lng}
+}
@@ -0,0 +1,266 @@
+// backend/internal/services/promotions/codes.go
+package promotions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// PromoInput describes a promo code to create
+type PromoInput struct {
+	OperatorID        string // empty for platform promos
+	CreatedBy         string
+	Code              string
+	Description       string
+	DiscountType      string
+	DiscountValue     float64
+	MaxDiscount       *float64
+	MinFare           *float64
+	StartsAt          *time.Time
+	EndsAt            *time.Time
+	MaxRedemptions    *int
+	MaxPerUser        *int
+	FirstRideOnly     bool
+	StacksWithLoyalty bool
+	RouteIDs          []string
+}
+
+var codePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,40}$`)
+
+// ListPromos returns an operator's promos, or platform promos when
+// operatorID is empty, including ended ones
+func (s *Service) ListPromos(ctx context.Context, operatorID string) ([]models.PromoCode, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+promoColumns+`
+		FROM promo_codes p
+		WHERE COALESCE(p.operator_id::TEXT, '') = $1
+		ORDER BY p.created_at DESC
+	`, operatorID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	promos := []models.PromoCode{}
+	for rows.Next() {
+		var p models.PromoCode
+		if err := scanPromo(rows, &p); err != nil {
+			log.Printf("[ERROR] Failed to scan promo code: %v", err)
+			return nil, fmt.Errorf("failed to scan promo code: %w", err)
+		}
+		promos = append(promos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range promos {
+		if promos[i].RouteIDs, err = s.promoRoutes(ctx, promos[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return promos, nil
+}
+
+func (s *Service) promoRoutes(ctx context.Context, promoID string) ([]string, error) {
+	var routeIDs pq.StringArray
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(array_agg(route_id::TEXT), '{}') FROM promo_code_routes WHERE promo_id = $1
+	`, promoID).Scan(&routeIDs)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return []string(routeIDs), nil
+}
+
+// CreatePromo adds a promo code
+func (s *Service) CreatePromo(ctx context.Context, in PromoInput) (*models.PromoCode, error) {
+	if err := validatePromo(&in); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, routeID := range in.RouteIDs {
+		// operators can only scope a promo to their own routes
+		var routeOperator sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT operator_id FROM bus_routes WHERE id = $1`, routeID).Scan(&routeOperator)
+		if err == sql.ErrNoRows || (in.OperatorID != "" && routeOperator.Valid && routeOperator.String != in.OperatorID) {
+			return nil, invalidPromo(fmt.Sprintf("route %s not found", routeID))
+		}
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	var operatorID, description, createdBy interface{}
+	if in.OperatorID != "" {
+		operatorID = in.OperatorID
+	}
+	if in.Description != "" {
+		description = in.Description
+	}
+	if in.CreatedBy != "" {
+		createdBy = in.CreatedBy
+	}
+	startsAt := time.Now()
+	if in.StartsAt != nil {
+		startsAt = *in.StartsAt
+	}
+
+	promoID := uuid.New().String()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO promo_codes (
+			id, operator_id, code, description, discount_type, discount_value, max_discount, min_fare,
+			starts_at, ends_at, max_redemptions, max_per_user, first_ride_only, stacks_with_loyalty, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`, promoID, operatorID, in.Code, description, in.DiscountType, in.DiscountValue, in.MaxDiscount, in.MinFare,
+		startsAt, in.EndsAt, in.MaxRedemptions, in.MaxPerUser, in.FirstRideOnly, in.StacksWithLoyalty, createdBy)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrPromoCodeTaken
+		}
+		log.Printf("[ERROR] Failed to create promo code: %v", err)
+		return nil, fmt.Errorf("failed to create promo code: %w", err)
+	}
+
+	for _, routeID := range in.RouteIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO promo_code_routes (promo_id, route_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, promoID, routeID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to scope promo code: %v", err)
+			return nil, fmt.Errorf("failed to scope promo code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Created promo code %s (%s)", promoID, in.Code)
+	return s.managedPromo(ctx, promoID, in.OperatorID)
+}
+
+// SetPromoActive pauses a promo or resumes it. Bookings already made keep
+// their discount.
+func (s *Service) SetPromoActive(ctx context.Context, promoID, operatorID string, active bool) (*models.PromoCode, error) {
+	if _, err := s.managedPromo(ctx, promoID, operatorID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE promo_codes SET active = $1, updated_at = NOW() WHERE id = $2
+	`, active, promoID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update promo code: %v", err)
+		return nil, fmt.Errorf("failed to update promo code: %w", err)
+	}
+
+	return s.managedPromo(ctx, promoID, operatorID)
+}
+
+// managedPromo loads a promo owned by the operator, or a platform promo when
+// operatorID is empty
+func (s *Service) managedPromo(ctx context.Context, promoID, operatorID string) (*models.PromoCode, error) {
+	if _, err := uuid.Parse(promoID); err != nil {
+		return nil, errors.ErrPromoNotFound
+	}
+
+	var p models.PromoCode
+	err := scanPromo(s.db.QueryRowContext(ctx, `
+		SELECT `+promoColumns+`
+		FROM promo_codes p
+		WHERE p.id = $1 AND COALESCE(p.operator_id::TEXT, '') = $2
+	`, promoID, operatorID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPromoNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.RouteIDs, err = s.promoRoutes(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func validatePromo(in *PromoInput) error {
+	in.Code = NormalizeCode(in.Code)
+	if !codePattern.MatchString(in.Code) {
+		return invalidPromo("code must be 3 to 40 letters, digits, dashes or underscores")
+	}
+
+	switch in.DiscountType {
+	case "percent":
+		if in.DiscountValue <= 0 || in.DiscountValue > 100 {
+			return invalidPromo("a percent discount must be above 0 and at most 100")
+		}
+	case "fixed":
+		if in.DiscountValue <= 0 {
+			return invalidPromo("discount_value must be positive")
+		}
+		if in.MaxDiscount != nil {
+			return invalidPromo("max_discount only applies to percent discounts")
+		}
+	default:
+		return invalidPromo("discount_type must be percent or fixed")
+	}
+
+	if in.MaxDiscount != nil && *in.MaxDiscount <= 0 {
+		return invalidPromo("max_discount must be positive")
+	}
+	if in.MinFare != nil && *in.MinFare < 0 {
+		return invalidPromo("min_fare cannot be negative")
+	}
+	if in.MaxRedemptions != nil && *in.MaxRedemptions <= 0 {
+		return invalidPromo("max_redemptions must be positive")
+	}
+	if in.MaxPerUser != nil && *in.MaxPerUser <= 0 {
+		return invalidPromo("max_per_user must be positive")
+	}
+
+	if in.EndsAt != nil {
+		if !in.EndsAt.After(time.Now()) {
+			return invalidPromo("ends_at must be in the future")
+		}
+		if in.StartsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+			return invalidPromo("ends_at must be after starts_at")
+		}
+	}
+
+	for _, routeID := range in.RouteIDs {
+		if _, err := uuid.Parse(routeID); err != nil {
+			return invalidPromo(fmt.Sprintf("route %s not found", routeID))
+		}
+	}
+	return nil
+}
+
+func invalidPromo(message string) error {
+	return &errors.PromoError{
+		Code:    "INVALID_PROMO",
+		Message: message,
+	}
+}
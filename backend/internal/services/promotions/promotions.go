@@ -0,0 +1,332 @@
+// backend/internal/services/promotions/promotions.go
+package promotions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// reservationTimeout is how long a promo use can stay reserved without
+// being tied to a booking, far longer than any booking takes to be paid for.
+// Older reservations were left by a request that died and are handed back.
+const reservationTimeout = 30 * time.Minute
+
+// Service runs promo codes, e.g. "first ride free" or "50% off on route X
+// this weekend"
+type Service struct {
+	db *sql.DB
+}
+
+func NewPromotionService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Promotion is a promo code applied to one booking's fare
+type Promotion struct {
+	PromoID           string
+	Code              string
+	StacksWithLoyalty bool
+	LineItem          models.BookingLineItem
+	RedemptionID      string // set by Reserve
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+const promoColumns = `p.id, p.operator_id, p.code, p.description, p.discount_type, p.discount_value,
+	p.max_discount, p.min_fare, p.starts_at, p.ends_at, p.max_redemptions, p.max_per_user,
+	p.redemption_count, p.first_ride_only, p.stacks_with_loyalty, p.active, p.created_at`
+
+func scanPromo(row scanner, p *models.PromoCode) error {
+	return row.Scan(
+		&p.ID, &p.OperatorID, &p.Code, &p.Description, &p.DiscountType, &p.DiscountValue,
+		&p.MaxDiscount, &p.MinFare, &p.StartsAt, &p.EndsAt, &p.MaxRedemptions, &p.MaxPerUser,
+		&p.RedemptionCount, &p.FirstRideOnly, &p.StacksWithLoyalty, &p.Active, &p.CreatedAt,
+	)
+}
+
+// Apply checks a code against a booking on the bus and prices its discount
+// off fare. Nothing is used up until Reserve, which checks the caps again
+// under the promo's row lock.
+func (s *Service) Apply(ctx context.Context, tx *sql.Tx, code, userID, busID string, fare float64) (*Promotion, error) {
+	var p models.PromoCode
+	err := scanPromo(tx.QueryRowContext(ctx, `
+		SELECT `+promoColumns+`
+		FROM promo_codes p
+		JOIN buses b ON b.id = $2
+		WHERE p.code = $1
+		AND p.active = TRUE
+		AND p.starts_at <= NOW()
+		AND (p.ends_at IS NULL OR p.ends_at > NOW())
+		AND (p.operator_id IS NULL OR p.operator_id = b.operator_id)
+		AND (
+			NOT EXISTS (SELECT 1 FROM promo_code_routes r WHERE r.promo_id = p.id)
+			OR EXISTS (
+				SELECT 1 FROM promo_code_routes r
+				JOIN bus_route_assignments a ON a.route_id = r.route_id
+				WHERE r.promo_id = p.id AND a.bus_id = b.id AND a.status = 'active'
+			)
+		)
+	`, NormalizeCode(code), busID), &p)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrPromoInvalid
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if p.MinFare != nil && fare < *p.MinFare {
+		return nil, &errors.BookingError{
+			Code:    "PROMO_INVALID",
+			Message: fmt.Sprintf("Promo code %s needs a fare of at least %.2f", p.Code, *p.MinFare),
+		}
+	}
+	if p.MaxRedemptions != nil && p.RedemptionCount >= *p.MaxRedemptions {
+		return nil, errors.ErrPromoExhausted
+	}
+
+	if err := checkPerUser(ctx, tx, p.ID, userID, p.MaxPerUser, p.FirstRideOnly); err != nil {
+		return nil, err
+	}
+
+	if p.FirstRideOnly {
+		var rides int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM bookings WHERE user_id = $1 AND status IN ('confirmed', 'completed')
+		`, userID).Scan(&rides)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if rides > 0 {
+			return nil, &errors.BookingError{
+				Code:    "PROMO_INVALID",
+				Message: fmt.Sprintf("Promo code %s is only for your first ride", p.Code),
+			}
+		}
+	}
+
+	discount := Discount(p, fare)
+	return &Promotion{
+		PromoID:           p.ID,
+		Code:              p.Code,
+		StacksWithLoyalty: p.StacksWithLoyalty,
+		LineItem: models.BookingLineItem{
+			Type:        "promo_code",
+			Description: describe(p),
+			Amount:      -discount,
+		},
+	}, nil
+}
+
+// Reserve uses the promo up before the booking is paid for, so a rider over
+// a cap is turned away before being charged. It runs in its own short
+// transaction, so the promo row is only locked while the caps are checked and
+// bookings using a popular code don't queue up behind each other's payments.
+// The use is tied to the booking with Record once it is written, or handed
+// back with Release.
+func (s *Service) Reserve(ctx context.Context, promo *Promotion, userID string) error {
+	if promo == nil {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// hand back reservations left by requests that died, under the row lock
+	_, err = tx.ExecContext(ctx, `
+		WITH abandoned AS (
+			DELETE FROM promo_redemptions
+			WHERE promo_id = $1 AND booking_id IS NULL AND created_at < $2
+			RETURNING id
+		)
+		UPDATE promo_codes
+		SET redemption_count = GREATEST(redemption_count - (SELECT COUNT(*) FROM abandoned), 0)
+		WHERE id = $1
+	`, promo.PromoID, time.Now().Add(-reservationTimeout))
+	if err != nil {
+		log.Printf("[ERROR] Failed to reserve promo code: %v", err)
+		return fmt.Errorf("failed to reserve promo code: %w", err)
+	}
+
+	var maxPerUser sql.NullInt64
+	var firstRideOnly bool
+	err = tx.QueryRowContext(ctx, `
+		UPDATE promo_codes
+		SET redemption_count = redemption_count + 1, updated_at = NOW()
+		WHERE id = $1 AND (max_redemptions IS NULL OR redemption_count < max_redemptions)
+		RETURNING max_per_user, first_ride_only
+	`, promo.PromoID).Scan(&maxPerUser, &firstRideOnly)
+	if err == sql.ErrNoRows {
+		return errors.ErrPromoExhausted
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to reserve promo code: %v", err)
+		return fmt.Errorf("failed to reserve promo code: %w", err)
+	}
+
+	var max *int
+	if maxPerUser.Valid {
+		n := int(maxPerUser.Int64)
+		max = &n
+	}
+	if err := checkPerUser(ctx, tx, promo.PromoID, userID, max, firstRideOnly); err != nil {
+		return err
+	}
+
+	var redemptionID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO promo_redemptions (promo_id, user_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, promo.PromoID, userID, -promo.LineItem.Amount).Scan(&redemptionID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to reserve promo code: %v", err)
+		return fmt.Errorf("failed to reserve promo code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	promo.RedemptionID = redemptionID
+	return nil
+}
+
+// Record ties a promo use taken with Reserve to the booking
+func (s *Service) Record(ctx context.Context, tx *sql.Tx, promo *Promotion, bookingID string) error {
+	if promo == nil {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE promo_redemptions SET booking_id = $1
+		WHERE id = $2 AND booking_id IS NULL AND reversed_at IS NULL
+	`, bookingID, promo.RedemptionID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record promo redemption: %v", err)
+		return fmt.Errorf("failed to record promo redemption: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		log.Printf("[ERROR] Promo reservation %s is gone", promo.RedemptionID)
+		return fmt.Errorf("promo reservation %s is gone", promo.RedemptionID)
+	}
+	return nil
+}
+
+// Release hands back a promo use taken with Reserve that the booking ended
+// up not needing, or that failed. Uses already tied to a booking are left
+// alone, so it is safe to call more than once.
+func (s *Service) Release(ctx context.Context, promo *Promotion) error {
+	if promo == nil || promo.RedemptionID == "" {
+		return nil
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		WITH released AS (
+			DELETE FROM promo_redemptions
+			WHERE id = $1 AND booking_id IS NULL
+			RETURNING promo_id
+		)
+		UPDATE promo_codes SET redemption_count = redemption_count - 1, updated_at = NOW()
+		WHERE id IN (SELECT promo_id FROM released) AND redemption_count > 0
+	`, promo.RedemptionID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to release promo redemption: %v", err)
+		return fmt.Errorf("failed to release promo redemption: %w", err)
+	}
+	return nil
+}
+
+// checkPerUser fails with ErrPromoUsed once the rider has used the promo as
+// often as it allows, a first ride promo only once
+func checkPerUser(ctx context.Context, tx *sql.Tx, promoID, userID string, maxPerUser *int, firstRideOnly bool) error {
+	if firstRideOnly {
+		// two first rides booked at once both look like the first
+		one := 1
+		maxPerUser = &one
+	}
+	if maxPerUser == nil {
+		return nil
+	}
+
+	var used int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM promo_redemptions
+		WHERE promo_id = $1 AND user_id = $2 AND reversed_at IS NULL
+	`, promoID, userID).Scan(&used)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	if used >= *maxPerUser {
+		return errors.ErrPromoUsed
+	}
+	return nil
+}
+
+// Reverse frees the promo use of a cancelled booking
+func (s *Service) Reverse(ctx context.Context, tx *sql.Tx, bookingID string) error {
+	var promoID string
+	err := tx.QueryRowContext(ctx, `
+		UPDATE promo_redemptions SET reversed_at = NOW()
+		WHERE booking_id = $1 AND reversed_at IS NULL
+		RETURNING promo_id
+	`, bookingID).Scan(&promoID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to reverse promo redemption: %v", err)
+		return fmt.Errorf("failed to reverse promo redemption: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE promo_codes SET redemption_count = redemption_count - 1, updated_at = NOW()
+		WHERE id = $1 AND redemption_count > 0
+	`, promoID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to reverse promo redemption: %v", err)
+		return fmt.Errorf("failed to reverse promo redemption: %w", err)
+	}
+	return nil
+}
+
+// Discount is what the promo takes off fare, never more than the fare
+func Discount(p models.PromoCode, fare float64) float64 {
+	discount := p.DiscountValue
+	if p.DiscountType == "percent" {
+		discount = fare * p.DiscountValue / 100
+		if p.MaxDiscount != nil && discount > *p.MaxDiscount {
+			discount = *p.MaxDiscount
+		}
+	}
+	if discount > fare {
+		discount = fare
+	}
+	return math.Round(discount*100) / 100
+}
+
+// NormalizeCode is how codes are stored and matched
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func describe(p models.PromoCode) string {
+	if p.DiscountType == "percent" {
+		return fmt.Sprintf("Promo %s (%g%% off)", p.Code, p.DiscountValue)
+	}
+	return fmt.Sprintf("Promo %s (%.2f off)", p.Code, p.DiscountValue)
+}
@@ -0,0 +1,190 @@
+// backend/internal/services/ratelimit/lockout.go
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	lockoutThreshold = 5              // failures before the first lock
+	lockoutBase      = time.Minute    // first lock, doubles after every further failure
+	lockoutMax       = time.Hour      // cap on a single lock
+	failureWindow    = 24 * time.Hour // failures older than this are forgotten
+)
+
+// LockoutStore counts failed attempts per key
+type LockoutStore interface {
+	// LockedFor returns how long the key is still locked, zero if it is not
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// RecordFailure counts a failure and returns the resulting lock, if any
+	RecordFailure(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// LockDuration is the lock applied after the given number of failures
+func LockDuration(failures int) time.Duration {
+	if failures < lockoutThreshold {
+		return 0
+	}
+
+	lock := lockoutBase
+	for i := lockoutThreshold; i < failures; i++ {
+		lock *= 2
+		if lock >= lockoutMax {
+			return lockoutMax
+		}
+	}
+	return lock
+}
+
+type failureRecord struct {
+	failures    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// MemoryLockoutStore keeps failure counters in process
+type MemoryLockoutStore struct {
+	mu      sync.Mutex
+	records map[string]*failureRecord
+}
+
+func NewMemoryLockoutStore() *MemoryLockoutStore {
+	store := &MemoryLockoutStore{
+		records: make(map[string]*failureRecord),
+	}
+
+	go store.cleanOldRecords()
+
+	return store
+}
+
+func (s *MemoryLockoutStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.records[key]
+	if !exists {
+		return 0, nil
+	}
+	if remaining := time.Until(record.lockedUntil); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryLockoutStore) RecordFailure(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	record, exists := s.records[key]
+	if !exists || now.Sub(record.lastFailure) > failureWindow {
+		record = &failureRecord{}
+		s.records[key] = record
+	}
+
+	record.failures++
+	record.lastFailure = now
+
+	lock := LockDuration(record.failures)
+	if lock > 0 {
+		record.lockedUntil = now.Add(lock)
+	}
+	return lock, nil
+}
+
+func (s *MemoryLockoutStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.records, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryLockoutStore) cleanOldRecords() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for key, record := range s.records {
+			if time.Since(record.lastFailure) > failureWindow && time.Now().After(record.lockedUntil) {
+				delete(s.records, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// PostgresLockoutStore keeps failure counters in the login_failures table
+type PostgresLockoutStore struct {
+	db *sql.DB
+}
+
+func NewPostgresLockoutStore(db *sql.DB) *PostgresLockoutStore {
+	return &PostgresLockoutStore{db: db}
+}
+
+func (s *PostgresLockoutStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	var remaining sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXTRACT(EPOCH FROM (locked_until - NOW()))
+		FROM login_failures
+		WHERE key = $1 AND locked_until > NOW()
+	`, key).Scan(&remaining)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+	return secondsToDuration(remaining.Float64), nil
+}
+
+func (s *PostgresLockoutStore) RecordFailure(ctx context.Context, key string) (time.Duration, error) {
+	var failures int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO login_failures (key, failures, last_failure_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_failures.last_failure_at < NOW() - make_interval(secs => $2) THEN 1
+				ELSE login_failures.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING failures
+	`, key, failureWindow.Seconds()).Scan(&failures)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	lock := LockDuration(failures)
+	if lock > 0 {
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE login_failures
+			SET locked_until = NOW() + make_interval(secs => $1)
+			WHERE key = $2
+		`, lock.Seconds(), key)
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return 0, fmt.Errorf("database error: %w", err)
+		}
+	}
+	return lock, nil
+}
+
+func (s *PostgresLockoutStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_failures WHERE key = $1`, key)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// backend/internal/services/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Requests tokens are added every Per, up to Burst
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// Enabled reports whether the limit is configured
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed
+}
+
+// Store takes tokens from buckets, either in memory or in postgres
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// TakeToken refills a bucket holding tokens for the time elapsed since it
+// was last used, then spends one token if there is one
+func TakeToken(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	capacity := limit.capacity()
+	tokens = math.Min(capacity, tokens+elapsed.Seconds()*limit.rate())
+
+	result := Result{Limit: int(capacity)}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.rate())
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = secondsToDuration((capacity - tokens) / limit.rate())
+	return tokens, result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps buckets in process, for a single node
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+
+	// drop idle buckets
+	go store.cleanIdleBuckets()
+
+	return store
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: limit.capacity(), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := TakeToken(b.tokens, now.Sub(b.updatedAt), limit)
+	b.tokens = tokens
+	b.updatedAt = now
+
+	return result, nil
+}
+
+func (s *MemoryStore) cleanIdleBuckets() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			if time.Since(b.updatedAt) > time.Hour {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so replicas share them
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (key) DO NOTHING
+	`, key, limit.capacity())
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return Result{}, fmt.Errorf("database error: %w", err)
+	}
+
+	var tokens float64
+	var elapsed float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT tokens, EXTRACT(EPOCH FROM (NOW() - updated_at))
+		FROM rate_limit_buckets
+		WHERE key = $1
+		FOR UPDATE
+	`, key).Scan(&tokens, &elapsed)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return Result{}, fmt.Errorf("database error: %w", err)
+	}
+
+	tokens, result := TakeToken(tokens, secondsToDuration(elapsed), limit)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets
+		SET tokens = $1, updated_at = NOW()
+		WHERE key = $2
+	`, tokens, key)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return Result{}, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,145 @@
+// backend/internal/services/routes/routes.go
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/planner"
+)
+
+type Notifier interface {
+	Send(userID string, msgType models.NotificationType, message string) error
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type Service struct {
+	db       *sql.DB
+	notifier Notifier
+	audit    *audit.Service
+	planner  *planner.Service // reloads its network when routes change, may be nil
+}
+
+func NewRouteService(db *sql.DB, notifier Notifier, auditService *audit.Service, ps *planner.Service) *Service {
+	return &Service{db: db, notifier: notifier, audit: auditService, planner: ps}
+}
+
+// RouteInput is the details of a route that can be changed without a new
+// version, empty fields are left as they are
+type RouteInput struct {
+	RouteName   string
+	Description *string
+	Origin      string
+	Destination string
+}
+
+// UpdateRoute changes one of the operator's routes' name, description, origin
+// or destination
+func (s *Service) UpdateRoute(ctx context.Context, routeID string, in RouteInput, actor audit.Entry) (*models.BusRoute, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route update: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := ownedRoute(ctx, tx, routeID, actor.OperatorID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	if name := strings.TrimSpace(in.RouteName); name != "" {
+		after.RouteName = name
+	}
+	if in.Description != nil {
+		after.Description = strings.TrimSpace(*in.Description)
+	}
+	if origin := strings.TrimSpace(in.Origin); origin != "" {
+		after.Origin = origin
+	}
+	if destination := strings.TrimSpace(in.Destination); destination != "" {
+		after.Destination = destination
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE bus_routes
+		SET route_name = $1, description = $2, origin = $3, destination = $4, updated_at = NOW()
+		WHERE id = $5
+		RETURNING updated_at
+	`, after.RouteName, after.Description, after.Origin, after.Destination, routeID).Scan(&after.UpdatedAt)
+	if err != nil {
+		// unique_violation, another route has the name
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrRouteNameTaken
+		}
+		log.Printf("[ERROR] Failed to update route: %v", err)
+		return nil, fmt.Errorf("failed to update route: %w", err)
+	}
+
+	entry := actor
+	entry.Action = "route.updated"
+	entry.EntityType = "route"
+	entry.EntityID = routeID
+	entry.Before = before
+	entry.After = after
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit route update: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &after, nil
+}
+
+// ownedRoute loads a route that belongs to the operator, or a legacy route
+// without an owner, locking it when forUpdate is set
+func ownedRoute(ctx context.Context, q queryer, routeID, operatorID string, forUpdate bool) (*models.BusRoute, error) {
+	if _, err := uuid.Parse(routeID); err != nil {
+		return nil, errors.ErrRouteNotFound
+	}
+
+	query := `
+		SELECT id, route_name, COALESCE(description, ''), COALESCE(origin, ''), COALESCE(destination, ''), created_at, updated_at
+		FROM bus_routes
+		WHERE id = $1 AND (operator_id::TEXT = $2 OR operator_id IS NULL)
+	`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var r models.BusRoute
+	err := q.QueryRowContext(ctx, query, routeID, operatorID).Scan(&r.ID, &r.RouteName, &r.Description, &r.Origin,
+		&r.Destination, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrRouteNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &r, nil
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,401 @@
+// backend/internal/services/routes/shapes.go
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/planner"
+)
+
+const (
+	// MaxShapePoints is the most positions a route shape can have
+	MaxShapePoints = 10000
+	// MinTracePoints is the fewest cleaned locations a shape is built from
+	MinTracePoints = 10
+	// MinTraceSpacing is how far apart in metres kept trace points are, GPS
+	// jitter while standing is dropped
+	MinTraceSpacing = 10.0
+	// MaxTraceSpeed is the fastest in metres per second a bus is taken to
+	// move between two fixes, faster jumps are GPS errors
+	MaxTraceSpeed = 35.0
+
+	// trips are traced from a little before departure until a little after
+	// their last stop, and never for longer than maxTraceDuration
+	traceGrace       = 30 * time.Minute
+	maxTraceDuration = 4 * time.Hour
+)
+
+// TracePoint is a recorded bus location
+type TracePoint struct {
+	planner.Point
+	At time.Time
+}
+
+// geoJSON is the parts of a GeoJSON object a route shape is read from
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates [][]float64     `json:"coordinates,omitempty"`
+	Geometry    json.RawMessage `json:"geometry,omitempty"`
+	Features    []struct {
+		Geometry json.RawMessage `json:"geometry"`
+	} `json:"features,omitempty"`
+}
+
+// ParseLineString reads a route shape from a GeoJSON LineString, a Feature
+// holding one, or a FeatureCollection of a single such Feature
+func ParseLineString(raw []byte) ([]planner.Point, error) {
+	var g geoJSON
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return nil, invalidShape("The shape must be GeoJSON")
+	}
+
+	switch g.Type {
+	case "FeatureCollection":
+		if len(g.Features) != 1 {
+			return nil, invalidShape("A FeatureCollection shape must hold exactly one feature")
+		}
+		return ParseLineString(g.Features[0].Geometry)
+	case "Feature":
+		if len(g.Geometry) == 0 {
+			return nil, invalidShape("The feature has no geometry")
+		}
+		return ParseLineString(g.Geometry)
+	case "LineString":
+	default:
+		return nil, invalidShape("The shape must be a LineString")
+	}
+
+	if len(g.Coordinates) < 2 {
+		return nil, invalidShape("A shape needs at least two positions")
+	}
+	if len(g.Coordinates) > MaxShapePoints {
+		return nil, invalidShape(fmt.Sprintf("A shape can have at most %d positions", MaxShapePoints))
+	}
+	points := make([]planner.Point, len(g.Coordinates))
+	for i, c := range g.Coordinates {
+		// GeoJSON positions are longitude first, an altitude may follow
+		if len(c) < 2 || c[0] < -180 || c[0] > 180 || c[1] < -90 || c[1] > 90 {
+			return nil, invalidShape(fmt.Sprintf("Position %d is not a longitude and latitude", i+1))
+		}
+		points[i] = planner.Point{Lat: c[1], Lng: c[0]}
+	}
+	return points, nil
+}
+
+// LineString encodes points as a GeoJSON LineString
+func LineString(points []planner.Point) json.RawMessage {
+	coordinates := make([][]float64, len(points))
+	for i, p := range points {
+		coordinates[i] = []float64{p.Lng, p.Lat}
+	}
+	raw, _ := json.Marshal(geoJSON{Type: "LineString", Coordinates: coordinates})
+	return raw
+}
+
+// CleanTrace turns recorded locations, in time order, into a path: fixes
+// that would have the bus move impossibly fast are dropped, as are fixes
+// within MinTraceSpacing of the last one kept
+func CleanTrace(trace []TracePoint) []planner.Point {
+	points := []planner.Point{}
+	var last TracePoint
+	for i, p := range trace {
+		if i > 0 {
+			meters := planner.Distance(last.Point, p.Point)
+			if meters < MinTraceSpacing {
+				continue
+			}
+			if seconds := p.At.Sub(last.At).Seconds(); seconds <= 0 || meters/seconds > MaxTraceSpeed {
+				continue
+			}
+		}
+		points = append(points, p.Point)
+		last = p
+	}
+	return points
+}
+
+// TrimTrace cuts a path down to the part between the first and last stops
+// of the route: from where it last comes closest to the first stop, before
+// the bus pulls away, to where it first comes closest to the last stop
+func TrimTrace(points []planner.Point, first, last planner.Point) []planner.Point {
+	if len(points) == 0 {
+		return points
+	}
+
+	start := 0
+	best := math.Inf(1)
+	for i, p := range points {
+		if d := planner.Distance(p, first); d <= best {
+			start, best = i, d
+		}
+	}
+	end := start
+	best = math.Inf(1)
+	for i := start; i < len(points); i++ {
+		if d := planner.Distance(points[i], last); d < best {
+			end, best = i, d
+		}
+	}
+	return points[start : end+1]
+}
+
+// StopSpacing is the distance along the shape from each stop to the one
+// before it, nil for the first stop and where either isn't on the shape or
+// the stop lies behind the one before it
+func StopSpacing(along []*float64) []*float64 {
+	spacing := make([]*float64, len(along))
+	for i := 1; i < len(along); i++ {
+		if along[i] == nil || along[i-1] == nil || *along[i] < *along[i-1] {
+			continue
+		}
+		d := math.Round(*along[i] - *along[i-1])
+		spacing[i] = &d
+	}
+	return spacing
+}
+
+// Shape returns a route's shape, nil when it has none
+func Shape(ctx context.Context, q queryer, routeID string) (*models.RouteShape, error) {
+	var shape models.RouteShape
+	var geometry string
+	var scheduleID, createdBy sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT route_id, ST_AsGeoJSON(geometry), length_meters, source, source_schedule_id, created_by, created_at, updated_at
+		FROM route_shapes
+		WHERE route_id = $1
+	`, routeID).Scan(&shape.RouteID, &geometry, &shape.LengthMeters, &shape.Source, &scheduleID, &createdBy,
+		&shape.CreatedAt, &shape.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	shape.Geometry = json.RawMessage(geometry)
+	if scheduleID.Valid {
+		shape.SourceScheduleID = &scheduleID.String
+	}
+	if createdBy.Valid {
+		shape.CreatedBy = &createdBy.String
+	}
+	return &shape, nil
+}
+
+// SetShape sets a route's shape from GeoJSON
+func (s *Service) SetShape(ctx context.Context, routeID string, raw []byte, actor audit.Entry) (*models.RouteShape, error) {
+	points, err := ParseLineString(raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveShape(ctx, routeID, points, "geojson", "", actor)
+}
+
+// RecordShape builds a route's shape from the locations the bus recorded on
+// a reference trip of the route that has already departed
+func (s *Service) RecordShape(ctx context.Context, routeID, scheduleID string, actor audit.Entry) (*models.RouteShape, error) {
+	if _, err := ownedRoute(ctx, s.db, routeID, actor.OperatorID, false); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		return nil, errors.ErrReferenceTripNotFound
+	}
+
+	var busID string
+	var departure time.Time
+	var duration int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT s.bus_id, s.departure_time,
+			EXTRACT(EPOCH FROM COALESCE(MAX(ss.estimated_arrival_time), INTERVAL '0'))::BIGINT
+		FROM schedules s
+		LEFT JOIN schedule_stops ss ON ss.schedule_id = s.id
+		WHERE s.id = $1 AND s.route_id = $2 AND s.departure_time < NOW()
+		GROUP BY s.id
+	`, scheduleID, routeID).Scan(&busID, &departure, &duration)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrReferenceTripNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	until := departure.Add(time.Duration(duration)*time.Second + traceGrace)
+	if limit := departure.Add(maxTraceDuration); duration == 0 || until.After(limit) {
+		until = limit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT latitude, longitude, timestamp
+		FROM bus_locations
+		WHERE bus_id = $1 AND timestamp BETWEEN $2 AND $3
+		ORDER BY timestamp
+	`, busID, departure.Add(-traceGrace), until)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	trace := []TracePoint{}
+	for rows.Next() {
+		var p TracePoint
+		if err := rows.Scan(&p.Lat, &p.Lng, &p.At); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan bus location: %w", err)
+		}
+		trace = append(trace, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	points := CleanTrace(trace)
+	var first, last planner.Point
+	err = s.db.QueryRowContext(ctx, `
+		SELECT f.latitude, f.longitude, l.latitude, l.longitude
+		FROM (SELECT bus_stop_id FROM schedule_stops WHERE schedule_id = $1 ORDER BY stop_order LIMIT 1) fs
+		JOIN bus_stops f ON f.id = fs.bus_stop_id,
+		(SELECT bus_stop_id FROM schedule_stops WHERE schedule_id = $1 ORDER BY stop_order DESC LIMIT 1) ls
+		JOIN bus_stops l ON l.id = ls.bus_stop_id
+	`, scheduleID).Scan(&first.Lat, &first.Lng, &last.Lat, &last.Lng)
+	switch {
+	case err == nil:
+		points = TrimTrace(points, first, last)
+	case err != sql.ErrNoRows:
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if len(points) < MinTracePoints {
+		return nil, errors.ErrTraceTooShort
+	}
+	if len(points) > MaxShapePoints {
+		points = thin(points, MaxShapePoints)
+	}
+	return s.saveShape(ctx, routeID, points, "trace", scheduleID, actor)
+}
+
+// DeleteShape removes a route's shape
+func (s *Service) DeleteShape(ctx context.Context, routeID string, actor audit.Entry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route shape: %v", err)
+		return fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedRoute(ctx, tx, routeID, actor.OperatorID, true); err != nil {
+		return err
+	}
+	var source string
+	var length float64
+	err = tx.QueryRowContext(ctx, `DELETE FROM route_shapes WHERE route_id = $1 RETURNING source, length_meters`, routeID).
+		Scan(&source, &length)
+	if err == sql.ErrNoRows {
+		return errors.ErrRouteShapeNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete route shape: %v", err)
+		return fmt.Errorf("failed to delete route shape: %w", err)
+	}
+
+	entry := actor
+	entry.Action = "route.shape_deleted"
+	entry.EntityType = "route"
+	entry.EntityID = routeID
+	entry.Before = map[string]interface{}{"source": source, "length_meters": length}
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit route shape deletion: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// saveShape stores a route's shape, replacing the one it had
+func (s *Service) saveShape(ctx context.Context, routeID string, points []planner.Point, source, scheduleID string, actor audit.Entry) (*models.RouteShape, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route shape: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedRoute(ctx, tx, routeID, actor.OperatorID, true); err != nil {
+		return nil, err
+	}
+	before, err := Shape(ctx, tx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH g AS (SELECT ST_SetSRID(ST_GeomFromGeoJSON($2), 4326)::geography AS geometry)
+		INSERT INTO route_shapes (route_id, geometry, length_meters, source, source_schedule_id, created_by)
+		SELECT $1, g.geometry, ST_Length(g.geometry), $3, $4, $5
+		FROM g
+		ON CONFLICT (route_id) DO UPDATE
+		SET geometry = EXCLUDED.geometry, length_meters = EXCLUDED.length_meters, source = EXCLUDED.source,
+			source_schedule_id = EXCLUDED.source_schedule_id, created_by = EXCLUDED.created_by, updated_at = NOW()
+	`, routeID, string(LineString(points)), source, nullable(scheduleID), nullable(actor.ActorID))
+	if err != nil {
+		log.Printf("[ERROR] Failed to save route shape: %v", err)
+		return nil, fmt.Errorf("failed to save route shape: %w", err)
+	}
+	shape, err := Shape(ctx, tx, routeID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := actor
+	entry.Action = "route.shape_set"
+	entry.EntityType = "route"
+	entry.EntityID = routeID
+	if before != nil {
+		entry.Before = map[string]interface{}{"source": before.Source, "length_meters": before.LengthMeters}
+	}
+	entry.After = map[string]interface{}{
+		"source":             shape.Source,
+		"source_schedule_id": scheduleID,
+		"length_meters":      shape.LengthMeters,
+		"points":             len(points),
+	}
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit route shape: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return shape, nil
+}
+
+// thin keeps n points of a path, evenly spread and with both ends
+func thin(points []planner.Point, n int) []planner.Point {
+	kept := make([]planner.Point, n)
+	step := float64(len(points)-1) / float64(n-1)
+	for i := range kept {
+		kept[i] = points[int(math.Round(float64(i)*step))]
+	}
+	return kept
+}
+
+func invalidShape(message string) error {
+	return &errors.RouteError{
+		Code:    "INVALID_SHAPE",
+		Message: message,
+	}
+}
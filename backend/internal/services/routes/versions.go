@@ -0,0 +1,632 @@
+// backend/internal/services/routes/versions.go
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/fares"
+)
+
+// StopInput is one stop of a draft version, in the order given
+type StopInput struct {
+	StopID     string
+	TravelTime int // minutes from the start of the route
+	Timetable  []string
+	Active     bool
+}
+
+const versionColumns = `id, route_id, version, status, COALESCE(notes, ''), created_by, created_at,
+	published_by, published_at, effective_from`
+
+// Versions lists one of the operator's routes' versions, newest first
+func (s *Service) Versions(ctx context.Context, routeID, operatorID string) ([]models.RouteVersion, error) {
+	if _, err := ownedRoute(ctx, s.db, routeID, operatorID, false); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+versionColumns+`
+		FROM route_versions
+		WHERE route_id = $1
+		ORDER BY version DESC
+	`, routeID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []models.RouteVersion{}
+	for rows.Next() {
+		var v models.RouteVersion
+		if err := scanVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan route version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// Version returns one version of the operator's route with its stops
+func (s *Service) Version(ctx context.Context, routeID string, version int, operatorID string) (*models.RouteVersion, error) {
+	if _, err := ownedRoute(ctx, s.db, routeID, operatorID, false); err != nil {
+		return nil, err
+	}
+	v, err := routeVersion(ctx, s.db, routeID, version, false)
+	if err != nil {
+		return nil, err
+	}
+	if v.Stops, err = versionStops(ctx, s.db, v.ID); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CreateDraft starts a new version of the route from the stops of the
+// published one. A route has at most one draft at a time.
+func (s *Service) CreateDraft(ctx context.Context, routeID, notes string, actor audit.Entry) (*models.RouteVersion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route draft: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedRoute(ctx, tx, routeID, actor.OperatorID, true); err != nil {
+		return nil, err
+	}
+
+	var v models.RouteVersion
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO route_versions (id, route_id, version, status, notes, created_by)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, 'draft', $3, $4
+		FROM route_versions
+		WHERE route_id = $2
+		RETURNING `+versionColumns,
+		uuid.New().String(), routeID, nullable(strings.TrimSpace(notes)), nullable(actor.ActorID))
+	if err := scanVersion(row, &v); err != nil {
+		// unique_violation, the route already has a draft
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrRouteDraftExists
+		}
+		log.Printf("[ERROR] Failed to create route draft: %v", err)
+		return nil, fmt.Errorf("failed to create route draft: %w", err)
+	}
+
+	// every stop of the published version, deactivated ones too so they can
+	// be brought back
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO route_version_stops (route_version_id, bus_stop_id, stop_order, estimated_arrival_time, timetable, is_active)
+		SELECT $1, rvs.bus_stop_id, rvs.stop_order, rvs.estimated_arrival_time, rvs.timetable, rvs.is_active
+		FROM bus_routes r
+		JOIN route_version_stops rvs ON rvs.route_version_id = r.current_version_id
+		WHERE r.id = $2
+	`, v.ID, routeID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to copy route stops to draft: %v", err)
+		return nil, fmt.Errorf("failed to copy route stops: %w", err)
+	}
+	if v.Stops, err = versionStops(ctx, tx, v.ID); err != nil {
+		return nil, err
+	}
+
+	entry := actor
+	entry.Action = "route.draft_created"
+	entry.EntityType = "route"
+	entry.EntityID = routeID
+	entry.After = map[string]interface{}{"version_id": v.ID, "version": v.Version}
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit route draft: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &v, nil
+}
+
+// ReplaceStops sets a draft's stops. Stops left out are removed from the
+// version, inactive ones stay in their place without being called at.
+func (s *Service) ReplaceStops(ctx context.Context, routeID string, version int, stops []StopInput, operatorID string) (*models.RouteVersion, error) {
+	if err := ValidateStops(stops); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route stops: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ownedRoute(ctx, tx, routeID, operatorID, false); err != nil {
+		return nil, err
+	}
+	v, err := routeVersion(ctx, tx, routeID, version, true)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != "draft" {
+		return nil, errors.ErrRouteVersionNotDraft
+	}
+
+	ids := make([]string, len(stops))
+	for i, st := range stops {
+		ids[i] = st.StopID
+	}
+	var known int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bus_stops WHERE id = ANY($1)`, pq.Array(ids)).Scan(&known); err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if known != len(ids) {
+		return nil, invalidStops("Every stop must be an existing bus stop")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM route_version_stops WHERE route_version_id = $1`, v.ID); err != nil {
+		log.Printf("[ERROR] Failed to clear draft stops: %v", err)
+		return nil, fmt.Errorf("failed to clear draft stops: %w", err)
+	}
+	for i, st := range stops {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO route_version_stops (route_version_id, bus_stop_id, stop_order, estimated_arrival_time, timetable, is_active)
+			VALUES ($1, $2, $3, make_interval(mins => $4), $5, $6)
+		`, v.ID, st.StopID, i+1, st.TravelTime, pq.Array(st.Timetable), st.Active)
+		if err != nil {
+			log.Printf("[ERROR] Failed to add draft stop: %v", err)
+			return nil, fmt.Errorf("failed to add draft stop: %w", err)
+		}
+	}
+	if v.Stops, err = versionStops(ctx, tx, v.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit draft stops: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return v, nil
+}
+
+// DeleteDraft discards a draft version
+func (s *Service) DeleteDraft(ctx context.Context, routeID string, version int, operatorID string) error {
+	if _, err := ownedRoute(ctx, s.db, routeID, operatorID, false); err != nil {
+		return err
+	}
+	v, err := routeVersion(ctx, s.db, routeID, version, false)
+	if err != nil {
+		return err
+	}
+	if v.Status != "draft" {
+		return errors.ErrRouteVersionNotDraft
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM route_versions WHERE id = $1 AND status = 'draft'`, v.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete route draft: %v", err)
+		return fmt.Errorf("failed to delete route draft: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.ErrRouteVersionNotDraft
+	}
+	return nil
+}
+
+// affectedBooking is a booking on a trip moved to a newly published version
+type affectedBooking struct {
+	userID      string
+	versionID   string
+	boardingID  string
+	alightingID string
+	departsAt   time.Time
+}
+
+// Publish makes a draft the route's current version. The route's stops
+// become the version's active stops, and upcoming trips departing from
+// effectiveFrom on run it; earlier trips keep the version they run. Bookings
+// keep the version they were sold against, and riders whose stops or times
+// change on a moved trip are sent a schedule update.
+func (s *Service) Publish(ctx context.Context, routeID string, version int, effectiveFrom time.Time, actor audit.Entry) (*models.RoutePublication, error) {
+	if now := time.Now(); effectiveFrom.Before(now) {
+		effectiveFrom = now
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for route publication: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	route, err := ownedRoute(ctx, tx, routeID, actor.OperatorID, true)
+	if err != nil {
+		return nil, err
+	}
+	v, err := routeVersion(ctx, tx, routeID, version, true)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != "draft" {
+		return nil, errors.ErrRouteVersionNotDraft
+	}
+	if v.Stops, err = versionStops(ctx, tx, v.ID); err != nil {
+		return nil, err
+	}
+	inputs := make([]StopInput, len(v.Stops))
+	for i, st := range v.Stops {
+		inputs[i] = StopInput{StopID: st.BusStopID, TravelTime: st.TravelTime, Timetable: st.Timetable, Active: st.Active}
+	}
+	if err := ValidateStops(inputs); err != nil {
+		return nil, err
+	}
+
+	var previous sql.NullString
+	if err := tx.QueryRowContext(ctx, `SELECT current_version_id FROM bus_routes WHERE id = $1`, routeID).Scan(&previous); err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if previous.Valid {
+		_, err := tx.ExecContext(ctx, `UPDATE route_versions SET status = 'superseded' WHERE id = $1`, previous.String)
+		if err != nil {
+			log.Printf("[ERROR] Failed to supersede route version: %v", err)
+			return nil, fmt.Errorf("failed to supersede route version: %w", err)
+		}
+	}
+	row := tx.QueryRowContext(ctx, `
+		UPDATE route_versions
+		SET status = 'published', published_by = $1, published_at = NOW(), effective_from = $2
+		WHERE id = $3
+		RETURNING `+versionColumns,
+		nullable(actor.ActorID), effectiveFrom, v.ID)
+	stops := v.Stops
+	if err := scanVersion(row, v); err != nil {
+		log.Printf("[ERROR] Failed to publish route version: %v", err)
+		return nil, fmt.Errorf("failed to publish route version: %w", err)
+	}
+	v.Stops = stops
+
+	// the route's live stops are the version's active ones
+	if _, err := tx.ExecContext(ctx, `UPDATE bus_routes SET current_version_id = $1, updated_at = NOW() WHERE id = $2`, v.ID, routeID); err != nil {
+		log.Printf("[ERROR] Failed to update route version: %v", err)
+		return nil, fmt.Errorf("failed to update route version: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM route_bus_stops WHERE route_id = $1`, routeID); err != nil {
+		log.Printf("[ERROR] Failed to clear route stops: %v", err)
+		return nil, fmt.Errorf("failed to clear route stops: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO route_bus_stops (route_id, bus_stop_id, stop_order, timetable, estimated_arrival_time)
+		SELECT $1, bus_stop_id, ROW_NUMBER() OVER (ORDER BY stop_order), timetable, estimated_arrival_time
+		FROM route_version_stops
+		WHERE route_version_id = $2 AND is_active
+	`, routeID, v.ID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to set route stops: %v", err)
+		return nil, fmt.Errorf("failed to set route stops: %w", err)
+	}
+
+	// upcoming trips from the effective time on run the new version
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE schedules
+		SET route_version_id = $1
+		WHERE route_id = $2 AND cancelled_at IS NULL
+		AND departure_time > NOW() AND departure_time >= $3
+		AND route_version_id IS DISTINCT FROM $1
+		RETURNING id
+	`, v.ID, routeID, effectiveFrom)
+	if err != nil {
+		log.Printf("[ERROR] Failed to move schedules to route version: %v", err)
+		return nil, fmt.Errorf("failed to move schedules: %w", err)
+	}
+	moved := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		moved = append(moved, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	notices, err := s.scheduleUpdates(ctx, tx, route.RouteName, moved, v.Stops)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := actor
+	entry.Action = "route.version_published"
+	entry.EntityType = "route"
+	entry.EntityID = routeID
+	entry.Before = map[string]interface{}{"version_id": previous.String}
+	entry.After = map[string]interface{}{
+		"version_id":      v.ID,
+		"version":         v.Version,
+		"effective_from":  effectiveFrom,
+		"schedules_moved": len(moved),
+		"riders_notified": len(notices),
+	}
+	if err := s.audit.Record(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit route publication: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.planner.Invalidate()
+	s.notify(notices)
+	log.Printf("[INFO] Published version %d of route %s, %d schedules moved", v.Version, routeID, len(moved))
+	return &models.RoutePublication{Version: *v, SchedulesMoved: len(moved), RidersNotified: len(notices)}, nil
+}
+
+// notice is a schedule update for one rider
+type notice struct {
+	userID  string
+	message string
+}
+
+// scheduleUpdates works out what changes for riders booked on the moved
+// trips, comparing the version each booking was sold against with the new one
+func (s *Service) scheduleUpdates(ctx context.Context, tx *sql.Tx, routeName string, scheduleIDs []string, after []models.RouteVersionStop) ([]notice, error) {
+	if len(scheduleIDs) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.user_id, COALESCE(b.route_version_id::TEXT, ''), COALESCE(b.boarding_stop_id::TEXT, ''),
+			COALESCE(b.alighting_stop_id::TEXT, ''), s.departure_time
+		FROM bookings b
+		JOIN schedules s ON s.id = b.schedule_id
+		WHERE b.schedule_id = ANY($1) AND b.status IN ('confirmed', 'pending_payment')
+		ORDER BY s.departure_time
+	`, pq.Array(scheduleIDs))
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	bookings := []affectedBooking{}
+	for rows.Next() {
+		var b affectedBooking
+		if err := rows.Scan(&b.userID, &b.versionID, &b.boardingID, &b.alightingID, &b.departsAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan booking: %w", err)
+		}
+		bookings = append(bookings, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sold := map[string][]models.RouteVersionStop{}
+	notices := []notice{}
+	for _, b := range bookings {
+		// bookings to or from a route's origin or destination name have no
+		// stop to compare
+		if b.versionID == "" || b.boardingID == "" || b.alightingID == "" {
+			continue
+		}
+		before, ok := sold[b.versionID]
+		if !ok {
+			if before, err = versionStops(ctx, tx, b.versionID); err != nil {
+				return nil, err
+			}
+			sold[b.versionID] = before
+		}
+		change := RideChange(before, after, b.boardingID, b.alightingID)
+		if change == "" {
+			continue
+		}
+		notices = append(notices, notice{
+			userID: b.userID,
+			message: fmt.Sprintf("Route %s has changed for your trip on %s: %s.",
+				routeName, b.departsAt.Format("2 Jan 2006 15:04"), change),
+		})
+	}
+	return notices, nil
+}
+
+func (s *Service) notify(notices []notice) {
+	if s.notifier == nil {
+		return
+	}
+	for _, n := range notices {
+		if err := s.notifier.Send(n.userID, models.NotificationScheduleUpdate, n.message); err != nil {
+			log.Printf("[ERROR] Failed to send route change notification: %v", err)
+		}
+	}
+}
+
+// ValidateStops checks a version's stops: each once, with at least two
+// active, times from the start of the route that never go back along the
+// active stops, and HH:MM timetables
+func ValidateStops(stops []StopInput) error {
+	seen := map[string]bool{}
+	active := 0
+	last := 0
+	for i, st := range stops {
+		if _, err := uuid.Parse(st.StopID); err != nil {
+			return invalidStops(fmt.Sprintf("Stop %d is not a valid stop id", i+1))
+		}
+		if seen[st.StopID] {
+			return invalidStops("A stop can only be on a route once")
+		}
+		seen[st.StopID] = true
+		if st.TravelTime < 0 {
+			return invalidStops("Travel times cannot be negative")
+		}
+		for _, clock := range st.Timetable {
+			if _, err := fares.ClockMinutes(clock); err != nil {
+				return invalidStops(fmt.Sprintf("Timetable times must be HH:MM, not %q", clock))
+			}
+		}
+		if !st.Active {
+			continue
+		}
+		if active > 0 && st.TravelTime < last {
+			return invalidStops("Travel times must not decrease along the route")
+		}
+		active++
+		last = st.TravelTime
+	}
+	if active < 2 {
+		return invalidStops("A route needs at least two active stops")
+	}
+	return nil
+}
+
+// RideChange describes how a ride between two stops changes from one
+// version of a route to another, empty when it doesn't
+func RideChange(before, after []models.RouteVersionStop, boardingStopID, alightingStopID string) string {
+	find := func(stops []models.RouteVersionStop, id string) (models.RouteVersionStop, bool) {
+		for _, st := range stops {
+			if st.BusStopID == id && st.Active {
+				return st, true
+			}
+		}
+		return models.RouteVersionStop{}, false
+	}
+	name := func(id string) string {
+		for _, st := range before {
+			if st.BusStopID == id {
+				return st.Name
+			}
+		}
+		for _, st := range after {
+			if st.BusStopID == id {
+				return st.Name
+			}
+		}
+		return "your stop"
+	}
+
+	newBoarding, okBoarding := find(after, boardingStopID)
+	newAlighting, okAlighting := find(after, alightingStopID)
+	switch {
+	case !okBoarding:
+		return fmt.Sprintf("%s is no longer served", name(boardingStopID))
+	case !okAlighting:
+		return fmt.Sprintf("%s is no longer served", name(alightingStopID))
+	case newAlighting.StopOrder <= newBoarding.StopOrder:
+		return fmt.Sprintf("the route no longer runs from %s to %s", newBoarding.Name, newAlighting.Name)
+	}
+
+	changes := []string{}
+	if old, ok := find(before, boardingStopID); ok && old.TravelTime != newBoarding.TravelTime {
+		changes = append(changes, "the bus now reaches "+newBoarding.Name+" "+minutesShift(newBoarding.TravelTime-old.TravelTime))
+	}
+	if old, ok := find(before, alightingStopID); ok && old.TravelTime != newAlighting.TravelTime {
+		changes = append(changes, "arrives at "+newAlighting.Name+" "+minutesShift(newAlighting.TravelTime-old.TravelTime))
+	}
+	return strings.Join(changes, " and ")
+}
+
+func minutesShift(delta int) string {
+	switch {
+	case delta == 1:
+		return "1 minute later"
+	case delta == -1:
+		return "1 minute earlier"
+	case delta > 0:
+		return fmt.Sprintf("%d minutes later", delta)
+	default:
+		return fmt.Sprintf("%d minutes earlier", -delta)
+	}
+}
+
+// routeVersion loads a version of a route by its number
+func routeVersion(ctx context.Context, q queryer, routeID string, version int, forUpdate bool) (*models.RouteVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM route_versions WHERE route_id = $1 AND version = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var v models.RouteVersion
+	err := scanVersion(q.QueryRowContext(ctx, query, routeID, version), &v)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrRouteVersionNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &v, nil
+}
+
+// versionStops loads a version's stops in order
+func versionStops(ctx context.Context, q queryer, versionID string) ([]models.RouteVersionStop, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT rvs.bus_stop_id, bs.name, rvs.stop_order,
+			(EXTRACT(EPOCH FROM COALESCE(rvs.estimated_arrival_time, INTERVAL '0')) / 60)::INT,
+			COALESCE(rvs.timetable, '{}'), rvs.is_active
+		FROM route_version_stops rvs
+		JOIN bus_stops bs ON bs.id = rvs.bus_stop_id
+		WHERE rvs.route_version_id = $1
+		ORDER BY rvs.stop_order
+	`, versionID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	stops := []models.RouteVersionStop{}
+	for rows.Next() {
+		var st models.RouteVersionStop
+		if err := rows.Scan(&st.BusStopID, &st.Name, &st.StopOrder, &st.TravelTime, pq.Array(&st.Timetable), &st.Active); err != nil {
+			return nil, fmt.Errorf("failed to scan route stop: %w", err)
+		}
+		stops = append(stops, st)
+	}
+	return stops, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVersion(row scanner, v *models.RouteVersion) error {
+	var createdBy, publishedBy sql.NullString
+	var publishedAt, effectiveFrom sql.NullTime
+	if err := row.Scan(&v.ID, &v.RouteID, &v.Version, &v.Status, &v.Notes, &createdBy, &v.CreatedAt,
+		&publishedBy, &publishedAt, &effectiveFrom); err != nil {
+		return err
+	}
+	if createdBy.Valid {
+		v.CreatedBy = &createdBy.String
+	}
+	if publishedBy.Valid {
+		v.PublishedBy = &publishedBy.String
+	}
+	if publishedAt.Valid {
+		v.PublishedAt = &publishedAt.Time
+	}
+	if effectiveFrom.Valid {
+		v.EffectiveFrom = &effectiveFrom.Time
+	}
+	return nil
+}
+
+func invalidStops(message string) error {
+	return &errors.RouteError{
+		Code:    "INVALID_ROUTE_STOPS",
+		Message: message,
+	}
+}
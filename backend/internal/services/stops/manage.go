@@ -0,0 +1,197 @@
+// backend/internal/services/stops/manage.go
+package stops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+// Stop returns a stop with its aliases and the routes calling at it
+func (s *Service) Stop(ctx context.Context, stopID string) (*models.StopDetails, error) {
+	if _, err := uuid.Parse(stopID); err != nil {
+		return nil, errors.ErrStopNotFound
+	}
+
+	var stop models.StopDetails
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(landmark_description, ''), latitude, longitude, created_at, updated_at
+		FROM bus_stops
+		WHERE id = $1
+	`, stopID).Scan(&stop.ID, &stop.Name, &stop.LandmarkDescription, &stop.Latitude, &stop.Longitude, &stop.CreatedAt, &stop.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrStopNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if stop.Aliases, err = s.aliases(ctx, stopID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, r.route_name, rbs.stop_order
+		FROM route_bus_stops rbs
+		JOIN bus_routes r ON r.id = rbs.route_id
+		WHERE rbs.bus_stop_id = $1
+		ORDER BY r.route_name
+	`, stopID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	stop.Routes = []models.StopRoute{}
+	for rows.Next() {
+		var r models.StopRoute
+		if err := rows.Scan(&r.ID, &r.Name, &r.StopOrder); err != nil {
+			return nil, fmt.Errorf("failed to scan stop route: %w", err)
+		}
+		stop.Routes = append(stop.Routes, r)
+	}
+	return &stop, rows.Err()
+}
+
+// AddAlias gives a stop on one of the operator's routes another name to be
+// found by
+func (s *Service) AddAlias(ctx context.Context, stopID, operatorID, userID, alias string) (*models.BusStopAlias, error) {
+	alias = strings.TrimSpace(alias)
+	if SearchKey(alias) == "" || len(alias) > 255 {
+		return nil, &errors.StopError{
+			Code:    "INVALID_ALIAS",
+			Message: "An alias needs letters or digits and at most 255 characters",
+		}
+	}
+	name, err := s.operatorStop(ctx, stopID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if SearchKey(name) == SearchKey(alias) {
+		return nil, errors.ErrStopAliasExists
+	}
+
+	a := models.BusStopAlias{ID: uuid.New().String(), BusStopID: stopID, Alias: alias}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO bus_stop_aliases (id, bus_stop_id, alias, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`, a.ID, stopID, alias, nullable(userID)).Scan(&a.CreatedAt)
+	if err != nil {
+		// unique_violation, the stop already has the alias
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, errors.ErrStopAliasExists
+		}
+		log.Printf("[ERROR] Failed to add stop alias: %v", err)
+		return nil, fmt.Errorf("failed to add stop alias: %w", err)
+	}
+	return &a, nil
+}
+
+// DeleteAlias removes one of a stop's aliases
+func (s *Service) DeleteAlias(ctx context.Context, stopID, aliasID, operatorID string) error {
+	if _, err := uuid.Parse(aliasID); err != nil {
+		return errors.ErrStopAliasNotFound
+	}
+	if _, err := s.operatorStop(ctx, stopID, operatorID); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM bus_stop_aliases WHERE id = $1 AND bus_stop_id = $2`, aliasID, stopID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to delete stop alias: %v", err)
+		return fmt.Errorf("failed to delete stop alias: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.ErrStopAliasNotFound
+	}
+	return nil
+}
+
+// UpdateLandmark sets the landmark description riders can find a stop by,
+// clearing it when empty
+func (s *Service) UpdateLandmark(ctx context.Context, stopID, operatorID, landmark string) (*models.StopDetails, error) {
+	if _, err := s.operatorStop(ctx, stopID, operatorID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE bus_stops SET landmark_description = $1, updated_at = NOW() WHERE id = $2
+	`, nullable(strings.TrimSpace(landmark)), stopID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to update stop landmark: %v", err)
+		return nil, fmt.Errorf("failed to update stop landmark: %w", err)
+	}
+	return s.Stop(ctx, stopID)
+}
+
+func (s *Service) aliases(ctx context.Context, stopID string) ([]models.BusStopAlias, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, bus_stop_id, alias, created_at
+		FROM bus_stop_aliases
+		WHERE bus_stop_id = $1
+		ORDER BY alias
+	`, stopID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []models.BusStopAlias{}
+	for rows.Next() {
+		var a models.BusStopAlias
+		if err := rows.Scan(&a.ID, &a.BusStopID, &a.Alias, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan stop alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// operatorStop checks the stop is on one of the operator's routes, or on a
+// legacy route without an owner, and returns its name
+func (s *Service) operatorStop(ctx context.Context, stopID, operatorID string) (string, error) {
+	if _, err := uuid.Parse(stopID); err != nil {
+		return "", errors.ErrStopNotFound
+	}
+
+	var name string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT bs.name
+		FROM bus_stops bs
+		WHERE bs.id = $1
+		AND EXISTS (
+			SELECT 1
+			FROM route_bus_stops rbs
+			JOIN bus_routes r ON r.id = rbs.route_id
+			WHERE rbs.bus_stop_id = bs.id
+			AND (r.operator_id::TEXT = $2 OR r.operator_id IS NULL)
+		)
+	`, stopID, operatorID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", errors.ErrStopNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return "", fmt.Errorf("database error: %w", err)
+	}
+	return name, nil
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
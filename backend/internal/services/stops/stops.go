@@ -0,0 +1,226 @@
+// backend/internal/services/stops/stops.go
+package stops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+const (
+	// MinMatchScore is the least a stop must match a search to be listed
+	MinMatchScore = 0.3
+	// ResolveScore is the least a stop must match a name to stand for it in
+	// a booking, closer than a search needs
+	ResolveScore = 0.5
+
+	// WalkSpeed is a rider's walking pace in metres per second
+	WalkSpeed = 1.2
+	// WalkDetour is how much longer a walk on the streets is than the
+	// straight line
+	WalkDetour = 1.3
+
+	// MaxNearbyRadius is the furthest nearest stop search looks, in metres
+	MaxNearbyRadius = 5000
+	DefaultLimit    = 20
+	MaxLimit        = 50
+)
+
+// Queryer is satisfied by *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SearchOptions narrows a stop search
+type SearchOptions struct {
+	RouteID  string  // only stops on the route
+	Limit    int     // DefaultLimit when zero
+	MinScore float64 // MinMatchScore when zero
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewStopService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// SearchKey folds a stop name the way the database does for matching: lower
+// case letters and digits only, so "KenCom", "Ken-Com" and "kencom" match
+func SearchKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WalkingDistance is the distance on foot for a straight line distance, and
+// the whole minutes it takes
+func WalkingDistance(meters float64) (int, int) {
+	walk := meters * WalkDetour
+	return int(math.Round(walk)), int(math.Ceil(walk / WalkSpeed / 60))
+}
+
+// routeNames lists the routes calling at the stop bs
+const routeNames = `
+	ARRAY(
+		SELECT r.route_name
+		FROM route_bus_stops rbs
+		JOIN bus_routes r ON r.id = rbs.route_id
+		WHERE rbs.bus_stop_id = bs.id
+		ORDER BY r.route_name
+	)`
+
+// Search finds stops by name, alias or landmark description. Names and
+// aliases match on their search keys, exactly, containing the query, or by
+// trigram similarity for misspellings; landmarks match on the words in them.
+// The closest match of each stop counts, best first.
+func Search(ctx context.Context, q Queryer, query string, opts SearchOptions) ([]models.StopMatch, error) {
+	if SearchKey(query) == "" {
+		return nil, errors.ErrInvalidStopQuery
+	}
+	if opts.Limit <= 0 || opts.Limit > MaxLimit {
+		opts.Limit = DefaultLimit
+	}
+	if opts.MinScore <= 0 {
+		opts.MinScore = MinMatchScore
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		WITH q AS (SELECT stop_search_key($1) AS key, LOWER(TRIM($1)) AS text),
+		matches AS (
+			SELECT bs.id AS stop_id, 'name' AS matched_on, bs.name AS matched,
+				CASE
+					WHEN stop_search_key(bs.name) = q.key THEN 1
+					WHEN strpos(stop_search_key(bs.name), q.key) > 0 THEN 0.5 + similarity(stop_search_key(bs.name), q.key) / 2
+					ELSE similarity(stop_search_key(bs.name), q.key)
+				END AS score
+			FROM bus_stops bs, q
+			WHERE stop_search_key(bs.name) % q.key OR strpos(stop_search_key(bs.name), q.key) > 0
+			UNION ALL
+			-- a little below a name matching as well, so names win ties
+			SELECT a.bus_stop_id, 'alias', a.alias,
+				0.95 * CASE
+					WHEN stop_search_key(a.alias) = q.key THEN 1
+					WHEN strpos(stop_search_key(a.alias), q.key) > 0 THEN 0.5 + similarity(stop_search_key(a.alias), q.key) / 2
+					ELSE similarity(stop_search_key(a.alias), q.key)
+				END
+			FROM bus_stop_aliases a, q
+			WHERE stop_search_key(a.alias) % q.key OR strpos(stop_search_key(a.alias), q.key) > 0
+			UNION ALL
+			SELECT bs.id, 'landmark', bs.landmark_description,
+				0.8 * word_similarity(q.text, LOWER(bs.landmark_description))
+			FROM bus_stops bs, q
+			WHERE q.text <% LOWER(bs.landmark_description)
+		),
+		best AS (
+			SELECT DISTINCT ON (stop_id) stop_id, matched_on, matched, score
+			FROM matches
+			ORDER BY stop_id, score DESC
+		)
+		SELECT bs.id, bs.name, COALESCE(bs.landmark_description, ''), bs.latitude, bs.longitude, bs.created_at, bs.updated_at,
+			b.matched_on, b.matched, b.score::FLOAT8, `+routeNames+`
+		FROM best b
+		JOIN bus_stops bs ON bs.id = b.stop_id
+		WHERE b.score >= $2
+		AND ($3::TEXT = '' OR EXISTS (
+			SELECT 1 FROM route_bus_stops rbs WHERE rbs.route_id::TEXT = $3 AND rbs.bus_stop_id = bs.id
+		))
+		ORDER BY b.score DESC, bs.name
+		LIMIT $4
+	`, query, opts.MinScore, opts.RouteID, opts.Limit)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.StopMatch{}
+	for rows.Next() {
+		var m models.StopMatch
+		if err := rows.Scan(&m.ID, &m.Name, &m.LandmarkDescription, &m.Latitude, &m.Longitude, &m.CreatedAt, &m.UpdatedAt,
+			&m.MatchedOn, &m.Matched, &m.Score, pq.Array(&m.Routes)); err != nil {
+			return nil, fmt.Errorf("failed to scan stop match: %w", err)
+		}
+		m.Score = math.Round(m.Score*100) / 100
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Resolve finds the stop on a route that a rider's stop name stands for, the
+// best match scoring at least ResolveScore. It returns nil when none does.
+func Resolve(ctx context.Context, q Queryer, routeID, name string) (*models.StopMatch, error) {
+	if SearchKey(name) == "" {
+		return nil, nil
+	}
+	matches, err := Search(ctx, q, name, SearchOptions{RouteID: routeID, Limit: 1, MinScore: ResolveScore})
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	return &matches[0], nil
+}
+
+// Nearby lists the stops within radius metres of a point, nearest first,
+// with the walk to each
+func Nearby(ctx context.Context, q Queryer, lat, lng float64, radius, limit int) ([]models.NearbyStop, error) {
+	if radius <= 0 || radius > MaxNearbyRadius {
+		radius = MaxNearbyRadius
+	}
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		WITH p AS (SELECT ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography AS geo)
+		SELECT bs.id, bs.name, COALESCE(bs.landmark_description, ''), bs.latitude, bs.longitude, bs.created_at, bs.updated_at,
+			ST_Distance(bs.geolocation, p.geo) AS distance, `+routeNames+`
+		FROM bus_stops bs, p
+		WHERE ST_DWithin(bs.geolocation, p.geo, $3)
+		ORDER BY distance, bs.name
+		LIMIT $4
+	`, lat, lng, radius, limit)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	stops := []models.NearbyStop{}
+	for rows.Next() {
+		var s models.NearbyStop
+		var distance float64
+		if err := rows.Scan(&s.ID, &s.Name, &s.LandmarkDescription, &s.Latitude, &s.Longitude, &s.CreatedAt, &s.UpdatedAt,
+			&distance, pq.Array(&s.Routes)); err != nil {
+			return nil, fmt.Errorf("failed to scan nearby stop: %w", err)
+		}
+		s.DistanceMeters = int(math.Round(distance))
+		s.WalkingMeters, s.WalkingMinutes = WalkingDistance(distance)
+		stops = append(stops, s)
+	}
+	return stops, rows.Err()
+}
+
+// Search finds stops by name, alias or landmark description
+func (s *Service) Search(ctx context.Context, query string, opts SearchOptions) ([]models.StopMatch, error) {
+	return Search(ctx, s.db, query, opts)
+}
+
+// Nearby lists the stops within radius metres of a point
+func (s *Service) Nearby(ctx context.Context, lat, lng float64, radius, limit int) ([]models.NearbyStop, error) {
+	return Nearby(ctx, s.db, lat, lng, radius, limit)
+}
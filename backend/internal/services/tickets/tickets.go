@@ -0,0 +1,411 @@
+// backend/internal/services/tickets/tickets.go
+package tickets
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/lifecycle"
+)
+
+// TicketValidity is how long a ticket stays valid after the departure it is
+// for, or after it is issued when the booking has no known departure
+const TicketValidity = 12 * time.Hour
+
+// Service issues e-tickets and boards riders when they are scanned
+type Service struct {
+	db     *sql.DB
+	signer *TicketSigner
+	audit  *audit.Service
+}
+
+// NewTicketService signs with TICKET_SIGNING_KEY, a base64 Ed25519 seed, or a
+// key derived from JWT_SECRET when it is not set
+func NewTicketService(db *sql.DB) (*Service, error) {
+	seed := DeriveTicketSeed(os.Getenv("JWT_SECRET"))
+	if key := os.Getenv("TICKET_SIGNING_KEY"); key != "" {
+		var err error
+		if seed, err = base64.StdEncoding.DecodeString(key); err != nil {
+			return nil, fmt.Errorf("invalid TICKET_SIGNING_KEY: %w", err)
+		}
+	}
+
+	signer, err := NewTicketSigner(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		db:     db,
+		signer: signer,
+		audit:  audit.NewAuditService(db),
+	}, nil
+}
+
+// PublicKey is the key conductors' devices verify tickets with
+func (s *Service) PublicKey() []byte {
+	return s.signer.PublicKey()
+}
+
+// Issue returns a signed ticket for the rider's booking, for the seats it
+// has left. It can be fetched again at any time, e.g. after some seats are
+// cancelled.
+func (s *Service) Issue(ctx context.Context, bookingID, userID string) (*models.Ticket, error) {
+	ticket, seats, _, err := s.bookingTicket(ctx, bookingID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	ticket.Seats = seats.Count
+	ticket.SeatNumbers = seats.SeatNumbers
+
+	if ticket.Token, err = s.signer.Sign(ticket); err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// PassengerTickets returns a ticket of its own for each passenger named on a
+// booking whose seat has not been cancelled. The rider who booked gets every
+// passenger's ticket; a registered rider named as a passenger gets only theirs.
+func (s *Service) PassengerTickets(ctx context.Context, bookingID, userID string) ([]models.Ticket, error) {
+	base, _, bookerID, err := s.bookingTicket(ctx, bookingID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, seat_number
+		FROM booking_passengers
+		WHERE booking_id = $1 AND status = 'active' AND ($2 OR user_id::TEXT = $3)
+		ORDER BY position
+	`, bookingID, bookerID == userID, userID)
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []models.Ticket{}
+	for rows.Next() {
+		ticket := *base
+		var passengerID string
+		var seatNumber sql.NullString
+		if err := rows.Scan(&passengerID, &ticket.Passenger, &seatNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan booking passenger: %w", err)
+		}
+		ticket.PassengerID = &passengerID
+		ticket.Seats = 1
+		if seatNumber.Valid {
+			ticket.SeatNumbers = []string{seatNumber.String}
+		}
+		if ticket.Token, err = s.signer.Sign(&ticket); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, ticket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, &errors.TicketError{
+			Code:    "NO_PASSENGER_TICKETS",
+			Message: "The booking has no named passengers with seats left",
+		}
+	}
+	return tickets, nil
+}
+
+// bookingTicket loads the booking a ticket is for, unsigned and without its
+// seats, along with the seats left and who booked it. asPassenger also lets a
+// registered rider named as one of its passengers see it.
+func (s *Service) bookingTicket(ctx context.Context, bookingID, userID string, asPassenger bool) (*models.Ticket, models.SeatMap, string, error) {
+	var seats models.SeatMap
+	if _, err := uuid.Parse(bookingID); err != nil {
+		return nil, seats, "", errors.ErrTicketNotFound
+	}
+
+	var ticket models.Ticket
+	var bookerID, status string
+	var seatsJSON []byte
+	var scheduleID sql.NullString
+	var departsAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT b.id, b.user_id, b.bus_id, b.schedule_id, b.departs_at, b.seats, b.status
+		FROM bookings b
+		WHERE b.id = $1 AND (b.user_id::TEXT = $2 OR ($3 AND EXISTS (
+			SELECT 1 FROM booking_passengers p
+			WHERE p.booking_id = b.id AND p.user_id::TEXT = $2 AND p.status = 'active'
+		)))
+	`, bookingID, userID, asPassenger).Scan(&ticket.BookingID, &bookerID, &ticket.BusID, &scheduleID, &departsAt, &seatsJSON, &status)
+	if err == sql.ErrNoRows {
+		return nil, seats, "", errors.ErrTicketNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, seats, "", fmt.Errorf("database error: %w", err)
+	}
+
+	if status != lifecycle.StatusConfirmed && status != lifecycle.StatusBoarded {
+		return nil, seats, "", &errors.TicketError{
+			Code:    "TICKET_UNAVAILABLE",
+			Message: fmt.Sprintf("There is no ticket for a %s booking", status),
+		}
+	}
+
+	if err := json.Unmarshal(seatsJSON, &seats); err != nil {
+		return nil, seats, "", fmt.Errorf("failed to read booking seats: %w", err)
+	}
+	if scheduleID.Valid {
+		ticket.ScheduleID = &scheduleID.String
+	}
+	ticket.ExpiresAt = time.Now().Add(TicketValidity)
+	if departsAt.Valid {
+		ticket.DepartsAt = &departsAt.Time
+		ticket.ExpiresAt = departsAt.Time.Add(TicketValidity)
+	}
+	ticket.ExpiresAt = ticket.ExpiresAt.Truncate(time.Second)
+	return &ticket, seats, bookerID, nil
+}
+
+// ScanInput is one scan of a ticket by a conductor or driver
+type ScanInput struct {
+	Token      string
+	BusID      string // the bus the scan was made on
+	ScheduleID string // optional, the trip the bus is running
+	DeviceID   string
+	ScannedAt  time.Time // zero for now
+	Offline    bool      // collected without a connection and synced later
+}
+
+// Scan checks a ticket against the bus and trip and boards the rider. The
+// first scan wins: later scans of the same ticket are recorded as duplicates
+// and fail with ErrTicketAlreadyUsed, returning the scan with when the rider
+// boarded. A passenger's own ticket boards only that passenger, the booking
+// is boarded with the first of them.
+func (s *Service) Scan(ctx context.Context, in ScanInput, actor audit.Entry) (*models.TicketScan, error) {
+	now := time.Now()
+	if in.ScannedAt.IsZero() || in.ScannedAt.After(now) {
+		in.ScannedAt = now
+	}
+
+	ticket, err := s.signer.Verify(in.Token, in.ScannedAt)
+	if err != nil {
+		return nil, err
+	}
+	if ticket.BusID != in.BusID {
+		return nil, errors.ErrTicketWrongTrip
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[ERROR] Failed to begin transaction for ticket scan: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer tx.Rollback()
+
+	var busID, status string
+	var scheduleID sql.NullString
+	var boardedAt sql.NullTime
+	var seatsJSON []byte
+	err = tx.QueryRowContext(ctx, `
+		SELECT b.bus_id, b.status, b.schedule_id, b.boarded_at, b.seats
+		FROM bookings b
+		JOIN buses bu ON bu.id = b.bus_id
+		WHERE b.id = $1 AND bu.operator_id = $2
+		FOR UPDATE OF b
+	`, ticket.BookingID, actor.OperatorID).Scan(&busID, &status, &scheduleID, &boardedAt, &seatsJSON)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrTicketNotFound
+	}
+	if err != nil {
+		log.Printf("[ERROR] Database error: %v", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if busID != in.BusID || (in.ScheduleID != "" && scheduleID.Valid && scheduleID.String != in.ScheduleID) {
+		return nil, errors.ErrTicketWrongTrip
+	}
+
+	var seats models.SeatMap
+	if err := json.Unmarshal(seatsJSON, &seats); err != nil {
+		return nil, fmt.Errorf("failed to read booking seats: %w", err)
+	}
+
+	scan := &models.TicketScan{
+		BookingID: ticket.BookingID,
+		BusID:     in.BusID,
+		ScannedBy: actor.ActorID,
+		Offline:   in.Offline,
+		Seats:     seats.Count,
+		ScannedAt: in.ScannedAt,
+	}
+	if in.DeviceID != "" {
+		scan.DeviceID = &in.DeviceID
+	}
+
+	if ticket.PassengerID != nil {
+		scan.PassengerID = ticket.PassengerID
+		scan.Seats = 1
+		var passengerStatus string
+		var passengerBoardedAt sql.NullTime
+		err := tx.QueryRowContext(ctx, `
+			SELECT status, boarded_at FROM booking_passengers
+			WHERE id = $1 AND booking_id = $2
+			FOR UPDATE
+		`, *ticket.PassengerID, ticket.BookingID).Scan(&passengerStatus, &passengerBoardedAt)
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrTicketNotFound
+		}
+		if err != nil {
+			log.Printf("[ERROR] Database error: %v", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if passengerStatus != "active" {
+			return nil, &errors.TicketError{
+				Code:    "TICKET_NOT_BOARDABLE",
+				Message: "The passenger's seat was cancelled",
+			}
+		}
+		// the booking may already be boarded by another of its passengers
+		boardedAt = passengerBoardedAt
+	}
+
+	if boardedAt.Valid {
+		scan.Result = "duplicate"
+		scan.BoardedAt = boardedAt.Time
+		if err := s.recordScan(ctx, tx, scan); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return scan, errors.ErrTicketAlreadyUsed
+	}
+
+	if status != lifecycle.StatusBoarded {
+		if !lifecycle.CanTransition(status, lifecycle.StatusBoarded) {
+			return nil, &errors.TicketError{
+				Code:    "TICKET_NOT_BOARDABLE",
+				Message: fmt.Sprintf("The booking is %s", status),
+			}
+		}
+
+		if err := lifecycle.Transition(ctx, tx, ticket.BookingID, status, lifecycle.StatusBoarded, actor, "ticket scanned"); err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE bookings SET boarded_at = $1 WHERE id = $2
+		`, in.ScannedAt, ticket.BookingID)
+		if err != nil {
+			log.Printf("[ERROR] Failed to board booking: %v", err)
+			return nil, fmt.Errorf("failed to board booking: %w", err)
+		}
+	}
+
+	// a booking's own ticket boards every passenger on it
+	_, err = tx.ExecContext(ctx, `
+		UPDATE booking_passengers SET boarded_at = $1
+		WHERE booking_id = $2 AND status = 'active' AND boarded_at IS NULL AND ($3::UUID IS NULL OR id = $3)
+	`, in.ScannedAt, ticket.BookingID, scan.PassengerID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to board booking passengers: %v", err)
+		return nil, fmt.Errorf("failed to board booking passengers: %w", err)
+	}
+
+	scan.Result = "boarded"
+	scan.BoardedAt = in.ScannedAt
+	if err := s.recordScan(ctx, tx, scan); err != nil {
+		return nil, err
+	}
+
+	actor.Action = "booking.boarded"
+	actor.EntityType = "booking"
+	actor.EntityID = ticket.BookingID
+	actor.Before = map[string]interface{}{"status": status}
+	after := map[string]interface{}{"status": lifecycle.StatusBoarded, "boarded_at": in.ScannedAt, "bus_id": in.BusID, "offline": in.Offline}
+	if ticket.PassengerID != nil {
+		actor.Action = "booking.passenger_boarded"
+		after["passenger_id"] = *ticket.PassengerID
+	}
+	actor.After = after
+	if err := s.audit.Record(ctx, tx, actor); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("[ERROR] Failed to commit ticket scan: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return scan, nil
+}
+
+func (s *Service) recordScan(ctx context.Context, tx *sql.Tx, scan *models.TicketScan) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO ticket_scans (booking_id, bus_id, scanned_by, device_id, result, offline, scanned_at, passenger_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, received_at
+	`, scan.BookingID, scan.BusID, nullable(scan.ScannedBy), scan.DeviceID, scan.Result, scan.Offline, scan.ScannedAt, scan.PassengerID).Scan(&scan.ID, &scan.ReceivedAt)
+	if err != nil {
+		log.Printf("[ERROR] Failed to record ticket scan: %v", err)
+		return fmt.Errorf("failed to record ticket scan: %w", err)
+	}
+	return nil
+}
+
+// Sync applies scans collected offline, earliest first so the first scan of
+// a ticket is the one that boards the rider. One bad scan does not stop the
+// rest; each gets its own result, in the order they were sent.
+func (s *Service) Sync(ctx context.Context, scans []ScanInput, actor audit.Entry) []models.TicketSyncResult {
+	order := make([]int, len(scans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scans[order[a]].ScannedAt.Before(scans[order[b]].ScannedAt)
+	})
+
+	results := make([]models.TicketSyncResult, len(scans))
+	for _, i := range order {
+		in := scans[i]
+		in.Offline = true
+		scan, err := s.Scan(ctx, in, actor)
+
+		result := models.TicketSyncResult{Index: i, Scan: scan}
+		if scan != nil {
+			result.BookingID = scan.BookingID
+			result.Result = scan.Result
+		}
+		if err != nil && scan == nil {
+			result.Result = "rejected"
+		}
+		if err != nil {
+			if e, ok := err.(*errors.TicketError); ok {
+				result.Error = e.Message
+			} else {
+				log.Printf("[ERROR] Failed to sync ticket scan: %v", err)
+				result.Error = "Internal server error"
+			}
+		}
+		results[i] = result
+	}
+	return results
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
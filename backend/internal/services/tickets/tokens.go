@@ -0,0 +1,120 @@
+// backend/internal/services/tickets/tokens.go
+package tickets
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+)
+
+const ticketAudience = "ticket"
+
+// TicketSigner signs e-tickets with an Ed25519 key. Conductors' devices hold
+// only the public key, so they can check tickets without a connection but
+// cannot make new ones.
+type TicketSigner struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// NewTicketSigner uses a 32 byte Ed25519 seed as the signing key
+func NewTicketSigner(seed []byte) (*TicketSigner, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ticket signing key must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	private := ed25519.NewKeyFromSeed(seed)
+	return &TicketSigner{private: private, public: private.Public().(ed25519.PublicKey)}, nil
+}
+
+// DeriveTicketSeed derives a signing seed from a shared secret, for
+// deployments without their own ticket key
+func DeriveTicketSeed(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ticketAudience))
+	return mac.Sum(nil)
+}
+
+// PublicKey is the key that verifies tickets
+func (s *TicketSigner) PublicKey() ed25519.PublicKey {
+	return s.public
+}
+
+// ticketClaims uses short names to keep the QR code small
+type ticketClaims struct {
+	BusID       string   `json:"bus"`
+	ScheduleID  string   `json:"sch,omitempty"`
+	Seats       int      `json:"n"`
+	SeatNumbers []string `json:"sn,omitempty"`
+	PassengerID string   `json:"px,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Sign returns the ticket's token
+func (s *TicketSigner) Sign(ticket *models.Ticket) (string, error) {
+	claims := ticketClaims{
+		BusID:       ticket.BusID,
+		Seats:       ticket.Seats,
+		SeatNumbers: ticket.SeatNumbers,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        ticket.BookingID,
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			ExpiresAt: jwt.NewNumericDate(ticket.ExpiresAt),
+		},
+	}
+	if ticket.ScheduleID != nil {
+		claims.ScheduleID = *ticket.ScheduleID
+	}
+	if ticket.PassengerID != nil {
+		claims.PassengerID = *ticket.PassengerID
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(s.private)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign ticket: %w", err)
+	}
+	return token, nil
+}
+
+// Verify returns the ticket in a token signed by Sign, as it was when it was
+// scanned at the given time. Scans collected offline are checked against
+// when they were made rather than when they arrive.
+func (s *TicketSigner) Verify(token string, at time.Time) (*models.Ticket, error) {
+	var claims ticketClaims
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	parsed, err := parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return s.public, nil
+	})
+	if err != nil || !parsed.Valid || !claims.VerifyAudience(ticketAudience, true) ||
+		claims.ID == "" || claims.ExpiresAt == nil {
+		return nil, errors.ErrTicketInvalid
+	}
+	if !at.Before(claims.ExpiresAt.Time) {
+		return nil, errors.ErrTicketExpired
+	}
+
+	ticket := &models.Ticket{
+		BookingID:   claims.ID,
+		BusID:       claims.BusID,
+		Seats:       claims.Seats,
+		SeatNumbers: claims.SeatNumbers,
+		Token:       token,
+		ExpiresAt:   claims.ExpiresAt.Time,
+	}
+	if claims.ScheduleID != "" {
+		ticket.ScheduleID = &claims.ScheduleID
+	}
+	if claims.PassengerID != "" {
+		ticket.PassengerID = &claims.PassengerID
+	}
+	return ticket, nil
+}
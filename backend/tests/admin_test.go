@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/handlers"
+	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sentEmails records queued emails instead of sending them
+type sentEmails struct {
+	to []string
+}
+
+func (s *sentEmails) QueueEmail(email, subject, body string) {
+	s.to = append(s.to, email)
+}
+
+// pendingOperator inserts an operator awaiting review with its owner, and
+// returns the operator and owner ids
+func pendingOperator(t *testing.T) (operatorID, ownerID string) {
+	operatorID, ownerID = uuid.New().String(), uuid.New().String()
+
+	_, err := testDB.Exec(`
+		INSERT INTO users (id, email, password_hash, first_name, last_name)
+		VALUES ($1, $2, 'hashed_password', 'Test', 'Owner')
+	`, ownerID, ownerID+"@example.com")
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO bus_operators (id, user_id, name, contact_info, email, status)
+		VALUES ($1, $2, 'Pending Company', 'pending@example.com', 'pending@example.com', 'pending')
+	`, operatorID, ownerID)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO operator_members (operator_id, user_id, email, role, status, accepted_at)
+		VALUES ($1, $2, $3, 'owner', 'active', NOW())
+	`, operatorID, ownerID, ownerID+"@example.com")
+	require.NoError(t, err)
+
+	return operatorID, ownerID
+}
+
+func TestOperatorActiveRequiredAfterApproval(t *testing.T) {
+	operatorID, ownerID := pendingOperator(t)
+	adminID := uuid.New().String()
+	_, err := testDB.Exec(`
+		INSERT INTO users (id, email, password_hash, first_name, last_name, role)
+		VALUES ($1, $2, 'hashed_password', 'Test', 'Admin', 'admin')
+	`, adminID, adminID+"@example.com")
+	require.NoError(t, err)
+
+	mailer := &sentEmails{}
+	adminHandler := handlers.NewAdminHandler(testDB, audit.NewAuditService(testDB), mailer)
+
+	router := setupTestRouter()
+	router.GET("/op/buses", func(c *gin.Context) {
+		c.Set("user_id", ownerID)
+	}, middleware.OperatorMemberRequired(testDB), middleware.OperatorActiveRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	router.POST("/admin/operators/:id/approve", func(c *gin.Context) {
+		c.Set("user_id", adminID)
+	}, middleware.AdminRequired(testDB), adminHandler.ApproveOperator)
+
+	operatorRequest := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/op/buses", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	approve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/operators/"+operatorID+"/approve", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// pending operators are refused
+	w := operatorRequest()
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "pending", body["operator_status"])
+
+	// approval waits on both documents
+	assert.Equal(t, http.StatusConflict, approve().Code)
+	for _, documentType := range []string{"business_registration", "ntsa_licence"} {
+		_, err := testDB.Exec(`
+			INSERT INTO operator_kyc_documents (operator_id, document_type, file_path, status, reviewed_by, reviewed_at)
+			VALUES ($1, $2, 'kyc/test.pdf', 'approved', $3, NOW())
+		`, operatorID, documentType, adminID)
+		require.NoError(t, err)
+	}
+
+	w = approve()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"pending@example.com"}, mailer.to)
+
+	// and let in once approved
+	assert.Equal(t, http.StatusOK, operatorRequest().Code)
+}
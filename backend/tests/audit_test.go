@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{
+			name:   "changed field",
+			before: `{"capacity":30,"status":"active"}`,
+			after:  `{"capacity":33,"status":"active"}`,
+			want:   `{"capacity":{"from":30,"to":33}}`,
+		},
+		{
+			name:  "create",
+			after: `{"status":"active"}`,
+			want:  `{"status":{"from":null,"to":"active"}}`,
+		},
+		{
+			name:   "removed field",
+			before: `{"reason":"late","status":"suspended"}`,
+			after:  `{"status":"active"}`,
+			want:   `{"reason":{"from":"late","to":null},"status":{"from":"suspended","to":"active"}}`,
+		},
+		{
+			name:   "no change",
+			before: `{"status":"active"}`,
+			after:  `{"status":"active"}`,
+			want:   ``,
+		},
+		{
+			name:   "not objects",
+			before: `1`,
+			after:  `2`,
+			want:   `{"value":{"from":1,"to":2}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after audit.RawJSON
+			if tt.before != "" {
+				before = audit.RawJSON(tt.before)
+			}
+			if tt.after != "" {
+				after = audit.RawJSON(tt.after)
+			}
+
+			diff, err := audit.Diff(before, after)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(diff))
+		})
+	}
+}
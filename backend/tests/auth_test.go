@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/Mvoii/zurura/internal/handlers"
+	"github.com/Mvoii/zurura/internal/services/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,7 +22,7 @@ func setupTestRouter() *gin.Engine {
 
 func TestRegister(t *testing.T) {
 	router := setupTestRouter()
-	handler := handlers.NewAuthHandler(testDB)
+	handler := handlers.NewAuthHandler(testDB, ratelimit.NewMemoryLockoutStore())
 
 	tests := []struct {
 		name           string
@@ -86,7 +87,7 @@ func TestRegister(t *testing.T) {
 
 func TestLogin(t *testing.T) {
 	router := setupTestRouter()
-	handler := handlers.NewAuthHandler(testDB)
+	handler := handlers.NewAuthHandler(testDB, ratelimit.NewMemoryLockoutStore())
 
 	tests := []struct {
 		name           string
@@ -145,7 +146,7 @@ func TestLogin(t *testing.T) {
 
 func TestLogout(t *testing.T) {
 	router := setupTestRouter()
-	handler := handlers.NewAuthHandler(testDB)
+	handler := handlers.NewAuthHandler(testDB, ratelimit.NewMemoryLockoutStore())
 
 	tests := []struct {
 		name           string
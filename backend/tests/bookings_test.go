@@ -17,7 +17,7 @@ import (
 func TestCreateBooking(t *testing.T) {
 	router := setupTestRouter()
 	paymentService := payments.NewMockPaymentService()
-	bookingService := booking.NewBookingService(testDB, paymentService)
+	bookingService := booking.NewBookingService(testDB, paymentService, nil)
 	handler := handlers.NewBookingHandler(testDB, bookingService)
 
 	tests := []struct {
@@ -89,7 +89,7 @@ func TestCreateBooking(t *testing.T) {
 func TestCancelBooking(t *testing.T) {
 	router := setupTestRouter()
 	paymentService := payments.NewMockPaymentService()
-	bookingService := booking.NewBookingService(testDB, paymentService)
+	bookingService := booking.NewBookingService(testDB, paymentService, nil)
 	handler := handlers.NewBookingHandler(testDB, bookingService)
 
 	tests := []struct {
@@ -131,3 +131,54 @@ func TestCancelBooking(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitSeats(t *testing.T) {
+	seats := models.SeatMap{SeatNumbers: []string{"A1", "A2", "A3"}, Count: 3}
+
+	remaining, cancelled, err := booking.SplitSeats(seats, 0, []string{"A2"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.SeatMap{SeatNumbers: []string{"A1", "A3"}, Count: 2}, remaining)
+	assert.Equal(t, models.SeatMap{SeatNumbers: []string{"A2"}, Count: 1}, cancelled)
+
+	remaining, cancelled, err = booking.SplitSeats(seats, 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SeatMap{SeatNumbers: []string{"A1"}, Count: 1}, remaining)
+	assert.Equal(t, models.SeatMap{SeatNumbers: []string{"A2", "A3"}, Count: 2}, cancelled)
+
+	remaining, cancelled, err = booking.SplitSeats(seats, 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, remaining.Count)
+	assert.Equal(t, seats, cancelled)
+
+	// bookings made by count only have no seat numbers
+	remaining, cancelled, err = booking.SplitSeats(models.SeatMap{Count: 4}, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, remaining.Count)
+	assert.Equal(t, 1, cancelled.Count)
+
+	for name, tt := range map[string]struct {
+		count       int
+		seatNumbers []string
+	}{
+		"seat not on booking": {seatNumbers: []string{"B1"}},
+		"seat given twice":    {seatNumbers: []string{"A1", "A1"}},
+		"count mismatch":      {count: 2, seatNumbers: []string{"A1"}},
+		"more than booked":    {count: 4},
+		"negative count":      {count: -1},
+	} {
+		_, _, err := booking.SplitSeats(seats, tt.count, tt.seatNumbers)
+		assert.Error(t, err, name)
+	}
+
+	_, _, err = booking.SplitSeats(models.SeatMap{}, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestSeatRefund(t *testing.T) {
+	assert.Equal(t, 50.0, booking.SeatRefund(150, 3, 1))
+	assert.Equal(t, 33.33, booking.SeatRefund(100, 3, 1))
+	// the last seat gets what rounding left behind
+	assert.Equal(t, 33.34, booking.SeatRefund(100-33.33-33.33, 1, 1))
+	assert.Equal(t, 100.0, booking.SeatRefund(100, 3, 3))
+	assert.Equal(t, 0.0, booking.SeatRefund(0, 2, 1))
+}
@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/cancellation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancellationRefundPercent(t *testing.T) {
+	departs := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	// full refund up to an hour before, half within the hour, nothing after departure
+	policy := &models.CancellationPolicy{Rules: []models.CancellationRule{
+		{MinMinutesBefore: 60, RefundPercent: 100},
+		{MinMinutesBefore: 0, RefundPercent: 50},
+	}}
+
+	tests := []struct {
+		name      string
+		policy    *models.CancellationPolicy
+		departsAt *time.Time
+		at        time.Time
+		want      float64
+	}{
+		{name: "no policy", policy: nil, departsAt: &departs, at: departs.Add(time.Hour), want: 100},
+		{name: "well before", policy: policy, departsAt: &departs, at: departs.Add(-3 * time.Hour), want: 100},
+		{name: "an hour before", policy: policy, departsAt: &departs, at: departs.Add(-time.Hour), want: 100},
+		{name: "within the hour", policy: policy, departsAt: &departs, at: departs.Add(-59 * time.Minute), want: 50},
+		{name: "at departure", policy: policy, departsAt: &departs, at: departs, want: 50},
+		{name: "after departure", policy: policy, departsAt: &departs, at: departs.Add(time.Second), want: 0},
+		{name: "unknown departure", policy: policy, departsAt: nil, at: departs, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cancellation.RefundPercent(tt.policy, tt.departsAt, tt.at))
+		})
+	}
+
+	// a negative cutoff covers cancellations after departure
+	late := &models.CancellationPolicy{Rules: []models.CancellationRule{
+		{MinMinutesBefore: 0, RefundPercent: 80},
+		{MinMinutesBefore: -15, RefundPercent: 25},
+	}}
+	assert.Equal(t, 25.0, cancellation.RefundPercent(late, &departs, departs.Add(10*time.Minute)))
+	assert.Equal(t, 0.0, cancellation.RefundPercent(late, &departs, departs.Add(20*time.Minute)))
+}
+
+func TestNoShowDue(t *testing.T) {
+	departs := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	policy := &models.CancellationPolicy{NoShowGraceMinutes: 30}
+
+	assert.False(t, cancellation.NoShowDue(nil, departs, departs.Add(time.Hour)))
+	assert.False(t, cancellation.NoShowDue(policy, departs, departs.Add(29*time.Minute)))
+	assert.True(t, cancellation.NoShowDue(policy, departs, departs.Add(30*time.Minute)))
+}
+
+func TestCancellationRefund(t *testing.T) {
+	assert.Equal(t, 50.0, cancellation.Refund(100, 50))
+	assert.Equal(t, 33.33, cancellation.Refund(66.66, 50))
+	assert.Equal(t, 0.0, cancellation.Refund(100, 0))
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/fares"
+	"github.com/stretchr/testify/assert"
+)
+
+func clock(s string) *string {
+	return &s
+}
+
+func TestFareMultiplierApplies(t *testing.T) {
+	// Monday 19 October 2026, 07:30 in Nairobi
+	monday := time.Date(2026, 10, 19, 7, 30, 0, 0, fares.EAT)
+	weekdays := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name string
+		m    models.FareMultiplier
+		at   time.Time
+		want bool
+	}{
+		{name: "all day", m: models.FareMultiplier{Active: true}, at: monday, want: true},
+		{name: "inactive", m: models.FareMultiplier{}, at: monday, want: false},
+		{name: "morning peak", m: models.FareMultiplier{Active: true, DaysOfWeek: weekdays, StartTime: clock("06:00"), EndTime: clock("09:00")}, at: monday, want: true},
+		{name: "peak read in EAT", m: models.FareMultiplier{Active: true, StartTime: clock("06:00"), EndTime: clock("09:00")}, at: monday.UTC(), want: true},
+		{name: "end is exclusive", m: models.FareMultiplier{Active: true, StartTime: clock("06:00"), EndTime: clock("07:30")}, at: monday, want: false},
+		{name: "weekend only", m: models.FareMultiplier{Active: true, DaysOfWeek: []int{0, 6}}, at: monday, want: false},
+		{name: "past midnight", m: models.FareMultiplier{Active: true, StartTime: clock("22:00"), EndTime: clock("05:00")}, at: monday.Add(-5 * time.Hour), want: true},
+		{name: "outside overnight window", m: models.FareMultiplier{Active: true, StartTime: clock("22:00"), EndTime: clock("05:00")}, at: monday, want: false},
+		{name: "not started", m: models.FareMultiplier{Active: true, StartsAt: timePtr(monday.Add(time.Hour))}, at: monday, want: false},
+		{name: "ended", m: models.FareMultiplier{Active: true, EndsAt: timePtr(monday)}, at: monday, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fares.MultiplierApplies(tt.m, tt.at))
+		})
+	}
+}
+
+func TestPickFareMultiplier(t *testing.T) {
+	at := time.Date(2026, 10, 19, 8, 0, 0, 0, fares.EAT)
+	operatorID, routeID := "operator", "route"
+
+	platform := models.FareMultiplier{ID: "platform", Active: true, Multiplier: 2}
+	operator := models.FareMultiplier{ID: "operator", Active: true, Multiplier: 1.2, OperatorID: &operatorID}
+	operatorPeak := models.FareMultiplier{ID: "operator-peak", Active: true, Multiplier: 1.5, OperatorID: &operatorID}
+	route := models.FareMultiplier{ID: "route", Active: true, Multiplier: 1.1, OperatorID: &operatorID, RouteID: &routeID}
+	offPeak := models.FareMultiplier{ID: "off-peak", Active: true, Multiplier: 3, OperatorID: &operatorID, RouteID: &routeID,
+		StartTime: clock("10:00"), EndTime: clock("16:00")}
+
+	assert.Nil(t, fares.PickMultiplier(nil, at))
+	assert.Equal(t, "platform", fares.PickMultiplier([]models.FareMultiplier{platform}, at).ID)
+	assert.Equal(t, "operator-peak", fares.PickMultiplier([]models.FareMultiplier{platform, operator, operatorPeak}, at).ID)
+	assert.Equal(t, "route", fares.PickMultiplier([]models.FareMultiplier{platform, operatorPeak, route, offPeak}, at).ID)
+}
+
+func TestClockMinutes(t *testing.T) {
+	minutes, err := fares.ClockMinutes("07:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 450, minutes)
+
+	for _, bad := range []string{"7", "24:00", "07:60", "07:5", "ab:cd"} {
+		_, err := fares.ClockMinutes(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFareLineItems(t *testing.T) {
+	items := fares.LineItems(fares.Fare{PerSeat: 70, Description: "Fare CBD to Westlands"}, 2)
+	assert.Equal(t, []models.BookingLineItem{
+		{Type: "base_fare", Description: "Fare CBD to Westlands x 2", Amount: 140},
+	}, items)
+
+	items = fares.LineItems(fares.Fare{
+		PerSeat:     50,
+		Description: "Base fare",
+		Multiplier:  &models.FareMultiplier{Name: "Morning peak", Multiplier: 1.5},
+	}, 3)
+	assert.Equal(t, []models.BookingLineItem{
+		{Type: "base_fare", Description: "Base fare x 3", Amount: 150},
+		{Type: "fare_multiplier", Description: "Morning peak (x1.5)", Amount: 75},
+	}, items)
+}
+
+func TestFareQuoteToken(t *testing.T) {
+	signer := fares.NewQuoteSigner("secret")
+	quote := &models.FareQuote{
+		ID:                "quote",
+		BusID:             "bus",
+		RouteID:           "route",
+		BoardingStopName:  "Kencom",
+		AlightingStopName: "Westlands",
+		Seats:             2,
+		LineItems: []models.BookingLineItem{
+			{Type: "base_fare", Description: "Base fare x 2", Amount: 100},
+			{Type: "fare_multiplier", Description: "Morning peak (x1.5)", Amount: 50},
+		},
+		Total:     150,
+		ExpiresAt: time.Now().Add(fares.QuoteLockPeriod).Truncate(time.Second),
+	}
+
+	token, err := signer.Sign(quote)
+	assert.NoError(t, err)
+
+	verified, err := signer.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, quote.LineItems, verified.LineItems)
+	assert.Equal(t, quote.Total, verified.Total)
+	assert.Equal(t, quote.Seats, verified.Seats)
+	assert.True(t, quote.ExpiresAt.Equal(verified.ExpiresAt))
+
+	// a cheaper payload under the original signature is rejected
+	cheaper := *quote
+	cheaper.LineItems = []models.BookingLineItem{{Type: "base_fare", Description: "Base fare x 2", Amount: 1}}
+	cheaper.Total = 1
+	cheaperToken, err := signer.Sign(&cheaper)
+	assert.NoError(t, err)
+	original := strings.Split(token, ".")
+	tampered := strings.Split(cheaperToken, ".")
+	_, err = signer.Verify(tampered[0] + "." + tampered[1] + "." + original[2])
+	assert.Equal(t, errors.ErrQuoteInvalid, err)
+
+	_, err = fares.NewQuoteSigner("other").Verify(token)
+	assert.Equal(t, errors.ErrQuoteInvalid, err)
+
+	_, err = signer.Verify("not a token")
+	assert.Equal(t, errors.ErrQuoteInvalid, err)
+
+	quote.ExpiresAt = time.Now().Add(-time.Minute)
+	expired, err := signer.Sign(quote)
+	assert.NoError(t, err)
+	_, err = signer.Verify(expired)
+	assert.Equal(t, errors.ErrQuoteExpired, err)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
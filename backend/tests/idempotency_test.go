@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/services/idempotency"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyFingerprint(t *testing.T) {
+	body := []byte(`{"bus_id":"bus","seats":{"count":1}}`)
+	fingerprint := idempotency.Fingerprint("POST", "/a/v1/bookings", body)
+
+	assert.Len(t, fingerprint, 64)
+	assert.Equal(t, fingerprint, idempotency.Fingerprint("POST", "/a/v1/bookings", body))
+	assert.NotEqual(t, fingerprint, idempotency.Fingerprint("POST", "/a/v1/bookings", []byte(`{"bus_id":"bus","seats":{"count":2}}`)))
+	assert.NotEqual(t, fingerprint, idempotency.Fingerprint("POST", "/a/v1/passes", body))
+	assert.NotEqual(t,
+		idempotency.Fingerprint("POST", "/a/v1/bookings/1/cancel", nil),
+		idempotency.Fingerprint("POST", "/a/v1/bookings/2/cancel", nil))
+}
+
+// idempotentRouter runs the middleware in front of a handler that counts its
+// calls and answers with the status it is given
+func idempotentRouter(userID string, status *int, calls *int) *gin.Engine {
+	router := setupTestRouter()
+	svc := idempotency.NewIdempotencyService(testDB)
+	router.POST("/bookings", func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}, middleware.Idempotency(svc), func(c *gin.Context) {
+		*calls++
+		c.JSON(*status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func idempotentRequest(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/bookings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	userID := uuid.New().String()
+	defer testDB.Exec(`DELETE FROM idempotency_keys WHERE scope = $1`, userID)
+	status, calls := http.StatusCreated, 0
+	router := idempotentRouter(userID, &status, &calls)
+
+	first := idempotentRequest(router, "key-1", `{"seats": 1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// a retry gets the first response, the handler doesn't run again
+	retry := idempotentRequest(router, "key-1", `{"seats": 1}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// the key reused for a different request
+	other := idempotentRequest(router, "key-1", `{"seats": 2}`)
+	assert.Equal(t, http.StatusConflict, other.Code)
+	assert.Empty(t, other.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, calls)
+
+	// a new key is a new request
+	assert.Equal(t, http.StatusCreated, idempotentRequest(router, "key-2", `{"seats": 2}`).Code)
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyReleaseOnServerError(t *testing.T) {
+	userID := uuid.New().String()
+	defer testDB.Exec(`DELETE FROM idempotency_keys WHERE scope = $1`, userID)
+	status, calls := http.StatusInternalServerError, 0
+	router := idempotentRouter(userID, &status, &calls)
+
+	assert.Equal(t, http.StatusInternalServerError, idempotentRequest(router, "key-1", `{}`).Code)
+	var kept int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM idempotency_keys WHERE scope = $1`, userID).Scan(&kept))
+	assert.Zero(t, kept)
+
+	// the retry runs again rather than replaying the error
+	status = http.StatusCreated
+	retry := idempotentRequest(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeepsServerErrorAfterCharge(t *testing.T) {
+	userID := uuid.New().String()
+	defer testDB.Exec(`DELETE FROM idempotency_keys WHERE scope = $1`, userID)
+	calls := 0
+	router := setupTestRouter()
+	router.POST("/bookings", func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}, middleware.Idempotency(idempotency.NewIdempotencyService(testDB)), func(c *gin.Context) {
+		calls++
+		// charged, then the commit failed
+		idempotency.Charged(c.Request.Context())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, idempotentRequest(router, "key-1", `{}`).Code)
+	retry := idempotentRequest(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusInternalServerError, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyTakeover(t *testing.T) {
+	userID := uuid.New().String()
+	defer testDB.Exec(`DELETE FROM idempotency_keys WHERE scope = $1`, userID)
+	status, calls := http.StatusCreated, 0
+	router := idempotentRouter(userID, &status, &calls)
+	fingerprint := idempotency.Fingerprint("POST", "/bookings", []byte(`{}`))
+
+	// another request is still running with the key
+	_, err := testDB.Exec(`
+		INSERT INTO idempotency_keys (scope, key, fingerprint) VALUES ($1, 'running', $2)
+	`, userID, fingerprint)
+	require.NoError(t, err)
+	w := idempotentRequest(router, "running", `{}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, 0, calls)
+
+	// the request holding the key died more than the lock timeout ago
+	_, err = testDB.Exec(`
+		INSERT INTO idempotency_keys (scope, key, fingerprint, locked_at)
+		VALUES ($1, 'stale', $2, NOW() - INTERVAL '2 minutes')
+	`, userID, fingerprint)
+	require.NoError(t, err)
+	w = idempotentRequest(router, "stale", `{}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 1, calls)
+
+	// a running request refreshing its key keeps it
+	_, err = testDB.Exec(`
+		INSERT INTO idempotency_keys (scope, key, fingerprint, locked_at)
+		VALUES ($1, 'refreshed', $2, NOW() - INTERVAL '2 minutes')
+	`, userID, fingerprint)
+	require.NoError(t, err)
+	require.NoError(t, idempotency.NewIdempotencyService(testDB).Refresh(context.Background(), userID, "refreshed"))
+	assert.Equal(t, http.StatusConflict, idempotentRequest(router, "refreshed", `{}`).Code)
+	assert.Equal(t, 1, calls)
+
+	var stored sql.NullInt64
+	require.NoError(t, testDB.QueryRow(`
+		SELECT status_code FROM idempotency_keys WHERE scope = $1 AND key = 'stale'
+	`, userID).Scan(&stored))
+	assert.Equal(t, int64(http.StatusCreated), stored.Int64)
+}
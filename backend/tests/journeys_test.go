@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTransfers(t *testing.T) {
+	start := time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC)
+	legs := func(transferStop string, wait time.Duration) []models.ItineraryLeg {
+		return []models.ItineraryLeg{
+			{BoardingStopName: "Campus", AlightingStopName: "Kencom", DepartsAt: start, ArrivesAt: start.Add(30 * time.Minute)},
+			{BoardingStopName: transferStop, AlightingStopName: "Westlands", DepartsAt: start.Add(30*time.Minute + wait), ArrivesAt: start.Add(time.Hour + wait)},
+		}
+	}
+
+	ok := legs("kencom", 10*time.Minute)
+	assert.NoError(t, booking.CheckTransfers(ok))
+	assert.Equal(t, 10, ok[1].TransferWait)
+
+	assert.Error(t, booking.CheckTransfers(legs("Archives", 10*time.Minute)), "legs must meet at a stop")
+	assert.Error(t, booking.CheckTransfers(legs("Kencom", 2*time.Minute)), "no time to change buses")
+	assert.Error(t, booking.CheckTransfers(legs("Kencom", time.Hour)), "too long a wait")
+}
+
+func TestRankItineraries(t *testing.T) {
+	at := time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC)
+	leg := func(schedule string, departs, arrives int) models.ItineraryLeg {
+		return models.ItineraryLeg{
+			ScheduleID: schedule,
+			DepartsAt:  at.Add(time.Duration(departs) * time.Minute),
+			ArrivesAt:  at.Add(time.Duration(arrives) * time.Minute),
+		}
+	}
+	itinerary := func(legs ...models.ItineraryLeg) models.Itinerary {
+		return models.Itinerary{
+			Legs:      legs,
+			DepartsAt: legs[0].DepartsAt,
+			ArrivesAt: legs[len(legs)-1].ArrivesAt,
+			Transfers: len(legs) - 1,
+		}
+	}
+
+	ranked := booking.RankItineraries([]models.Itinerary{
+		itinerary(leg("slow", 0, 90)),
+		itinerary(leg("a", 0, 30), leg("b", 40, 60)),
+		itinerary(leg("direct", 10, 60)),
+		itinerary(leg("early", 0, 60)),
+		// the same trips changing at another stop
+		itinerary(leg("a", 0, 35), leg("b", 45, 60)),
+	}, 10)
+
+	var order []string
+	for _, it := range ranked {
+		order = append(order, it.Legs[0].ScheduleID)
+	}
+	// earliest arrival, then fewest transfers, then latest departure
+	assert.Equal(t, []string{"direct", "early", "a", "slow"}, order)
+
+	assert.Len(t, booking.RankItineraries(ranked, 2), 2)
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/ledger"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerRejectsBadEntries(t *testing.T) {
+	svc := ledger.NewLedgerService(nil)
+	passID := uuid.New().String()
+	pass := ledger.PassAccount(passID)
+	operator := ledger.OperatorAccount(uuid.New().String())
+
+	invalid := []struct {
+		name  string
+		entry ledger.Entry
+	}{
+		{"no postings", ledger.Entry{Kind: "ride", PassID: passID}},
+		{"one posting", ledger.Entry{Kind: "ride", PassID: passID, Postings: []ledger.Posting{
+			{Account: pass, Amount: 100},
+		}}},
+		{"unbalanced", ledger.Entry{Kind: "ride", PassID: passID, Postings: []ledger.Posting{
+			{Account: pass, Amount: 100},
+			{Account: operator, Amount: -99},
+		}}},
+		{"zero posting", ledger.Entry{Kind: "ride", PassID: passID, Postings: []ledger.Posting{
+			{Account: pass, Amount: 100},
+			{Account: operator, Amount: -100},
+			{Account: ledger.PassFeesAccount, Amount: 0},
+		}}},
+		{"same account twice", ledger.Entry{Kind: "ride", PassID: passID, Postings: []ledger.Posting{
+			{Account: pass, Amount: 100},
+			{Account: pass, Amount: -100},
+		}}},
+		{"pass not named", ledger.Entry{Kind: "ride", Postings: []ledger.Posting{
+			{Account: pass, Amount: 100},
+			{Account: operator, Amount: -100},
+		}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			// rejected before the transaction is touched
+			_, err := svc.Post(context.Background(), nil, tt.entry)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLedgerCents(t *testing.T) {
+	assert.Equal(t, int64(12345), ledger.Cents(123.45))
+	assert.Equal(t, int64(30), ledger.Cents(0.1+0.2))
+	assert.Equal(t, 123.45, ledger.Amount(12345))
+}
+
+// ledgerPass inserts a user with an empty prepaid pass
+func ledgerPass(t *testing.T) string {
+	userID, passID := uuid.New().String(), uuid.New().String()
+	_, err := testDB.Exec(`
+		INSERT INTO users (id, email, password_hash, first_name, last_name)
+		VALUES ($1, $2, 'hashed_password', 'Test', 'User')
+	`, userID, userID+"@example.com")
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO bus_passes (id, user_id, pass_type, balance, fee, expiration_date)
+		VALUES ($1, $2, 'prepaid', 0, 0, NOW() + INTERVAL '30 days')
+	`, passID, userID)
+	require.NoError(t, err)
+	return passID
+}
+
+func passBalance(t *testing.T, passID string) float64 {
+	var balance float64
+	require.NoError(t, testDB.QueryRow(`SELECT balance FROM bus_passes WHERE id = $1`, passID).Scan(&balance))
+	return balance
+}
+
+func TestLedgerPostKeepsPassBalance(t *testing.T) {
+	svc := ledger.NewLedgerService(testDB)
+	ctx := context.Background()
+	passID := ledgerPass(t)
+	operatorID := uuid.New().String()
+
+	tx, err := testDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// 450 paid for 500 of credit
+	result, err := svc.LoadPass(ctx, tx, "pass_purchase", passID, payments.PaymentMethodMPesa, 450, 500, "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, result.PassBalance)
+
+	// more than the pass holds
+	_, err = svc.PayFare(ctx, tx, passID, operatorID, 500.01, "", "")
+	assert.Equal(t, errors.ErrInsufficientBalance, err)
+
+	result, err = svc.PayFare(ctx, tx, passID, operatorID, 120.50, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 379.5, result.PassBalance)
+	result, err = svc.RefundFare(ctx, tx, passID, operatorID, 20.50, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 400.0, result.PassBalance)
+	result, err = svc.PayFare(ctx, tx, passID, operatorID, 400, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result.PassBalance)
+	require.NoError(t, tx.Commit())
+
+	// the cached balance and the pass account agree
+	assert.Equal(t, 0.0, passBalance(t, passID))
+	balance, err := svc.AccountBalance(ctx, ledger.PassAccount(passID))
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, balance)
+	balance, err = svc.AccountBalance(ctx, ledger.OperatorAccount(operatorID))
+	require.NoError(t, err)
+	assert.Equal(t, -500.0, balance)
+}
+
+func TestLedgerReconcileReportsDrift(t *testing.T) {
+	svc := ledger.NewLedgerService(testDB)
+	ctx := context.Background()
+	passID := ledgerPass(t)
+
+	tx, err := testDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = svc.LoadPass(ctx, tx, "pass_purchase", passID, payments.PaymentMethodMPesa, 300, 300, "", "", "")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	drift := func() *ledger.Drift {
+		report, err := svc.Reconcile(ctx)
+		require.NoError(t, err)
+		for _, d := range report.Drift {
+			if d.PassID == passID {
+				assert.False(t, report.OK)
+				return &d
+			}
+		}
+		return nil
+	}
+	assert.Nil(t, drift())
+
+	// the cached balance changed outside the ledger
+	_, err = testDB.Exec(`UPDATE bus_passes SET balance = balance + 25 WHERE id = $1`, passID)
+	require.NoError(t, err)
+	d := drift()
+	require.NotNil(t, d)
+	assert.Equal(t, ledger.PassAccount(passID), d.Account)
+	assert.Equal(t, 325.0, d.Cached)
+	assert.Equal(t, 300.0, d.Ledger)
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/services/lifecycle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookingTransitions(t *testing.T) {
+	allowed := [][2]string{
+		{lifecycle.StatusPendingPayment, lifecycle.StatusConfirmed},
+		{lifecycle.StatusPendingPayment, lifecycle.StatusExpired},
+		{lifecycle.StatusPendingPayment, lifecycle.StatusCancelled},
+		{lifecycle.StatusConfirmed, lifecycle.StatusBoarded},
+		{lifecycle.StatusConfirmed, lifecycle.StatusCancelled},
+		{lifecycle.StatusConfirmed, lifecycle.StatusNoShow},
+		{lifecycle.StatusBoarded, lifecycle.StatusCompleted},
+	}
+	for _, tr := range allowed {
+		assert.True(t, lifecycle.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+
+	denied := [][2]string{
+		{lifecycle.StatusPendingPayment, lifecycle.StatusBoarded},
+		{lifecycle.StatusConfirmed, lifecycle.StatusExpired},
+		{lifecycle.StatusConfirmed, lifecycle.StatusCompleted},
+		{lifecycle.StatusBoarded, lifecycle.StatusCancelled},
+		{lifecycle.StatusBoarded, lifecycle.StatusNoShow},
+		{lifecycle.StatusCancelled, lifecycle.StatusConfirmed},
+		{lifecycle.StatusCompleted, lifecycle.StatusCancelled},
+		{lifecycle.StatusNoShow, lifecycle.StatusBoarded},
+		{lifecycle.StatusConfirmed, lifecycle.StatusConfirmed},
+		{"pending", lifecycle.StatusConfirmed},
+	}
+	for _, tr := range denied {
+		assert.False(t, lifecycle.CanTransition(tr[0], tr[1]), "%s -> %s", tr[0], tr[1])
+	}
+
+	for _, status := range []string{lifecycle.StatusCompleted, lifecycle.StatusExpired, lifecycle.StatusCancelled, lifecycle.StatusNoShow} {
+		assert.True(t, lifecycle.Final(status), status)
+	}
+	assert.False(t, lifecycle.Final(lifecycle.StatusBoarded))
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/loyalty"
+	"github.com/stretchr/testify/assert"
+)
+
+var loyaltyTiers = []models.LoyaltyTier{
+	{Name: "Gold", ThresholdPoints: 1000, EarnMultiplier: 2, DiscountPercent: 10},
+	{Name: "Bronze", ThresholdPoints: 0, EarnMultiplier: 1},
+	{Name: "Silver", ThresholdPoints: 300, EarnMultiplier: 1.5, DiscountPercent: 5},
+}
+
+func TestLoyaltyTierFor(t *testing.T) {
+	assert.Nil(t, loyalty.TierFor(nil, 500))
+	assert.Equal(t, "Bronze", loyalty.TierFor(loyaltyTiers, 0).Name)
+	assert.Equal(t, "Bronze", loyalty.TierFor(loyaltyTiers, 299).Name)
+	assert.Equal(t, "Silver", loyalty.TierFor(loyaltyTiers, 300).Name)
+	assert.Equal(t, "Gold", loyalty.TierFor(loyaltyTiers, 5000).Name)
+}
+
+func TestLoyaltyPointsForRide(t *testing.T) {
+	programme := models.LoyaltyProgramme{PointsPerRide: 5, PointsPerShilling: 0.1}
+
+	assert.Equal(t, 10, loyalty.PointsForRide(programme, nil, 50))
+	assert.Equal(t, 15, loyalty.PointsForRide(programme, &loyaltyTiers[2], 50))
+	// fractions of a point are dropped
+	assert.Equal(t, 7, loyalty.PointsForRide(programme, nil, 29))
+}
+
+func TestLoyaltyDiscounts(t *testing.T) {
+	programme := models.LoyaltyProgramme{RedemptionValue: 0.5, MinRedeemPoints: 20, MaxRedeemPercent: 50}
+
+	t.Run("tier discount only", func(t *testing.T) {
+		items, points, err := loyalty.Discounts(programme, &loyaltyTiers[0], 100, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, points)
+		assert.Len(t, items, 1)
+		assert.Equal(t, "loyalty_tier_discount", items[0].Type)
+		assert.Equal(t, -10.0, items[0].Amount)
+	})
+
+	t.Run("redemption after tier discount", func(t *testing.T) {
+		items, points, err := loyalty.Discounts(programme, &loyaltyTiers[2], 100, 500, 40)
+		assert.NoError(t, err)
+		assert.Equal(t, 40, points)
+		assert.Len(t, items, 2)
+		assert.Equal(t, "loyalty_redemption", items[1].Type)
+		assert.Equal(t, -20.0, items[1].Amount)
+		assert.Equal(t, 40, items[1].Points)
+	})
+
+	t.Run("redemption capped at share of fare", func(t *testing.T) {
+		items, points, err := loyalty.Discounts(programme, nil, 100, 500, 500)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, points)
+		assert.Len(t, items, 1)
+		assert.Equal(t, -50.0, items[0].Amount)
+	})
+
+	t.Run("not enough points", func(t *testing.T) {
+		_, _, err := loyalty.Discounts(programme, nil, 100, 30, 40)
+		assert.Equal(t, errors.ErrInsufficientPoints, err)
+	})
+
+	t.Run("below minimum", func(t *testing.T) {
+		_, _, err := loyalty.Discounts(programme, nil, 100, 500, 10)
+		assert.Error(t, err)
+		assert.Equal(t, "INVALID_REDEMPTION", err.(*errors.BookingError).Code)
+	})
+}
@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/services/otp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Local 07xx", input: "0712345678", expected: "+254712345678"},
+		{name: "Local 01xx", input: "0110 123 456", expected: "+254110123456"},
+		{name: "Country code without plus", input: "254712345678", expected: "+254712345678"},
+		{name: "Already E.164", input: "+254 712-345-678", expected: "+254712345678"},
+		{name: "Missing leading zero", input: "712345678", expected: "+254712345678"},
+		{name: "International prefix", input: "00254712345678", expected: "+254712345678"},
+		{name: "Other country", input: "+255712345678", expected: "+255712345678"},
+		{name: "Kenyan landline", input: "0201234567", wantErr: true},
+		{name: "Too short", input: "07123", wantErr: true},
+		{name: "Letters", input: "07abc45678", wantErr: true},
+		{name: "Empty", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := otp.NormalizePhone(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, phone)
+		})
+	}
+}
+
+func TestPhoneFromJSON(t *testing.T) {
+	key := middleware.PhoneFromJSON("phone_number")
+	account := func(body string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/auth/otp/request", strings.NewReader(body))
+		return key(c)
+	}
+
+	// every spelling of a number shares one limit
+	for _, raw := range []string{"0712345678", "254712345678", "+254 712 345 678"} {
+		assert.Equal(t, "+254712345678", account(`{"phone_number": "`+raw+`"}`), raw)
+	}
+	assert.Equal(t, "not-a-phone", account(`{"phone_number": "Not-A-Phone"}`))
+}
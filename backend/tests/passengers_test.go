@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassengerSeats(t *testing.T) {
+	passengers, err := booking.PassengerSeats(nil, 2, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, passengers)
+
+	// passengers take the booked seat numbers in order
+	passengers, err = booking.PassengerSeats([]booking.Passenger{
+		{Name: " Amina "},
+		{Name: "Otieno", Child: true},
+	}, 2, []string{"A1", "A2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Amina", passengers[0].Name)
+	assert.Equal(t, "A1", passengers[0].SeatNumber)
+	assert.Equal(t, "A2", passengers[1].SeatNumber)
+	assert.True(t, passengers[1].Child)
+
+	// or pick their own, which must match the seats booked
+	passengers, err = booking.PassengerSeats([]booking.Passenger{
+		{Name: "Amina", SeatNumber: "A2"},
+		{Name: "Otieno", SeatNumber: "A1"},
+	}, 0, []string{"A1", "A2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "A2", passengers[0].SeatNumber)
+
+	// without seat numbers each passenger takes one of the seats counted
+	passengers, err = booking.PassengerSeats([]booking.Passenger{{Name: "Amina"}, {Name: "Otieno"}}, 0, nil)
+	assert.NoError(t, err)
+	assert.Len(t, passengers, 2)
+	assert.Empty(t, passengers[0].SeatNumber)
+
+	invalid := []struct {
+		name        string
+		passengers  []booking.Passenger
+		count       int
+		seatNumbers []string
+	}{
+		{"fewer passengers than seats", []booking.Passenger{{Name: "Amina"}}, 2, nil},
+		{"missing name", []booking.Passenger{{Name: " "}}, 1, nil},
+		{"some seat numbers", []booking.Passenger{{Name: "Amina", SeatNumber: "A1"}, {Name: "Otieno"}}, 2, nil},
+		{"shared seat", []booking.Passenger{{Name: "Amina", SeatNumber: "A1"}, {Name: "Otieno", SeatNumber: "A1"}}, 2, nil},
+		{"seat not booked", []booking.Passenger{{Name: "Amina", SeatNumber: "A3"}, {Name: "Otieno", SeatNumber: "A1"}}, 2, []string{"A1", "A2"}},
+		{"rider named twice", []booking.Passenger{{Name: "Amina", UserID: "user"}, {Name: "Amina", UserID: "user"}}, 2, nil},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := booking.PassengerSeats(tt.passengers, tt.count, tt.seatNumbers)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCancelledPassengers(t *testing.T) {
+	a1, a2 := "A1", "A2"
+	numbered := []models.BookingPassenger{
+		{ID: "amina", SeatNumber: &a1},
+		{ID: "otieno", SeatNumber: &a2},
+	}
+
+	count, seatNumbers := booking.PassengerCancellation(numbered[1:])
+	assert.Equal(t, 0, count)
+	assert.Equal(t, []string{"A2"}, seatNumbers)
+
+	count, seatNumbers = booking.PassengerCancellation([]models.BookingPassenger{{ID: "amina"}})
+	assert.Equal(t, 1, count)
+	assert.Empty(t, seatNumbers)
+
+	// the passengers asked for, or all of them when every seat goes
+	ids, err := booking.CancelledPassengers(numbered, models.SeatMap{Count: 2}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"amina", "otieno"}, ids)
+
+	// cancelled seats are matched to the passengers in them
+	ids, err = booking.CancelledPassengers(numbered, models.SeatMap{Count: 1, SeatNumbers: []string{"A2"}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"otieno"}, ids)
+
+	// a count alone does not say whose seats go
+	_, err = booking.CancelledPassengers(numbered, models.SeatMap{Count: 1}, false)
+	assert.Error(t, err)
+
+	ids, err = booking.CancelledPassengers(nil, models.SeatMap{Count: 1}, false)
+	assert.NoError(t, err)
+	assert.Nil(t, ids)
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/audit"
+	"github.com/Mvoii/zurura/internal/services/passes"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassEligibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		eligibility string
+		schools     []string
+		schoolName  string
+		verified    bool
+		want        bool
+	}{
+		{name: "open to anyone", eligibility: "anyone", want: true},
+		{name: "student with school", eligibility: "student", schoolName: "Strathmore", want: true},
+		{name: "student without school", eligibility: "student", want: false},
+		{name: "verified student without school", eligibility: "student", verified: true, want: true},
+		{name: "unverified student", eligibility: "verified_student", schoolName: "Strathmore", want: false},
+		{name: "verified student", eligibility: "verified_student", schoolName: "Strathmore", verified: true, want: true},
+		{
+			name:        "listed school",
+			eligibility: "student",
+			schools:     []string{"University of Nairobi", "Strathmore"},
+			schoolName:  " strathmore ",
+			want:        true,
+		},
+		{
+			name:        "other school",
+			eligibility: "student",
+			schools:     []string{"University of Nairobi"},
+			schoolName:  "Strathmore",
+			want:        false,
+		},
+		{
+			name:        "verified student at other school",
+			eligibility: "verified_student",
+			schools:     []string{"University of Nairobi"},
+			schoolName:  "Strathmore",
+			verified:    true,
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := models.PassProduct{Eligibility: tt.eligibility, SchoolNames: tt.schools}
+			assert.Equal(t, tt.want, passes.Eligible(product, tt.schoolName, tt.verified))
+		})
+	}
+}
+
+func TestPassExpiresAt(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	endsSoon := start.AddDate(0, 0, 10)
+	endsLate := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name   string
+		days   int
+		endsAt *time.Time
+		want   time.Time
+	}{
+		{name: "validity period", days: 30, want: start.AddDate(0, 0, 30)},
+		{name: "product ends first", days: 30, endsAt: &endsSoon, want: endsSoon},
+		{name: "product ends later", days: 7, endsAt: &endsLate, want: start.AddDate(0, 0, 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := &models.PassProduct{ValidityDays: tt.days, EndsAt: tt.endsAt}
+			assert.Equal(t, tt.want, passes.ExpiresAt(product, start))
+		})
+	}
+}
+
+func TestPassRenewedExpiration(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	product := &models.PassProduct{ValidityDays: 30}
+
+	tests := []struct {
+		name       string
+		expiration time.Time
+		want       time.Time
+	}{
+		{name: "renewed early", expiration: now.Add(48 * time.Hour), want: now.Add(48 * time.Hour).AddDate(0, 0, 30)},
+		{name: "just lapsed", expiration: now.Add(-48 * time.Hour), want: now.Add(-48 * time.Hour).AddDate(0, 0, 30)},
+		{name: "lapsed a whole period", expiration: now.AddDate(0, 0, -45), want: now.AddDate(0, 0, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, passes.RenewedExpiration(tt.expiration, now, product))
+		})
+	}
+}
+
+// expiringGateway charges like the mock gateway but runs expire while the
+// payment is going through, and records refunds
+type expiringGateway struct {
+	*payments.MockPaymentService
+	expire func()
+
+	mu      sync.Mutex
+	refunds []float64
+}
+
+func (g *expiringGateway) ProcessPayment(ctx context.Context, req payments.PaymentRequest) (*payments.PaymentResponse, error) {
+	resp, err := g.MockPaymentService.ProcessPayment(ctx, req)
+	g.expire()
+	return resp, err
+}
+
+func (g *expiringGateway) RefundPayment(ctx context.Context, transactionID string, amount float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refunds = append(g.refunds, amount)
+	return nil
+}
+
+func TestPassTopUpRefundsWhenPassExpires(t *testing.T) {
+	userID, passID := uuid.New().String(), uuid.New().String()
+	_, err := testDB.Exec(`
+		INSERT INTO users (id, email, password_hash, first_name, last_name)
+		VALUES ($1, $2, 'hashed_password', 'Test', 'User')
+	`, userID, userID+"@example.com")
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO bus_passes (id, user_id, pass_type, balance, fee, status, expiration_date)
+		VALUES ($1, $2, 'prepaid', 0, 0, 'active', NOW() + INTERVAL '30 days')
+	`, passID, userID)
+	require.NoError(t, err)
+
+	gateway := &expiringGateway{
+		MockPaymentService: payments.NewMockPaymentService(),
+		expire: func() {
+			_, err := testDB.Exec(`UPDATE bus_passes SET status = 'expired' WHERE id = $1`, passID)
+			require.NoError(t, err)
+		},
+	}
+	svc := passes.NewPassService(testDB, gateway, audit.NewAuditService(testDB), nil)
+
+	_, err = svc.TopUp(context.Background(), userID, passID, 200, payments.PaymentMethodMPesa)
+	assert.Equal(t, errors.ErrPassNotActive, err)
+	assert.Equal(t, []float64{200}, gateway.refunds)
+	assert.Equal(t, 0.0, passBalance(t, passID))
+
+	var recorded int
+	require.NoError(t, testDB.QueryRow(`SELECT COUNT(*) FROM payments WHERE user_id = $1`, userID).Scan(&recorded))
+	assert.Equal(t, 0, recorded)
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/planner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// two routes meeting at stops a short walk apart
+func plannerNetwork() *planner.Network {
+	stop := func(id string, lat, lng float64) planner.Stop {
+		return planner.Stop{ID: id, Name: id, Point: planner.Point{Lat: lat, Lng: lng}}
+	}
+	return planner.NewNetwork(
+		[]planner.Stop{
+			stop("Campus", -1.2800, 36.8000),
+			stop("Museum", -1.2700, 36.8000),
+			stop("Kencom", -1.2600, 36.8000),
+			stop("Archives", -1.2600, 36.8020),
+			stop("Westlands", -1.2600, 36.8300),
+		},
+		[]planner.Route{
+			{ID: "a", Name: "Campus - Kencom", BaseFare: 50, Stops: []string{"Campus", "Museum", "Kencom"},
+				Offsets: []time.Duration{0, 10 * time.Minute, 20 * time.Minute}},
+			{ID: "b", Name: "Archives - Westlands", BaseFare: 70, Stops: []string{"Archives", "Westlands"},
+				Offsets: []time.Duration{0, 15 * time.Minute}},
+		},
+	)
+}
+
+func TestPlanWithTransfer(t *testing.T) {
+	n := plannerNetwork()
+	at := time.Date(2026, 10, 20, 6, 55, 0, 0, time.UTC)
+	trip := func(id, route string, minutes int) planner.Trip {
+		return planner.Trip{ScheduleID: id, BusID: "bus-" + id, RouteID: route, Departure: at.Add(time.Duration(minutes) * time.Minute)}
+	}
+	trips := []planner.Trip{
+		trip("a1", "a", 5), trip("a2", "a", 25),
+		// a1 reaches Kencom at 7:20, too soon after for b1
+		trip("b1", "b", 30), trip("b2", "b", 40), trip("b3", "b", 60),
+	}
+
+	from := planner.Point{Lat: -1.2805, Lng: 36.8000}
+	to := planner.Point{Lat: -1.2600, Lng: 36.8300}
+	its := n.Plan(from, to, at, trips, 5)
+	require.Len(t, its, 2)
+
+	first := its[0]
+	assert.Equal(t, 1, first.Transfers)
+	assert.Equal(t, at.Add(55*time.Minute), first.ArrivesAt)
+	modes := []string{}
+	for _, leg := range first.Legs {
+		modes = append(modes, leg.Mode)
+	}
+	assert.Equal(t, []string{"walk", "bus", "walk", "bus"}, modes)
+	assert.Equal(t, "a1", first.Legs[1].ScheduleID)
+	assert.Equal(t, "Kencom", first.Legs[1].To.Name)
+	assert.Equal(t, "Archives", first.Legs[2].To.Name)
+	assert.Equal(t, "b2", first.Legs[3].ScheduleID)
+	// the walk to the first stop ends as the bus leaves
+	assert.Equal(t, first.Legs[1].DepartsAt, first.Legs[0].ArrivesAt)
+	assert.Greater(t, first.WalkMeters, 200)
+
+	// the next departure, a2 then b3
+	assert.Equal(t, "a2", its[1].Legs[1].ScheduleID)
+	assert.Equal(t, "b3", its[1].Legs[3].ScheduleID)
+}
+
+func TestPlanPrefersWalkingWhenFaster(t *testing.T) {
+	n := plannerNetwork()
+	at := time.Date(2026, 10, 20, 6, 55, 0, 0, time.UTC)
+	trips := []planner.Trip{{ScheduleID: "a1", RouteID: "a", Departure: at.Add(40 * time.Minute)}}
+
+	// Campus to Museum, about 1.1 km
+	its := n.Plan(planner.Point{Lat: -1.2800, Lng: 36.8000}, planner.Point{Lat: -1.2700, Lng: 36.8000}, at, trips, 5)
+	require.Len(t, its, 1)
+	assert.Len(t, its[0].Legs, 1)
+	assert.Equal(t, "walk", its[0].Legs[0].Mode)
+	assert.Equal(t, at, its[0].DepartsAt)
+}
+
+func TestPlanOutOfReach(t *testing.T) {
+	n := plannerNetwork()
+	at := time.Date(2026, 10, 20, 6, 55, 0, 0, time.UTC)
+
+	its := n.Plan(planner.Point{Lat: -1.3500, Lng: 36.9000}, planner.Point{Lat: -1.2600, Lng: 36.8300}, at, nil, 5)
+	assert.Empty(t, its)
+}
+
+func TestRankPlannedItineraries(t *testing.T) {
+	at := time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC)
+	it := func(schedule string, arrives, transfers, walk int) models.PlannedItinerary {
+		return models.PlannedItinerary{
+			Legs:       []models.PlannedLeg{{Mode: "bus", ScheduleID: schedule}},
+			ArrivesAt:  at.Add(time.Duration(arrives) * time.Minute),
+			Transfers:  transfers,
+			WalkMeters: walk,
+		}
+	}
+
+	ranked := planner.Rank([]models.PlannedItinerary{
+		it("late", 50, 0, 0),
+		it("change", 30, 1, 100),
+		it("direct", 30, 0, 300),
+		it("direct", 30, 0, 300),
+		it("short-walk", 30, 0, 100),
+	}, 3)
+	require.Len(t, ranked, 3)
+	assert.Equal(t, "short-walk", ranked[0].Legs[0].ScheduleID)
+	assert.Equal(t, "direct", ranked[1].Legs[0].ScheduleID)
+	assert.Equal(t, "change", ranked[2].Legs[0].ScheduleID)
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/promotions"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoDiscount(t *testing.T) {
+	maxDiscount := 30.0
+	tests := []struct {
+		name  string
+		promo models.PromoCode
+		fare  float64
+		want  float64
+	}{
+		{name: "percent", promo: models.PromoCode{DiscountType: "percent", DiscountValue: 50}, fare: 80, want: 40},
+		{name: "percent capped", promo: models.PromoCode{DiscountType: "percent", DiscountValue: 50, MaxDiscount: &maxDiscount}, fare: 80, want: 30},
+		{name: "first ride free", promo: models.PromoCode{DiscountType: "percent", DiscountValue: 100}, fare: 120, want: 120},
+		{name: "fixed", promo: models.PromoCode{DiscountType: "fixed", DiscountValue: 20}, fare: 80, want: 20},
+		{name: "fixed above fare", promo: models.PromoCode{DiscountType: "fixed", DiscountValue: 100}, fare: 80, want: 80},
+		{name: "rounded to cents", promo: models.PromoCode{DiscountType: "percent", DiscountValue: 15}, fare: 33.33, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, promotions.Discount(tt.promo, tt.fare))
+		})
+	}
+}
+
+func TestPromoNormalizeCode(t *testing.T) {
+	assert.Equal(t, "WEEKEND50", promotions.NormalizeCode(" weekend50 "))
+}
+
+// singleUsePromo inserts a promo that can be used once, and two riders
+func singleUsePromo(t *testing.T) (promoID string, riders [2]string) {
+	promoID = uuid.New().String()
+	riders = [2]string{uuid.New().String(), uuid.New().String()}
+	for _, id := range riders {
+		_, err := testDB.Exec(`
+			INSERT INTO users (id, email, password_hash, first_name, last_name)
+			VALUES ($1, $2, 'hashed_password', 'Test', 'User')
+		`, id, id+"@example.com")
+		require.NoError(t, err)
+	}
+	_, err := testDB.Exec(`
+		INSERT INTO promo_codes (id, code, discount_type, discount_value, max_redemptions)
+		VALUES ($1, $2, 'fixed', 20, 1)
+	`, promoID, "ONCE"+promoID[:8])
+	require.NoError(t, err)
+	return promoID, riders
+}
+
+func promoRedemptions(t *testing.T, promoID string) int {
+	var count int
+	require.NoError(t, testDB.QueryRow(`SELECT redemption_count FROM promo_codes WHERE id = $1`, promoID).Scan(&count))
+	return count
+}
+
+func TestPromoReserveAndRelease(t *testing.T) {
+	svc := promotions.NewPromotionService(testDB)
+	ctx := context.Background()
+	promoID, riders := singleUsePromo(t)
+	lineItem := models.BookingLineItem{Type: "promo", Amount: -20}
+
+	first := &promotions.Promotion{PromoID: promoID, LineItem: lineItem}
+	require.NoError(t, svc.Reserve(ctx, first, riders[0]))
+	assert.NotEmpty(t, first.RedemptionID)
+	assert.Equal(t, 1, promoRedemptions(t, promoID))
+
+	// the reservation counts against the cap while the first rider pays
+	second := &promotions.Promotion{PromoID: promoID, LineItem: lineItem}
+	assert.Equal(t, errors.ErrPromoExhausted, svc.Reserve(ctx, second, riders[1]))
+	assert.Empty(t, second.RedemptionID)
+
+	// handing it back frees the use, and a second release changes nothing
+	require.NoError(t, svc.Release(ctx, first))
+	require.NoError(t, svc.Release(ctx, first))
+	assert.Equal(t, 0, promoRedemptions(t, promoID))
+
+	require.NoError(t, svc.Reserve(ctx, second, riders[1]))
+	assert.Equal(t, 1, promoRedemptions(t, promoID))
+}
+
+func TestPromoReserveReclaimsAbandoned(t *testing.T) {
+	svc := promotions.NewPromotionService(testDB)
+	ctx := context.Background()
+	promoID, riders := singleUsePromo(t)
+
+	// left by a request that died an hour ago
+	_, err := testDB.Exec(`
+		INSERT INTO promo_redemptions (promo_id, user_id, amount, created_at)
+		VALUES ($1, $2, 20, NOW() - INTERVAL '1 hour')
+	`, promoID, riders[0])
+	require.NoError(t, err)
+	_, err = testDB.Exec(`UPDATE promo_codes SET redemption_count = 1 WHERE id = $1`, promoID)
+	require.NoError(t, err)
+
+	promo := &promotions.Promotion{PromoID: promoID, LineItem: models.BookingLineItem{Type: "promo", Amount: -20}}
+	require.NoError(t, svc.Reserve(ctx, promo, riders[1]))
+	assert.Equal(t, 1, promoRedemptions(t, promoID))
+
+	var reserved int
+	require.NoError(t, testDB.QueryRow(`
+		SELECT COUNT(*) FROM promo_redemptions WHERE promo_id = $1 AND booking_id IS NULL
+	`, promoID).Scan(&reserved))
+	assert.Equal(t, 1, reserved)
+}
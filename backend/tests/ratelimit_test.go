@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/services/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeToken(t *testing.T) {
+	// 10 a minute is a token every 6 seconds, up to 5
+	limit := ratelimit.Limit{Requests: 10, Per: time.Minute, Burst: 5}
+
+	tests := []struct {
+		name       string
+		limit      ratelimit.Limit
+		tokens     float64
+		elapsed    time.Duration
+		left       float64
+		allowed    bool
+		remaining  int
+		reset      time.Duration
+		retryAfter time.Duration
+	}{
+		{name: "full bucket", limit: limit, tokens: 5, left: 4, allowed: true, remaining: 4, reset: 6 * time.Second},
+		{name: "empty bucket", limit: limit, tokens: 0, left: 0, remaining: 0, reset: 30 * time.Second, retryAfter: 6 * time.Second},
+		{name: "half a token refilled", limit: limit, tokens: 0, elapsed: 3 * time.Second, left: 0.5, reset: 27 * time.Second, retryAfter: 3 * time.Second},
+		{name: "a token refilled", limit: limit, tokens: 0, elapsed: 6 * time.Second, left: 0, allowed: true, reset: 30 * time.Second},
+		{name: "refill stops at the burst", limit: limit, tokens: 2, elapsed: time.Hour, left: 4, allowed: true, remaining: 4, reset: 6 * time.Second},
+		{name: "no burst holds one period", limit: ratelimit.Limit{Requests: 3, Per: time.Second}, tokens: 3, left: 2, allowed: true, remaining: 2, reset: time.Second / 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, result := ratelimit.TakeToken(tt.tokens, tt.elapsed, tt.limit)
+			assert.InDelta(t, tt.left, left, 1e-9)
+			assert.Equal(t, tt.allowed, result.Allowed)
+			assert.Equal(t, tt.remaining, result.Remaining)
+			assert.InDelta(t, tt.reset.Seconds(), result.Reset.Seconds(), 1e-6)
+			assert.InDelta(t, tt.retryAfter.Seconds(), result.RetryAfter.Seconds(), 1e-6)
+		})
+	}
+
+	_, result := ratelimit.TakeToken(5, 0, limit)
+	assert.Equal(t, 5, result.Limit)
+}
+
+func TestLockDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		lock     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.lock, ratelimit.LockDuration(tt.failures), "%d failures", tt.failures)
+	}
+}
+
+func TestMemoryStoreBurst(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 1, Per: time.Hour, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(context.Background(), "burst", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, err := store.Take(context.Background(), "burst", limit)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	// buckets are per key
+	result, err = store.Take(context.Background(), "other", limit)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+// fixedStore answers every take with the result set for the key's kind
+type fixedStore struct {
+	ip, account ratelimit.Result
+	err         error
+}
+
+func (s fixedStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	if strings.Contains(key, ":account:") {
+		return s.account, s.err
+	}
+	return s.ip, s.err
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := middleware.RateLimitConfig{
+		Name:       "login",
+		PerIP:      ratelimit.Limit{Requests: 20, Per: time.Minute},
+		PerAccount: ratelimit.Limit{Requests: 5, Per: time.Minute},
+		AccountKey: middleware.AccountFromJSON("email"),
+	}
+	request := func(store ratelimit.Store) (*httptest.ResponseRecorder, bool) {
+		called := false
+		router := setupTestRouter()
+		router.POST("/auth/login", middleware.RateLimit(store, cfg), func(c *gin.Context) {
+			called = true
+			c.JSON(http.StatusOK, gin.H{})
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/auth/login", strings.NewReader(`{"email": "rider@example.com"}`))
+		router.ServeHTTP(w, req)
+		return w, called
+	}
+
+	// the account bucket is closer to running out
+	w, called := request(fixedStore{
+		ip:      ratelimit.Result{Allowed: true, Limit: 20, Remaining: 12, Reset: 24 * time.Second},
+		account: ratelimit.Result{Allowed: true, Limit: 5, Remaining: 2, Reset: 36 * time.Second},
+	})
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "36", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	// seconds are rounded up
+	w, called = request(fixedStore{
+		ip:      ratelimit.Result{Allowed: true, Limit: 20, Remaining: 12, Reset: 24 * time.Second},
+		account: ratelimit.Result{Allowed: false, Limit: 5, Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 11200 * time.Millisecond},
+	})
+	assert.False(t, called)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "5", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "12", w.Header().Get("Retry-After"))
+
+	// a failing store lets the request through
+	w, called = request(fixedStore{err: errors.New("connection refused")})
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
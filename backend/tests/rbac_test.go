@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/middleware"
+	"github.com/Mvoii/zurura/internal/rbac"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRolePermissions(t *testing.T) {
+	all := []rbac.Permission{
+		rbac.PermManageBuses, rbac.PermManageRoutes, rbac.PermManageSchedules, rbac.PermViewRevenue,
+		rbac.PermIssueRefunds, rbac.PermManageStaff, rbac.PermValidateTickets, rbac.PermManageVerification,
+		rbac.PermViewAudit, rbac.PermManagePricing,
+	}
+	granted := map[rbac.Role][]rbac.Permission{
+		rbac.RoleOwner: all,
+		rbac.RoleDispatcher: {
+			rbac.PermManageBuses, rbac.PermManageRoutes, rbac.PermManageSchedules, rbac.PermValidateTickets,
+		},
+		rbac.RoleConductor: {rbac.PermValidateTickets},
+		rbac.RoleAccountant: {
+			rbac.PermViewRevenue, rbac.PermIssueRefunds, rbac.PermViewAudit, rbac.PermManagePricing,
+		},
+		// unknown roles get nothing
+		"driver": nil,
+		"":       nil,
+	}
+
+	for role, perms := range granted {
+		t.Run(string(role), func(t *testing.T) {
+			assert.ElementsMatch(t, perms, rbac.Permissions(role))
+			for _, p := range all {
+				assert.Equal(t, grants(perms, p), rbac.Can(role, p), "%s", p)
+			}
+		})
+	}
+
+	assert.True(t, rbac.ValidRole("accountant"))
+	assert.False(t, rbac.ValidRole("driver"))
+	assert.False(t, rbac.ValidRole("Owner"))
+
+	// the list handed out is a copy
+	perms := rbac.Permissions(rbac.RoleConductor)
+	perms[0] = rbac.PermManageStaff
+	assert.False(t, rbac.Can(rbac.RoleConductor, rbac.PermManageStaff))
+}
+
+func grants(perms []rbac.Permission, p rbac.Permission) bool {
+	for _, granted := range perms {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPermissionRequired(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		perm           rbac.Permission
+		expectedStatus int
+	}{
+		{"owner issues refunds", "owner", rbac.PermIssueRefunds, http.StatusOK},
+		{"accountant views revenue", "accountant", rbac.PermViewRevenue, http.StatusOK},
+		{"conductor scans tickets", "conductor", rbac.PermValidateTickets, http.StatusOK},
+		{"dispatcher can't issue refunds", "dispatcher", rbac.PermIssueRefunds, http.StatusForbidden},
+		{"conductor can't manage staff", "conductor", rbac.PermManageStaff, http.StatusForbidden},
+		{"no operator role", "", rbac.PermValidateTickets, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			router := setupTestRouter()
+			router.GET("/op/resource", func(c *gin.Context) {
+				c.Set("operator_role", tt.role)
+			}, middleware.PermissionRequired(tt.perm), func(c *gin.Context) {
+				called = true
+				c.JSON(http.StatusOK, gin.H{})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/op/resource", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, called)
+		})
+	}
+}
+
+func TestOperatorMemberRequiredRejectsBadOperatorID(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/op/resource", func(c *gin.Context) {
+		c.Set("user_id", "test-user-id")
+	}, middleware.OperatorMemberRequired(testDB), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/op/resource", nil)
+	req.Header.Set("X-Operator-ID", "not-a-uuid")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/services/planner"
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineString(t *testing.T) {
+	line := `{"type": "LineString", "coordinates": [[36.8219, -1.2864], [36.8250, -1.2800, 1700]]}`
+	points, err := routes.ParseLineString([]byte(line))
+	require.NoError(t, err)
+	assert.Equal(t, []planner.Point{{Lat: -1.2864, Lng: 36.8219}, {Lat: -1.28, Lng: 36.825}}, points)
+
+	// wrapped in a feature, or a collection of one
+	feature := `{"type": "Feature", "properties": {}, "geometry": ` + line + `}`
+	points, err = routes.ParseLineString([]byte(feature))
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+	points, err = routes.ParseLineString([]byte(`{"type": "FeatureCollection", "features": [` + feature + `]}`))
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	for name, raw := range map[string]string{
+		"not json":      `LINESTRING(36.8 -1.2, 36.9 -1.3)`,
+		"a point":       `{"type": "Point", "coordinates": [36.8219, -1.2864]}`,
+		"one position":  `{"type": "LineString", "coordinates": [[36.8219, -1.2864]]}`,
+		"lat first":     `{"type": "LineString", "coordinates": [[-1.2864, 36.8219], [-1.28, 136.825]]}`,
+		"two features":  `{"type": "FeatureCollection", "features": [` + feature + `, ` + feature + `]}`,
+		"no geometry":   `{"type": "Feature", "properties": {}}`,
+		"bad positions": `{"type": "LineString", "coordinates": [[36.8219], [36.825, -1.28]]}`,
+	} {
+		_, err := routes.ParseLineString([]byte(raw))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLineStringRoundTrip(t *testing.T) {
+	points := []planner.Point{{Lat: -1.2864, Lng: 36.8219}, {Lat: -1.28, Lng: 36.825}}
+	assert.JSONEq(t, `{"type": "LineString", "coordinates": [[36.8219, -1.2864], [36.825, -1.28]]}`, string(routes.LineString(points)))
+
+	parsed, err := routes.ParseLineString(routes.LineString(points))
+	require.NoError(t, err)
+	assert.Equal(t, points, parsed)
+}
+
+func TestCleanTrace(t *testing.T) {
+	at := time.Date(2026, 10, 20, 7, 0, 0, 0, time.UTC)
+	fix := func(seconds int, lat float64) routes.TracePoint {
+		return routes.TracePoint{Point: planner.Point{Lat: lat, Lng: 36.82}, At: at.Add(time.Duration(seconds) * time.Second)}
+	}
+
+	// about 111 m per 0.001 degrees of latitude
+	points := routes.CleanTrace([]routes.TracePoint{
+		fix(0, -1.3000),
+		fix(5, -1.30001), // standing, jitter
+		fix(20, -1.2990),
+		fix(21, -1.2900), // a km in a second
+		fix(40, -1.2980),
+	})
+	assert.Equal(t, []planner.Point{{Lat: -1.3, Lng: 36.82}, {Lat: -1.299, Lng: 36.82}, {Lat: -1.298, Lng: 36.82}}, points)
+}
+
+func TestTrimTrace(t *testing.T) {
+	p := func(lat float64) planner.Point { return planner.Point{Lat: lat, Lng: 36.82} }
+	first, last := p(-1.300), p(-1.290)
+
+	// waiting at the first stop, driving, then on past the last
+	points := []planner.Point{p(-1.301), p(-1.300), p(-1.300), p(-1.297), p(-1.293), p(-1.290), p(-1.288), p(-1.290)}
+	assert.Equal(t, points[2:6], routes.TrimTrace(points, first, last))
+	assert.Empty(t, routes.TrimTrace(nil, first, last))
+}
+
+func TestStopSpacing(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	spacing := routes.StopSpacing([]*float64{f(0), f(420.4), nil, f(1500), f(1200)})
+	require.Len(t, spacing, 5)
+	assert.Nil(t, spacing[0])
+	assert.Equal(t, 420.0, *spacing[1])
+	// off the shape, and the stop after it has nothing to measure from
+	assert.Nil(t, spacing[2])
+	assert.Nil(t, spacing[3])
+	// behind the stop before it along the shape
+	assert.Nil(t, spacing[4])
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/routes"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	stopA = "6f1c1a52-0d55-4b51-9a39-000000000001"
+	stopB = "6f1c1a52-0d55-4b51-9a39-000000000002"
+	stopC = "6f1c1a52-0d55-4b51-9a39-000000000003"
+)
+
+func versionStop(id, name string, order, minutes int, active bool) models.RouteVersionStop {
+	return models.RouteVersionStop{BusStopID: id, Name: name, StopOrder: order, TravelTime: minutes, Active: active}
+}
+
+func TestValidateRouteStops(t *testing.T) {
+	valid := []routes.StopInput{
+		{StopID: stopA, TravelTime: 0, Timetable: []string{"06:30"}, Active: true},
+		{StopID: stopB, TravelTime: 30, Active: false},
+		{StopID: stopC, TravelTime: 20, Active: true},
+	}
+	// the inactive stop's time is not checked against the others
+	assert.NoError(t, routes.ValidateStops(valid))
+
+	assert.Error(t, routes.ValidateStops([]routes.StopInput{
+		{StopID: stopA, TravelTime: 0, Active: true},
+		{StopID: stopA, TravelTime: 10, Active: true},
+	}), "a stop twice")
+	assert.Error(t, routes.ValidateStops([]routes.StopInput{
+		{StopID: stopA, TravelTime: 10, Active: true},
+		{StopID: stopB, TravelTime: 5, Active: true},
+	}), "going back in time")
+	assert.Error(t, routes.ValidateStops([]routes.StopInput{
+		{StopID: stopA, TravelTime: 0, Active: true},
+		{StopID: stopB, TravelTime: 5, Active: false},
+	}), "one active stop")
+	assert.Error(t, routes.ValidateStops([]routes.StopInput{
+		{StopID: stopA, TravelTime: 0, Timetable: []string{"6.30"}, Active: true},
+		{StopID: stopB, TravelTime: 5, Active: true},
+	}), "bad timetable")
+	assert.Error(t, routes.ValidateStops([]routes.StopInput{
+		{StopID: "kencom", TravelTime: 0, Active: true},
+		{StopID: stopB, TravelTime: 5, Active: true},
+	}), "not a stop id")
+}
+
+func TestRideChange(t *testing.T) {
+	before := []models.RouteVersionStop{
+		versionStop(stopA, "Kencom", 1, 0, true),
+		versionStop(stopB, "Ngara", 2, 10, true),
+		versionStop(stopC, "Thika Road Mall", 3, 25, true),
+	}
+
+	// unchanged
+	assert.Equal(t, "", routes.RideChange(before, before, stopA, stopC))
+
+	// a stop in between closing doesn't change the ride
+	after := []models.RouteVersionStop{
+		versionStop(stopA, "Kencom", 1, 0, true),
+		versionStop(stopB, "Ngara", 2, 10, false),
+		versionStop(stopC, "Thika Road Mall", 3, 25, true),
+	}
+	assert.Equal(t, "", routes.RideChange(before, after, stopA, stopC))
+	assert.Equal(t, "Ngara is no longer served", routes.RideChange(before, after, stopA, stopB))
+
+	// a stop dropped from the route altogether
+	assert.Equal(t, "Ngara is no longer served", routes.RideChange(before, after[:1], stopB, stopC))
+
+	// reordered
+	after = []models.RouteVersionStop{
+		versionStop(stopC, "Thika Road Mall", 1, 0, true),
+		versionStop(stopA, "Kencom", 2, 25, true),
+	}
+	assert.Equal(t, "the route no longer runs from Kencom to Thika Road Mall", routes.RideChange(before, after, stopA, stopC))
+
+	// retimed
+	after = []models.RouteVersionStop{
+		versionStop(stopA, "Kencom", 1, 0, true),
+		versionStop(stopB, "Ngara", 2, 9, true),
+		versionStop(stopC, "Thika Road Mall", 3, 40, true),
+	}
+	assert.Equal(t, "arrives at Thika Road Mall 15 minutes later", routes.RideChange(before, after, stopA, stopC))
+	assert.Equal(t, "the bus now reaches Ngara 1 minute earlier and arrives at Thika Road Mall 15 minutes later",
+		routes.RideChange(before, after, stopB, stopC))
+}
@@ -14,7 +14,7 @@ import (
 
 func TestGetRouteDetails(t *testing.T) {
 	router := setupTestRouter()
-	handler := handlers.NewRouteHandler(testDB)
+	handler := handlers.NewRouteHandler(testDB, nil)
 
 	tests := []struct {
 		name           string
@@ -57,7 +57,7 @@ func TestGetRouteDetails(t *testing.T) {
 
 func TestFindNearbyStops(t *testing.T) {
 	router := setupTestRouter()
-	handler := handlers.NewRouteHandler(testDB)
+	handler := handlers.NewRouteHandler(testDB, nil)
 
 	tests := []struct {
 		name           string
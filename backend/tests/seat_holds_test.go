@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/Mvoii/zurura/internal/services/payments"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeatSelection(t *testing.T) {
+	seats, err := booking.SeatSelection(2, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, seats)
+
+	seats, err = booking.SeatSelection(0, []string{"A1", "A2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A1", "A2"}, seats)
+
+	seats, err = booking.SeatSelection(2, []string{"A1", "A2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A1", "A2"}, seats)
+
+	invalid := []struct {
+		name        string
+		count       int
+		seatNumbers []string
+	}{
+		{"nothing asked for", 0, nil},
+		{"negative count", -1, nil},
+		{"count does not match seat numbers", 3, []string{"A1", "A2"}},
+		{"duplicate seat", 0, []string{"A1", "A1"}},
+		{"empty seat number", 0, []string{"A1", ""}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := booking.SeatSelection(tt.count, tt.seatNumbers)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// seatHoldBus inserts an operator with a four seat bus on a route from Town
+// to Campus, and returns the bus and two riders
+func seatHoldBus(t *testing.T) (busID string, riders [2]string) {
+	ownerID, operatorID, routeID := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	busID = uuid.New().String()
+	riders = [2]string{uuid.New().String(), uuid.New().String()}
+
+	for _, id := range []string{ownerID, riders[0], riders[1]} {
+		_, err := testDB.Exec(`
+			INSERT INTO users (id, email, password_hash, first_name, last_name)
+			VALUES ($1, $2, 'hashed_password', 'Test', 'User')
+		`, id, id+"@example.com")
+		require.NoError(t, err)
+	}
+	_, err := testDB.Exec(`
+		INSERT INTO bus_operators (id, user_id, name, contact_info, status)
+		VALUES ($1, $2, 'Seat Hold Company', 'holds@example.com', 'active')
+	`, operatorID, ownerID)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO buses (id, operator_id, registration_plate, capacity, status)
+		VALUES ($1, $2, $3, 4, 'active')
+	`, busID, operatorID, "HOLD"+busID[:8])
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO bus_routes (id, route_name, origin, destination, base_fare, operator_id)
+		VALUES ($1, $2, 'Town', 'Campus', 100.00, $3)
+	`, routeID, "Hold Route "+routeID[:8], operatorID)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`
+		INSERT INTO bus_route_assignments (bus_id, route_id, operator_id, start_date, end_date)
+		VALUES ($1, $2, $3, NOW() - INTERVAL '1 day', NOW() + INTERVAL '30 days')
+	`, busID, routeID, operatorID)
+	require.NoError(t, err)
+
+	return busID, riders
+}
+
+func busOccupancy(t *testing.T, busID string) int {
+	var occupancy int
+	require.NoError(t, testDB.QueryRow(`SELECT current_occupancy FROM buses WHERE id = $1`, busID).Scan(&occupancy))
+	return occupancy
+}
+
+func TestSeatHoldTakesSeats(t *testing.T) {
+	svc := booking.NewBookingService(testDB, payments.NewMockPaymentService(), nil)
+	ctx := context.Background()
+	busID, riders := seatHoldBus(t)
+
+	_, err := svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[0], BusID: busID, SeatNumbers: []string{"A1", "A2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+
+	// a held seat, and more seats than are left
+	_, err = svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[1], BusID: busID, SeatNumbers: []string{"A2"}})
+	var bookingErr *errors.BookingError
+	require.ErrorAs(t, err, &bookingErr)
+	assert.Equal(t, "SEATS_UNAVAILABLE", bookingErr.Code)
+	_, err = svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[1], BusID: busID, SeatCount: 3})
+	assert.Equal(t, errors.ErrSeatsUnavailable, err)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+
+	_, err = svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[1], BusID: busID, SeatCount: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 4, busOccupancy(t, busID))
+
+	// holding again gives the rider's earlier seats back first
+	_, err = svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[0], BusID: busID, SeatCount: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, busOccupancy(t, busID))
+}
+
+func TestCreateBookingUsesSeatHold(t *testing.T) {
+	svc := booking.NewBookingService(testDB, payments.NewMockPaymentService(), nil)
+	ctx := context.Background()
+	busID, riders := seatHoldBus(t)
+
+	hold, err := svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[0], BusID: busID, SeatCount: 2})
+	require.NoError(t, err)
+
+	request := booking.CreateBookingRequest{
+		UserID:            riders[0],
+		HoldID:            hold.ID,
+		BoardingStopName:  "Town",
+		AlightingStopName: "Campus",
+		PaymentMethod:     payments.PaymentMethodMPesa,
+	}
+	created, err := svc.CreateBooking(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, busID, created.BusID)
+	assert.Equal(t, 2, created.Seats.Count)
+	// the held seats are booked, not taken a second time
+	assert.Equal(t, 2, busOccupancy(t, busID))
+
+	var status string
+	var bookingID sql.NullString
+	require.NoError(t, testDB.QueryRow(`SELECT status, booking_id FROM seat_holds WHERE id = $1`, hold.ID).Scan(&status, &bookingID))
+	assert.Equal(t, "booked", status)
+	assert.Equal(t, created.ID, bookingID.String)
+
+	// a hold books once, and only for its rider
+	_, err = svc.CreateBooking(ctx, request)
+	assert.Error(t, err)
+	request.UserID = riders[1]
+	_, err = svc.CreateBooking(ctx, request)
+	assert.Error(t, err)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+}
+
+func TestProcessSeatHoldsExpiresHolds(t *testing.T) {
+	svc := booking.NewBookingService(testDB, payments.NewMockPaymentService(), nil)
+	ctx := context.Background()
+	busID, riders := seatHoldBus(t)
+
+	hold, err := svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[0], BusID: busID, SeatNumbers: []string{"A1", "A2"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+
+	// not due yet
+	_, err = svc.ProcessSeatHolds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+
+	_, err = testDB.Exec(`UPDATE seat_holds SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, hold.ID)
+	require.NoError(t, err)
+	expired, err := svc.ProcessSeatHolds(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, expired, 1)
+
+	var status string
+	require.NoError(t, testDB.QueryRow(`SELECT status FROM seat_holds WHERE id = $1`, hold.ID).Scan(&status))
+	assert.Equal(t, "expired", status)
+	assert.Equal(t, 0, busOccupancy(t, busID))
+
+	// the seats can be held again, and the expired hold no longer books
+	_, err = svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[1], BusID: busID, SeatNumbers: []string{"A1"}})
+	require.NoError(t, err)
+	_, err = svc.CreateBooking(ctx, booking.CreateBookingRequest{
+		UserID:            riders[0],
+		HoldID:            hold.ID,
+		BoardingStopName:  "Town",
+		AlightingStopName: "Campus",
+		PaymentMethod:     payments.PaymentMethodMPesa,
+	})
+	assert.Equal(t, errors.ErrSeatHoldExpired, err)
+}
+
+func TestCreateBookingRejectsHeldSeat(t *testing.T) {
+	svc := booking.NewBookingService(testDB, payments.NewMockPaymentService(), nil)
+	ctx := context.Background()
+	busID, riders := seatHoldBus(t)
+
+	_, err := svc.HoldSeats(ctx, booking.SeatHoldRequest{UserID: riders[0], BusID: busID, SeatNumbers: []string{"A1"}})
+	require.NoError(t, err)
+
+	request := booking.CreateBookingRequest{
+		UserID:            riders[1],
+		BusID:             busID,
+		SeatNumbers:       []string{"A1"},
+		BoardingStopName:  "Town",
+		AlightingStopName: "Campus",
+		PaymentMethod:     payments.PaymentMethodMPesa,
+	}
+	_, err = svc.CreateBooking(ctx, request)
+	var bookingErr *errors.BookingError
+	require.ErrorAs(t, err, &bookingErr)
+	assert.Equal(t, "SEATS_UNAVAILABLE", bookingErr.Code)
+	assert.Equal(t, 1, busOccupancy(t, busID))
+
+	// a free seat books, and then can't be booked again
+	request.SeatNumbers = []string{"A2"}
+	_, err = svc.CreateBooking(ctx, request)
+	require.NoError(t, err)
+	_, err = svc.CreateBooking(ctx, request)
+	require.ErrorAs(t, err, &bookingErr)
+	assert.Equal(t, "SEATS_UNAVAILABLE", bookingErr.Code)
+	assert.Equal(t, 2, busOccupancy(t, busID))
+}
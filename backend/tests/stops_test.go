@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/services/stops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopSearchKey(t *testing.T) {
+	assert.Equal(t, "kencom", stops.SearchKey("KenCom"))
+	assert.Equal(t, "kencom", stops.SearchKey("Ken-Com "))
+	assert.Equal(t, "stage4", stops.SearchKey("Stage 4"))
+	assert.Equal(t, "thika", stops.SearchKey(" Thika! "))
+	assert.Equal(t, "", stops.SearchKey(" - "))
+}
+
+func TestWalkingDistance(t *testing.T) {
+	meters, minutes := stops.WalkingDistance(0)
+	assert.Equal(t, 0, meters)
+	assert.Equal(t, 0, minutes)
+
+	// 500 m in a straight line is 650 m on the streets, just over 9 minutes
+	meters, minutes = stops.WalkingDistance(500)
+	assert.Equal(t, 650, meters)
+	assert.Equal(t, 10, minutes)
+}
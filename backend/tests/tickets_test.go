@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mvoii/zurura/internal/errors"
+	"github.com/Mvoii/zurura/internal/models"
+	"github.com/Mvoii/zurura/internal/services/tickets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicketToken(t *testing.T) {
+	signer, err := tickets.NewTicketSigner(tickets.DeriveTicketSeed("secret"))
+	assert.NoError(t, err)
+
+	scheduleID := "schedule"
+	expires := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	ticket := &models.Ticket{
+		BookingID:   "booking",
+		BusID:       "bus",
+		ScheduleID:  &scheduleID,
+		Seats:       2,
+		SeatNumbers: []string{"A1", "A2"},
+		ExpiresAt:   expires,
+	}
+
+	token, err := signer.Sign(ticket)
+	assert.NoError(t, err)
+
+	verified, err := signer.Verify(token, expires.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "booking", verified.BookingID)
+	assert.Equal(t, "bus", verified.BusID)
+	assert.Equal(t, &scheduleID, verified.ScheduleID)
+	assert.Equal(t, 2, verified.Seats)
+	assert.Equal(t, []string{"A1", "A2"}, verified.SeatNumbers)
+	assert.Nil(t, verified.PassengerID)
+
+	// scans are checked against when they were made, not when they arrive
+	_, err = signer.Verify(token, expires)
+	assert.Equal(t, errors.ErrTicketExpired, err)
+
+	// a ticket for another bus under the original signature is rejected
+	ticket.BusID = "other-bus"
+	forged, err := signer.Sign(ticket)
+	assert.NoError(t, err)
+	original := strings.Split(token, ".")
+	tampered := strings.Split(forged, ".")
+	_, err = signer.Verify(tampered[0]+"."+tampered[1]+"."+original[2], expires.Add(-time.Hour))
+	assert.Equal(t, errors.ErrTicketInvalid, err)
+
+	other, err := tickets.NewTicketSigner(tickets.DeriveTicketSeed("other"))
+	assert.NoError(t, err)
+	_, err = other.Verify(token, expires.Add(-time.Hour))
+	assert.Equal(t, errors.ErrTicketInvalid, err)
+
+	_, err = signer.Verify("not a ticket", expires.Add(-time.Hour))
+	assert.Equal(t, errors.ErrTicketInvalid, err)
+
+	_, err = tickets.NewTicketSigner([]byte("short"))
+	assert.Error(t, err)
+}
+
+func TestPassengerTicketToken(t *testing.T) {
+	signer, err := tickets.NewTicketSigner(tickets.DeriveTicketSeed("secret"))
+	assert.NoError(t, err)
+
+	passengerID := "passenger"
+	expires := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+	token, err := signer.Sign(&models.Ticket{
+		BookingID:   "booking",
+		BusID:       "bus",
+		Seats:       1,
+		SeatNumbers: []string{"A2"},
+		PassengerID: &passengerID,
+		Passenger:   "Wanjiru",
+		ExpiresAt:   expires,
+	})
+	assert.NoError(t, err)
+
+	verified, err := signer.Verify(token, expires.Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "booking", verified.BookingID)
+	assert.Equal(t, &passengerID, verified.PassengerID)
+	assert.Equal(t, 1, verified.Seats)
+	assert.Equal(t, []string{"A2"}, verified.SeatNumbers)
+}
@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/Mvoii/zurura/internal/services/booking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitlistOffers(t *testing.T) {
+	tests := []struct {
+		name   string
+		free   int
+		wanted []int
+		want   int
+	}{
+		{"no free seats", 0, []int{1, 1}, 0},
+		{"empty waitlist", 3, nil, 0},
+		{"one seat to the first rider", 1, []int{1, 1}, 1},
+		{"several riders", 4, []int{1, 2, 1, 1}, 3},
+		{"exact fit", 3, []int{2, 1}, 2},
+		{"head wants more than is free", 2, []int{3, 1, 1}, 0},
+		{"nobody skips ahead", 3, []int{2, 2, 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, booking.WaitlistOffers(tt.free, tt.wanted))
+		})
+	}
+}